@@ -235,7 +235,7 @@ func loadOptions(category reflect.Value, optsType types.Object, pkg *packages.Pa
 			return nil, err
 		}
 
-		typ := typesField.Type().String()
+		typ := types.TypeString(typesField.Type(), types.RelativeTo(pkg.Types))
 		if _, ok := enums[typesField.Type()]; ok {
 			typ = "enum"
 		}
@@ -245,7 +245,7 @@ func loadOptions(category reflect.Value, optsType types.Object, pkg *packages.Pa
 		if m, ok := typesField.Type().Underlying().(*types.Map); ok {
 			e, ok := enums[m.Key()]
 			if ok {
-				typ = strings.Replace(typ, m.Key().String(), m.Key().Underlying().String(), 1)
+				typ = strings.Replace(typ, types.TypeString(m.Key(), types.RelativeTo(pkg.Types)), m.Key().Underlying().String(), 1)
 			}
 			keys, err := collectEnumKeys(name, m, reflectField, e)
 			if err != nil {
@@ -54,23 +54,36 @@ type unit = struct{}
 // It shares state such as parsed files and imports, to optimize type-checking
 // for packages with overlapping dependency graphs.
 type typeCheckBatch struct {
-	activePackageCache interface {
-		getActivePackage(id PackageID) *Package
-		setActivePackage(id PackageID, pkg *Package)
-	}
-	syntaxIndex map[PackageID]int // requested ID -> index in ids
-	pre         preTypeCheck
-	post        postTypeCheck
-	handles     map[PackageID]*packageHandle
-	parseCache  *parseCache
-	fset        *token.FileSet // describes all parsed or imported files
-	cpulimit    chan unit      // concurrency limiter for CPU-bound operations
+	activePackageCache activePackageCache
+	syntaxIndex        map[PackageID]int // requested ID -> index in ids
+	pre                preTypeCheck
+	post               postTypeCheck
+	handles            map[PackageID]*packageHandle
+	parseCache         *parseCache
+	fset               *token.FileSet // describes all parsed or imported files
+	cpulimit           chan unit      // concurrency limiter for CPU-bound operations
 
 	mu             sync.Mutex
 	syntaxPackages map[PackageID]*futurePackage // results of processing a requested package; may hold (nil, nil)
 	importPackages map[PackageID]*futurePackage // package results to use for importing
 }
 
+// An activePackageCache holds the type-checked packages of open files.
+type activePackageCache interface {
+	getActivePackage(id PackageID) *Package
+	setActivePackage(id PackageID, pkg *Package)
+}
+
+// An inactivePackageCache is an activePackageCache that records active
+// packages but never returns them.
+type inactivePackageCache struct{ s *Snapshot }
+
+func (c inactivePackageCache) getActivePackage(id PackageID) *Package { return nil }
+
+func (c inactivePackageCache) setActivePackage(id PackageID, pkg *Package) {
+	c.s.setActivePackage(id, pkg)
+}
+
 // A futurePackage is a future result of type checking or importing a package,
 // to be cached in a map.
 //
@@ -289,7 +302,7 @@ func (s *Snapshot) resolveImportGraph() (*importGraph, error) {
 		return lastImportGraph, nil
 	}
 
-	b, err := s.forEachPackageInternal(ctx, nil, ids, nil, nil, nil, handles)
+	b, err := s.forEachPackageInternal(ctx, nil, ids, nil, nil, nil, handles, true)
 	if err != nil {
 		return nil, err
 	}
@@ -348,17 +361,49 @@ func (s *Snapshot) forEachPackage(ctx context.Context, ids []PackageID, pre preT
 	}
 
 	impGraph := s.getImportGraph(ctx)
-	_, err = s.forEachPackageInternal(ctx, impGraph, nil, ids, pre, post, handles)
+	_, err = s.forEachPackageInternal(ctx, impGraph, nil, ids, pre, post, handles, true)
 	return err
 }
 
+// TypeCheckProgram is like TypeCheck, but it type-checks all the
+// requested packages in a single batch, without reusing active
+// packages or the shared import graph. Consequently, a requested
+// package that imports another refers to the same *types.Package as
+// the type-checked form of the other, which is required to analyze
+// the packages as a whole, for example by constructing their SSA form.
+//
+// TypeCheckProgram is much more expensive than TypeCheck.
+func (s *Snapshot) TypeCheckProgram(ctx context.Context, ids ...PackageID) ([]*Package, error) {
+	ctx, done := event.Start(ctx, "cache.TypeCheckProgram", tag.PackageCount.Of(len(ids)))
+	defer done()
+
+	pkgs := make([]*Package, len(ids))
+	if len(ids) == 0 {
+		return pkgs, nil
+	}
+	handles, err := s.getPackageHandles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	post := func(i int, pkg *Package) {
+		pkgs[i] = pkg
+	}
+	_, err = s.forEachPackageInternal(ctx, nil, nil, ids, nil, post, handles, false)
+	return pkgs, err
+}
+
 // forEachPackageInternal is used by both forEachPackage and loadImportGraph to
 // type-check a graph of packages.
 //
 // If a non-nil importGraph is provided, imports in this graph will be reused.
-func (s *Snapshot) forEachPackageInternal(ctx context.Context, importGraph *importGraph, importIDs, syntaxIDs []PackageID, pre preTypeCheck, post postTypeCheck, handles map[PackageID]*packageHandle) (*typeCheckBatch, error) {
+// If reuseActive is set, active packages will be reused too.
+func (s *Snapshot) forEachPackageInternal(ctx context.Context, importGraph *importGraph, importIDs, syntaxIDs []PackageID, pre preTypeCheck, post postTypeCheck, handles map[PackageID]*packageHandle, reuseActive bool) (*typeCheckBatch, error) {
+	var active activePackageCache = s
+	if !reuseActive {
+		active = inactivePackageCache{s}
+	}
 	b := &typeCheckBatch{
-		activePackageCache: s,
+		activePackageCache: active,
 		pre:                pre,
 		post:               post,
 		handles:            handles,
@@ -573,7 +618,7 @@ func storePackageResults(ctx context.Context, ph *packageHandle, p *Package) {
 	toCache := map[string][]byte{
 		xrefsKind:       p.pkg.xrefs(),
 		methodSetsKind:  p.pkg.methodsets().Encode(),
-		diagnosticsKind: encodeDiagnostics(p.pkg.diagnostics),
+		diagnosticsKind: EncodeDiagnostics(p.pkg.diagnostics),
 	}
 
 	if p.metadata.PkgPath != "unsafe" { // unsafe cannot be exported
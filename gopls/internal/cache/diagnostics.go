@@ -81,6 +81,7 @@ const (
 	UpgradeNotification      DiagnosticSource = "upgrade available"
 	Vulncheck                DiagnosticSource = "vulncheck imports"
 	Govulncheck              DiagnosticSource = "govulncheck"
+	Deadcode                 DiagnosticSource = "deadcode"
 	TemplateError            DiagnosticSource = "template"
 	WorkFileError            DiagnosticSource = "go.work file"
 	ConsistencyInfo          DiagnosticSource = "consistency"
@@ -165,8 +165,8 @@ func editGoDirectiveQuickFix(moduleMode bool, uri protocol.DocumentURI, version
 	return []SuggestedFix{SuggestedFixFromCommand(cmd, protocol.QuickFix)}
 }
 
-// encodeDiagnostics gob-encodes the given diagnostics.
-func encodeDiagnostics(srcDiags []*Diagnostic) []byte {
+// EncodeDiagnostics gob-encodes the given diagnostics.
+func EncodeDiagnostics(srcDiags []*Diagnostic) []byte {
 	var gobDiags []gobDiagnostic
 	for _, srcDiag := range srcDiags {
 		var gobFixes []gobSuggestedFix
@@ -219,8 +219,8 @@ func encodeDiagnostics(srcDiags []*Diagnostic) []byte {
 	return diagnosticsCodec.Encode(gobDiags)
 }
 
-// decodeDiagnostics decodes the given gob-encoded diagnostics.
-func decodeDiagnostics(data []byte) []*Diagnostic {
+// DecodeDiagnostics decodes the given gob-encoded diagnostics.
+func DecodeDiagnostics(data []byte) []*Diagnostic {
 	var gobDiags []gobDiagnostic
 	diagnosticsCodec.Decode(data, &gobDiags)
 	var srcDiags []*Diagnostic
@@ -119,8 +119,8 @@ func TestDiagnosticEncoding(t *testing.T) {
 		},
 	}
 
-	data := encodeDiagnostics(diags)
-	diags2 := decodeDiagnostics(data)
+	data := EncodeDiagnostics(diags)
+	diags2 := DecodeDiagnostics(data)
 
 	if diff := cmp.Diff(diags, diags2); diff != "" {
 		t.Errorf("decoded diagnostics do not match (-original +decoded):\n%s", diff)
@@ -18,7 +18,6 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
-	"runtime"
 	"sort"
 	"strconv"
 	"strings"
@@ -26,7 +25,6 @@ import (
 
 	"golang.org/x/tools/parser"
 
-	"golang.org/x/sync/errgroup"
 	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/go/types/objectpath"
 	"golang.org/x/tools/gopls/internal/cache/metadata"
@@ -205,6 +203,12 @@ type Snapshot struct {
 	// gcOptimizationDetails describes the packages for which we want
 	// optimization details to be included in the diagnostics.
 	gcOptimizationDetails map[metadata.PackageID]unit
+
+	// programResults maps a key to a handle for the future result of
+	// an analysis of the whole program, such as its call graph. Since
+	// such results depend on every package, they are not inherited by
+	// clones of the snapshot.
+	programResults map[string]*memoize.Promise // *memoize.Promise[programResult]
 }
 
 var _ memoize.RefCounted = (*Snapshot)(nil) // snapshots are reference-counted
@@ -213,6 +217,55 @@ func (s *Snapshot) awaitPromise(ctx context.Context, p *memoize.Promise) (interf
 	return p.Get(ctx, s)
 }
 
+type programResult struct {
+	value interface{}
+	err   error
+}
+
+// ProgramResult returns the result of compute, an analysis of the whole
+// program of this snapshot, such as the construction of its call graph.
+// The result is computed at most once per snapshot for each key:
+// concurrent and later calls with the same key share it. The
+// computation is cancelled only if all callers awaiting it are.
+func (s *Snapshot) ProgramResult(ctx context.Context, key string, compute func(context.Context, *Snapshot) (interface{}, error)) (interface{}, error) {
+	s.mu.Lock()
+	promise, ok := s.programResults[key]
+	if !ok {
+		promise = memoize.NewPromise("programResult", func(ctx context.Context, arg interface{}) interface{} {
+			value, err := compute(ctx, arg.(*Snapshot))
+			return programResult{value, err}
+		})
+		if s.programResults == nil {
+			s.programResults = make(map[string]*memoize.Promise)
+		}
+		s.programResults[key] = promise
+	}
+	s.mu.Unlock()
+
+	v, err := s.awaitPromise(ctx, promise)
+	if err != nil {
+		return nil, err
+	}
+	res := v.(programResult)
+	return res.value, res.err
+}
+
+// CachedProgramResult returns the value of a successfully completed
+// call of ProgramResult with the specified key, without computing it.
+func (s *Snapshot) CachedProgramResult(key string) (interface{}, bool) {
+	s.mu.Lock()
+	promise := s.programResults[key]
+	s.mu.Unlock()
+	if promise == nil {
+		return nil, false
+	}
+	res, ok := promise.Cached().(programResult)
+	if !ok || res.err != nil {
+		return nil, false
+	}
+	return res.value, true
+}
+
 // Acquire prevents the snapshot from being destroyed until the returned
 // function is called.
 //
@@ -650,6 +703,8 @@ const (
 	exportDataKind  = "export"
 	diagnosticsKind = "diagnostics"
 	typerefsKind    = "typerefs"
+	symbolsKind     = "symbols"
+	symbolIndexKind = "symbolindex"
 )
 
 // PackageDiagnostics returns diagnostics for files contained in specified
@@ -674,7 +729,7 @@ func (s *Snapshot) PackageDiagnostics(ctx context.Context, ids ...PackageID) (ma
 		data, err := filecache.Get(diagnosticsKind, ph.key)
 		if err == nil { // hit
 			collect(ph.loadDiagnostics)
-			collect(decodeDiagnostics(data))
+			collect(DecodeDiagnostics(data))
 			return false
 		} else if err != filecache.ErrNotFound {
 			event.Error(ctx, "reading diagnostics from filecache", err)
@@ -1103,66 +1158,6 @@ func (s *Snapshot) isWorkspacePackage(id PackageID) bool {
 	return ok
 }
 
-// Symbols extracts and returns symbol information for every file contained in
-// a loaded package. It awaits snapshot loading.
-//
-// If workspaceOnly is set, this only includes symbols from files in a
-// workspace package. Otherwise, it returns symbols from all loaded packages.
-//
-// TODO(rfindley): move to symbols.go.
-func (s *Snapshot) Symbols(ctx context.Context, workspaceOnly bool) (map[protocol.DocumentURI][]Symbol, error) {
-	var (
-		meta []*metadata.Package
-		err  error
-	)
-	if workspaceOnly {
-		meta, err = s.WorkspaceMetadata(ctx)
-	} else {
-		meta, err = s.AllMetadata(ctx)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("loading metadata: %v", err)
-	}
-
-	goFiles := make(map[protocol.DocumentURI]struct{})
-	for _, mp := range meta {
-		for _, uri := range mp.GoFiles {
-			goFiles[uri] = struct{}{}
-		}
-		for _, uri := range mp.CompiledGoFiles {
-			goFiles[uri] = struct{}{}
-		}
-	}
-
-	// Symbolize them in parallel.
-	var (
-		group    errgroup.Group
-		nprocs   = 2 * runtime.GOMAXPROCS(-1) // symbolize is a mix of I/O and CPU
-		resultMu sync.Mutex
-		result   = make(map[protocol.DocumentURI][]Symbol)
-	)
-	group.SetLimit(nprocs)
-	for uri := range goFiles {
-		uri := uri
-		group.Go(func() error {
-			symbols, err := s.symbolize(ctx, uri)
-			if err != nil {
-				return err
-			}
-			resultMu.Lock()
-			result[uri] = symbols
-			resultMu.Unlock()
-			return nil
-		})
-	}
-	// Keep going on errors, but log the first failure.
-	// Partial results are better than no symbol results.
-	if err := group.Wait(); err != nil {
-		event.Error(ctx, "getting snapshot symbols", err)
-	}
-	return result, nil
-}
-
 // AllMetadata returns a new unordered array of metadata for
 // all packages known to this snapshot, which includes the
 // packages of all workspace modules plus their transitive
@@ -6,15 +6,25 @@ package cache
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
+	"runtime"
+	"sort"
 	"strings"
 
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/tools/gopls/internal/cache/metadata"
 	"golang.org/x/tools/gopls/internal/cache/parsego"
 	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/filecache"
 	"golang.org/x/tools/gopls/internal/protocol"
 	"golang.org/x/tools/gopls/internal/util/astutil"
+	"golang.org/x/tools/gopls/internal/util/frob"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/memoize"
 )
 
 // Symbol holds a precomputed symbol value. Note: we avoid using the
@@ -26,6 +36,301 @@ type Symbol struct {
 	Range protocol.Range
 }
 
+// FileSymbols holds the symbols declared in a Go file, along with the
+// identity of a package containing the file.
+type FileSymbols struct {
+	URI     protocol.DocumentURI
+	PkgID   PackageID
+	PkgPath PackagePath
+	PkgName PackageName
+	Symbols []Symbol
+}
+
+// Symbols extracts and returns symbol information for every file contained in
+// a loaded package. It awaits snapshot loading.
+//
+// If workspaceOnly is set, this only includes symbols from files in a
+// workspace package. Otherwise, it returns symbols from all loaded packages.
+//
+// The symbols of each package are persisted in the file cache, keyed by the
+// content of the package's files, so that later sessions need not compute
+// them again. The resulting index is persisted too, for use by
+// [Snapshot.IndexedSymbols] while a later session is loading.
+func (s *Snapshot) Symbols(ctx context.Context, workspaceOnly bool) (map[protocol.DocumentURI][]Symbol, error) {
+	files, err := s.symbolIndex(ctx, workspaceOnly)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[protocol.DocumentURI][]Symbol, len(files))
+	for _, f := range files {
+		result[f.URI] = f.Symbols
+	}
+	return result, nil
+}
+
+// IndexedSymbols returns the symbol index persisted for the snapshot's view
+// by an earlier session, and reports whether it is available.
+//
+// The index is only used until the view's initial workspace load is
+// complete; after that, callers should use [Snapshot.Symbols]. The first
+// call that returns the index also starts computing an up-to-date index in
+// the background, once the workspace is loaded, which replaces the
+// persisted one.
+func (s *Snapshot) IndexedSymbols(ctx context.Context, workspaceOnly bool) ([]FileSymbols, bool) {
+	select {
+	case <-s.view.initialWorkspaceLoad:
+		return nil, false
+	default:
+	}
+
+	data, err := filecache.Get(symbolIndexKind, s.symbolIndexKey(workspaceOnly))
+	if err != nil {
+		if err != filecache.ErrNotFound {
+			event.Error(ctx, "reading symbol index", err)
+		}
+		return nil, false
+	}
+	var files []FileSymbols
+	symbolsCodec.Decode(data, &files)
+
+	s.view.refreshSymbolIndexOnce.Do(func() {
+		release := s.Acquire()
+		go func() {
+			defer release()
+			ctx := s.BackgroundContext()
+			if _, err := s.symbolIndex(ctx, workspaceOnly); err != nil && ctx.Err() == nil {
+				event.Error(ctx, "refreshing symbol index", err)
+			}
+		}()
+	})
+	return files, true
+}
+
+// symbolIndex returns the symbols of every file contained in a loaded
+// package, or only in workspace packages if workspaceOnly is set, and
+// persists them as the symbol index of the snapshot's view.
+//
+// Each file is attributed to the first package containing it, in order of
+// package ID.
+func (s *Snapshot) symbolIndex(ctx context.Context, workspaceOnly bool) ([]FileSymbols, error) {
+	var (
+		meta []*metadata.Package
+		err  error
+	)
+	if workspaceOnly {
+		meta, err = s.WorkspaceMetadata(ctx)
+	} else {
+		meta, err = s.AllMetadata(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading metadata: %v", err)
+	}
+	sort.Slice(meta, func(i, j int) bool { return meta[i].ID < meta[j].ID })
+
+	// Skip packages whose files all belong to an earlier package,
+	// such as the test variants of a package.
+	var pkgs []*metadata.Package
+	claimed := make(map[protocol.DocumentURI]bool)
+	for _, mp := range meta {
+		novel := false
+		for _, uri := range symbolFiles(mp) {
+			if !claimed[uri] {
+				claimed[uri] = true
+				novel = true
+			}
+		}
+		if novel {
+			pkgs = append(pkgs, mp)
+		}
+	}
+
+	// Symbolize them in parallel.
+	var (
+		group   errgroup.Group
+		nprocs  = 2 * runtime.GOMAXPROCS(-1) // symbolize is a mix of I/O and CPU
+		results = make([]packageSymbolsResult, len(pkgs))
+	)
+	group.SetLimit(nprocs)
+	for i, mp := range pkgs {
+		i, mp := i, mp
+		group.Go(func() error {
+			var err error
+			results[i], err = s.packageSymbols(ctx, mp)
+			return err
+		})
+	}
+	// Keep going on errors, but log the first failure.
+	// Partial results are better than no symbol results.
+	err = group.Wait()
+	if err != nil {
+		event.Error(ctx, "getting snapshot symbols", err)
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var files []FileSymbols
+	seen := make(map[protocol.DocumentURI]bool)
+	digest := sha256.New()
+	for i, mp := range pkgs {
+		fmt.Fprintf(digest, "%s %s %s %x\n", mp.ID, mp.PkgPath, mp.Name, results[i].key)
+		for _, f := range results[i].files {
+			if !seen[f.URI] {
+				seen[f.URI] = true
+				f.PkgID, f.PkgPath, f.PkgName = mp.ID, mp.PkgPath, mp.Name
+				files = append(files, f)
+			}
+		}
+	}
+
+	// Persist the index, unless it is incomplete or unchanged.
+	if err == nil {
+		var hash file.Hash
+		digest.Sum(hash[:0])
+		s.view.symbolIndexMu.Lock()
+		changed := hash != s.view.symbolIndexDigests[workspaceOnly]
+		if s.view.symbolIndexDigests == nil {
+			s.view.symbolIndexDigests = make(map[bool]file.Hash)
+		}
+		s.view.symbolIndexDigests[workspaceOnly] = hash
+		s.view.symbolIndexMu.Unlock()
+		if changed {
+			key := s.symbolIndexKey(workspaceOnly)
+			go func() {
+				if err := filecache.Set(symbolIndexKind, key, symbolsCodec.Encode(files)); err != nil {
+					event.Error(ctx, "storing symbol index", err)
+				}
+			}()
+		}
+	}
+	return files, nil
+}
+
+// symbolIndexKey returns the file cache key of the symbol index of the
+// snapshot's view.
+func (s *Snapshot) symbolIndexKey(workspaceOnly bool) file.Hash {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "symbolindex: %s %s %s\n", s.view.folder.Dir, s.view.root, s.view.typ)
+	fmt.Fprintf(hasher, "env: %s/%s %s\n", s.view.GOOS(), s.view.GOARCH(), strings.Join(s.view.EnvOverlay(), " "))
+	fmt.Fprintf(hasher, "workspaceOnly: %t\n", workspaceOnly)
+	var hash [sha256.Size]byte
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// symbolFiles returns the sorted set of Go files of package mp whose
+// symbols are indexed.
+func symbolFiles(mp *metadata.Package) []protocol.DocumentURI {
+	var uris []protocol.DocumentURI
+	uris = append(uris, mp.GoFiles...)
+	uris = append(uris, mp.CompiledGoFiles...)
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	out := uris[:0]
+	for i, uri := range uris {
+		if i == 0 || uri != uris[i-1] {
+			out = append(out, uri)
+		}
+	}
+	return out
+}
+
+// packageSymbolsResult holds the symbols of the files of a package, along
+// with the file cache key under which they are persisted.
+type packageSymbolsResult struct {
+	key   file.Hash
+	files []FileSymbols // package identity not set
+}
+
+// packageSymbols returns the symbols of the Go files of package mp.
+//
+// Unless all the files have already been symbolized by this session, it
+// first consults the file cache, which holds the symbols of each package
+// keyed by the content of its files. Symbols read from the file cache are
+// recorded as the result of symbolizing each file, so that later
+// snapshots reuse them.
+func (s *Snapshot) packageSymbols(ctx context.Context, mp *metadata.Package) (packageSymbolsResult, error) {
+	uris := symbolFiles(mp)
+	fhs := make([]file.Handle, len(uris))
+	for i, uri := range uris {
+		fh, err := s.ReadFile(ctx, uri)
+		if err != nil {
+			return packageSymbolsResult{}, err
+		}
+		fhs[i] = fh
+	}
+	key := packageSymbolsKey(fhs)
+
+	s.mu.Lock()
+	symbolized := true
+	for _, uri := range uris {
+		if _, ok := s.symbolizeHandles.Get(uri); !ok {
+			symbolized = false
+			break
+		}
+	}
+	s.mu.Unlock()
+
+	cached := false
+	if !symbolized {
+		if data, err := filecache.Get(symbolsKind, key); err == nil {
+			var files []FileSymbols
+			symbolsCodec.Decode(data, &files)
+			if len(files) == len(fhs) {
+				cached = true
+				for i, f := range files {
+					symbols := f.Symbols
+					s.setSymbolizeHandle(fhs[i], func(context.Context, *Snapshot) ([]Symbol, error) {
+						return symbols, nil
+					})
+				}
+			}
+		} else if err != filecache.ErrNotFound {
+			event.Error(ctx, fmt.Sprintf("reading symbols for %s", mp.ID), err)
+		}
+	}
+
+	res := packageSymbolsResult{key: key}
+	for _, uri := range uris {
+		symbols, err := s.symbolize(ctx, uri)
+		if err != nil {
+			return res, err
+		}
+		res.files = append(res.files, FileSymbols{URI: uri, Symbols: symbols})
+	}
+
+	// Store the symbols computed by this session in the cache.
+	if !symbolized && !cached {
+		go func() {
+			if err := filecache.Set(symbolsKind, key, symbolsCodec.Encode(res.files)); err != nil {
+				event.Error(ctx, fmt.Sprintf("storing symbols for %s", mp.ID), err)
+			}
+		}()
+	}
+	return res, nil
+}
+
+// packageSymbolsKey returns the file cache key of the symbols of a
+// package, which depend only on the names and content of its files.
+func packageSymbolsKey(fhs []file.Handle) file.Hash {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "symbols: %d\n", len(fhs))
+	for _, fh := range fhs {
+		fmt.Fprintln(hasher, fh.Identity())
+	}
+	var hash [sha256.Size]byte
+	hasher.Sum(hash[:0])
+	return hash
+}
+
+// symbolsCodec encodes the symbols of a package, and the symbol index.
+var symbolsCodec = frob.CodecFor[[]FileSymbols]()
+
+// A symbolizeResult holds the result of symbolizing a file.
+type symbolizeResult struct {
+	symbols []Symbol
+	err     error
+}
+
 // symbolize returns the result of symbolizing the file identified by uri, using a cache.
 func (s *Snapshot) symbolize(ctx context.Context, uri protocol.DocumentURI) ([]Symbol, error) {
 
@@ -33,29 +338,15 @@ func (s *Snapshot) symbolize(ctx context.Context, uri protocol.DocumentURI) ([]S
 	entry, hit := s.symbolizeHandles.Get(uri)
 	s.mu.Unlock()
 
-	type symbolizeResult struct {
-		symbols []Symbol
-		err     error
-	}
-
 	// Cache miss?
 	if !hit {
 		fh, err := s.ReadFile(ctx, uri)
 		if err != nil {
 			return nil, err
 		}
-		type symbolHandleKey file.Hash
-		key := symbolHandleKey(fh.Identity().Hash)
-		promise, release := s.store.Promise(key, func(ctx context.Context, arg interface{}) interface{} {
-			symbols, err := symbolizeImpl(ctx, arg.(*Snapshot), fh)
-			return symbolizeResult{symbols, err}
+		entry = s.setSymbolizeHandle(fh, func(ctx context.Context, snapshot *Snapshot) ([]Symbol, error) {
+			return symbolizeImpl(ctx, snapshot, fh)
 		})
-
-		entry = promise
-
-		s.mu.Lock()
-		s.symbolizeHandles.Set(uri, entry, func(_, _ interface{}) { release() })
-		s.mu.Unlock()
 	}
 
 	// Await result.
@@ -67,6 +358,22 @@ func (s *Snapshot) symbolize(ctx context.Context, uri protocol.DocumentURI) ([]S
 	return res.symbols, res.err
 }
 
+// setSymbolizeHandle records a promise for the symbols of file fh,
+// computed by symbolize, and returns it.
+func (s *Snapshot) setSymbolizeHandle(fh file.Handle, symbolize func(context.Context, *Snapshot) ([]Symbol, error)) *memoize.Promise {
+	type symbolHandleKey file.Hash
+	key := symbolHandleKey(fh.Identity().Hash)
+	promise, release := s.store.Promise(key, func(ctx context.Context, arg interface{}) interface{} {
+		symbols, err := symbolize(ctx, arg.(*Snapshot))
+		return symbolizeResult{symbols, err}
+	})
+
+	s.mu.Lock()
+	s.symbolizeHandles.Set(fh.URI(), promise, func(_, _ interface{}) { release() })
+	s.mu.Unlock()
+	return promise
+}
+
 // symbolizeImpl reads and parses a file and extracts symbols from it.
 func symbolizeImpl(ctx context.Context, snapshot *Snapshot, fh file.Handle) ([]Symbol, error) {
 	pgfs, err := snapshot.view.parseCache.parseFiles(ctx, token.NewFileSet(), parsego.Full, false, fh)
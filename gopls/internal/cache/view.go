@@ -127,6 +127,16 @@ type View struct {
 	// Document filters are constructed once, in View.filterFunc.
 	filterFuncOnce sync.Once
 	_filterFunc    func(protocol.DocumentURI) bool // only accessed by View.filterFunc
+
+	// refreshSymbolIndexOnce guards the background refresh of the symbol
+	// index persisted by an earlier session; see Snapshot.IndexedSymbols.
+	refreshSymbolIndexOnce sync.Once
+
+	// symbolIndexMu guards symbolIndexDigests, which identify the content
+	// of the symbol indexes most recently persisted for this view, by
+	// workspaceOnly, to avoid storing the same index repeatedly.
+	symbolIndexMu      sync.Mutex
+	symbolIndexDigests map[bool]file.Hash
 }
 
 // definition implements the viewDefiner interface.
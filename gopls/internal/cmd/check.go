@@ -6,26 +6,63 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 
 	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/gopls/internal/settings"
+	"golang.org/x/tools/gopls/internal/util/slices"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/tool"
 )
 
 // check implements the check verb for gopls.
 type check struct {
+	EditFlags
 	app *Application
+
+	Fix       bool   `flag:"fix" help:"apply the suggested fixes of the diagnostics"`
+	Analyzers string `flag:"analyzers" help:"comma-separated list of the analyzers to run, instead of the enabled ones"`
 }
 
-func (c *check) Name() string      { return "check" }
-func (c *check) Parent() string    { return c.app.Name() }
-func (c *check) Usage() string     { return "<filename>" }
-func (c *check) ShortHelp() string { return "show diagnostic results for the specified file" }
+func (c *check) Name() string   { return "check" }
+func (c *check) Parent() string { return c.app.Name() }
+func (c *check) Usage() string  { return "[check-flags] <filename or package pattern>..." }
+func (c *check) ShortHelp() string {
+	return "show diagnostic results for the specified files or packages"
+}
 func (c *check) DetailedHelp(f *flag.FlagSet) {
 	fmt.Fprint(f.Output(), `
+The check command prints the diagnostics of the specified files, or of
+the files, including tests, of the packages matching the specified
+patterns.
+
 Example: show the diagnostic results of this file:
 
 	$ gopls check internal/cmd/check.go
+
+The -analyzers flag selects the analyzers to run, instead of those
+enabled by default.
+
+With the -fix flag, the suggested fixes of the diagnostics are applied,
+as directed by the -w, -d, and -l flags, and only the diagnostics
+without a fix are printed, to stderr. Only fixes that consist of edits
+are applied; of several fixes for the same diagnostic, only the
+preferred or first one is applied; and a fix whose edits conflict with
+those of a fix already applied is skipped, so running the command
+again may apply more fixes.
+
+Example: apply the fixes of the unusedparams and simplifyrange analyzers
+to the module, showing their diffs:
+
+	$ gopls check -fix -d -analyzers=unusedparams,simplifyrange ./...
+
+check-flags:
 `)
 	printFlagDefaults(f)
 }
@@ -37,17 +74,54 @@ func (c *check) Run(ctx context.Context, args ...string) error {
 		// no files, so no results
 		return nil
 	}
-	checking := map[protocol.DocumentURI]*cmdFile{}
-	var uris []protocol.DocumentURI
+	c.app.editFlags = &c.EditFlags
+
+	// Enable only the selected analyzers.
+	unknown := make(map[string]bool)
+	if c.Analyzers != "" {
+		origOptions := c.app.options
+		c.app.options = func(opts *settings.Options) {
+			origOptions(opts)
+			selected := make(map[string]bool)
+			for _, name := range strings.Split(c.Analyzers, ",") {
+				selected[strings.TrimSpace(name)] = true
+			}
+			opts.Analyses = make(map[string]bool)
+			for _, analyzers := range []map[string]*settings.Analyzer{opts.DefaultAnalyzers, opts.StaticcheckAnalyzers} {
+				for name := range analyzers {
+					opts.Analyses[name] = selected[name]
+				}
+			}
+			for name := range selected {
+				if _, ok := opts.StaticcheckAnalyzers[name]; ok {
+					opts.Staticcheck = true
+				} else if _, ok := opts.DefaultAnalyzers[name]; !ok {
+					unknown[name] = true
+				}
+			}
+		}
+	}
+
 	// now we ready to kick things off
 	conn, err := c.app.connect(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer conn.terminate(ctx)
-	for _, arg := range args {
-		uri := protocol.URIFromPath(arg)
-		uris = append(uris, uri)
+	if len(unknown) > 0 {
+		var names []string
+		for name := range unknown {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return tool.CommandLineErrorf("unknown analyzers: %s", strings.Join(names, ", "))
+	}
+	uris, err := checkFiles(ctx, conn, args)
+	if err != nil {
+		return err
+	}
+	checking := map[protocol.DocumentURI]*cmdFile{}
+	for _, uri := range uris {
 		file, err := conn.openFile(ctx, uri)
 		if err != nil {
 			return err
@@ -57,6 +131,11 @@ func (c *check) Run(ctx context.Context, args ...string) error {
 	if err := conn.diagnoseFiles(ctx, uris); err != nil {
 		return err
 	}
+
+	if c.Fix {
+		return c.fix(ctx, conn, uris, checking)
+	}
+
 	conn.client.filesMu.Lock()
 	defer conn.client.filesMu.Unlock()
 
@@ -71,3 +150,214 @@ func (c *check) Run(ctx context.Context, args ...string) error {
 	}
 	return nil
 }
+
+// checkFiles returns the files denoted by args, each of which is
+// either the name of a Go file or a package pattern. The patterns are
+// resolved by the server, so that they are interpreted with its build
+// configuration.
+func checkFiles(ctx context.Context, conn *connection, args []string) ([]protocol.DocumentURI, error) {
+	var (
+		uris     []protocol.DocumentURI
+		patterns []string
+	)
+	for _, arg := range args {
+		if strings.HasSuffix(arg, ".go") {
+			uris = append(uris, protocol.URIFromPath(arg))
+		} else {
+			patterns = append(patterns, arg)
+		}
+	}
+	if len(patterns) == 0 {
+		return uris, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := command.NewPackageFilesCommand("", command.PackageFilesArgs{
+		URI:      protocol.URIFromPath(wd),
+		Patterns: patterns,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res, err := conn.ExecuteCommand(ctx, &protocol.ExecuteCommandParams{
+		Command:   cmd.Command,
+		Arguments: cmd.Arguments,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result command.PackageFilesResult
+	data, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	seen := make(map[protocol.DocumentURI]bool)
+	for _, uri := range uris {
+		seen[uri] = true
+	}
+	for _, uri := range result.Files {
+		if !seen[uri] {
+			seen[uri] = true
+			uris = append(uris, uri)
+		}
+	}
+	return uris, nil
+}
+
+// fix applies the suggested fixes of the diagnostics of the files
+// checked, and prints the diagnostics that have none to stderr.
+func (c *check) fix(ctx context.Context, conn *connection, uris []protocol.DocumentURI, checking map[protocol.DocumentURI]*cmdFile) error {
+	sortSlice(uris)
+
+	// The edits of each fix are accepted if they can be applied
+	// together with those of the fixes already accepted, that is, if
+	// they do not overlap. Identical edits, such as those of a fix
+	// reported for both a package and its test variant, are merged.
+	accepted := make(map[protocol.DocumentURI][]diff.Edit)
+	var skipped int
+	accept := func(fix map[protocol.DocumentURI][]diff.Edit) (bool, error) {
+		merged := make(map[protocol.DocumentURI][]diff.Edit)
+		for uri, edits := range fix {
+			file := conn.client.openFile(uri)
+			if file.err != nil {
+				return false, file.err
+			}
+			all := append([]diff.Edit{}, accepted[uri]...)
+			for _, edit := range edits {
+				if !slices.Contains(all, edit) {
+					all = append(all, edit)
+				}
+			}
+			if _, err := diff.Apply(string(file.mapper.Content), all); err != nil {
+				return false, nil // conflict
+			}
+			merged[uri] = all
+		}
+		for uri, edits := range merged {
+			accepted[uri] = edits
+		}
+		return true, nil
+	}
+
+	for _, uri := range uris {
+		conn.client.filesMu.Lock()
+		file := checking[uri]
+		diagnostics := append([]protocol.Diagnostic{}, file.diagnostics...) // LSP wants non-nil slice
+		conn.client.filesMu.Unlock()
+		if len(diagnostics) == 0 {
+			continue
+		}
+
+		actions, err := conn.CodeAction(ctx, &protocol.CodeActionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Context: protocol.CodeActionContext{
+				Only:        []protocol.CodeActionKind{protocol.QuickFix},
+				Diagnostics: diagnostics,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %v", uri.Path(), err)
+		}
+
+		// Choose the preferred, or else the first, fix of each
+		// diagnostic that consists of edits.
+		type key struct {
+			rng     protocol.Range
+			message string
+		}
+		chosen := make(map[key]*protocol.CodeAction)
+		for i := range actions {
+			action := &actions[i]
+			if action.Edit == nil || action.Command != nil || len(action.Diagnostics) == 0 {
+				continue
+			}
+			for _, diag := range action.Diagnostics {
+				k := key{diag.Range, diag.Message}
+				if prev := chosen[k]; prev == nil || action.IsPreferred && !prev.IsPreferred {
+					chosen[k] = action
+				}
+			}
+		}
+
+		for _, diag := range diagnostics {
+			action := chosen[key{diag.Range, diag.Message}]
+			if action == nil {
+				spn, err := file.rangeSpan(diag.Range)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "%v: %v\n", spn, diag.Message)
+				continue
+			}
+			fix, err := fixEdits(conn, action.Edit)
+			if err != nil {
+				return err
+			}
+			if ok, err := accept(fix); err != nil {
+				return err
+			} else if !ok {
+				skipped++
+			}
+		}
+	}
+
+	// Apply the accepted edits to each file, which may include files
+	// other than those checked.
+	var edited []protocol.DocumentURI
+	for uri := range accepted {
+		edited = append(edited, uri)
+	}
+	sortSlice(edited)
+	for _, uri := range edited {
+		edits := accepted[uri]
+		file := conn.client.openFile(uri)
+		if file.err != nil {
+			return file.err
+		}
+		diff.SortEdits(edits)
+		var textEdits []protocol.TextEdit
+		for _, edit := range edits {
+			rng, err := file.mapper.OffsetRange(edit.Start, edit.End)
+			if err != nil {
+				return err
+			}
+			textEdits = append(textEdits, protocol.TextEdit{Range: rng, NewText: edit.New})
+		}
+		if err := applyTextEdits(file.mapper, textEdits, c.app.editFlags); err != nil {
+			return err
+		}
+	}
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %d fixes that conflict with others; run again to apply them\n", skipped)
+	}
+	return nil
+}
+
+// fixEdits returns the edits of a suggested fix, by file, as byte offsets.
+func fixEdits(conn *connection, edit *protocol.WorkspaceEdit) (map[protocol.DocumentURI][]diff.Edit, error) {
+	fix := make(map[protocol.DocumentURI][]diff.Edit)
+	for _, change := range edit.DocumentChanges {
+		tde := change.TextDocumentEdit
+		if tde == nil {
+			return nil, fmt.Errorf("fix %v is not a text edit", change)
+		}
+		uri := tde.TextDocument.URI
+		file := conn.client.openFile(uri)
+		if file.err != nil {
+			return nil, file.err
+		}
+		for _, edit := range protocol.AsTextEdits(tde.Edits) {
+			start, end, err := file.mapper.RangeOffsets(edit.Range)
+			if err != nil {
+				return nil, err
+			}
+			fix[uri] = append(fix[uri], diff.Edit{Start: start, End: end, New: edit.NewText})
+		}
+	}
+	return fix, nil
+}
@@ -102,6 +102,7 @@ func New(options func(*settings.Options)) *Application {
 
 		Serve: Serve{
 			RemoteListenTimeout: 1 * time.Minute,
+			SlowRequest:         debug.DefaultSlowRequestThreshold,
 		},
 	}
 	app.Serve.app = app
@@ -286,12 +287,16 @@ func (app *Application) featureCommands() []tool.Application {
 		&execute{app: app},
 		&foldingRanges{app: app},
 		&format{app: app},
+		&gcdetails{app: app},
+		&gentest{app: app},
 		&highlight{app: app},
 		&implementation{app: app},
 		&imports{app: app},
+		&index{app: app},
 		newRemote(app, ""),
 		newRemote(app, "inspect"),
 		&links{app: app},
+		&modgraph{app: app},
 		&prepareRename{app: app},
 		&references{app: app},
 		&rename{app: app},
@@ -370,6 +375,10 @@ func (c *connection) initialize(ctx context.Context, options func(*settings.Opti
 	params.Capabilities.TextDocument.SemanticTokens.Requests.Full = &protocol.Or_ClientSemanticTokensRequestOptions_full{Value: true}
 	params.Capabilities.TextDocument.SemanticTokens.TokenTypes = protocol.SemanticTypes()
 	params.Capabilities.TextDocument.SemanticTokens.TokenModifiers = protocol.SemanticModifiers()
+	// "create" operations are used to add new files, such as tests.
+	params.Capabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
+		ResourceOperations: []protocol.ResourceOperationKind{protocol.Create},
+	}
 
 	// If the subcommand has registered a progress handler, report the progress
 	// capability.
@@ -525,6 +534,11 @@ func (cli *cmdClient) applyWorkspaceEdit(edit *protocol.WorkspaceEdit) error {
 				c.RenameFile.OldURI,
 				c.RenameFile.NewURI)
 		}
+		if c.CreateFile != nil {
+			if err := cli.createFile(c.CreateFile); err != nil {
+				return err
+			}
+		}
 	}
 	sortSlice(orderedURIs)
 	for _, uri := range orderedURIs {
@@ -539,6 +553,27 @@ func (cli *cmdClient) applyWorkspaceEdit(edit *protocol.WorkspaceEdit) error {
 	return nil
 }
 
+// createFile records the creation of a new empty file by a
+// workspace edit. The file is written, like any other, by the
+// subsequent edits of its content.
+func (cli *cmdClient) createFile(op *protocol.CreateFile) error {
+	if _, err := os.Stat(op.URI.Path()); err == nil {
+		if op.Options == nil || !op.Options.Overwrite && !op.Options.IgnoreIfExists {
+			return fmt.Errorf("cannot create %s: file exists", op.URI.Path())
+		}
+		if !op.Options.Overwrite {
+			return nil
+		}
+	}
+	cli.filesMu.Lock()
+	defer cli.filesMu.Unlock()
+	cli.files[op.URI] = &cmdFile{
+		uri:    op.URI,
+		mapper: protocol.NewMapper(op.URI, nil),
+	}
+	return nil
+}
+
 func sortSlice[T constraints.Ordered](slice []T) {
 	sort.Slice(slice, func(i, j int) bool { return slice[i] < slice[j] })
 }
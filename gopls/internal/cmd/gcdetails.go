@@ -0,0 +1,157 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/gopls/internal/golang"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/server"
+	"golang.org/x/tools/gopls/internal/settings"
+	"golang.org/x/tools/internal/tool"
+)
+
+// gcdetails implements the gcdetails verb for gopls.
+type gcdetails struct {
+	app *Application
+
+	Annotations string `flag:"annotations" help:"comma-separated kinds of details to show: bounds, escape, inline, nil (default: all)"`
+}
+
+func (g *gcdetails) Name() string      { return "gcdetails" }
+func (g *gcdetails) Parent() string    { return g.app.Name() }
+func (g *gcdetails) Usage() string     { return "[gcdetails-flags] <file>" }
+func (g *gcdetails) ShortHelp() string { return "show the compiler's optimization details for a file" }
+func (g *gcdetails) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+The gcdetails command compiles the package of the specified file and
+prints the optimization decisions of the compiler for the file, such
+as escape analysis, inlining, bounds checks, and nil checks, as shown
+by the gcDetails inlay hints. Each decision is followed by the
+compiler's explanation, if any.
+
+Example: show the inlining decisions and heap allocations in a.go:
+
+	$ gopls gcdetails -annotations=inline,escape a.go
+
+gcdetails-flags:
+`)
+	printFlagDefaults(f)
+}
+
+func (g *gcdetails) Run(ctx context.Context, args ...string) error {
+	if len(args) != 1 {
+		return tool.CommandLineErrorf("gcdetails expects 1 argument (file)")
+	}
+
+	var annotations map[settings.Annotation]bool
+	if g.Annotations != "" {
+		annotations = make(map[settings.Annotation]bool)
+		for _, name := range strings.Split(g.Annotations, ",") {
+			switch a := settings.Annotation(strings.TrimSpace(name)); a {
+			case settings.Bounds, settings.Escape, settings.Inline, settings.Nil:
+				annotations[a] = true
+			default:
+				return tool.CommandLineErrorf("unknown annotation %q", name)
+			}
+		}
+	}
+
+	// Enable only the gcDetails inlay hints.
+	origOptions := g.app.options
+	g.app.options = func(opts *settings.Options) {
+		origOptions(opts)
+		opts.Hints = map[string]bool{golang.GCDetails: true}
+		if annotations != nil {
+			opts.Annotations = annotations
+		}
+	}
+
+	// The package is compiled in the background when its hints are
+	// first requested, in which case we wait for the compilation to
+	// end and request them again.
+	var (
+		mu       sync.Mutex
+		started  bool
+		token    protocol.ProgressToken
+		computed = make(chan struct{})
+	)
+	onProgress := func(p *protocol.ProgressParams) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch v := p.Value.(type) {
+		case *protocol.WorkDoneProgressBegin:
+			if v.Title == server.GCDetailsWorkTitle && !started {
+				started, token = true, p.Token
+			}
+		case *protocol.WorkDoneProgressEnd:
+			if started && p.Token == token {
+				close(computed)
+			}
+		}
+	}
+
+	conn, err := g.app.connect(ctx, onProgress)
+	if err != nil {
+		return err
+	}
+	defer conn.terminate(ctx)
+
+	from := parseSpan(args[0])
+	file, err := conn.openFile(ctx, from.URI())
+	if err != nil {
+		return err
+	}
+	params := &protocol.InlayHintParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: from.URI()},
+	}
+	hints, err := conn.InlayHint(ctx, params)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	wait := started
+	mu.Unlock()
+	if wait {
+		select {
+		case <-computed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		hints, err = conn.InlayHint(ctx, params)
+		if err != nil {
+			return err
+		}
+	}
+	sort.SliceStable(hints, func(i, j int) bool {
+		return protocol.ComparePosition(hints[i].Position, hints[j].Position) < 0
+	})
+	for _, hint := range hints {
+		sp, err := file.rangeSpan(protocol.Range{Start: hint.Position, End: hint.Position})
+		if err != nil {
+			return err
+		}
+		// The tooltip holds the complete message, followed by the
+		// explanation.
+		var lines []string
+		if hint.Tooltip != nil {
+			lines = strings.Split(fmt.Sprint(hint.Tooltip.Value), "\n")
+		}
+		if len(lines) == 0 || lines[0] == "" {
+			continue
+		}
+		fmt.Printf("%v: %s\n", sp, lines[0])
+		for _, line := range lines[1:] {
+			fmt.Printf("\t%s\n", line)
+		}
+	}
+	return nil
+}
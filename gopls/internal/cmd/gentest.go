@@ -0,0 +1,152 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"regexp"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/internal/tool"
+)
+
+// gentest implements the gentest verb for gopls.
+type gentest struct {
+	EditFlags
+	app *Application
+
+	RunTest bool `flag:"run" help:"run the generated test (requires -w)"`
+}
+
+func (g *gentest) Name() string      { return "gentest" }
+func (g *gentest) Parent() string    { return g.app.Name() }
+func (g *gentest) Usage() string     { return "[gentest-flags] <position>" }
+func (g *gentest) ShortHelp() string { return "generate a table-driven test for a function" }
+func (g *gentest) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+The gentest command adds a table-driven test of the function or
+method declared at the specified position to the corresponding
+_test.go file, creating the file if necessary. The test has a field
+for the receiver and for each parameter and result of the function.
+
+With the -run flag, the new test is run once it has been written.
+
+Example: add a test of the function declared on line 10 to a_test.go:
+
+	$ gopls gentest -w a.go:10:6
+	$ gopls gentest -w -run a.go:10:6
+
+gentest-flags:
+`)
+	printFlagDefaults(f)
+}
+
+// generatedTest matches the declaration of a generated test.
+var generatedTest = regexp.MustCompile(`(?m)^func (Test\w*)\(`)
+
+func (g *gentest) Run(ctx context.Context, args ...string) error {
+	if len(args) != 1 {
+		return tool.CommandLineErrorf("gentest expects 1 argument (position)")
+	}
+	if g.RunTest && !g.Write {
+		return tool.CommandLineErrorf("gentest -run requires -w")
+	}
+	g.app.editFlags = &g.EditFlags
+
+	cmdDone, onProgress := commandProgress()
+	conn, err := g.app.connect(ctx, onProgress)
+	if err != nil {
+		return err
+	}
+	defer conn.terminate(ctx)
+
+	from := parseSpan(args[0])
+	file, err := conn.openFile(ctx, from.URI())
+	if err != nil {
+		return err
+	}
+	loc, err := file.spanLocation(from)
+	if err != nil {
+		return err
+	}
+
+	// Compute the edits, and apply them as directed by the edit flags.
+	cmd, err := command.NewGenerateTestCommand("", command.GenerateTestArgs{
+		Location:     loc,
+		ResolveEdits: true,
+	})
+	if err != nil {
+		return err
+	}
+	res, err := conn.ExecuteCommand(ctx, &protocol.ExecuteCommandParams{
+		Command:   cmd.Command,
+		Arguments: cmd.Arguments,
+	})
+	if err != nil {
+		return err
+	}
+	// The result may be a *protocol.WorkspaceEdit, or its JSON
+	// form if the server is remote.
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	var edit protocol.WorkspaceEdit
+	if err := json.Unmarshal(data, &edit); err != nil {
+		return err
+	}
+	if err := conn.client.applyWorkspaceEdit(&edit); err != nil {
+		return err
+	}
+	if !g.RunTest {
+		return nil
+	}
+
+	// Run the new test, which is the last edit of the test file.
+	var (
+		testURI  protocol.DocumentURI
+		testName string
+		created  bool
+	)
+	for _, change := range edit.DocumentChanges {
+		if change.CreateFile != nil {
+			created = true
+		}
+		if change.TextDocumentEdit != nil {
+			testURI = change.TextDocumentEdit.TextDocument.URI
+			edits := protocol.AsTextEdits(change.TextDocumentEdit.Edits)
+			if len(edits) > 0 {
+				if m := generatedTest.FindStringSubmatch(edits[len(edits)-1].NewText); m != nil {
+					testName = m[1]
+				}
+			}
+		}
+	}
+	if testName == "" {
+		return fmt.Errorf("cannot find generated test")
+	}
+	changeType := protocol.Changed
+	if created {
+		changeType = protocol.Created
+	}
+	if err := conn.DidChangeWatchedFiles(ctx, &protocol.DidChangeWatchedFilesParams{
+		Changes: []protocol.FileEvent{{URI: testURI, Type: changeType}},
+	}); err != nil {
+		return err
+	}
+	cmd, err = command.NewRunTestsCommand("", command.RunTestsArgs{
+		URI:   testURI,
+		Tests: []string{testName},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = conn.executeCommand(ctx, cmdDone, &cmd)
+	return err
+}
@@ -0,0 +1,106 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/internal/tool"
+)
+
+// index implements the index verb for gopls.
+type index struct {
+	app *Application
+
+	Output string `flag:"o,output" help:"write the index to this file instead of standard output"`
+}
+
+func (i *index) Name() string      { return "index" }
+func (i *index) Parent() string    { return i.app.Name() }
+func (i *index) Usage() string     { return "[index-flags]" }
+func (i *index) ShortHelp() string { return "write an LSIF index of the workspace" }
+func (i *index) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+Load the workspace for the current directory, and write an index of it in
+the Language Server Index Format (LSIF), for use by code browsing tools.
+
+The index records the definitions, references, hover text and
+implementations of the symbols of the workspace packages, and the monikers
+that link them to the indexes of other modules.
+
+Example:
+  $ gopls index -o dump.lsif
+
+index-flags:
+`)
+	printFlagDefaults(f)
+}
+
+func (i *index) Run(ctx context.Context, args ...string) error {
+	if len(args) != 0 {
+		return tool.CommandLineErrorf("index expects no arguments")
+	}
+	if i.app.Remote != "" {
+		// The index is written to a file local to the server.
+		return fmt.Errorf("the index subcommand does not work with -remote")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	cmdDone, onProgress := commandProgress()
+	conn, err := i.app.connect(ctx, onProgress)
+	if err != nil {
+		return err
+	}
+	defer conn.terminate(ctx)
+
+	cmdArgs, err := command.MarshalArgs(command.IndexArgs{URI: protocol.URIFromPath(wd)})
+	if err != nil {
+		return err
+	}
+	res, err := conn.executeCommand(ctx, cmdDone, &protocol.Command{
+		Command:   command.Index.ID(),
+		Arguments: cmdArgs,
+	})
+	if err != nil {
+		return err
+	}
+	var result command.IndexResult
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	defer os.Remove(result.File)
+
+	in, err := os.Open(result.File)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var out io.Writer = os.Stdout
+	if i.Output != "" {
+		f, err := os.Create(i.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -440,6 +440,88 @@ func f() {}
 	}
 }
 
+// TestGentest tests the 'gentest' subcommand (../gentest.go).
+func TestGentest(t *testing.T) {
+	t.Parallel()
+
+	tree := writeTree(t, `
+-- go.mod --
+module example.com
+go 1.18
+
+-- a.go --
+package a
+
+type T struct{}
+
+func (t T) F(x int, _ string) (int, error) { return x, nil }
+`)
+	// no arguments
+	{
+		res := gopls(t, tree, "gentest")
+		res.checkExit(false)
+		res.checkStderr("expects 1 argument")
+	}
+	// -run without -w
+	{
+		res := gopls(t, tree, "gentest", "-run", "a.go:5:13")
+		res.checkExit(false)
+		res.checkStderr("requires -w")
+	}
+	// success
+	{
+		res := gopls(t, tree, "gentest", "-w", "a.go:5:13")
+		res.checkExit(true)
+		got, err := os.ReadFile(filepath.Join(tree, "a_test.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `package a
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestT_F(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       T
+		x       int
+		arg1    string
+		want    int
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.t.F(tt.x, tt.arg1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("T.F() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("T.F() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+`
+		if string(got) != want {
+			t.Errorf("gentest: got <<%s>>, want <<%s>>\nstderr:\n%s", got, want, res.stderr)
+		}
+	}
+	// the test already exists
+	{
+		res := gopls(t, tree, "gentest", "-w", "a.go:5:13")
+		res.checkExit(false)
+		res.checkStderr("TestT_F already exists")
+	}
+}
+
 // TestHighlight tests the 'highlight' subcommand (../highlight.go).
 func TestHighlight(t *testing.T) {
 	t.Parallel()
@@ -996,6 +1078,68 @@ func (c C) Read(p []byte) (n int, err error) {
 	}
 }
 
+// TestCheckFix tests the 'check -fix' subcommand (../check.go).
+func TestCheckFix(t *testing.T) {
+	t.Parallel()
+
+	tree := writeTree(t, `
+-- go.mod --
+module example.com
+go 1.18
+
+-- a.go --
+package a
+
+func f(s []int) []int { return s[:len(s)] }
+
+-- b/b.go --
+package b
+
+func g(x int) int {
+	return x
+	{
+		x = x
+	}
+	return x
+}
+
+-- b/b_test.go --
+package b
+
+func h(s []int) []int { return s[:len(s)] }
+`)
+
+	// apply the fixes of a file
+	{
+		res := gopls(t, tree, "check", "-fix", "-d", "a.go")
+		res.checkExit(true)
+		res.checkStdout(`(?m)^-func f\(s \[\]int\) \[\]int \{ return s\[:len\(s\)\] \}$`)
+		res.checkStdout(`(?m)^\+func f\(s \[\]int\) \[\]int \{ return s\[:\] \}$`)
+	}
+	// the removal of the self-assignment overlaps that of the
+	// unreachable block containing it, which comes first
+	{
+		res := gopls(t, tree, "check", "-fix", "-d", "b/b.go")
+		res.checkExit(true)
+		res.checkStdout(`(?m)^-\t\{\n-\t\tx = x\n-\t\}$`)
+		res.checkStderr("skipped 1 fixes that conflict with others")
+	}
+	// package patterns, including test files
+	{
+		res := gopls(t, tree, "check", "./...")
+		res.checkExit(true)
+		res.checkStdout(`a.go:.* unneeded: len\(s\)`)
+		res.checkStdout(`b.go:.* unreachable code`)
+		res.checkStdout(`b_test.go:.* unneeded: len\(s\)`)
+	}
+	// an invalid pattern
+	{
+		res := gopls(t, tree, "check", "example.com/nonesuch")
+		res.checkExit(false)
+		res.checkStderr("nonesuch")
+	}
+}
+
 // TestWorkspaceSymbol tests the 'workspace_symbol' subcommand (../workspace_symbol.go).
 func TestWorkspaceSymbol(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,167 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/internal/tool"
+)
+
+// modgraph implements the modgraph verb for gopls.
+type modgraph struct {
+	app *Application
+
+	Target   string `flag:"target" help:"plan an upgrade of the module path[@version] (default version: latest available offline)"`
+	ProxyDir string `flag:"proxydir" help:"a directory laid out as a module proxy to read go.mod files from, before the module cache"`
+	JSON     bool   `flag:"json" help:"print the result as JSON"`
+}
+
+func (m *modgraph) Name() string      { return "modgraph" }
+func (m *modgraph) Parent() string    { return m.app.Name() }
+func (m *modgraph) Usage() string     { return "[modgraph-flags] [dir]" }
+func (m *modgraph) ShortHelp() string { return "print the module graph of the workspace" }
+func (m *modgraph) DetailedHelp(f *flag.FlagSet) {
+	fmt.Fprint(f.Output(), `
+The modgraph command prints the module requirement graph of the
+workspace modules of the specified directory (default: the current
+directory), across all the modules of its go.work file, if any.
+
+Each module version is followed by its requirements, and each selected
+version by the shortest chain of requirements from a workspace module
+that explains why it is in the graph. Versions that are not selected
+are marked "(unselected)", those whose go.mod file is not available
+offline "(missing)", and those whose requirements are pruned out of the
+graph, as by the go command for modules at go 1.17 or later, "(pruned)".
+
+With the -target flag, the command also prints the minimal set of
+upgrades implied by upgrading the target module, and the requirements
+to add to each workspace module so that it selects the same versions on
+its own as in the workspace.
+
+The graph is computed offline, from the go.mod files in the -proxydir
+directory, in the file:// entries of GOPROXY, and in the module cache.
+
+Example: plan an upgrade of golang.org/x/text to v0.14.0:
+
+	$ gopls modgraph -target=golang.org/x/text@v0.14.0
+
+modgraph-flags:
+`)
+	printFlagDefaults(f)
+}
+
+func (m *modgraph) Run(ctx context.Context, args ...string) error {
+	if len(args) > 1 {
+		return tool.CommandLineErrorf("modgraph expects at most 1 argument (dir)")
+	}
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	proxyDir := m.ProxyDir
+	if proxyDir != "" {
+		if proxyDir, err = filepath.Abs(proxyDir); err != nil {
+			return err
+		}
+	}
+
+	cmdDone, onProgress := commandProgress()
+	conn, err := m.app.connect(ctx, onProgress)
+	if err != nil {
+		return err
+	}
+	defer conn.terminate(ctx)
+
+	cmdArgs, err := command.MarshalArgs(command.ModuleGraphArgs{
+		URI:      protocol.URIFromPath(dir),
+		Target:   m.Target,
+		ProxyDir: proxyDir,
+	})
+	if err != nil {
+		return err
+	}
+	res, err := conn.executeCommand(ctx, cmdDone, &protocol.Command{
+		Command:   command.ModuleGraph.ID(),
+		Arguments: cmdArgs,
+	})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	var result command.ModuleGraphResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	if m.JSON {
+		data, err := json.MarshalIndent(result, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", data)
+		return nil
+	}
+
+	for _, node := range result.Modules {
+		name := node.Path
+		if !node.Main {
+			name += "@" + node.Version
+		}
+		switch {
+		case node.Missing:
+			name += " (missing)"
+		case node.Pruned:
+			name += " (pruned)"
+		case !node.Selected:
+			name += " (unselected)"
+		}
+		fmt.Println(name)
+		for _, req := range node.Requires {
+			fmt.Printf("\trequires %s\n", req)
+		}
+		if node.Selected && !node.Main {
+			fmt.Printf("\twhy: %s\n", strings.Join(node.Why, " -> "))
+		}
+	}
+	if plan := result.Upgrade; plan != nil {
+		fmt.Printf("\nupgrade %s:\n", plan.Target)
+		if len(plan.Upgrades) == 0 {
+			fmt.Printf("\tnothing to do\n")
+		}
+		for _, u := range plan.Upgrades {
+			from := u.From
+			if from == "" {
+				from = "none"
+			}
+			fmt.Printf("\t%s %s => %s\n", u.Path, from, u.To)
+		}
+		for _, edit := range plan.Edits {
+			fmt.Printf("%s:\n", edit.URI.Path())
+			for _, req := range edit.Require {
+				fmt.Printf("\trequire %s\n", strings.Replace(req, "@", " ", 1))
+			}
+		}
+		if len(plan.Missing) > 0 {
+			fmt.Fprintf(os.Stderr, "go.mod files not available offline, the plan may be incomplete: %s\n", strings.Join(plan.Missing, ", "))
+		}
+	}
+	return nil
+}
@@ -34,6 +34,9 @@ type Serve struct {
 	IdleTimeout time.Duration `flag:"listen.timeout" help:"when used with -listen, shut down the server when there are no connected clients for this duration"`
 	Trace       bool          `flag:"rpc.trace" help:"print the full rpc trace in lsp inspector format"`
 	Debug       string        `flag:"debug" help:"serve debug information on the supplied address"`
+	Latency     bool          `flag:"latency" help:"record the latency of inbound requests, for the debug server"`
+	SaveLatency bool          `flag:"latency.save" help:"when used with -latency, save the latency report in the file cache on exit, for the stats -latency command"`
+	SlowRequest time.Duration `flag:"slowrequest" help:"when used with -latency, record inbound requests that take at least this long along with their traces (0 to disable traces)"`
 
 	RemoteListenTimeout time.Duration `flag:"remote.listen.timeout" help:"when used with -remote=auto, the -listen.timeout value used to start the daemon"`
 	RemoteDebug         string        `flag:"remote.debug" help:"when used with -remote=auto, the -debug value used to start the daemon"`
@@ -94,6 +97,10 @@ func (s *Serve) Run(ctx context.Context, args ...string) error {
 		}
 		defer closeLog()
 		di.ServerAddress = s.Address
+		if s.Latency {
+			saveLatencies := di.RecordLatencies(s.SlowRequest, s.SaveLatency)
+			defer saveLatencies()
+		}
 		di.Serve(ctx, s.Debug)
 	}
 	var ss jsonrpc2.StreamServer
@@ -19,6 +19,7 @@ import (
 	"sync"
 	"time"
 
+	"golang.org/x/tools/gopls/internal/debug"
 	"golang.org/x/tools/gopls/internal/filecache"
 	"golang.org/x/tools/gopls/internal/protocol"
 	"golang.org/x/tools/gopls/internal/protocol/command"
@@ -32,7 +33,8 @@ import (
 type stats struct {
 	app *Application
 
-	Anon bool `flag:"anon" help:"hide any fields that may contain user names, file names, or source code"`
+	Anon    bool `flag:"anon" help:"hide any fields that may contain user names, file names, or source code"`
+	Latency bool `flag:"latency" help:"instead of loading the workspace, summarize the request latency recorded by earlier gopls servers"`
 }
 
 func (s *stats) Name() string      { return "stats" }
@@ -50,8 +52,17 @@ By default, this command may include output that refers to the location or
 content of user code. When the -anon flag is set, fields that may refer to user
 code are hidden.
 
+When the -latency flag is set, the command does not load the workspace.
+Instead, it outputs a JSON summary of the latency of the requests served by
+earlier "gopls serve" processes using this executable that were run with
+the -latency and -latency.save flags: a histogram of the latencies of each
+method, and the slowest requests along with their traces. The -anon flag
+hides the traces. The -slowrequest flag of the serve command sets the latency
+at or above which a request is recorded with its trace.
+
 Example:
   $ gopls stats -anon
+  $ gopls stats -latency
 `)
 	printFlagDefaults(f)
 }
@@ -65,6 +76,10 @@ func (s *stats) Run(ctx context.Context, args ...string) error {
 		return fmt.Errorf("the stats subcommand does not work with -remote")
 	}
 
+	if s.Latency {
+		return s.latency()
+	}
+
 	if !s.app.Verbose {
 		event.SetExporter(nil) // don't log errors to stderr
 	}
@@ -218,6 +233,24 @@ func (s *stats) Run(ctx context.Context, args ...string) error {
 	return nil
 }
 
+// latency prints a summary of the latency reports persisted in the
+// file cache by gopls servers.
+func (s *stats) latency() error {
+	summary := debug.SummarizeLatency(debug.LatencyReports())
+	if s.Anon {
+		for _, req := range summary.SlowRequests {
+			req.Trace = "" // may refer to user files or code
+		}
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(data)
+	fmt.Println()
+	return nil
+}
+
 // GoplsStats holds information extracted from a gopls session in the current
 // workspace.
 //
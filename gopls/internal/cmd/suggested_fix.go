@@ -51,6 +51,7 @@ to be applied; the default set is {"quickfix"}, but valid kinds include:
 	refactor.rewrite
 	source.organizeImports
 	source.fixAll
+	source.generateTest
 
 CodeAction kinds are hierarchical, so "refactor" includes
 "refactor.inline". There is currently no way to enable or even
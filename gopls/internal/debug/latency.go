@@ -0,0 +1,473 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/gopls/internal/filecache"
+	"golang.org/x/tools/internal/event/export"
+	"golang.org/x/tools/internal/event/tag"
+)
+
+// LatencyTmpl extends BaseTemplate and renders a LatencyReport, e.g. from getData().
+var LatencyTmpl = template.Must(template.Must(BaseTemplate.Clone()).Parse(`
+{{define "title"}}Request Latency{{end}}
+{{define "body"}}
+	<H2>Inbound requests</H2>
+	{{if not .Methods}}
+	<p>
+	No requests recorded. Latency is recorded only if gopls serve is run with the -latency flag.
+	</p>
+	{{end}}
+	<table>
+	<tr><th align=left>Method</th><th>Count</th><th>Errors</th><th>Mean</th><th>Min</th><th>P50</th><th>P90</th><th>P99</th><th>Max</th></tr>
+	{{range .Methods}}
+	<tr>
+		<td><a href="/trace/{{.Method}}">{{.Method}}</a></td>
+		<td class="value">{{.Count}}</td>
+		<td class="value">{{.Errors}}</td>
+		<td class="value">{{.Mean}}</td>
+		<td class="value">{{.Min}}</td>
+		<td class="value">{{.P50}}</td>
+		<td class="value">{{.P90}}</td>
+		<td class="value">{{.P99}}</td>
+		<td class="value">{{.Max}}</td>
+	</tr>
+	<tr><td></td><td colspan=8><i>By bucket</i> {{range .Buckets}}{{if gt .Count 0}}<b>{{.Count}}</b> {{if .Limit}}&le;{{.Limit}}{{else}}slower{{end}} {{end}}{{end}}</td></tr>
+	{{end}}
+	</table>
+
+	<H2>Slow requests (slowest first)</H2>
+	<p>
+	Requests that took at least {{.Threshold}} are recorded below with their trace.
+	</p>
+	{{range .SlowRequests}}
+		<H3>{{.Method}} {{.ID}} ({{.Duration}}{{if .Error}}, failed{{end}})</H3>
+		<pre>{{.Trace}}</pre>
+	{{end}}
+{{end}}
+`))
+
+// latencyBuckets are the upper limits of the buckets of the latency
+// histogram of each method. A final bucket holds slower requests.
+//
+// The limits are the same for all reports in the file cache of a
+// given executable, so histograms can be merged bucket by bucket.
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+}
+
+const (
+	// DefaultSlowRequestThreshold is the default latency at or above
+	// which a request is recorded with its trace.
+	DefaultSlowRequestThreshold = 1 * time.Second
+
+	maxSlowRequests = 20              // number of slow requests kept, slowest first
+	maxTraceLines   = 1000            // number of lines of each recorded trace
+	latencySaveWait = 5 * time.Second // delay between an update and its persistence
+
+	latencyKind = "latency" // filecache kind for latency reports
+)
+
+// A Duration is a time.Duration whose JSON form is its string form, e.g. "1.5s".
+type Duration time.Duration
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// A LatencyReport summarizes the latency of the inbound requests of one
+// or more gopls processes.
+type LatencyReport struct {
+	PID          int       `json:",omitempty"` // zero for a summary of several processes
+	Start        time.Time // start of the (earliest) process
+	Sessions     int       `json:",omitempty"` // number of processes of a summary
+	Threshold    Duration  // latency at or above which requests were recorded
+	Methods      []*MethodLatency
+	SlowRequests []*SlowRequest
+}
+
+// A MethodLatency holds the latency histogram of the requests of one method.
+type MethodLatency struct {
+	Method        string
+	Count         int64
+	Errors        int64
+	Total         Duration
+	Min, Max      Duration
+	P50, P90, P99 Duration // estimated from Buckets
+	Buckets       []LatencyBucket
+}
+
+// A LatencyBucket counts the requests whose latency did not exceed Limit
+// but exceeded the limit of the previous bucket. A zero Limit denotes the
+// final bucket, which has no limit.
+type LatencyBucket struct {
+	Limit Duration
+	Count int64
+}
+
+// Mean returns the mean latency of the requests of the method.
+func (m *MethodLatency) Mean() Duration {
+	if m.Count == 0 {
+		return 0
+	}
+	return m.Total / Duration(m.Count)
+}
+
+// A SlowRequest records an inbound request whose latency reached the
+// slow request threshold, along with a textual dump of its trace.
+type SlowRequest struct {
+	Method   string
+	ID       string `json:",omitempty"` // empty for notifications
+	Start    time.Time
+	Duration Duration
+	Error    bool   `json:",omitempty"`
+	Trace    string `json:",omitempty"`
+}
+
+// latencies records the latency of the inbound requests of an Instance.
+type latencies struct {
+	start time.Time
+
+	mu        sync.Mutex
+	record    bool // whether to record latencies at all
+	threshold time.Duration
+	methods   map[string]*MethodLatency
+	slow      []*SlowRequest // sorted by decreasing duration
+	persist   bool           // whether to save the report in the file cache
+	saving    bool           // a save is pending
+}
+
+func newLatencies(start time.Time) *latencies {
+	return &latencies{
+		start:   start,
+		methods: make(map[string]*MethodLatency),
+	}
+}
+
+// spanEnd records the latency of the span, if it is that of an
+// inbound request. It is called by traces with its lock held, as td
+// and its descendants are updated under that lock.
+func (l *latencies) spanEnd(span *export.Span, td *traceSpan) {
+	lm := span.Start()
+	method := tag.Method.Get(lm)
+	if method == "" || tag.RPCDirection.Get(lm) != tag.Inbound {
+		return
+	}
+	failed := getStatusCode(span) == "ERROR"
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.record {
+		return
+	}
+	m, ok := l.methods[method]
+	if !ok {
+		m = newMethodLatency(method)
+		l.methods[method] = m
+	}
+	m.add(Duration(td.Duration), failed)
+
+	if l.threshold > 0 && td.Duration >= l.threshold {
+		if n := len(l.slow); n < maxSlowRequests || td.Duration > time.Duration(l.slow[n-1].Duration) {
+			l.addSlowLocked(&SlowRequest{
+				Method:   method,
+				ID:       tag.RPCID.Get(lm),
+				Start:    td.Start,
+				Duration: Duration(td.Duration),
+				Error:    failed,
+				Trace:    formatTrace(td),
+			})
+		}
+	}
+
+	if l.persist && !l.saving {
+		l.saving = true
+		time.AfterFunc(latencySaveWait, l.save)
+	}
+}
+
+// addSlowLocked adds req to the slow requests, keeping the slowest ones.
+func (l *latencies) addSlowLocked(req *SlowRequest) {
+	l.slow = append(l.slow, req)
+	sort.SliceStable(l.slow, func(i, j int) bool {
+		return l.slow[i].Duration > l.slow[j].Duration
+	})
+	if len(l.slow) > maxSlowRequests {
+		l.slow[maxSlowRequests] = nil // aid GC
+		l.slow = l.slow[:maxSlowRequests]
+	}
+}
+
+// report returns a new report of the latencies recorded so far.
+func (l *latencies) report() *LatencyReport {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := &LatencyReport{
+		PID:       os.Getpid(),
+		Start:     l.start,
+		Threshold: Duration(l.threshold),
+	}
+	for _, m := range l.methods {
+		mc := *m
+		mc.Buckets = append([]LatencyBucket(nil), m.Buckets...)
+		mc.estimatePercentiles()
+		r.Methods = append(r.Methods, &mc)
+	}
+	sort.Slice(r.Methods, func(i, j int) bool {
+		return r.Methods[i].Method < r.Methods[j].Method
+	})
+	for _, req := range l.slow {
+		rc := *req
+		r.SlowRequests = append(r.SlowRequests, &rc)
+	}
+	return r
+}
+
+// save persists the current report in the file cache, where a later
+// "gopls stats -latency" command may find it, if persistence is enabled.
+func (l *latencies) save() {
+	l.mu.Lock()
+	l.saving = false
+	persist := l.persist
+	l.mu.Unlock()
+
+	if !persist {
+		return
+	}
+
+	r := l.report()
+	if len(r.Methods) == 0 {
+		return // nothing to report
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return // can't happen
+	}
+	key := sha256.Sum256([]byte(fmt.Sprintf("%d %s", os.Getpid(), l.start.Format(time.RFC3339Nano))))
+	_ = filecache.Set(latencyKind, key, data) // ignore errors
+}
+
+// getData returns the LatencyReport rendered by LatencyTmpl for the /latency endpoint.
+func (l *latencies) getData(req *http.Request) interface{} {
+	return l.report()
+}
+
+func newMethodLatency(method string) *MethodLatency {
+	m := &MethodLatency{Method: method}
+	for _, limit := range latencyBuckets {
+		m.Buckets = append(m.Buckets, LatencyBucket{Limit: Duration(limit)})
+	}
+	m.Buckets = append(m.Buckets, LatencyBucket{}) // slower requests
+	return m
+}
+
+// add records one request of the method.
+func (m *MethodLatency) add(d Duration, failed bool) {
+	if m.Count == 0 || d < m.Min {
+		m.Min = d
+	}
+	if d > m.Max {
+		m.Max = d
+	}
+	m.Count++
+	m.Total += d
+	if failed {
+		m.Errors++
+	}
+	for i := range m.Buckets {
+		if limit := m.Buckets[i].Limit; limit == 0 || d <= limit {
+			m.Buckets[i].Count++
+			break
+		}
+	}
+}
+
+// merge adds the requests of other, a histogram of the same method.
+func (m *MethodLatency) merge(other *MethodLatency) {
+	if other.Count == 0 {
+		return
+	}
+	if m.Count == 0 || other.Min < m.Min {
+		m.Min = other.Min
+	}
+	if other.Max > m.Max {
+		m.Max = other.Max
+	}
+	m.Count += other.Count
+	m.Errors += other.Errors
+	m.Total += other.Total
+	for _, b := range other.Buckets {
+		for i := range m.Buckets {
+			if m.Buckets[i].Limit == b.Limit {
+				m.Buckets[i].Count += b.Count
+				break
+			}
+		}
+	}
+}
+
+// estimatePercentiles sets the percentile fields of m to the limits of
+// the buckets that contain them, clamped to the observed range.
+func (m *MethodLatency) estimatePercentiles() {
+	percentile := func(p float64) Duration {
+		target := int64(math.Ceil(p * float64(m.Count)))
+		var n int64
+		for _, b := range m.Buckets {
+			n += b.Count
+			if n >= target {
+				switch {
+				case b.Limit == 0 || b.Limit > m.Max:
+					return m.Max
+				case b.Limit < m.Min:
+					return m.Min
+				}
+				return b.Limit
+			}
+		}
+		return m.Max
+	}
+	if m.Count > 0 {
+		m.P50 = percentile(0.50)
+		m.P90 = percentile(0.90)
+		m.P99 = percentile(0.99)
+	}
+}
+
+// formatTrace returns an indented dump of the trace of the span td:
+// its events and those of its descendants, with their offsets from
+// the start of td. Spans that were still running when td finished are
+// marked as such.
+func formatTrace(td *traceSpan) string {
+	var b strings.Builder
+	lines := 0
+	var visit func(span *traceSpan, depth int)
+	visit = func(span *traceSpan, depth int) {
+		if lines >= maxTraceLines {
+			return
+		}
+		indent := strings.Repeat("  ", depth)
+		lines++
+		fmt.Fprintf(&b, "%s+%s %s (%s) %s\n", indent, span.Start.Sub(td.Start), span.Name, span.Duration, span.Tags)
+		for _, ev := range span.Events {
+			if lines >= maxTraceLines {
+				return
+			}
+			lines++
+			fmt.Fprintf(&b, "%s  +%s event %s\n", indent, ev.Time.Sub(td.Start), ev.Tags)
+		}
+		for _, child := range span.ChildStartEnd {
+			if lines >= maxTraceLines {
+				return
+			}
+			switch {
+			case !child.Start:
+				visit(child.Span, depth+1)
+			case child.Span.Finish.IsZero():
+				lines++
+				fmt.Fprintf(&b, "%s  +%s %s (unfinished) %s\n", indent, child.Span.Start.Sub(td.Start), child.Span.Name, child.Span.Tags)
+			}
+		}
+	}
+	visit(td, 0)
+	if lines >= maxTraceLines {
+		b.WriteString("...\n")
+	}
+	return b.String()
+}
+
+// LatencyReports returns a new unordered array of the latency reports
+// persisted in the file cache by gopls processes using this executable.
+func LatencyReports() []*LatencyReport {
+	var reports []*LatencyReport
+	for _, data := range filecache.GetAll(latencyKind) {
+		r := new(LatencyReport)
+		if err := json.Unmarshal(data, r); err == nil { // ignore malformed reports
+			reports = append(reports, r)
+		}
+	}
+	return reports
+}
+
+// SummarizeLatency merges the specified reports into a single report,
+// with the histograms of each method combined and the slowest requests
+// of all reports.
+func SummarizeLatency(reports []*LatencyReport) *LatencyReport {
+	summary := &LatencyReport{
+		Sessions:     len(reports),
+		Methods:      []*MethodLatency{},
+		SlowRequests: []*SlowRequest{},
+	}
+	methods := make(map[string]*MethodLatency)
+	for _, r := range reports {
+		if summary.Start.IsZero() || r.Start.Before(summary.Start) {
+			summary.Start = r.Start
+		}
+		if summary.Threshold == 0 || r.Threshold < summary.Threshold {
+			summary.Threshold = r.Threshold
+		}
+		for _, m := range r.Methods {
+			sum, ok := methods[m.Method]
+			if !ok {
+				sum = newMethodLatency(m.Method)
+				methods[m.Method] = sum
+				summary.Methods = append(summary.Methods, sum)
+			}
+			sum.merge(m)
+		}
+		summary.SlowRequests = append(summary.SlowRequests, r.SlowRequests...)
+	}
+	for _, m := range summary.Methods {
+		m.estimatePercentiles()
+	}
+	sort.Slice(summary.Methods, func(i, j int) bool {
+		return summary.Methods[i].Method < summary.Methods[j].Method
+	})
+	sort.SliceStable(summary.SlowRequests, func(i, j int) bool {
+		return summary.SlowRequests[i].Duration > summary.SlowRequests[j].Duration
+	})
+	if len(summary.SlowRequests) > maxSlowRequests {
+		summary.SlowRequests = summary.SlowRequests[:maxSlowRequests]
+	}
+	return summary
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSummarizeLatency(t *testing.T) {
+	const ms = Duration(time.Millisecond)
+
+	// report returns a report of requests of method "m" with the
+	// specified latencies, in milliseconds.
+	report := func(pid int, latencies ...int) *LatencyReport {
+		m := newMethodLatency("m")
+		var slow []*SlowRequest
+		for _, l := range latencies {
+			d := Duration(l) * ms
+			m.add(d, false)
+			if d >= 1000*ms {
+				slow = append(slow, &SlowRequest{Method: "m", Duration: d})
+			}
+		}
+		return &LatencyReport{
+			PID:          pid,
+			Start:        time.Unix(int64(pid), 0),
+			Threshold:    1000 * ms,
+			Methods:      []*MethodLatency{m},
+			SlowRequests: slow,
+		}
+	}
+	reports := []*LatencyReport{
+		report(2, 5, 5, 5, 5, 5, 5, 5, 5, 20, 2000),
+		report(1, 5, 5, 5, 5, 5, 5, 5, 5, 40, 60000),
+	}
+
+	// Round trip the reports through JSON, as for the file cache.
+	for i, r := range reports {
+		data, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reports[i] = new(LatencyReport)
+		if err := json.Unmarshal(data, reports[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary := SummarizeLatency(reports)
+	if summary.Sessions != 2 || !summary.Start.Equal(time.Unix(1, 0)) {
+		t.Errorf("got %d sessions starting at %v, want 2 starting at %v", summary.Sessions, summary.Start, time.Unix(1, 0))
+	}
+	if len(summary.Methods) != 1 {
+		t.Fatalf("got %d methods, want 1", len(summary.Methods))
+	}
+	m := summary.Methods[0]
+	got := []Duration{Duration(m.Count), m.Min, m.P50, m.P90, m.P99, m.Max}
+	want := []Duration{20, 5 * ms, 10 * ms, 50 * ms, 60000 * ms, 60000 * ms}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("count, min, p50, p90, p99, max: got %v, want %v", got, want)
+	}
+	var durations []Duration
+	for _, req := range summary.SlowRequests {
+		durations = append(durations, req.Duration)
+	}
+	if want := []Duration{60000 * ms, 2000 * ms}; !reflect.DeepEqual(durations, want) {
+		t.Errorf("slow requests: got %v, want %v", durations, want)
+	}
+}
@@ -61,6 +61,7 @@ type Instance struct {
 	prometheus *prometheus.Exporter
 	rpcs       *Rpcs
 	traces     *traces
+	latencies  *latencies
 	State      *State
 
 	serveMu              sync.Mutex
@@ -379,12 +380,29 @@ func WithInstance(ctx context.Context, agent string) context.Context {
 	i.ocagent = ocagent.Connect(ocConfig)
 	i.prometheus = prometheus.New()
 	i.rpcs = &Rpcs{}
-	i.traces = &traces{}
+	i.latencies = newLatencies(i.StartTime)
+	i.traces = &traces{onEnd: i.latencies.spanEnd}
 	i.State = &State{}
 	i.exporter = makeInstanceExporter(i)
 	return context.WithValue(ctx, instanceKey, i)
 }
 
+// RecordLatencies enables the recording of the latency of inbound
+// requests, which is otherwise disabled. Requests that take at least
+// slowThreshold are recorded with their traces; a non-positive threshold
+// disables the recording of traces. If persist is set, the latency
+// report of this instance is persisted in the file cache for use by the
+// stats command, and the caller must call the returned function to
+// persist the final report before the process exits.
+func (i *Instance) RecordLatencies(slowThreshold time.Duration, persist bool) (save func()) {
+	i.latencies.mu.Lock()
+	defer i.latencies.mu.Unlock()
+	i.latencies.record = true
+	i.latencies.threshold = slowThreshold
+	i.latencies.persist = persist
+	return i.latencies.save
+}
+
 // SetLogFile sets the logfile for use with this instance.
 func (i *Instance) SetLogFile(logfile string, isDaemon bool) (func(), error) {
 	// TODO: probably a better solution for deferring closure to the caller would
@@ -459,6 +477,9 @@ func (i *Instance) Serve(ctx context.Context, addr string) (string, error) {
 		if i.traces != nil {
 			mux.HandleFunc("/trace/", render(TraceTmpl, i.traces.getData))
 		}
+		if i.latencies != nil {
+			mux.HandleFunc("/latency/", render(LatencyTmpl, i.latencies.getData))
+		}
 		mux.HandleFunc("/analysis/", render(AnalysisTmpl, i.getAnalysis))
 		mux.HandleFunc("/cache/", render(CacheTmpl, i.getCache))
 		mux.HandleFunc("/session/", render(SessionTmpl, i.getSession))
@@ -665,6 +686,7 @@ body {
 <a href="/metrics">Metrics</a>
 <a href="/rpc">RPC</a>
 <a href="/trace">Trace</a>
+<a href="/latency">Latency</a>
 <a href="/analysis">Analysis</a>
 <hr>
 <h1>{{template "title" .}}</h1>
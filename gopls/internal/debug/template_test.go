@@ -35,6 +35,7 @@ var templates = map[string]struct {
 	"DebugTmpl":   {debug.DebugTmpl, nil},
 	"RPCTmpl":     {debug.RPCTmpl, &debug.Rpcs{}},
 	"TraceTmpl":   {debug.TraceTmpl, debug.TraceResults{}},
+	"LatencyTmpl": {debug.LatencyTmpl, &debug.LatencyReport{}},
 	"CacheTmpl":   {debug.CacheTmpl, &cache.Cache{}},
 	"SessionTmpl": {debug.SessionTmpl, &cache.Session{}},
 	"ClientTmpl":  {debug.ClientTmpl, &debug.Client{}},
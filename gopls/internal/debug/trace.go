@@ -61,6 +61,8 @@ type traces struct {
 	unfinished      map[export.SpanContext]*traceSpan
 	recent          []spanStartEnd
 	recentEvictions int
+
+	onEnd func(*export.Span, *traceSpan) // if non-nil, called with mu held as each span ends
 }
 
 // A spanStartEnd records the start or end of a span.
@@ -252,6 +254,9 @@ func (t *traces) ProcessEvent(ctx context.Context, ev core.Event, lm label.Map)
 		} else {
 			fillOffsets(td, td.Start)
 		}
+		if t.onEnd != nil {
+			t.onEnd(span, td)
+		}
 	}
 	return ctx
 }
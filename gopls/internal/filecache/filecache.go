@@ -310,7 +310,7 @@ func filename(kind string, key [32]byte) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	// Keep the BugReports function consistent with this one.
+	// Keep the GetAll function consistent with this one.
 	return filepath.Join(dir, base[:2], base), nil
 }
 
@@ -582,27 +582,48 @@ func BugReports() (string, []bug.Bug) {
 		return "", nil // ignore initialization errors
 	}
 	var result []bug.Bug
+	for _, content := range GetAll(bugKind) {
+		var b bug.Bug
+		if err := json.Unmarshal(content, &b); err != nil {
+			log.Printf("error marshalling bug %q: %v", string(content), err)
+		}
+		result = append(result, b)
+	}
+	return dir, result
+}
+
+// GetAll returns a new unordered array of the values of all entries
+// of the specified kind in the cache of this executable. Entries that
+// cannot be read are ignored.
+//
+// It walks the entire cache directory, so it is intended only for
+// infrequent queries of kinds with few entries, such as reports.
+func GetAll(kind string) [][]byte {
+	dir, err := getCacheDir()
+	if err != nil {
+		return nil // ignore initialization errors
+	}
+	var result [][]byte
 	_ = filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return nil // ignore readdir/stat errors
 		}
-		// Parse the key from each "XXXX-bug" cache file name.
-		if !info.IsDir() && strings.HasSuffix(path, bugKind) {
+		// Parse the key from each "XXXX-kind" cache file name.
+		if !info.IsDir() && strings.HasSuffix(path, "-"+kind) {
 			var key [32]byte
-			n, err := hex.Decode(key[:], []byte(filepath.Base(path)[:len(key)*2]))
+			base := filepath.Base(path)
+			if len(base) < len(key)*2 {
+				return nil // ignore malformed file names
+			}
+			n, err := hex.Decode(key[:], []byte(base[:len(key)*2]))
 			if err != nil || n != len(key) {
 				return nil // ignore malformed file names
 			}
-			content, err := Get(bugKind, key)
-			if err == nil { // ignore read errors
-				var b bug.Bug
-				if err := json.Unmarshal(content, &b); err != nil {
-					log.Printf("error marshalling bug %q: %v", string(content), err)
-				}
-				result = append(result, b)
+			if content, err := Get(kind, key); err == nil { // ignore read errors
+				result = append(result, content)
 			}
 		}
 		return nil
 	})
-	return dir, result
+	return result
 }
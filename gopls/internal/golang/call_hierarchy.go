@@ -18,6 +18,7 @@ import (
 	"golang.org/x/tools/gopls/internal/cache/parsego"
 	"golang.org/x/tools/gopls/internal/file"
 	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/settings"
 	"golang.org/x/tools/gopls/internal/util/bug"
 	"golang.org/x/tools/gopls/internal/util/safetoken"
 	"golang.org/x/tools/internal/event"
@@ -103,6 +104,14 @@ func IncomingCalls(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle
 	for _, callItem := range incomingCalls {
 		incomingCallItems = append(incomingCallItems, *callItem)
 	}
+
+	if snapshot.Options().DynamicCallHierarchy != settings.DynamicCallsOff {
+		dynamic, err := dynamicIncomingCalls(ctx, snapshot, fh, pos)
+		if err != nil {
+			return nil, err
+		}
+		incomingCallItems = append(incomingCallItems, dynamic...)
+	}
 	return incomingCallItems, nil
 }
 
@@ -309,5 +318,13 @@ func OutgoingCalls(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle
 	for _, callItem := range outgoingCalls {
 		outgoingCallItems = append(outgoingCallItems, *callItem)
 	}
+
+	if snapshot.Options().DynamicCallHierarchy != settings.DynamicCallsOff {
+		dynamic, err := dynamicOutgoingCalls(ctx, snapshot, fh, pp)
+		if err != nil {
+			return nil, err
+		}
+		outgoingCallItems = append(outgoingCallItems, dynamic...)
+	}
 	return outgoingCallItems, nil
 }
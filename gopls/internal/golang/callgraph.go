@@ -0,0 +1,428 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file computes the dynamic calls, that is, calls through
+// interface methods and function values, reported by call hierarchy
+// requests when the dynamicCallHierarchy setting is enabled.
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/settings"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/internal/event"
+)
+
+// A dynamicCallGraph is the call graph of the workspace packages and
+// their dependencies, computed by one of the algorithms of the
+// dynamicCallHierarchy setting.
+type dynamicCallGraph struct {
+	fset  *token.FileSet
+	graph *callgraph.Graph
+	nodes map[funcKey][]*callgraph.Node // nodes of each source function and its instances
+}
+
+// A funcKey identifies a function by the position of its declaration,
+// which, unlike a token.Pos, does not depend on the file set.
+type funcKey struct {
+	filename string
+	offset   int
+}
+
+// getDynamicCallGraph returns the call graph of the workspace, computed
+// by the specified algorithm. Computing a call graph requires building
+// the SSA form of the entire program, so the result is memoized by the
+// snapshot.
+func getDynamicCallGraph(ctx context.Context, snapshot *cache.Snapshot, algorithm settings.DynamicCallHierarchy) (*dynamicCallGraph, error) {
+	g, err := snapshot.ProgramResult(ctx, "dynamicCallGraph/"+string(algorithm), func(ctx context.Context, snapshot *cache.Snapshot) (interface{}, error) {
+		return buildDynamicCallGraph(ctx, snapshot, algorithm)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g.(*dynamicCallGraph), nil
+}
+
+// buildDynamicCallGraph type-checks the workspace packages, builds
+// their SSA form, and computes their call graph.
+func buildDynamicCallGraph(ctx context.Context, snapshot *cache.Snapshot, algorithm settings.DynamicCallHierarchy) (*dynamicCallGraph, error) {
+	ctx, done := event.Start(ctx, "golang.buildDynamicCallGraph")
+	defer done()
+
+	mps, err := snapshot.WorkspaceMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []PackageID
+	for _, mp := range mps {
+		if mp.ForTest == "" {
+			ids = append(ids, mp.ID)
+		}
+	}
+	prog, _, err := buildProgram(ctx, snapshot, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var graph *callgraph.Graph
+	switch algorithm {
+	case settings.DynamicCallsRTA:
+		// The roots are the source functions of the workspace, as
+		// any of them may be called by a client of the package.
+		var roots []*ssa.Function
+		for fn := range ssautil.AllFunctions(prog) {
+			if fn.Syntax() != nil && fn.Synthetic == "" && fn.Parent() == nil && fn.TypeParams().Len() == 0 {
+				roots = append(roots, fn)
+			}
+		}
+		sort.Slice(roots, func(i, j int) bool { return roots[i].Pos() < roots[j].Pos() })
+		graph = rta.Analyze(roots, true).CallGraph
+	case settings.DynamicCallsVTA:
+		graph = vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog))
+	default:
+		return nil, fmt.Errorf("invalid call graph algorithm %q", algorithm)
+	}
+
+	g := &dynamicCallGraph{
+		fset:  prog.Fset,
+		graph: graph,
+		nodes: make(map[funcKey][]*callgraph.Node),
+	}
+	for fn, node := range graph.Nodes {
+		if fn == nil || fn.Synthetic != "" {
+			continue
+		}
+		if origin := fn.Origin(); origin != nil {
+			fn = origin
+		}
+		if key, ok := g.funcKey(fn.Pos()); ok {
+			g.nodes[key] = append(g.nodes[key], node)
+		}
+	}
+	return g, nil
+}
+
+// buildProgram type-checks the specified packages, which must not
+// be test variants, in a single batch, and builds the SSA form of the
+// program they form, returning also the packages built from syntax.
+// Packages that are not specified, or that have errors, are created
+// from their types alone.
+func buildProgram(ctx context.Context, snapshot *cache.Snapshot, ids []PackageID) (*ssa.Program, map[*ssa.Package]*cache.Package, error) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	pkgs, err := snapshot.TypeCheckProgram(ctx, ids...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pkgs) == 0 {
+		return nil, nil, fmt.Errorf("no packages")
+	}
+
+	prog := ssa.NewProgram(pkgs[0].FileSet(), ssa.InstantiateGenerics)
+	built := make(map[*ssa.Package]*cache.Package)
+	created := make(map[*types.Package]bool)
+	for _, pkg := range pkgs {
+		if len(pkg.GetParseErrors()) > 0 || len(pkg.GetTypeErrors()) > 0 {
+			continue
+		}
+		var files []*ast.File
+		for _, pgf := range pkg.CompiledGoFiles() {
+			files = append(files, pgf.File)
+		}
+		built[prog.CreatePackage(pkg.GetTypes(), files, pkg.GetTypesInfo(), false)] = pkg
+		created[pkg.GetTypes()] = true
+	}
+	var createDeps func(*types.Package)
+	createDeps = func(pkg *types.Package) {
+		if !created[pkg] {
+			created[pkg] = true
+			prog.CreatePackage(pkg, nil, nil, true)
+		}
+		for _, imp := range pkg.Imports() {
+			if !created[imp] {
+				createDeps(imp)
+			}
+		}
+	}
+	for _, pkg := range pkgs {
+		createDeps(pkg.GetTypes())
+	}
+	prog.Build()
+	return prog, built, nil
+}
+
+// funcKey returns the key of the function declared at pos.
+func (g *dynamicCallGraph) funcKey(pos token.Pos) (funcKey, bool) {
+	return posFuncKey(g.fset, pos)
+}
+
+func posFuncKey(fset *token.FileSet, pos token.Pos) (funcKey, bool) {
+	if !pos.IsValid() {
+		return funcKey{}, false
+	}
+	tok := fset.File(pos)
+	if tok == nil {
+		return funcKey{}, false
+	}
+	offset, err := safetoken.Offset(tok, pos)
+	if err != nil {
+		return funcKey{}, false
+	}
+	return funcKey{tok.Name(), offset}, true
+}
+
+// dynamicCallTarget returns the call graph, and the key of the source
+// function denoted by the identifier at pp, or ok=false if there is
+// none.
+func dynamicCallTarget(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, pp protocol.Position) (g *dynamicCallGraph, key funcKey, ok bool, err error) {
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, funcKey{}, false, err
+	}
+	pos, err := pgf.PositionPos(pp)
+	if err != nil {
+		return nil, funcKey{}, false, err
+	}
+	_, obj, _ := referencedObject(pkg, pgf, pos)
+	fn, isFunc := obj.(*types.Func)
+	if !isFunc || fn.Pkg() == nil {
+		return nil, funcKey{}, false, nil
+	}
+	key, ok = posFuncKey(pkg.FileSet(), fn.Origin().Pos())
+	if !ok {
+		return nil, funcKey{}, false, nil
+	}
+	g, err = getDynamicCallGraph(ctx, snapshot, snapshot.Options().DynamicCallHierarchy)
+	if err != nil {
+		return nil, funcKey{}, false, err
+	}
+	return g, key, true, nil
+}
+
+// dynamicIncomingCalls returns the dynamic calls to the function
+// denoted by the identifier at pp, grouped by their enclosing
+// function.
+func dynamicIncomingCalls(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, pp protocol.Position) ([]protocol.CallHierarchyIncomingCall, error) {
+	g, key, ok, err := dynamicCallTarget(ctx, snapshot, fh, pp)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	// Calls to a synthetic wrapper, such as a promoted method, are
+	// reported as calls to the function it wraps.
+	var sites []*callgraph.Edge
+	seen := make(map[*callgraph.Node]bool)
+	var visit func(node *callgraph.Node)
+	visit = func(node *callgraph.Node) {
+		if seen[node] {
+			return
+		}
+		seen[node] = true
+		for _, edge := range node.In {
+			switch {
+			case edge.Caller.Func == nil:
+				// the root of the graph
+			case edge.Caller.Func.Synthetic != "":
+				visit(edge.Caller)
+			case edge.Site != nil && edge.Site.Common().StaticCallee() == nil:
+				sites = append(sites, edge)
+			}
+		}
+	}
+	for _, node := range g.nodes[key] {
+		visit(node)
+	}
+
+	incomingCalls := make(map[protocol.Location]*protocol.CallHierarchyIncomingCall)
+	var order []protocol.Location
+	for _, edge := range sites {
+		caller := edge.Caller.Func
+		start, end, ok := callSiteRange(caller, edge.Site.Common().Pos())
+		if !ok || caller.Pkg == nil {
+			continue
+		}
+		loc, err := mapPosition(ctx, g.fset, snapshot, start, end)
+		if err != nil {
+			return nil, err
+		}
+		callItem, err := enclosingNodeCallItem(ctx, snapshot, PackagePath(caller.Pkg.Pkg.Path()), loc)
+		if err != nil {
+			return nil, err
+		}
+		callItem.Detail += " • dynamic"
+		itemLoc := protocol.Location{URI: callItem.URI, Range: callItem.Range}
+		call, ok := incomingCalls[itemLoc]
+		if !ok {
+			call = &protocol.CallHierarchyIncomingCall{From: callItem}
+			incomingCalls[itemLoc] = call
+			order = append(order, itemLoc)
+		}
+		if !containsRange(call.FromRanges, loc.Range) {
+			call.FromRanges = append(call.FromRanges, loc.Range)
+		}
+	}
+
+	var calls []protocol.CallHierarchyIncomingCall
+	for _, loc := range order {
+		calls = append(calls, *incomingCalls[loc])
+	}
+	return calls, nil
+}
+
+// dynamicOutgoingCalls returns the functions called dynamically by the
+// function denoted by the identifier at pp, including its function
+// literals.
+func dynamicOutgoingCalls(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, pp protocol.Position) ([]protocol.CallHierarchyOutgoingCall, error) {
+	g, key, ok, err := dynamicCallTarget(ctx, snapshot, fh, pp)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	// A dynamic call of a synthetic wrapper, such as a promoted
+	// method, is reported as a call to the function it wraps.
+	var callees func(node *callgraph.Node, seen map[*callgraph.Node]bool) []*ssa.Function
+	callees = func(node *callgraph.Node, seen map[*callgraph.Node]bool) []*ssa.Function {
+		if node.Func == nil {
+			return nil
+		}
+		if node.Func.Synthetic == "" {
+			return []*ssa.Function{node.Func}
+		}
+		if seen[node] {
+			return nil
+		}
+		seen[node] = true
+		var result []*ssa.Function
+		for _, edge := range node.Out {
+			result = append(result, callees(edge.Callee, seen)...)
+		}
+		return result
+	}
+
+	outgoingCalls := make(map[funcKey]*protocol.CallHierarchyOutgoingCall)
+	var order []funcKey
+	var visit func(node *callgraph.Node) error
+	visit = func(node *callgraph.Node) error {
+		caller := node.Func
+		for _, edge := range node.Out {
+			if edge.Site == nil || edge.Site.Common().StaticCallee() != nil {
+				continue
+			}
+			start, end, ok := callSiteRange(caller, edge.Site.Common().Pos())
+			if !ok {
+				continue
+			}
+			for _, callee := range callees(edge.Callee, make(map[*callgraph.Node]bool)) {
+				if origin := callee.Origin(); origin != nil {
+					callee = origin
+				}
+				obj := callee.Object()
+				calleeKey, ok := g.funcKey(callee.Pos())
+				if obj == nil || obj.Pkg() == nil || !ok {
+					continue // e.g. a function literal
+				}
+				call, ok := outgoingCalls[calleeKey]
+				if !ok {
+					loc, err := mapPosition(ctx, g.fset, snapshot, obj.Pos(), obj.Pos()+token.Pos(len(obj.Name())))
+					if err != nil {
+						continue // e.g. a dependency without source
+					}
+					call = &protocol.CallHierarchyOutgoingCall{
+						To: protocol.CallHierarchyItem{
+							Name:           obj.Name(),
+							Kind:           protocol.Function,
+							Tags:           []protocol.SymbolTag{},
+							Detail:         fmt.Sprintf("%s • %s • dynamic", obj.Pkg().Path(), filepath.Base(loc.URI.Path())),
+							URI:            loc.URI,
+							Range:          loc.Range,
+							SelectionRange: loc.Range,
+						},
+					}
+					outgoingCalls[calleeKey] = call
+					order = append(order, calleeKey)
+				}
+				loc, err := mapPosition(ctx, g.fset, snapshot, start, end)
+				if err != nil {
+					return err
+				}
+				if !containsRange(call.FromRanges, loc.Range) {
+					call.FromRanges = append(call.FromRanges, loc.Range)
+				}
+			}
+		}
+		for _, anon := range caller.AnonFuncs {
+			if anonNode := g.graph.Nodes[anon]; anonNode != nil {
+				if err := visit(anonNode); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, node := range g.nodes[key] {
+		if err := visit(node); err != nil {
+			return nil, err
+		}
+	}
+
+	var calls []protocol.CallHierarchyOutgoingCall
+	for _, key := range order {
+		calls = append(calls, *outgoingCalls[key])
+	}
+	return calls, nil
+}
+
+// callSiteRange returns the range of the name of the function called
+// by the call expression of fn whose left parenthesis is at lparen,
+// as reported for static calls.
+func callSiteRange(fn *ssa.Function, lparen token.Pos) (start, end token.Pos, ok bool) {
+	if fn.Syntax() == nil || !lparen.IsValid() {
+		return token.NoPos, token.NoPos, false
+	}
+	ast.Inspect(fn.Syntax(), func(n ast.Node) bool {
+		if ok {
+			return false
+		}
+		if call, isCall := n.(*ast.CallExpr); isCall && call.Lparen == lparen {
+			switch fun := astutil.Unparen(call.Fun).(type) {
+			case *ast.SelectorExpr:
+				start, end = fun.Sel.NamePos, call.Lparen
+			case *ast.Ident:
+				start, end = fun.NamePos, call.Lparen
+			default:
+				start, end = call.Fun.Pos(), call.Lparen
+			}
+			ok = true
+		}
+		return !ok
+	})
+	return start, end, ok
+}
+
+// containsRange reports whether ranges contains rng.
+func containsRange(ranges []protocol.Range, rng protocol.Range) bool {
+	for _, r := range ranges {
+		if r == rng {
+			return true
+		}
+	}
+	return false
+}
@@ -13,6 +13,7 @@ import (
 	"go/token"
 	"go/types"
 	"regexp"
+	"sort"
 
 	"golang.org/x/tools/parser"
 
@@ -416,7 +417,7 @@ func rewriteCalls(ctx context.Context, rw signatureRewrite) (map[protocol.Docume
 	// Type check pkg again with the modified file, to compute the synthetic
 	// callee.
 	logf := logger(ctx, "change signature", rw.snapshot.Options().VerboseOutput)
-	pkg2, info, err := reTypeCheck(logf, rw.pkg, map[protocol.DocumentURI]*ast.File{rw.pgf.URI: modifiedFile}, false)
+	pkg2, info, err := reTypeCheck(logf, rw.pkg, map[protocol.DocumentURI]*ast.File{rw.pgf.URI: modifiedFile}, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -430,14 +431,15 @@ func rewriteCalls(ctx context.Context, rw signatureRewrite) (map[protocol.Docume
 }
 
 // reTypeCheck re-type checks orig with new file contents defined by fileMask.
+// Files of fileMask that are not among the compiled files of orig are
+// added to the package.
 //
 // It expects that any newly added imports are already present in the
 // transitive imports of orig.
 //
-// If expectErrors is true, reTypeCheck allows errors in the new package.
-// TODO(rfindley): perhaps this should be a filter to specify which errors are
-// acceptable.
-func reTypeCheck(logf func(string, ...any), orig *cache.Package, fileMask map[protocol.DocumentURI]*ast.File, expectErrors bool) (*types.Package, *types.Info, error) {
+// If acceptError is non-nil, reTypeCheck allows the errors in the new
+// package for which it returns true.
+func reTypeCheck(logf func(string, ...any), orig *cache.Package, fileMask map[protocol.DocumentURI]*ast.File, acceptError func(types.Error) bool) (*types.Package, *types.Info, error) {
 	pkg := types.NewPackage(string(orig.Metadata().PkgPath), string(orig.Metadata().Name))
 	info := &types.Info{
 		Types:      make(map[ast.Expr]types.TypeAndValue),
@@ -451,13 +453,25 @@ func reTypeCheck(logf func(string, ...any), orig *cache.Package, fileMask map[pr
 	versions.InitFileVersions(info)
 	{
 		var files []*ast.File
+		masked := make(map[protocol.DocumentURI]bool)
 		for _, pgf := range orig.CompiledGoFiles() {
 			if mask, ok := fileMask[pgf.URI]; ok {
 				files = append(files, mask)
+				masked[pgf.URI] = true
 			} else {
 				files = append(files, pgf.File)
 			}
 		}
+		var added []protocol.DocumentURI
+		for uri := range fileMask {
+			if !masked[uri] {
+				added = append(added, uri)
+			}
+		}
+		sort.Slice(added, func(i, j int) bool { return added[i] < added[j] })
+		for _, uri := range added {
+			files = append(files, fileMask[uri])
+		}
 
 		// Implement a BFS for imports in the transitive package graph.
 		//
@@ -519,15 +533,19 @@ func reTypeCheck(logf func(string, ...any), orig *cache.Package, fileMask map[pr
 				typesinternal.SetGoVersion(cfg, goVersion)
 			}
 		}
-		if expectErrors {
-			cfg.Error = func(err error) {
+		var firstErr error
+		cfg.Error = func(err error) {
+			if terr, ok := err.(types.Error); ok && acceptError != nil && acceptError(terr) {
 				logf("re-type checking: expected error: %v", err)
+			} else if firstErr == nil {
+				firstErr = err
 			}
 		}
 		typesinternal.SetUsesCgo(cfg)
 		checker := types.NewChecker(cfg, orig.FileSet(), pkg, info)
-		if err := checker.Files(files); err != nil && !expectErrors {
-			return nil, nil, fmt.Errorf("type checking rewritten package: %v", err)
+		checker.Files(files)
+		if firstErr != nil {
+			return nil, nil, fmt.Errorf("type checking rewritten package: %v", firstErr)
 		}
 	}
 	return pkg, info, nil
@@ -9,6 +9,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"go/ast"
+	"go/token"
+	"go/types"
 	"strings"
 
 	"golang.org/x/tools/go/ast/inspector"
@@ -93,7 +95,9 @@ func CodeActions(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle,
 	// Code actions requiring type information.
 	if want[protocol.RefactorRewrite] ||
 		want[protocol.RefactorInline] ||
-		want[protocol.GoTest] {
+		want[protocol.RefactorExtract] ||
+		want[protocol.GoTest] ||
+		want[protocol.SourceGenerateTest] {
 		pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
 		if err != nil {
 			return nil, err
@@ -106,6 +110,14 @@ func CodeActions(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle,
 			actions = append(actions, rewrites...)
 		}
 
+		if want[protocol.RefactorExtract] {
+			extractions, err := getExtractInterfaceCodeActions(pkg, pgf, rng, snapshot.Options())
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, extractions...)
+		}
+
 		if want[protocol.RefactorInline] {
 			rewrites, err := getInlineCodeActions(pkg, pgf, rng, snapshot.Options())
 			if err != nil {
@@ -121,6 +133,14 @@ func CodeActions(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle,
 			}
 			actions = append(actions, fixes...)
 		}
+
+		if want[protocol.SourceGenerateTest] {
+			fixes, err := getGenerateTestCodeActions(pkg, pgf, rng, snapshot.Options())
+			if err != nil {
+				return nil, err
+			}
+			actions = append(actions, fixes...)
+		}
 	}
 	return actions, nil
 }
@@ -227,6 +247,16 @@ func getExtractCodeActions(pgf *parsego.File, rng protocol.Range, options *setti
 		}
 		commands = append(commands, cmd)
 	}
+	if slices.Contains(options.SupportedResourceOperations, protocol.Create) && CanExtractToFile(pgf, start, end) {
+		cmd, err := command.NewExtractToFileCommand("Extract declarations to new file", command.ExtractToFileArgs{
+			Location:     protocol.Location{URI: puri, Range: rng},
+			ResolveEdits: supportsResolveEdits(options),
+		})
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
 	var actions []protocol.CodeAction
 	for i := range commands {
 		actions = append(actions, newCodeAction(commands[i].Title, protocol.RefactorExtract, &commands[i], nil, options))
@@ -234,6 +264,79 @@ func getExtractCodeActions(pgf *parsego.File, rng protocol.Range, options *setti
 	return actions, nil
 }
 
+// getExtractInterfaceCodeActions returns the "extract interface" code
+// actions for the selection: one for all the exported methods of a
+// type whose name is selected, or one for the selected methods of a
+// type.
+func getExtractInterfaceCodeActions(pkg *cache.Package, pgf *parsego.File, rng protocol.Range, options *settings.Options) ([]protocol.CodeAction, error) {
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		tname   *types.TypeName
+		methods []string
+		title   = "Extract interface"
+	)
+	if t, err := extractableType(pkg, pgf, start); err == nil && t.Pos() <= start && end <= t.Pos()+token.Pos(len(t.Name())) {
+		tname = t
+	} else if start < end {
+		// Are whole exported methods of a single type selected?
+		info := pkg.GetTypesInfo()
+		for _, decl := range pgf.File.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.End() <= start || end <= fn.Pos() {
+				continue
+			}
+			if fn.Recv == nil || !fn.Name.IsExported() || fn.Pos() < start || end < fn.End() {
+				return nil, nil
+			}
+			method, ok := info.Defs[fn.Name].(*types.Func)
+			if !ok {
+				return nil, nil // ill-typed
+			}
+			recv := method.Type().(*types.Signature).Recv().Type()
+			if ptr, ok := recv.(*types.Pointer); ok {
+				recv = ptr.Elem()
+			}
+			named, ok := recv.(*types.Named)
+			if !ok || tname != nil && named.Obj() != tname {
+				return nil, nil
+			}
+			tname = named.Obj()
+			methods = append(methods, fn.Name.Name)
+		}
+		if tname == nil {
+			return nil, nil
+		}
+		if _, err := checkExtractableType(pkg, tname); err != nil {
+			return nil, nil
+		}
+		title = "Extract interface from selected methods"
+	} else {
+		return nil, nil
+	}
+
+	// The command refers to the declaration of the type.
+	declPGF, err := pkg.File(protocol.URIFromPath(pkg.FileSet().File(tname.Pos()).Name()))
+	if err != nil {
+		return nil, err
+	}
+	loc, err := declPGF.PosLocation(tname.Pos(), tname.Pos()+token.Pos(len(tname.Name())))
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := command.NewExtractInterfaceCommand(title, command.ExtractInterfaceArgs{
+		Location:     loc,
+		Methods:      methods,
+		ResolveEdits: supportsResolveEdits(options),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []protocol.CodeAction{newCodeAction(title, protocol.RefactorExtract, &cmd, nil, options)}, nil
+}
+
 func newCodeAction(title string, kind protocol.CodeActionKind, cmd *protocol.Command, diagnostics []protocol.Diagnostic, options *settings.Options) protocol.CodeAction {
 	action := protocol.CodeAction{
 		Title:       title,
@@ -330,6 +433,45 @@ func getRewriteCodeActions(ctx context.Context, pkg *cache.Package, snapshot *ca
 		commands = append(commands, cmd)
 	}
 
+	if CanNameStructType(pgf.File, pkg.GetTypes(), pkg.GetTypesInfo(), start, end) {
+		cmd, err := command.NewApplyFixCommand("Convert anonymous struct to named type", command.ApplyFixArgs{
+			Fix:          fixNameStructType,
+			URI:          pgf.URI,
+			Range:        rng,
+			ResolveEdits: supportsResolveEdits(options),
+		})
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+
+	if CanGeneralizeParam(pgf.File, pkg.GetTypesInfo(), start, end) {
+		cmd, err := command.NewApplyFixCommand("Convert parameter type to type parameter", command.ApplyFixArgs{
+			Fix:          fixGeneralizeParam,
+			URI:          pgf.URI,
+			Range:        rng,
+			ResolveEdits: supportsResolveEdits(options),
+		})
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+
+	if msg, ok := CanReorderFields(pkg, pgf, start, end); ok {
+		cmd, err := command.NewApplyFixCommand(msg, command.ApplyFixArgs{
+			Fix:          fixReorderFields,
+			URI:          pgf.URI,
+			Range:        rng,
+			ResolveEdits: supportsResolveEdits(options),
+		})
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, cmd)
+	}
+
 	// N.B.: an inspector only pays for itself after ~5 passes, which means we're
 	// currently not getting a good deal on this inspection.
 	//
@@ -494,6 +636,41 @@ func getGoTestCodeActions(pkg *cache.Package, pgf *parsego.File, rng protocol.Ra
 	}}, nil
 }
 
+// getGenerateTestCodeActions returns the "generate test" code action
+// for the function whose declaration encloses the selection, if any.
+func getGenerateTestCodeActions(pkg *cache.Package, pgf *parsego.File, rng protocol.Range, options *settings.Options) ([]protocol.CodeAction, error) {
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := testableFunc(pkg, pgf, start, end)
+	if err != nil {
+		return nil, nil // no function, or not testable
+	}
+	testName := generatedTestName(fn)
+	title := fmt.Sprintf("Add test %s", testName)
+	cmd, err := command.NewGenerateTestCommand(title, command.GenerateTestArgs{
+		Location:     protocol.Location{URI: pgf.URI, Range: rng},
+		ResolveEdits: supportsResolveEdits(options),
+	})
+	if err != nil {
+		return nil, err
+	}
+	action := newCodeAction(title, protocol.SourceGenerateTest, &cmd, nil, options)
+	if action.Command == nil {
+		// The client applies the resolved edit, then runs the new test.
+		run, err := command.NewRunTestsCommand("Run "+testName, command.RunTestsArgs{
+			URI:   testFileURI(pgf.URI),
+			Tests: []string{testName},
+		})
+		if err != nil {
+			return nil, err
+		}
+		action.Command = &run
+	}
+	return []protocol.CodeAction{action}, nil
+}
+
 func documentChanges(fh file.Handle, edits []protocol.TextEdit) []protocol.DocumentChanges {
 	return protocol.TextEditsToDocumentChanges(fh.URI(), fh.Version(), edits)
 }
@@ -0,0 +1,382 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/internal/event"
+)
+
+// DocumentColors returns the colors denoted by literals in the file,
+// so that an editor may decorate them and offer a color picker.
+//
+// Colors are reported for:
+//   - composite literals of the RGBA, NRGBA, RGBA64 and NRGBA64 types
+//     of package image/color whose fields are all constants, such as
+//     color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}; and
+//   - string literals of the form "#rgb", "#rgba", "#rrggbb" or
+//     "#rrggbbaa" passed as arguments to parameters whose names
+//     contain "color" or "colour", such as setBackground(color string).
+func DocumentColors(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle) ([]protocol.ColorInformation, error) {
+	ctx, done := event.Start(ctx, "golang.DocumentColors")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	var colors []protocol.ColorInformation
+	for _, lit := range findColorLits(pgf.File, pkg.GetTypesInfo()) {
+		rng, err := pgf.NodeRange(lit.expr)
+		if err != nil {
+			return nil, err
+		}
+		colors = append(colors, protocol.ColorInformation{Range: rng, Color: lit.color})
+	}
+	return colors, nil
+}
+
+// ColorPresentations returns the ways in which the color literal at
+// the given range may be rewritten to denote the given color. The
+// single presentation preserves the form of the original literal:
+// its type, keys and number base for a composite literal, or its
+// length, case and quotes for a hex string.
+//
+// It returns nil if there is no color literal at the range.
+func ColorPresentations(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, rng protocol.Range, color protocol.Color) ([]protocol.ColorPresentation, error) {
+	ctx, done := event.Start(ctx, "golang.ColorPresentations")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	for _, lit := range findColorLits(pgf.File, pkg.GetTypesInfo()) {
+		if lit.expr.Pos() == start && lit.expr.End() == end {
+			text := lit.format(color)
+			return []protocol.ColorPresentation{{
+				Label:    text,
+				TextEdit: &protocol.TextEdit{Range: rng, NewText: text},
+			}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// A colorLit is a literal expression that denotes a color.
+type colorLit struct {
+	expr   ast.Expr                    // *ast.CompositeLit or string *ast.BasicLit
+	color  protocol.Color              // the color denoted by expr
+	format func(protocol.Color) string // formats a color in the form of expr
+}
+
+// findColorLits returns the color literals of the file, in order.
+func findColorLits(file *ast.File, info *types.Info) []colorLit {
+	var lits []colorLit
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CompositeLit:
+			if lit, ok := compositeColorLit(info, n); ok {
+				lits = append(lits, lit)
+			}
+		case *ast.CallExpr:
+			lits = append(lits, hexColorArgs(info, n)...)
+		}
+		return true
+	})
+	return lits
+}
+
+// rgbaTypes describes the color types of package image/color that
+// have R, G, B and A fields.
+var rgbaTypes = map[string]struct {
+	bits          int  // size of each component
+	premultiplied bool // whether R, G and B are premultiplied by A
+}{
+	"RGBA":    {8, true},
+	"NRGBA":   {8, false},
+	"RGBA64":  {16, true},
+	"NRGBA64": {16, false},
+}
+
+// rgbaFields holds the names of the fields of the rgbaTypes, in order.
+var rgbaFields = [4]string{"R", "G", "B", "A"}
+
+// compositeColorLit reports whether lit is a composite literal of
+// one of the rgbaTypes with constant fields, and if so returns it as
+// a colorLit.
+func compositeColorLit(info *types.Info, lit *ast.CompositeLit) (colorLit, bool) {
+	named, ok := info.TypeOf(lit).(*types.Named)
+	if !ok {
+		return colorLit{}, false
+	}
+	obj := named.Obj()
+	typ, ok := rgbaTypes[obj.Name()]
+	if !ok || obj.Pkg() == nil || obj.Pkg().Path() != "image/color" {
+		return colorLit{}, false
+	}
+
+	var (
+		values [4]uint64
+		keys   []int // indices of the fields present in a keyed literal
+		keyed  = len(lit.Elts) == 0 || isKeyValue(lit.Elts[0])
+		hex    bool // components are written in hexadecimal
+		upper  bool // ...with upper case digits
+	)
+	for i, elt := range lit.Elts {
+		index, value := i, elt
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			key, ok := kv.Key.(*ast.Ident)
+			if !ok {
+				return colorLit{}, false
+			}
+			index = -1
+			for j, name := range rgbaFields {
+				if key.Name == name {
+					index = j
+				}
+			}
+			if index < 0 {
+				return colorLit{}, false
+			}
+			keys = append(keys, index)
+			value = kv.Value
+		}
+		if index >= len(values) {
+			return colorLit{}, false
+		}
+		tv, ok := info.Types[value]
+		if !ok || tv.Value == nil {
+			return colorLit{}, false
+		}
+		v, exact := constant.Uint64Val(constant.ToInt(tv.Value))
+		if !exact {
+			return colorLit{}, false
+		}
+		values[index] = v
+		if b, ok := astutil.Unparen(value).(*ast.BasicLit); ok && b.Kind == token.INT && len(b.Value) > 2 && strings.EqualFold(b.Value[:2], "0x") {
+			hex = true
+			upper = upper || strings.ContainsAny(b.Value[2:], "ABCDEF")
+		}
+	}
+
+	scale := float64(uint64(1)<<typ.bits - 1)
+	var c [4]float64
+	for i, v := range values {
+		c[i] = math.Min(float64(v)/scale, 1)
+	}
+	if typ.premultiplied {
+		for i := 0; i < 3; i++ {
+			if c[3] > 0 {
+				c[i] = math.Min(c[i]/c[3], 1)
+			} else {
+				c[i] = 0
+			}
+		}
+	}
+
+	var typeText string
+	if lit.Type != nil {
+		typeText = types.ExprString(lit.Type)
+	}
+	format := func(color protocol.Color) string {
+		c := [4]float64{color.Red, color.Green, color.Blue, color.Alpha}
+		if typ.premultiplied {
+			for i := 0; i < 3; i++ {
+				c[i] *= c[3]
+			}
+		}
+		var values [4]uint64
+		for i, x := range c {
+			values[i] = uint64(math.Round(math.Max(0, math.Min(x, 1)) * scale))
+		}
+		component := func(v uint64) string {
+			switch {
+			case hex && upper:
+				return fmt.Sprintf("0x%0*X", typ.bits/4, v)
+			case hex:
+				return fmt.Sprintf("0x%0*x", typ.bits/4, v)
+			}
+			return strconv.FormatUint(v, 10)
+		}
+
+		var elts []string
+		if keyed {
+			// Keep the fields of the original literal, in order,
+			// and add any other fields that are now non-zero.
+			order := append([]int(nil), keys...)
+			present := make(map[int]bool)
+			for _, i := range order {
+				present[i] = true
+			}
+			for i, v := range values {
+				if !present[i] && v != 0 {
+					order = append(order, i)
+				}
+			}
+			for _, i := range order {
+				elts = append(elts, rgbaFields[i]+": "+component(values[i]))
+			}
+		} else {
+			for _, v := range values {
+				elts = append(elts, component(v))
+			}
+		}
+		return typeText + "{" + strings.Join(elts, ", ") + "}"
+	}
+
+	return colorLit{
+		expr:   lit,
+		color:  protocol.Color{Red: c[0], Green: c[1], Blue: c[2], Alpha: c[3]},
+		format: format,
+	}, true
+}
+
+func isKeyValue(e ast.Expr) bool {
+	_, ok := e.(*ast.KeyValueExpr)
+	return ok
+}
+
+// hexColorArgs returns the hex color strings among the arguments of
+// call that are passed to parameters named like colors.
+func hexColorArgs(info *types.Info, call *ast.CallExpr) []colorLit {
+	if tv, ok := info.Types[call.Fun]; !ok || tv.IsType() {
+		return nil // not a function call
+	}
+	sig, ok := info.TypeOf(call.Fun).Underlying().(*types.Signature)
+	if !ok {
+		return nil
+	}
+	params := sig.Params()
+	var lits []colorLit
+	for i, arg := range call.Args {
+		var param *types.Var
+		switch {
+		case sig.Variadic() && i >= params.Len()-1:
+			if call.Ellipsis.IsValid() {
+				continue
+			}
+			param = params.At(params.Len() - 1)
+		case i < params.Len():
+			param = params.At(i)
+		default:
+			continue
+		}
+		if !isColorName(param.Name()) {
+			continue
+		}
+		b, ok := astutil.Unparen(arg).(*ast.BasicLit)
+		if !ok || b.Kind != token.STRING {
+			continue
+		}
+		s, err := strconv.Unquote(b.Value)
+		if err != nil {
+			continue
+		}
+		color, ok := parseHexColor(s)
+		if !ok {
+			continue
+		}
+		quote := b.Value[:1]
+		digits := len(s) - len("#")
+		upper := strings.ContainsAny(s, "ABCDEF")
+		lits = append(lits, colorLit{
+			expr:  b,
+			color: color,
+			format: func(color protocol.Color) string {
+				return quote + formatHexColor(color, digits, upper) + quote
+			},
+		})
+	}
+	return lits
+}
+
+// isColorName reports whether a parameter name suggests a color.
+func isColorName(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "color") || strings.Contains(name, "colour")
+}
+
+// parseHexColor parses a color of the form #rgb, #rgba, #rrggbb or
+// #rrggbbaa.
+func parseHexColor(s string) (protocol.Color, bool) {
+	if !strings.HasPrefix(s, "#") {
+		return protocol.Color{}, false
+	}
+	s = s[len("#"):]
+	var width int // digits per component
+	switch len(s) {
+	case 3, 4:
+		width = 1
+	case 6, 8:
+		width = 2
+	default:
+		return protocol.Color{}, false
+	}
+	c := [4]float64{1, 1, 1, 1}
+	for i := 0; i < len(s)/width; i++ {
+		v, err := strconv.ParseUint(s[i*width:(i+1)*width], 16, 8)
+		if err != nil {
+			return protocol.Color{}, false
+		}
+		if width == 1 {
+			v *= 0x11
+		}
+		c[i] = float64(v) / 0xff
+	}
+	return protocol.Color{Red: c[0], Green: c[1], Blue: c[2], Alpha: c[3]}, true
+}
+
+// formatHexColor formats a color as a hex string of the same form as
+// one with the given number of digits and case. The short forms are
+// used only if they denote the color exactly, and the alpha component
+// is added if the color is not opaque.
+func formatHexColor(color protocol.Color, digits int, upper bool) string {
+	var values [4]uint64
+	short := digits == 3 || digits == 4
+	for i, x := range [4]float64{color.Red, color.Green, color.Blue, color.Alpha} {
+		values[i] = uint64(math.Round(math.Max(0, math.Min(x, 1)) * 0xff))
+		if values[i]%0x11 != 0 {
+			short = false
+		}
+	}
+	n := 3
+	if digits == 4 || digits == 8 || values[3] != 0xff {
+		n = 4
+	}
+	verb := "%02x"
+	if short {
+		verb = "%x"
+	}
+	if upper {
+		verb = strings.ToUpper(verb)
+	}
+	var b strings.Builder
+	b.WriteString("#")
+	for _, v := range values[:n] {
+		if short {
+			v /= 0x11
+		}
+		fmt.Fprintf(&b, verb, v)
+	}
+	return b.String()
+}
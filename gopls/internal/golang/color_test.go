@@ -0,0 +1,139 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/parser"
+)
+
+func TestFindColorLits(t *testing.T) {
+	// A stand-in for package image/color.
+	const colorSrc = `package color
+
+type RGBA struct{ R, G, B, A uint8 }
+type NRGBA struct{ R, G, B, A uint8 }
+type RGBA64 struct{ R, G, B, A uint16 }
+type Gray struct{ Y uint8 }
+`
+	const src = `package p
+
+import "image/color"
+
+const dark = 0x20
+
+var (
+	a = color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}
+	b = color.NRGBA{255, 0, 0, 128}
+	c = color.RGBA{R: 0x40, A: 0x80}
+	d = []color.RGBA64{{0xFFFF, 0, 0, 0xFFFF}}
+	e = color.RGBA{G: dark, A: 0xff}
+	f = color.Gray{Y: 3}
+	g = color.RGBA{R: n}
+)
+
+var n uint8
+
+func paint(name string, fgColor string, colours ...string) {}
+
+func _() {
+	paint("#000", "#fff", "#AbCdEf", "#11223380", ` + "`#abcd`" + `, "#12345")
+}
+`
+	fset := token.NewFileSet()
+	parse := func(name, src string) *ast.File {
+		_, file, err := parser.ParseFile(fset, name, src, parser.AllErrors)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	colorPkg, err := new(types.Config).Check("image/color", fset, []*ast.File{parse("color.go", colorSrc)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := parse("p.go", src)
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := &types.Config{
+		Importer: importerFunc(func(string) (*types.Package, error) { return colorPkg, nil }),
+	}
+	if _, err := conf.Check("p", fset, []*ast.File{file}, info); err != nil {
+		t.Fatal(err)
+	}
+
+	rgba := func(r, g, b, a float64) protocol.Color {
+		return protocol.Color{Red: r / 255, Green: g / 255, Blue: b / 255, Alpha: a / 255}
+	}
+	red := protocol.Color{Red: 1, Alpha: 1}
+	halfBlue := protocol.Color{Blue: 1, Alpha: 0.5}
+	lits := findColorLits(file, info)
+	for i, test := range []struct {
+		text  string         // source text of the literal
+		color protocol.Color // color denoted by the literal
+		new   protocol.Color // a new color
+		want  string         // literal formatted for the new color
+	}{
+		{
+			"color.RGBA{R: 0x12, G: 0x34, B: 0x56, A: 0xff}", rgba(0x12, 0x34, 0x56, 0xff),
+			red, "color.RGBA{R: 0xff, G: 0x00, B: 0x00, A: 0xff}",
+		},
+		{
+			"color.NRGBA{255, 0, 0, 128}", rgba(255, 0, 0, 128),
+			halfBlue, "color.NRGBA{0, 0, 255, 128}",
+		},
+		{
+			// Premultiplied by alpha.
+			"color.RGBA{R: 0x40, A: 0x80}", protocol.Color{Red: 0x40 / float64(0x80), Alpha: 0x80 / float64(0xff)},
+			halfBlue, "color.RGBA{R: 0x00, A: 0x80, B: 0x80}",
+		},
+		{
+			"{0xFFFF, 0, 0, 0xFFFF}", red,
+			halfBlue, "{0x0000, 0x0000, 0x8000, 0x8000}",
+		},
+		{
+			"color.RGBA{G: dark, A: 0xff}", rgba(0, 0x20, 0, 0xff),
+			red, "color.RGBA{G: 0x00, A: 0xff, R: 0xff}",
+		},
+		{`"#fff"`, rgba(255, 255, 255, 255), red, `"#f00"`},
+		{`"#AbCdEf"`, rgba(0xab, 0xcd, 0xef, 255), halfBlue, `"#0000FF80"`},
+		{`"#11223380"`, rgba(0x11, 0x22, 0x33, 0x80), red, `"#ff0000ff"`},
+		{"`#abcd`", rgba(0xaa, 0xbb, 0xcc, 0xdd), rgba(0x12, 0, 0, 0xff), "`#120000ff`"},
+	} {
+		if len(lits) <= i {
+			t.Fatalf("found %d color literals, want more", len(lits))
+		}
+		lit := lits[i]
+		tok := fset.File(lit.expr.Pos())
+		if got := src[tok.Offset(lit.expr.Pos()):tok.Offset(lit.expr.End())]; got != test.text {
+			t.Errorf("literal %d: got %s, want %s", i, got, test.text)
+			continue
+		}
+		if !closeColors(lit.color, test.color) {
+			t.Errorf("%s: got color %v, want %v", test.text, lit.color, test.color)
+		}
+		if got := lit.format(test.new); got != test.want {
+			t.Errorf("%s: format(%v) = %s, want %s", test.text, test.new, got, test.want)
+		}
+		// Formatting the literal's own color preserves its value.
+		if i == 0 {
+			if got := lit.format(lit.color); got != test.text {
+				t.Errorf("%s: format of own color = %s", test.text, got)
+			}
+		}
+	}
+	if got, want := len(lits), 9; got != want {
+		t.Errorf("found %d color literals, want %d", got, want)
+	}
+}
+
+func closeColors(x, y protocol.Color) bool {
+	near := func(a, b float64) bool { return a-b < 1e-6 && b-a < 1e-6 }
+	return near(x.Red, y.Red) && near(x.Green, y.Green) && near(x.Blue, y.Blue) && near(x.Alpha, y.Alpha)
+}
@@ -28,6 +28,7 @@ import (
 
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/objectpath"
 	"golang.org/x/tools/gopls/internal/cache"
 	"golang.org/x/tools/gopls/internal/cache/metadata"
 	"golang.org/x/tools/gopls/internal/file"
@@ -37,6 +38,7 @@ import (
 	"golang.org/x/tools/gopls/internal/settings"
 	goplsastutil "golang.org/x/tools/gopls/internal/util/astutil"
 	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/gopls/internal/util/slices"
 	"golang.org/x/tools/gopls/internal/util/typesutil"
 	"golang.org/x/tools/internal/aliases"
 	"golang.org/x/tools/internal/event"
@@ -103,6 +105,11 @@ type CompletionItem struct {
 	// Documentation is the documentation for the completion item.
 	Documentation string
 
+	// Resolve, if non-nil, describes the properties of the item whose
+	// computation was deferred until the client resolves it.
+	// See [Resolve].
+	Resolve *ResolveData
+
 	// isSlice reports whether the underlying type of the object
 	// from which this candidate was derived is a slice.
 	// (Used to complete append() calls.)
@@ -113,10 +120,13 @@ type CompletionItem struct {
 type completionOptions struct {
 	unimported            bool
 	documentation         bool
-	fullDocumentation     bool
+	lazyDocumentation     bool // defer documentation until resolve
+	lazyDetail            bool // defer expensive details until resolve
+	lazyEdits             bool // defer import edits until resolve
 	placeholders          bool
 	snippets              bool
 	postfix               bool
+	postfixSnippets       []settings.PostfixSnippet
 	matcher               settings.Matcher
 	budget                time.Duration
 	completeFunctionCalls bool
@@ -179,6 +189,10 @@ type completer struct {
 	mq       golang.MetadataQualifier // for syntactic qualifying
 	opts     *completionOptions
 
+	// objectpathEncoder records the paths of deferred candidates;
+	// see [completer.resolvable].
+	objectpathEncoder objectpath.Encoder
+
 	// completionContext contains information about the trigger for this
 	// completion request.
 	completionContext completionContext
@@ -554,11 +568,14 @@ func Completion(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, p
 			matcher:               opts.Matcher,
 			unimported:            opts.CompleteUnimported,
 			documentation:         opts.CompletionDocumentation && opts.HoverKind != settings.NoDocumentation,
-			fullDocumentation:     opts.HoverKind == settings.FullDocumentation,
+			lazyDocumentation:     slices.Contains(opts.CompletionResolveOptions, "documentation"),
+			lazyDetail:            slices.Contains(opts.CompletionResolveOptions, "detail"),
+			lazyEdits:             slices.Contains(opts.CompletionResolveOptions, "additionalTextEdits"),
 			placeholders:          opts.UsePlaceholders,
 			budget:                opts.CompletionBudget,
 			snippets:              opts.InsertTextFormat == protocol.SnippetTextFormat,
 			postfix:               opts.ExperimentalPostfixCompletions,
+			postfixSnippets:       opts.PostfixSnippets,
 			completeFunctionCalls: opts.CompleteFunctionCalls,
 		},
 		// default to a matcher that always matches
@@ -10,12 +10,15 @@ import (
 	"fmt"
 	"go/ast"
 	"go/doc"
+	"go/token"
 	"go/types"
 	"strings"
 
+	"golang.org/x/tools/gopls/internal/cache"
 	"golang.org/x/tools/gopls/internal/golang"
 	"golang.org/x/tools/gopls/internal/golang/completion/snippet"
 	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/settings"
 	"golang.org/x/tools/gopls/internal/util/safetoken"
 	"golang.org/x/tools/internal/event"
 	"golang.org/x/tools/internal/imports"
@@ -54,6 +57,7 @@ func (c *completer) item(ctx context.Context, cand candidate) (CompletionItem, e
 		kind          = protocol.TextCompletion
 		snip          snippet.Builder
 		protocolEdits []protocol.TextEdit
+		resolve       ResolveData // properties deferred until resolve
 	)
 	if obj.Type() == nil {
 		detail = ""
@@ -78,10 +82,18 @@ func (c *completer) item(ctx context.Context, cand candidate) (CompletionItem, e
 		if _, ok := obj.Type().(*types.Struct); ok {
 			detail = "struct{...}" // for anonymous structs
 		} else if obj.IsField() {
-			var err error
-			detail, err = golang.FormatVarType(ctx, c.snapshot, c.pkg, obj, c.qf, c.mq)
-			if err != nil {
-				return CompletionItem{}, err
+			// Formatting the type of a field may require parsing the
+			// file that declares it, so defer it if the client allows.
+			// The detail of an imported or invoked candidate, or one
+			// with a provided detail, is not that of the field.
+			if c.opts.lazyDetail && cand.imp == nil && cand.detail == "" && !cand.hasMod(invoke) && c.resolvable(obj, &resolve) {
+				resolve.Detail = true
+			} else {
+				var err error
+				detail, err = golang.FormatVarType(ctx, c.snapshot, c.pkg, obj, c.qf, c.mq)
+				if err != nil {
+					return CompletionItem{}, err
+				}
 			}
 		}
 		if obj.IsField() {
@@ -183,13 +195,18 @@ Suffixes:
 	// If this candidate needs an additional import statement,
 	// add the additional text edits needed.
 	if cand.imp != nil {
-		addlEdits, err := c.importEdits(cand.imp)
+		if c.opts.lazyEdits {
+			resolve.ImportPath = cand.imp.importPath
+			resolve.ImportName = cand.imp.name
+		} else {
+			addlEdits, err := c.importEdits(cand.imp)
 
-		if err != nil {
-			return CompletionItem{}, err
-		}
+			if err != nil {
+				return CompletionItem{}, err
+			}
 
-		protocolEdits = append(protocolEdits, addlEdits...)
+			protocolEdits = append(protocolEdits, addlEdits...)
+		}
 		if kind != protocol.ModuleCompletion {
 			if detail != "" {
 				detail += " "
@@ -244,6 +261,11 @@ Suffixes:
 	if cand.detail != "" {
 		detail = cand.detail
 	}
+	// Finding the documentation requires parsing the declaring file,
+	// so defer it if the client allows.
+	if c.opts.documentation && c.opts.lazyDocumentation && c.resolvable(obj, &resolve) {
+		resolve.Documentation = true
+	}
 	item := CompletionItem{
 		Label:               label,
 		InsertText:          insert,
@@ -255,8 +277,13 @@ Suffixes:
 		snippet:             &snip,
 		isSlice:             isSlice(obj),
 	}
-	// If the user doesn't want documentation for completion items.
-	if !c.opts.documentation {
+	if resolve != (ResolveData{}) {
+		resolve.URI = protocol.URIFromPath(c.filename)
+		item.Resolve = &resolve
+	}
+	// If the user doesn't want documentation for completion items,
+	// or it is deferred.
+	if !c.opts.documentation || resolve.Documentation {
 		return item, nil
 	}
 	pos := safetoken.StartPosition(c.pkg.FileSet(), obj.Pos())
@@ -267,12 +294,21 @@ Suffixes:
 		return item, nil
 	}
 
-	comment, err := golang.HoverDocForObject(ctx, c.snapshot, c.pkg.FileSet(), obj)
+	setDocumentation(ctx, c.snapshot, c.pkg.FileSet(), obj, &item)
+	return item, nil
+}
+
+// setDocumentation sets the documentation of item to the doc comment
+// of obj, in full or as a synopsis according to the hoverKind setting,
+// and marks the item as deprecated if the comment says so.
+func setDocumentation(ctx context.Context, snapshot *cache.Snapshot, fset *token.FileSet, obj types.Object, item *CompletionItem) {
+	comment, err := golang.HoverDocForObject(ctx, snapshot, fset, obj)
 	if err != nil {
 		event.Error(ctx, fmt.Sprintf("failed to find Hover for %q", obj.Name()), err)
-		return item, nil
+		return
 	}
-	if c.opts.fullDocumentation {
+	opts := snapshot.Options()
+	if opts.HoverKind == settings.FullDocumentation {
 		item.Documentation = comment.Text()
 	} else {
 		item.Documentation = doc.Synopsis(comment.Text())
@@ -281,14 +317,12 @@ Suffixes:
 	// TODO(rfindley): It doesn't look like this does the right thing for
 	// multi-line comments.
 	if strings.HasPrefix(comment.Text(), "Deprecated") {
-		if c.snapshot.Options().CompletionTags {
+		if opts.CompletionTags {
 			item.Tags = []protocol.CompletionItemTag{protocol.ComplDeprecated}
-		} else if c.snapshot.Options().CompletionDeprecated {
+		} else if opts.CompletionDeprecated {
 			item.Deprecated = true
 		}
 	}
-
-	return item, nil
 }
 
 // importEdits produces the text edits necessary to add the given import to the current file.
@@ -20,6 +20,7 @@ import (
 	"golang.org/x/tools/gopls/internal/golang"
 	"golang.org/x/tools/gopls/internal/golang/completion/snippet"
 	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/settings"
 	"golang.org/x/tools/gopls/internal/util/safetoken"
 	"golang.org/x/tools/internal/aliases"
 	"golang.org/x/tools/internal/event"
@@ -573,7 +574,21 @@ func (c *completer) addPostfixSnippetCandidates(ctx context.Context, sel *ast.Se
 		afterDot = c.pos
 	}
 
-	for _, rule := range postfixTmpls {
+	// User-defined snippets follow the predefined ones.
+	rules := postfixTmpls
+	if len(c.opts.postfixSnippets) > 0 {
+		rules = append([]postfixTmpl(nil), postfixTmpls...)
+	}
+	for _, snippet := range c.opts.postfixSnippets {
+		rules = append(rules, postfixTmpl{
+			label:   snippet.Label,
+			details: snippet.Details,
+			body:    snippet.Body,
+			tmpl:    snippet.Template(),
+		})
+	}
+
+	for _, rule := range rules {
 		// When completing foo.print<>, "print" is naturally overwritten,
 		// but we need to also remove "foo." so the snippet has a clean
 		// slate.
@@ -632,9 +647,7 @@ func initPostfixRules() {
 		var idx int
 		for _, rule := range postfixTmpls {
 			var err error
-			rule.tmpl, err = template.New("postfix_snippet").Funcs(template.FuncMap{
-				"inc": inc,
-			}).Parse(rule.body)
+			rule.tmpl, err = settings.ParsePostfixTemplate("postfix_snippet", rule.body)
 			if err != nil {
 				log.Panicf("error parsing postfix snippet template: %v", err)
 			}
@@ -645,10 +658,6 @@ func initPostfixRules() {
 	})
 }
 
-func inc(i int) int {
-	return i + 1
-}
-
 // importIfNeeded returns the package identifier and any necessary
 // edits to import package pkgPath.
 func (c *completer) importIfNeeded(pkgPath string, scope *types.Scope) (string, []protocol.TextEdit, error) {
@@ -0,0 +1,120 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package completion
+
+import (
+	"context"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/types/objectpath"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/metadata"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/golang"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/util/typesutil"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/imports"
+)
+
+// ResolveData records the properties of a completion item whose
+// computation was deferred until the client resolves the item
+// (completionItem/resolve). It is sent to the client as the data of
+// the item, so it refers to the candidate object by its path, not by
+// its symbol.
+type ResolveData struct {
+	// URI is the file in which completion was requested.
+	URI protocol.DocumentURI `json:"uri"`
+
+	// PkgPath and ObjectPath identify the candidate object, if
+	// Documentation or Detail is set.
+	PkgPath    string `json:"pkgPath,omitempty"`
+	ObjectPath string `json:"objectPath,omitempty"`
+
+	// Documentation and Detail report whether the documentation and
+	// the detail of the item (the type of a field) were deferred.
+	Documentation bool `json:"documentation,omitempty"`
+	Detail        bool `json:"detail,omitempty"`
+
+	// ImportPath and ImportName, if set, describe the import that
+	// must be added for the candidate to be valid.
+	ImportPath string `json:"importPath,omitempty"`
+	ImportName string `json:"importName,omitempty"`
+}
+
+// resolvable reports whether obj can be found again when its item is
+// resolved, and if so records its path in data.
+func (c *completer) resolvable(obj types.Object, data *ResolveData) bool {
+	if obj.Pkg() == nil || !obj.Pos().IsValid() {
+		return false
+	}
+	if c.pkg.DependencyTypes(metadata.PackagePath(obj.Pkg().Path())) != obj.Pkg() {
+		return false // e.g. a candidate from an unimported package
+	}
+	path, err := c.objectpathEncoder.For(obj)
+	if err != nil {
+		return false
+	}
+	data.PkgPath = obj.Pkg().Path()
+	data.ObjectPath = string(path)
+	return true
+}
+
+// Resolve computes the deferred properties of a completion item,
+// described by data, and returns them in an otherwise empty item.
+func Resolve(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, data ResolveData) (CompletionItem, error) {
+	ctx, done := event.Start(ctx, "completion.Resolve")
+	defer done()
+
+	var item CompletionItem
+	pkg, pgf, err := golang.NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return item, err
+	}
+
+	if data.ImportPath != "" {
+		edits, err := golang.ComputeOneImportFixEdits(snapshot, pgf, &imports.ImportFix{
+			StmtInfo: imports.ImportInfo{
+				ImportPath: data.ImportPath,
+				Name:       data.ImportName,
+			},
+			FixType: imports.AddImport,
+		})
+		if err != nil {
+			return item, err
+		}
+		item.AdditionalTextEdits = edits
+	}
+
+	if !data.Documentation && !data.Detail {
+		return item, nil
+	}
+	// The file may have changed since completion, so the object may
+	// no longer exist.
+	typesPkg := pkg.DependencyTypes(metadata.PackagePath(data.PkgPath))
+	if typesPkg == nil {
+		return item, nil
+	}
+	obj, err := objectpath.Object(typesPkg, objectpath.Path(data.ObjectPath))
+	if err != nil {
+		return item, nil
+	}
+
+	if v, ok := obj.(*types.Var); ok && data.Detail {
+		qf := typesutil.FileQualifier(pgf.File, pkg.GetTypes(), pkg.GetTypesInfo())
+		mq := golang.MetadataQualifierForFile(snapshot, pgf.File, pkg.Metadata())
+		detail, err := golang.FormatVarType(ctx, snapshot, pkg, v, qf, mq)
+		if err != nil {
+			return item, err
+		}
+		item.Detail = strings.TrimPrefix(detail, "untyped ")
+	}
+
+	if data.Documentation {
+		setDocumentation(ctx, snapshot, pkg.FileSet(), obj, &item)
+	}
+	return item, nil
+}
@@ -0,0 +1,235 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file computes the "deadcode" diagnostics, which report the
+// functions of the workspace that are unreachable from any main
+// function, as does the deadcode command.
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/metadata"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/internal/event"
+)
+
+// DeadcodeDiagnostics returns a diagnostic, with a fix to delete it,
+// for each function of the workspace that is unreachable from the
+// main function of every main package of the workspace, computed
+// using Rapid Type Analysis.
+//
+// Only packages imported by a main package of the workspace are
+// reported. As the analysis is of the entire program, it reports
+// nothing if any of its packages has errors. The result is memoized
+// by the snapshot.
+func DeadcodeDiagnostics(ctx context.Context, snapshot *cache.Snapshot) (map[protocol.DocumentURI][]*cache.Diagnostic, error) {
+	v, err := snapshot.ProgramResult(ctx, deadcodeKey, func(ctx context.Context, snapshot *cache.Snapshot) (interface{}, error) {
+		return deadcodeDiagnostics(ctx, snapshot)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[protocol.DocumentURI][]*cache.Diagnostic), nil
+}
+
+// CachedDeadcodeDiagnostics returns the result of a completed call of
+// DeadcodeDiagnostics for the snapshot, if any, without computing it.
+func CachedDeadcodeDiagnostics(snapshot *cache.Snapshot) (map[protocol.DocumentURI][]*cache.Diagnostic, bool) {
+	v, ok := snapshot.CachedProgramResult(deadcodeKey)
+	if !ok {
+		return nil, false
+	}
+	return v.(map[protocol.DocumentURI][]*cache.Diagnostic), true
+}
+
+const deadcodeKey = "deadcode"
+
+func deadcodeDiagnostics(ctx context.Context, snapshot *cache.Snapshot) (map[protocol.DocumentURI][]*cache.Diagnostic, error) {
+	ctx, done := event.Start(ctx, "golang.deadcodeDiagnostics")
+	defer done()
+
+	// The program consists of the main packages of the workspace and
+	// the workspace packages they import. Other dependencies are
+	// created from their export data alone.
+	workspace, err := snapshot.WorkspaceMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	isWorkspace := make(map[PackageID]bool)
+	for _, mp := range workspace {
+		if mp.ForTest == "" {
+			isWorkspace[mp.ID] = true
+		}
+	}
+	var (
+		ids  []PackageID
+		seen = make(map[PackageID]bool)
+	)
+	var visit func(mp *metadata.Package)
+	visit = func(mp *metadata.Package) {
+		if mp == nil || seen[mp.ID] || !isWorkspace[mp.ID] {
+			return
+		}
+		seen[mp.ID] = true
+		ids = append(ids, mp.ID)
+		for _, dep := range mp.DepsByPkgPath {
+			visit(snapshot.Metadata(dep))
+		}
+	}
+	for _, mp := range workspace {
+		if mp.Name == "main" {
+			visit(mp)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	prog, built, err := buildProgram(ctx, snapshot, ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(built) < len(ids) {
+		return nil, nil // some package has errors
+	}
+
+	// Compute the functions reachable from main.
+	var roots []*ssa.Function
+	for ssaPkg, pkg := range built {
+		if pkg.Metadata().Name == "main" && ssaPkg.Func("main") != nil {
+			roots = append(roots, ssaPkg.Func("init"), ssaPkg.Func("main"))
+		}
+	}
+	if len(roots) == 0 {
+		return nil, nil
+	}
+	reachable := make(map[token.Pos]bool) // positions of reachable functions and their instances
+	for fn := range reachableFuncs(roots) {
+		if fn.Pos().IsValid() {
+			reachable[fn.Pos()] = true
+		}
+	}
+
+	// Report the source functions, ignoring nested functions, whose
+	// enclosing functions are dead if they are.
+	diagnostics := make(map[protocol.DocumentURI][]*cache.Diagnostic)
+	for ssaPkg, pkg := range built {
+		for _, pgf := range pkg.CompiledGoFiles() {
+			if IsGenerated(ctx, snapshot, pgf.URI) {
+				continue
+			}
+			for _, decl := range pgf.File.Decls {
+				decl, ok := decl.(*ast.FuncDecl)
+				if !ok || decl.Name.Name == "init" || decl.Name.Name == "_" {
+					continue
+				}
+				obj, ok := pkg.GetTypesInfo().Defs[decl.Name].(*types.Func)
+				if !ok || reachable[obj.Pos()] {
+					continue
+				}
+				fn := prog.FuncValue(obj)
+				if fn == nil {
+					continue
+				}
+				diag, err := deadcodeDiagnostic(pgf, decl, fn.RelString(ssaPkg.Pkg))
+				if err != nil {
+					return nil, err
+				}
+				diagnostics[pgf.URI] = append(diagnostics[pgf.URI], diag)
+			}
+		}
+	}
+	return diagnostics, nil
+}
+
+// reachableFuncs returns the functions reachable from roots, as
+// computed by Rapid Type Analysis. As the dependencies of the workspace
+// are created from their types alone, the calls they make are unknown,
+// so any function whose value is used by a reachable function is
+// assumed to be called.
+func reachableFuncs(roots []*ssa.Function) map[*ssa.Function]bool {
+	isRoot := make(map[*ssa.Function]bool)
+	for _, fn := range roots {
+		isRoot[fn] = true
+	}
+	for {
+		res := rta.Analyze(roots, false)
+		n := len(roots)
+		for fn := range res.Reachable {
+			for _, b := range fn.Blocks {
+				for _, instr := range b.Instrs {
+					rands := instr.Operands(nil)
+					if _, ok := instr.(ssa.CallInstruction); ok {
+						rands = rands[1:] // the callee
+					}
+					for _, op := range rands {
+						if g, ok := (*op).(*ssa.Function); ok && !isRoot[g] {
+							isRoot[g] = true
+							roots = append(roots, g)
+						}
+					}
+				}
+			}
+		}
+		if len(roots) == n {
+			reachable := make(map[*ssa.Function]bool)
+			for fn := range res.Reachable {
+				reachable[fn] = true
+			}
+			return reachable
+		}
+	}
+}
+
+// deadcodeDiagnostic returns the diagnostic for the dead function
+// declared by decl, whose fix deletes the declaration, its doc comment,
+// and the blank line that follows it.
+func deadcodeDiagnostic(pgf *parsego.File, decl *ast.FuncDecl, name string) (*cache.Diagnostic, error) {
+	rng, err := pgf.NodeRange(decl.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	start := decl.Pos()
+	if decl.Doc != nil {
+		start = decl.Doc.Pos()
+	}
+	start = pgf.Tok.LineStart(safetoken.Line(pgf.Tok, start))
+	startOffset, endOffset, err := safetoken.Offsets(pgf.Tok, start, decl.End())
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < 2 && endOffset < len(pgf.Src) && pgf.Src[endOffset] == '\n'; i++ {
+		endOffset++ // the end of the line, then a blank line
+	}
+	deleteRng, err := pgf.Mapper.OffsetRange(startOffset, endOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cache.Diagnostic{
+		URI:      pgf.URI,
+		Range:    rng,
+		Severity: protocol.SeverityHint,
+		Source:   cache.Deadcode,
+		Message:  fmt.Sprintf("%s is unreachable from any main", name),
+		Tags:     []protocol.DiagnosticTag{protocol.Unnecessary},
+		SuggestedFixes: []cache.SuggestedFix{{
+			Title:      fmt.Sprintf("Delete unreachable function %s", name),
+			Edits:      map[protocol.DocumentURI][]protocol.TextEdit{pgf.URI: {{Range: deleteRng}}},
+			ActionKind: protocol.QuickFix,
+		}},
+	}, nil
+}
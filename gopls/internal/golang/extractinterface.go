@@ -0,0 +1,379 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file defines the "extract interface" refactoring, which
+// declares an interface with the methods of a named type.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/imports"
+)
+
+// ExtractInterface declares an interface with the named methods of
+// the named type whose name is at the start of rng, or all of its
+// exported methods if methods is empty. The interface is declared
+// before the type, and is called name, or a name derived from that of
+// the type if name is empty.
+//
+// If replaceParams is set, the type (or a pointer to it) is replaced
+// by the interface in each parameter of a function of the package
+// that uses the parameter only to call methods of the interface,
+// provided the function is only ever called.
+func ExtractInterface(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, rng protocol.Range, name string, methods []string, replaceParams bool) ([]protocol.DocumentChanges, error) {
+	ctx, done := event.Start(ctx, "golang.ExtractInterface")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(rng.Start)
+	if err != nil {
+		return nil, err
+	}
+	tname, err := extractableType(pkg, pgf, pos)
+	if err != nil {
+		return nil, err
+	}
+	named := tname.Type().(*types.Named)
+
+	// Choose the methods.
+	exported := exportedMethods(named)
+	var chosen []*types.Func
+	if len(methods) == 0 {
+		chosen = exported
+	} else {
+		for _, m := range methods {
+			i := 0
+			for i < len(exported) && exported[i].Name() != m {
+				i++
+			}
+			if i == len(exported) {
+				return nil, fmt.Errorf("%s has no exported method %s", tname.Name(), m)
+			}
+			chosen = append(chosen, exported[i])
+		}
+	}
+	if len(chosen) == 0 {
+		return nil, fmt.Errorf("%s has no exported methods", tname.Name())
+	}
+	for _, m := range chosen {
+		if obj := inaccessibleType(m.Type(), pkg.GetTypes()); obj != nil {
+			return nil, fmt.Errorf("method %s refers to unexported type %s.%s", m.Name(), obj.Pkg().Name(), obj.Name())
+		}
+	}
+
+	// Choose the name.
+	scope := pkg.GetTypes().Scope()
+	if name == "" {
+		name = tname.Name() + "Interface"
+		for i := 2; scope.Lookup(name) != nil; i++ {
+			name = fmt.Sprintf("%sInterface%d", tname.Name(), i)
+		}
+	} else if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("invalid interface name %q", name)
+	} else if scope.Lookup(name) != nil {
+		return nil, fmt.Errorf("%s is already declared in package %s", name, pkg.GetTypes().Name())
+	}
+
+	// Find the declaration of the type.
+	declPGF, err := pkg.File(protocol.URIFromPath(pkg.FileSet().File(tname.Pos()).Name()))
+	if err != nil {
+		return nil, err
+	}
+	path, _ := astutil.PathEnclosingInterval(declPGF.File, tname.Pos(), tname.Pos())
+	decl, ok := path[len(path)-2].(*ast.GenDecl)
+	if !ok {
+		return nil, fmt.Errorf("can't find declaration of %s", tname.Name())
+	}
+	declStart := decl.Pos()
+	if decl.Doc != nil {
+		declStart = decl.Doc.Pos()
+	}
+	declOffset, err := safetoken.Offset(declPGF.Tok, declStart)
+	if err != nil {
+		return nil, err
+	}
+	declOffset = bytes.LastIndexByte(declPGF.Src[:declOffset], '\n') + 1
+
+	// Format the interface.
+	qual, newImports := newFileQualifier(snapshot, pkg.Metadata(), declPGF)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// %s is implemented by %s.\n", name, tname.Name())
+	fmt.Fprintf(&buf, "type %s interface {\n", name)
+	for _, m := range chosen {
+		if doc := methodDoc(pkg, m); doc != nil {
+			for _, c := range doc.List {
+				fmt.Fprintf(&buf, "\t%s\n", c.Text)
+			}
+		}
+		sig := types.TypeString(m.Type(), qual)
+		fmt.Fprintf(&buf, "\t%s%s\n", m.Name(), strings.TrimPrefix(sig, "func"))
+	}
+	buf.WriteString("}\n\n")
+
+	edits := make(map[protocol.DocumentURI][]protocol.TextEdit)
+	if len(*newImports) > 0 {
+		var fixes []*imports.ImportFix
+		for _, imp := range *newImports {
+			fixes = append(fixes, &imports.ImportFix{
+				StmtInfo: imports.ImportInfo{ImportPath: imp.importPath, Name: imp.name},
+				FixType:  imports.AddImport,
+			})
+		}
+		importEdits, err := computeImportFixEdits(snapshot, declPGF, fixes)
+		if err != nil {
+			return nil, err
+		}
+		edits[declPGF.URI] = importEdits
+	}
+	insertRange, err := declPGF.Mapper.OffsetRange(declOffset, declOffset)
+	if err != nil {
+		return nil, err
+	}
+	edits[declPGF.URI] = append(edits[declPGF.URI], protocol.TextEdit{Range: insertRange, NewText: buf.String()})
+
+	if replaceParams {
+		if err := replaceParamTypes(pkg, named, chosen, name, edits); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []protocol.DocumentChanges
+	for _, pgf := range pkg.CompiledGoFiles() {
+		if fileEdits, ok := edits[pgf.URI]; ok {
+			fh, err := snapshot.ReadFile(ctx, pgf.URI)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, documentChanges(fh, fileEdits)...)
+		}
+	}
+	return changes, nil
+}
+
+// extractableType returns the named type whose name is at pos, if it
+// is a package-level non-generic type of the package with exported
+// methods, from which an interface may be extracted.
+func extractableType(pkg *cache.Package, pgf *parsego.File, pos token.Pos) (*types.TypeName, error) {
+	path, _ := astutil.PathEnclosingInterval(pgf.File, pos, pos)
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("no type name at cursor")
+	}
+	return checkExtractableType(pkg, identObject(pkg.GetTypesInfo(), id))
+}
+
+// checkExtractableType reports whether obj is a type from which an
+// interface may be extracted; see extractableType.
+func checkExtractableType(pkg *cache.Package, obj types.Object) (*types.TypeName, error) {
+	tname, ok := obj.(*types.TypeName)
+	if !ok || tname.IsAlias() {
+		return nil, fmt.Errorf("not a named type")
+	}
+	named, ok := tname.Type().(*types.Named)
+	if !ok || tname.Pkg() != pkg.GetTypes() || tname.Parent() != tname.Pkg().Scope() {
+		return nil, fmt.Errorf("%s is not a package-level type of this package", tname.Name())
+	}
+	if named.TypeParams().Len() > 0 {
+		return nil, fmt.Errorf("cannot extract an interface from generic type %s", tname.Name())
+	}
+	if types.IsInterface(named) {
+		return nil, fmt.Errorf("%s is already an interface", tname.Name())
+	}
+	if len(exportedMethods(named)) == 0 {
+		return nil, fmt.Errorf("%s has no exported methods", tname.Name())
+	}
+	return tname, nil
+}
+
+// exportedMethods returns the exported methods of the method set of
+// *T, for the named type T.
+func exportedMethods(named *types.Named) []*types.Func {
+	var methods []*types.Func
+	mset := types.NewMethodSet(types.NewPointer(named))
+	for i := 0; i < mset.Len(); i++ {
+		if m := mset.At(i).Obj().(*types.Func); m.Exported() {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// methodDoc returns the doc comment of the method m, if it is
+// declared in the package.
+func methodDoc(pkg *cache.Package, m *types.Func) *ast.CommentGroup {
+	for _, pgf := range pkg.CompiledGoFiles() {
+		if pgf.Tok.Base() <= int(m.Pos()) && int(m.Pos()) <= pgf.Tok.Base()+pgf.Tok.Size() {
+			path, _ := astutil.PathEnclosingInterval(pgf.File, m.Pos(), m.Pos())
+			for _, n := range path {
+				if decl, ok := n.(*ast.FuncDecl); ok {
+					return decl.Doc
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// inaccessibleType returns the type name of an unexported type of
+// another package referenced by t, if any.
+func inaccessibleType(t types.Type, pkg *types.Package) *types.TypeName {
+	var found *types.TypeName
+	var visit func(t types.Type)
+	visit = func(t types.Type) {
+		if found != nil {
+			return
+		}
+		switch t := t.(type) {
+		case *types.Named:
+			if obj := t.Obj(); obj.Pkg() != nil && obj.Pkg() != pkg && !obj.Exported() {
+				found = obj
+				return
+			}
+			if args := t.TypeArgs(); args != nil {
+				for i := 0; i < args.Len(); i++ {
+					visit(args.At(i))
+				}
+			}
+		case *types.Array:
+			visit(t.Elem())
+		case *types.Chan:
+			visit(t.Elem())
+		case *types.Map:
+			visit(t.Key())
+			visit(t.Elem())
+		case *types.Pointer:
+			visit(t.Elem())
+		case *types.Slice:
+			visit(t.Elem())
+		case *types.Signature:
+			visit(t.Params())
+			visit(t.Results())
+		case *types.Tuple:
+			for i := 0; i < t.Len(); i++ {
+				visit(t.At(i).Type())
+			}
+		case *types.Struct:
+			for i := 0; i < t.NumFields(); i++ {
+				if f := t.Field(i); f.Pkg() != pkg && !f.Exported() {
+					found = types.NewTypeName(token.NoPos, f.Pkg(), f.Name(), nil)
+					return
+				}
+				visit(t.Field(i).Type())
+			}
+		case *types.Interface:
+			for i := 0; i < t.NumMethods(); i++ {
+				visit(t.Method(i).Type())
+			}
+		}
+	}
+	visit(t)
+	return found
+}
+
+// replaceParamTypes adds to edits the replacement of named (or a
+// pointer to it) by the interface iface, which has the given methods,
+// in the parameters of the package's functions that use the parameter
+// only to call those methods, and that are only ever called.
+func replaceParamTypes(pkg *cache.Package, named *types.Named, methods []*types.Func, iface string, edits map[protocol.DocumentURI][]protocol.TextEdit) error {
+	info := pkg.GetTypesInfo()
+	names := make(map[string]bool)
+	for _, m := range methods {
+		names[m.Name()] = true
+	}
+	// Does the value type implement the interface,
+	// or only the pointer type?
+	valueOK := true
+	vset := types.NewMethodSet(named)
+	for _, m := range methods {
+		if vset.Lookup(m.Pkg(), m.Name()) == nil {
+			valueOK = false
+		}
+	}
+
+	// Find the functions that are used other than by being called,
+	// and the uses of each parameter that are not method calls.
+	var (
+		called    = make(map[*ast.Ident]bool)
+		otherUses = make(map[types.Object]bool)
+	)
+	for _, pgf := range pkg.CompiledGoFiles() {
+		ast.Inspect(pgf.File, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.CallExpr:
+				if id, ok := astutil.Unparen(n.Fun).(*ast.Ident); ok {
+					called[id] = true
+				}
+			case *ast.SelectorExpr:
+				// The selection of a method of the interface
+				// is not an other use of a variable.
+				if _, ok := n.X.(*ast.Ident); ok {
+					if sel, ok := info.Selections[n]; ok && sel.Kind() == types.MethodVal && names[n.Sel.Name] {
+						return false
+					}
+				}
+			case *ast.Ident:
+				if obj := info.Uses[n]; obj != nil && !called[n] {
+					otherUses[obj] = true
+				}
+			}
+			return true
+		})
+	}
+
+	for _, pgf := range pkg.CompiledGoFiles() {
+		for _, decl := range pgf.File.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || fn.Body == nil {
+				continue // methods may need their signatures to satisfy interfaces
+			}
+			if obj := info.Defs[fn.Name]; obj == nil || otherUses[obj] || fn.Name.Name == "init" || fn.Name.Name == "main" {
+				continue
+			}
+			for _, field := range fn.Type.Params.List {
+				t := info.TypeOf(field.Type)
+				if ptr, ok := t.(*types.Pointer); ok {
+					t = ptr.Elem()
+				} else if !valueOK {
+					continue
+				}
+				if t != named {
+					continue
+				}
+				usedOnlyForMethods := true
+				for _, id := range field.Names {
+					if obj := info.Defs[id]; obj != nil && otherUses[obj] {
+						usedOnlyForMethods = false
+					}
+				}
+				if !usedOnlyForMethods {
+					continue
+				}
+				rng, err := pgf.NodeRange(field.Type)
+				if err != nil {
+					return err
+				}
+				edits[pgf.URI] = append(edits[pgf.URI], protocol.TextEdit{Range: rng, NewText: iface})
+			}
+		}
+	}
+	return nil
+}
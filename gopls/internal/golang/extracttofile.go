@@ -0,0 +1,492 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file defines the "extract to file" refactoring, which moves a
+// group of top-level declarations to another file of the same package.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"go/types"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/gopls/internal/util/slices"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/imports"
+)
+
+// CanExtractToFile reports whether the selection [start, end) of the
+// file consists of whole top-level declarations (other than imports)
+// that could be moved to another file.
+func CanExtractToFile(pgf *parsego.File, start, end token.Pos) bool {
+	_, _, _, err := selectedDecls(pgf, start, end)
+	return err == nil
+}
+
+// ExtractToFile moves the top-level declarations selected by rng in
+// the file fh to the file dest of the same package, creating dest if
+// it does not exist, or a new file named after the first declaration
+// if dest is empty. The doc comments of the declarations move with
+// them; the new file inherits the comments preceding the package
+// clause of fh, such as its copyright header and build constraints.
+// Each file is given the imports it needs, and no more.
+func ExtractToFile(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, rng protocol.Range, dest protocol.DocumentURI) ([]protocol.DocumentChanges, error) {
+	ctx, done := event.Start(ctx, "golang.ExtractToFile")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	decls, startOffset, endOffset, err := selectedDecls(pgf, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the imports needed by the moved declarations, and those
+	// still needed by the rest of the file.
+	info := pkg.GetTypesInfo()
+	needed, err := importsUsedBy(pkg.GetTypes(), info, pgf.File, decls)
+	if err != nil {
+		return nil, err
+	}
+	moved := make(map[ast.Decl]bool)
+	for _, decl := range decls {
+		moved[decl] = true
+	}
+	var rest []ast.Decl
+	for _, decl := range pgf.File.Decls {
+		if !moved[decl] {
+			rest = append(rest, decl)
+		}
+	}
+	stillNeeded, err := importsUsedBy(pkg.GetTypes(), info, pgf.File, rest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delete the declarations and any imports that are no longer
+	// needed from the source file.
+	var deletions []*imports.ImportFix
+	for _, spec := range pgf.File.Imports {
+		if needed[spec] && !stillNeeded[spec] {
+			fix := importSpecFix(spec)
+			fix.FixType = imports.DeleteImport
+			deletions = append(deletions, fix)
+		}
+	}
+	var srcEdits []protocol.TextEdit
+	if len(deletions) > 0 {
+		srcEdits, err = computeImportFixEdits(snapshot, pgf, deletions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	declRange, err := pgf.Mapper.OffsetRange(startOffset, endOffset)
+	if err != nil {
+		return nil, err
+	}
+	srcEdits = append(srcEdits, protocol.TextEdit{Range: declRange})
+	changes := documentChanges(fh, srcEdits)
+
+	text := string(bytes.TrimRight(pgf.Src[startOffset:endOffset], "\n")) + "\n"
+
+	if dest == "" {
+		dest, err = chooseNewFile(ctx, snapshot, pgf.URI, decls[0])
+		if err != nil {
+			return nil, err
+		}
+	} else if filepath.Dir(dest.Path()) != filepath.Dir(pgf.URI.Path()) {
+		return nil, fmt.Errorf("%s is not in the directory of %s", dest.Path(), pgf.URI.Path())
+	} else if !strings.HasSuffix(dest.Path(), ".go") {
+		return nil, fmt.Errorf("%s is not a Go file", dest.Path())
+	}
+
+	// Move the declarations to the end of an existing file.
+	if destPgf, err := pkg.File(dest); err == nil {
+		if destPgf.URI == pgf.URI {
+			return nil, fmt.Errorf("cannot move declarations to the file containing them")
+		}
+		var additions []*imports.ImportFix
+		for _, spec := range pgf.File.Imports {
+			if !needed[spec] {
+				continue
+			}
+			if ok, err := hasImport(info, destPgf, spec); err != nil {
+				return nil, err
+			} else if !ok {
+				fix := importSpecFix(spec)
+				fix.FixType = imports.AddImport
+				additions = append(additions, fix)
+			}
+		}
+		var destEdits []protocol.TextEdit
+		if len(additions) > 0 {
+			destEdits, err = computeImportFixEdits(snapshot, destPgf, additions)
+			if err != nil {
+				return nil, err
+			}
+		}
+		eof := len(destPgf.Src)
+		eofRange, err := destPgf.Mapper.OffsetRange(eof, eof)
+		if err != nil {
+			return nil, err
+		}
+		newText := "\n" + text
+		if eof > 0 && destPgf.Src[eof-1] != '\n' {
+			newText = "\n" + newText
+		}
+		destEdits = append(destEdits, protocol.TextEdit{Range: eofRange, NewText: newText})
+		destFh, err := snapshot.ReadFile(ctx, dest)
+		if err != nil {
+			return nil, err
+		}
+		return append(changes, documentChanges(destFh, destEdits)...), nil
+	}
+
+	// Otherwise create a new file.
+	if destFh, err := snapshot.ReadFile(ctx, dest); err != nil {
+		return nil, err
+	} else if _, err := destFh.Content(); err == nil {
+		return nil, fmt.Errorf("%s is not a file of package %s", dest.Path(), pkg.Metadata().PkgPath)
+	}
+	if !slices.Contains(snapshot.Options().SupportedResourceOperations, protocol.Create) {
+		return nil, errors.New("can't extract to a new file: LSP client does not support file creation")
+	}
+	var buf bytes.Buffer
+	header := pgf.File.Package
+	if pgf.File.Doc != nil {
+		header = pgf.File.Doc.Pos() // the package doc comment stays put
+	}
+	headerOffset, err := safetoken.Offset(pgf.Tok, header)
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(pgf.Src[:headerOffset])
+	fmt.Fprintf(&buf, "package %s\n", pgf.File.Name.Name)
+	var specs []*ast.ImportSpec
+	for _, spec := range pgf.File.Imports {
+		if needed[spec] {
+			specs = append(specs, spec)
+		}
+	}
+	switch len(specs) {
+	case 0:
+	case 1:
+		fmt.Fprintf(&buf, "\nimport %s\n", importSpecText(specs[0]))
+	default:
+		buf.WriteString("\nimport (\n")
+		for _, spec := range specs {
+			fmt.Fprintf(&buf, "\t%s\n", importSpecText(spec))
+		}
+		buf.WriteString(")\n")
+	}
+	buf.WriteString("\n")
+	buf.WriteString(text)
+
+	changes = append(changes,
+		protocol.DocumentChanges{
+			CreateFile: &protocol.CreateFile{Kind: "create", URI: dest},
+		},
+		protocol.DocumentChanges{
+			TextDocumentEdit: &protocol.TextDocumentEdit{
+				TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: dest},
+				},
+				Edits: protocol.AsAnnotatedTextEdits([]protocol.TextEdit{{NewText: buf.String()}}),
+			},
+		})
+	return changes, nil
+}
+
+// selectedDecls returns the top-level declarations of the file that
+// intersect the selection [start, end), which must consist of whole
+// lines of declarations other than imports. It also returns the
+// offsets of the text of the declarations, including their doc
+// comments and any trailing line comment and blank lines.
+func selectedDecls(pgf *parsego.File, start, end token.Pos) (decls []ast.Decl, startOffset, endOffset int, _ error) {
+	tok := pgf.Tok
+	for _, decl := range pgf.File.Decls {
+		declStart := decl.Pos()
+		if doc := declDoc(decl); doc != nil {
+			declStart = doc.Pos()
+		}
+		if decl.End() <= start || end <= declStart {
+			continue // disjoint
+		}
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			return nil, 0, 0, errors.New("cannot move import declarations")
+		}
+		// A selection that starts or ends within a declaration must
+		// do so on its first or last line.
+		if start > declStart && safetoken.Line(tok, start) > safetoken.Line(tok, decl.Pos()) ||
+			end < decl.End() && safetoken.Line(tok, end) < safetoken.Line(tok, decl.End()) {
+			return nil, 0, 0, errors.New("selection does not contain whole declarations")
+		}
+		if len(decls) == 0 {
+			var err error
+			startOffset, err = safetoken.Offset(tok, declStart)
+			if err != nil {
+				return nil, 0, 0, err
+			}
+		}
+		var err error
+		endOffset, err = safetoken.Offset(tok, decl.End())
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		decls = append(decls, decl)
+	}
+	if len(decls) == 0 {
+		return nil, 0, 0, errors.New("no declarations selected")
+	}
+
+	// Extend the text to whole lines, including any comment that
+	// follows the last declaration on its line, and the blank lines
+	// that follow it.
+	src := pgf.Src
+	lineStart := bytes.LastIndexByte(src[:startOffset], '\n') + 1
+	if len(bytes.TrimSpace(src[lineStart:startOffset])) > 0 {
+		return nil, 0, 0, errors.New("declaration does not start its line")
+	}
+	startOffset = lineStart
+	lineEnd := len(src)
+	if i := bytes.IndexByte(src[endOffset:], '\n'); i >= 0 {
+		lineEnd = endOffset + i + 1
+	}
+	if rest := bytes.TrimSpace(src[endOffset:lineEnd]); len(rest) > 0 && !bytes.HasPrefix(rest, []byte("//")) {
+		return nil, 0, 0, errors.New("declaration does not end its line")
+	}
+	endOffset = lineEnd
+	for endOffset < len(src) && src[endOffset] == '\n' {
+		endOffset++
+	}
+	if endOffset == len(src) {
+		// At the end of the file, remove the preceding blank lines.
+		for startOffset >= 2 && src[startOffset-1] == '\n' && src[startOffset-2] == '\n' {
+			startOffset--
+		}
+	}
+	return decls, startOffset, endOffset, nil
+}
+
+// declDoc returns the doc comment of a declaration, if any.
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Doc
+	case *ast.GenDecl:
+		return decl.Doc
+	}
+	return nil
+}
+
+// importsUsedBy returns the set of import specs of the file that are
+// referenced by the given declarations.
+func importsUsedBy(pkg *types.Package, info *types.Info, f *ast.File, decls []ast.Decl) (map[*ast.ImportSpec]bool, error) {
+	specs := make(map[*types.PkgName]*ast.ImportSpec)
+	dotSpecs := make(map[string]*ast.ImportSpec) // by package path
+	for _, spec := range f.Imports {
+		obj := info.Implicits[spec]
+		if obj == nil && spec.Name != nil {
+			obj = info.Defs[spec.Name]
+		}
+		if pkgName, ok := obj.(*types.PkgName); ok {
+			specs[pkgName] = spec
+			if spec.Name != nil && spec.Name.Name == "." {
+				dotSpecs[pkgName.Imported().Path()] = spec
+			}
+		}
+	}
+
+	used := make(map[*ast.ImportSpec]bool)
+	var err error
+	for _, decl := range decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			continue
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			return visitImportUse(n, pkg, info, specs, dotSpecs, used, &err)
+		})
+	}
+	return used, err
+}
+
+// visitImportUse records in used the import spec, if any, referred to
+// by the identifier n. It is a helper for importsUsedBy.
+func visitImportUse(n ast.Node, pkg *types.Package, info *types.Info, specs map[*types.PkgName]*ast.ImportSpec, dotSpecs map[string]*ast.ImportSpec, used map[*ast.ImportSpec]bool, errp *error) bool {
+	if *errp != nil {
+		return false
+	}
+	if sel, ok := n.(*ast.SelectorExpr); ok {
+		// Don't visit Sel, which is never a dot-imported name.
+		ast.Inspect(sel.X, func(n ast.Node) bool {
+			return visitImportUse(n, pkg, info, specs, dotSpecs, used, errp)
+		})
+		return false
+	}
+	id, ok := n.(*ast.Ident)
+	if !ok {
+		return true
+	}
+	switch obj := info.Uses[id].(type) {
+	case *types.PkgName:
+		if obj.Imported().Path() == "C" {
+			*errp = errors.New("cannot move declarations that use cgo")
+			return false
+		}
+		if spec, ok := specs[obj]; ok {
+			used[spec] = true
+		}
+	case nil:
+	default:
+		// A package-level object of another package, referred to
+		// without qualification, was dot-imported.
+		if p := obj.Pkg(); p != nil && p != pkg && obj.Parent() == p.Scope() {
+			if spec, ok := dotSpecs[p.Path()]; ok {
+				used[spec] = true
+			}
+		}
+	}
+	return true
+}
+
+// hasImport reports whether the file imports the package of spec
+// under the same name. It returns an error if the file imports another
+// package under that name.
+func hasImport(info *types.Info, pgf *parsego.File, spec *ast.ImportSpec) (bool, error) {
+	path, name := importSpecPathName(info, spec)
+	for _, other := range pgf.File.Imports {
+		otherPath, otherName := importSpecPathName(info, other)
+		if otherName != name {
+			continue
+		}
+		if otherPath == path {
+			return true, nil
+		}
+		if name != "." {
+			return false, fmt.Errorf("%s imports %q as %s", filepath.Base(pgf.URI.Path()), otherPath, name)
+		}
+	}
+	return false, nil
+}
+
+// importSpecPathName returns the path of the imported package and the
+// name by which it is known in the file.
+func importSpecPathName(info *types.Info, spec *ast.ImportSpec) (path, name string) {
+	path, _ = strconv.Unquote(spec.Path.Value)
+	if spec.Name != nil {
+		return path, spec.Name.Name
+	}
+	if pkgName, ok := info.Implicits[spec].(*types.PkgName); ok {
+		return path, pkgName.Imported().Name()
+	}
+	return path, ""
+}
+
+// importSpecFix returns an ImportFix (without a FixType) for the
+// given import spec.
+func importSpecFix(spec *ast.ImportSpec) *imports.ImportFix {
+	path, _ := strconv.Unquote(spec.Path.Value)
+	fix := &imports.ImportFix{StmtInfo: imports.ImportInfo{ImportPath: path}}
+	if spec.Name != nil {
+		fix.StmtInfo.Name = spec.Name.Name
+	}
+	return fix
+}
+
+// importSpecText returns the text of an import spec, without comments.
+func importSpecText(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name + " " + spec.Path.Value
+	}
+	return spec.Path.Value
+}
+
+// chooseNewFile returns the URI of a file that does not yet exist, in
+// the directory of the file src, named after the first name declared
+// by decl. The name of a test file ends in _test.go.
+func chooseNewFile(ctx context.Context, snapshot *cache.Snapshot, src protocol.DocumentURI, decl ast.Decl) (protocol.DocumentURI, error) {
+	var name string
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		name = decl.Name.Name
+	case *ast.GenDecl:
+		if len(decl.Specs) > 0 {
+			switch spec := decl.Specs[0].(type) {
+			case *ast.TypeSpec:
+				name = spec.Name.Name
+			case *ast.ValueSpec:
+				name = spec.Names[0].Name
+			}
+		}
+	}
+	// The go command ignores files whose names start with "_".
+	name = strings.TrimLeft(strings.ToLower(name), "_")
+	if name == "" {
+		name = "extracted"
+	}
+	// A name must not make a file a test file, or constrain it to a
+	// GOOS or GOARCH.
+	if strings.HasSuffix(name, "_test") || hasImplicitConstraint(name+".go") {
+		name += "_decls"
+	}
+	suffix := ".go"
+	if strings.HasSuffix(src.Path(), "_test.go") {
+		suffix = "_test.go"
+	}
+	dir := filepath.Dir(src.Path())
+	for i := 0; ; i++ {
+		base := name + suffix
+		if i > 0 {
+			base = fmt.Sprintf("%s.%d%s", name, i, suffix)
+		}
+		uri := protocol.URIFromPath(filepath.Join(dir, base))
+		fh, err := snapshot.ReadFile(ctx, uri)
+		if err != nil {
+			return "", err
+		}
+		if _, err := fh.Content(); err != nil {
+			return uri, nil // doesn't exist
+		}
+	}
+}
+
+// hasImplicitConstraint reports whether a Go file named base is built
+// only for some GOOS or GOARCH because of its name, as is
+// foo_windows.go. Names such as windows.go, which would become
+// constrained by the addition of a prefix, are also reported.
+func hasImplicitConstraint(base string) bool {
+	ctxt := build.Default // make a copy
+	// Match no GOOS or GOARCH suffix.
+	ctxt.GOOS, ctxt.GOARCH = "", ""
+	ctxt.OpenFile = func(string) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("package p")), nil
+	}
+	for _, name := range []string{base, "x_" + base} {
+		if ok, err := ctxt.MatchFile("", name); err != nil || !ok {
+			return true
+		}
+	}
+	return false
+}
@@ -66,6 +66,9 @@ const (
 	fixInvertIfCondition = "invert_if_condition"
 	fixSplitLines        = "split_lines"
 	fixJoinLines         = "join_lines"
+	fixReorderFields     = "reorder_fields"
+	fixNameStructType    = "name_struct_type"
+	fixGeneralizeParam   = "generalize_param"
 )
 
 // ApplyFix applies the specified kind of suggested fix to the given
@@ -119,6 +122,9 @@ func ApplyFix(ctx context.Context, fix string, snapshot *cache.Snapshot, fh file
 		fixInvertIfCondition: singleFile(invertIfCondition),
 		fixSplitLines:        singleFile(splitLines),
 		fixJoinLines:         singleFile(joinLines),
+		fixReorderFields:     reorderFields,
+		fixNameStructType:    singleFile(nameStructType),
+		fixGeneralizeParam:   generalizeParam,
 	}
 	fixer, ok := fixers[fix]
 	if !ok {
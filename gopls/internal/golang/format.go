@@ -159,6 +159,11 @@ func computeImportEdits(ctx context.Context, pgf *parsego.File, options *imports
 
 // ComputeOneImportFixEdits returns text edits for a single import fix.
 func ComputeOneImportFixEdits(snapshot *cache.Snapshot, pgf *parsego.File, fix *imports.ImportFix) ([]protocol.TextEdit, error) {
+	return computeImportFixEdits(snapshot, pgf, []*imports.ImportFix{fix})
+}
+
+// computeImportFixEdits returns text edits for a set of import fixes.
+func computeImportFixEdits(snapshot *cache.Snapshot, pgf *parsego.File, fixes []*imports.ImportFix) ([]protocol.TextEdit, error) {
 	options := &imports.Options{
 		LocalPrefix: snapshot.Options().Local,
 		// Defaults.
@@ -169,7 +174,7 @@ func ComputeOneImportFixEdits(snapshot *cache.Snapshot, pgf *parsego.File, fix *
 		TabIndent:  true,
 		TabWidth:   8,
 	}
-	return computeFixEdits(pgf, options, []*imports.ImportFix{fix})
+	return computeFixEdits(pgf, options, fixes)
 }
 
 func computeFixEdits(pgf *parsego.File, options *imports.Options, fixes []*imports.ImportFix) ([]protocol.TextEdit, error) {
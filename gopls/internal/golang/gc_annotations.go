@@ -7,35 +7,164 @@ package golang
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"go/token"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"golang.org/x/tools/gopls/internal/cache"
 	"golang.org/x/tools/gopls/internal/cache/metadata"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/filecache"
 	"golang.org/x/tools/gopls/internal/protocol"
 	"golang.org/x/tools/gopls/internal/settings"
+	"golang.org/x/tools/internal/event"
 	"golang.org/x/tools/internal/gocommand"
 )
 
+// GCOptimizationDetails returns the compiler's optimization details for
+// the package mp, as diagnostics, filtered by the annotations setting.
+//
+// The details are computed by compiling the package as it is saved on
+// disk, and are stored in the file cache, keyed by the build
+// configuration and the files of the package and of its non-standard
+// dependencies.
 func GCOptimizationDetails(ctx context.Context, snapshot *cache.Snapshot, mp *metadata.Package) (map[protocol.DocumentURI][]*cache.Diagnostic, error) {
+	details, err := gcDetails(ctx, snapshot, mp)
+	if err != nil {
+		return nil, err
+	}
+	return filterGCDetails(snapshot, details), nil
+}
+
+// filterGCDetails returns the details selected by the annotations
+// setting.
+func filterGCDetails(snapshot *cache.Snapshot, details map[protocol.DocumentURI][]*cache.Diagnostic) map[protocol.DocumentURI][]*cache.Diagnostic {
+	opts := snapshot.Options()
+	reports := make(map[protocol.DocumentURI][]*cache.Diagnostic)
+	for uri, diagnostics := range details {
+		for _, diag := range diagnostics {
+			if showDiagnostic(diag.Message, opts) {
+				diag := *diag // the details are shared
+				reports[uri] = append(reports[uri], &diag)
+			}
+		}
+	}
+	return reports
+}
+
+// MissingGCDetails returns the package of the Go file uri if the
+// gcDetails inlay hints of its files are missing because its
+// optimization details have not yet been computed by
+// GCOptimizationDetails. It returns nil if the details are cached, or
+// if the file has unsaved changes.
+func MissingGCDetails(ctx context.Context, snapshot *cache.Snapshot, uri protocol.DocumentURI) (*metadata.Package, error) {
+	mp, err := NarrowestMetadataForFile(ctx, snapshot, uri)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok, err := cachedGCDetails(ctx, snapshot, mp); err != nil || ok {
+		return nil, err
+	}
+	if _, saved, err := gcDetailsKey(ctx, snapshot, mp); err != nil || !saved {
+		return nil, err
+	}
+	return mp, nil
+}
+
+// The file cache kind of optimization details.
+const gcDetailsKind = "gcdetails"
+
+// gcDetailsKey returns the file cache key of the optimization details
+// of package mp. It reports whether the files of mp are saved: if not,
+// the details, which are those of the files on disk, must not be
+// stored under the key.
+func gcDetailsKey(ctx context.Context, snapshot *cache.Snapshot, mp *metadata.Package) (file.Hash, bool, error) {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "gcdetails: %s\n", mp.ID)
+	saved, err := writeBuildKey(ctx, snapshot, mp, hasher)
+	if err != nil {
+		return file.Hash{}, false, err
+	}
+	var key file.Hash
+	hasher.Sum(key[:0])
+	return key, saved, nil
+}
+
+// gcDetailsResultKey is the key of the optimization details of a
+// package in the program results of a snapshot.
+func gcDetailsResultKey(mp *metadata.Package) string {
+	return "gcdetails " + string(mp.ID)
+}
+
+// cachedGCDetails returns all the optimization details of the package
+// mp, if they have been computed for the snapshot or are in the file
+// cache.
+func cachedGCDetails(ctx context.Context, snapshot *cache.Snapshot, mp *metadata.Package) (map[protocol.DocumentURI][]*cache.Diagnostic, bool, error) {
 	if len(mp.CompiledGoFiles) == 0 {
-		return nil, nil
+		return nil, true, nil
 	}
-	pkgDir := filepath.Dir(mp.CompiledGoFiles[0].Path())
-	outDir := filepath.Join(os.TempDir(), fmt.Sprintf("gopls-%d.details", os.Getpid()))
+	if v, ok := snapshot.CachedProgramResult(gcDetailsResultKey(mp)); ok {
+		return v.(map[protocol.DocumentURI][]*cache.Diagnostic), true, nil
+	}
+	key, saved, err := gcDetailsKey(ctx, snapshot, mp)
+	if err != nil || !saved {
+		return nil, false, err
+	}
+	data, err := filecache.Get(gcDetailsKind, key)
+	if err == filecache.ErrNotFound {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return groupByURI(cache.DecodeDiagnostics(data)), true, nil
+}
 
-	if err := os.MkdirAll(outDir, 0700); err != nil {
+// gcDetails returns all the optimization details of the package mp,
+// compiling it if they are not in the file cache. Concurrent calls for
+// the same package of a snapshot share a single compilation.
+func gcDetails(ctx context.Context, snapshot *cache.Snapshot, mp *metadata.Package) (map[protocol.DocumentURI][]*cache.Diagnostic, error) {
+	if details, ok, err := cachedGCDetails(ctx, snapshot, mp); err != nil || ok {
+		return details, err
+	}
+	v, err := snapshot.ProgramResult(ctx, gcDetailsResultKey(mp), func(ctx context.Context, snapshot *cache.Snapshot) (interface{}, error) {
+		key, saved, err := gcDetailsKey(ctx, snapshot, mp)
+		if err != nil {
+			return nil, err
+		}
+		details, err := compileGCDetails(ctx, snapshot, mp)
+		if err != nil || !saved {
+			return details, err
+		}
+		var diags []*cache.Diagnostic
+		for _, fileDiags := range details {
+			diags = append(diags, fileDiags...)
+		}
+		if err := filecache.Set(gcDetailsKind, key, cache.EncodeDiagnostics(diags)); err != nil {
+			event.Error(ctx, "storing gc details in filecache", err)
+		}
+		return details, nil
+	})
+	if err != nil {
 		return nil, err
 	}
-	tmpFile, err := os.CreateTemp(os.TempDir(), "gopls-x")
+	return v.(map[protocol.DocumentURI][]*cache.Diagnostic), nil
+}
+
+// compileGCDetails compiles the package mp, discarding the result, and
+// returns all the optimization details that the compiler logs, in a
+// temporary directory, for its files.
+func compileGCDetails(ctx context.Context, snapshot *cache.Snapshot, mp *metadata.Package) (map[protocol.DocumentURI][]*cache.Diagnostic, error) {
+	pkgDir := filepath.Dir(mp.CompiledGoFiles[0].Path())
+	outDir, err := os.MkdirTemp("", "gopls-details")
 	if err != nil {
 		return nil, err
 	}
-	tmpFile.Close() // ignore error
-	defer os.Remove(tmpFile.Name())
+	defer os.RemoveAll(outDir)
 
 	outDirURI := protocol.URIFromPath(outDir)
 	// GC details doesn't handle Windows URIs in the form of "file:///C:/...",
@@ -47,7 +176,7 @@ func GCOptimizationDetails(ctx context.Context, snapshot *cache.Snapshot, mp *me
 		Verb: "build",
 		Args: []string{
 			fmt.Sprintf("-gcflags=-json=0,%s", outDirURI),
-			fmt.Sprintf("-o=%s", tmpFile.Name()),
+			fmt.Sprintf("-o=%s", os.DevNull),
 			".",
 		},
 		WorkingDir: pkgDir,
@@ -61,10 +190,9 @@ func GCOptimizationDetails(ctx context.Context, snapshot *cache.Snapshot, mp *me
 		return nil, err
 	}
 	reports := make(map[protocol.DocumentURI][]*cache.Diagnostic)
-	opts := snapshot.Options()
 	var parseError error
 	for _, fn := range files {
-		uri, diagnostics, err := parseDetailsFile(fn, opts)
+		uri, diagnostics, err := parseDetailsFile(fn)
 		if err != nil {
 			// expect errors for all the files, save 1
 			parseError = err
@@ -84,7 +212,55 @@ func GCOptimizationDetails(ctx context.Context, snapshot *cache.Snapshot, mp *me
 	return reports, parseError
 }
 
-func parseDetailsFile(filename string, options *settings.Options) (protocol.DocumentURI, []*cache.Diagnostic, error) {
+// GCDetailsInlayHints returns inlay hints for the compiler's
+// optimization details, filtered by the annotations setting, within
+// the range [start, end) of the file pgf of package mp. It returns no
+// hints if the file has unsaved changes, as the details are those of
+// the file on disk.
+//
+// Only cached details are shown, as compiling the package is too slow
+// for an inlay hint request; see MissingGCDetails.
+func GCDetailsInlayHints(ctx context.Context, snapshot *cache.Snapshot, mp *metadata.Package, pgf *parsego.File, start, end token.Pos) ([]protocol.InlayHint, error) {
+	fh, err := snapshot.ReadFile(ctx, pgf.URI)
+	if err != nil {
+		return nil, err
+	}
+	if !fh.SameContentsOnDisk() {
+		return nil, nil
+	}
+	details, _, err := cachedGCDetails(ctx, snapshot, mp)
+	if err != nil {
+		return nil, err
+	}
+	details = filterGCDetails(snapshot, details)
+	rng, err := pgf.PosRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+	var hints []protocol.InlayHint
+	for _, diag := range details[pgf.URI] {
+		if !protocol.Intersect(rng, diag.Range) {
+			continue
+		}
+		// The label may be truncated, so the tooltip shows the
+		// complete message and the explanation of the compiler.
+		tooltip := []string{diag.Message}
+		for _, related := range diag.Related {
+			tooltip = append(tooltip, related.Message)
+		}
+		hints = append(hints, protocol.InlayHint{
+			Position:    diag.Range.End,
+			Label:       buildLabel(diag.Message),
+			Tooltip:     &protocol.OrPTooltip_textDocument_inlayHint{Value: strings.Join(tooltip, "\n")},
+			PaddingLeft: true,
+		})
+	}
+	return hints, nil
+}
+
+// parseDetailsFile parses a file of optimization details logged by the
+// compiler.
+func parseDetailsFile(filename string) (protocol.DocumentURI, []*cache.Diagnostic, error) {
 	buf, err := os.ReadFile(filename)
 	if err != nil {
 		return "", nil, err
@@ -120,7 +296,7 @@ func parseDetailsFile(filename string, options *settings.Options) (protocol.Docu
 		if msg != "" {
 			msg = fmt.Sprintf("%s(%s)", msg, d.Message)
 		}
-		if !showDiagnostic(msg, d.Source, options) {
+		if d.Source != "go compiler" {
 			continue
 		}
 		var related []protocol.DiagnosticRelatedInformation
@@ -155,10 +331,7 @@ func parseDetailsFile(filename string, options *settings.Options) (protocol.Docu
 
 // showDiagnostic reports whether a given diagnostic should be shown to the end
 // user, given the current options.
-func showDiagnostic(msg, source string, o *settings.Options) bool {
-	if source != "go compiler" {
-		return false
-	}
+func showDiagnostic(msg string, o *settings.Options) bool {
 	if o.Annotations == nil {
 		return true
 	}
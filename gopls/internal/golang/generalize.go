@@ -0,0 +1,343 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file defines the "Convert parameter to type parameter" code
+// action, which generalizes a function over the type of one of its
+// parameters.
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/gopls/internal/util/typesutil"
+	"golang.org/x/tools/internal/analysisinternal"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/typeparams"
+	"golang.org/x/tools/internal/versions"
+	"golang.org/x/tools/parser"
+)
+
+// A generalizableParam is a parameter declaration of a function whose
+// type may be replaced by a type parameter.
+type generalizableParam struct {
+	decl  *ast.FuncDecl
+	field *ast.Field
+	vars  map[*types.Var]bool // the parameters declared by field
+	typ   types.Type
+}
+
+// CanGeneralizeParam reports whether the parameter declaration
+// enclosing [start, end) has a type that can be replaced by a type
+// parameter.
+func CanGeneralizeParam(file *ast.File, info *types.Info, start, end token.Pos) bool {
+	return findGeneralizableParam(file, info, start, end) != nil
+}
+
+// generalizeParam is a fixer that replaces the type of the parameter
+// declaration enclosing [start, end) by a new type parameter, whose
+// constraint is the smallest one that the function body needs: the
+// methods it calls, comparable if it compares parameters, and the
+// parameter's underlying type if it applies operators to them.
+//
+// The new declaration of the package is type-checked, so that the fix
+// fails if, for example, the function body or a call in the package
+// uses the parameter in a way that the constraint does not permit.
+// Uses of the function in other packages are not checked.
+func generalizeParam(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.Package, pgf *parsego.File, start, end token.Pos) (*token.FileSet, *analysis.SuggestedFix, error) {
+	info := pkg.GetTypesInfo()
+	param := findGeneralizableParam(pgf.File, info, start, end)
+	if param == nil {
+		return nil, nil, fmt.Errorf("no parameter to convert")
+	}
+	constraint, err := paramConstraint(param, pgf.File, pkg.GetTypes(), info)
+	if err != nil {
+		return nil, nil, err
+	}
+	name, _ := generateIdentifier(0, "T", func(name string) bool {
+		found := false
+		ast.Inspect(param.decl, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == name {
+				found = true
+			}
+			return !found
+		})
+		return found
+	})
+
+	edits := []analysis.TextEdit{{
+		Pos:     param.field.Type.Pos(),
+		End:     param.field.Type.End(),
+		NewText: []byte(name),
+	}}
+	if tparams := param.decl.Type.TypeParams; tparams != nil {
+		edits = append(edits, analysis.TextEdit{
+			Pos:     tparams.Closing,
+			End:     tparams.Closing,
+			NewText: []byte(fmt.Sprintf(", %s %s", name, constraint)),
+		})
+	} else {
+		edits = append(edits, analysis.TextEdit{
+			Pos:     param.decl.Name.End(),
+			End:     param.decl.Name.End(),
+			NewText: []byte(fmt.Sprintf("[%s %s]", name, constraint)),
+		})
+	}
+
+	// Check the package with the generalized function.
+	var diffEdits []diff.Edit
+	for _, edit := range edits {
+		start, end, err := safetoken.Offsets(pgf.Tok, edit.Pos, edit.End)
+		if err != nil {
+			return nil, nil, err
+		}
+		diffEdits = append(diffEdits, diff.Edit{Start: start, End: end, New: string(edit.NewText)})
+	}
+	src, err := diff.ApplyBytes(pgf.Src, diffEdits)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkGeneralized(ctx, snapshot, pkg, pgf, param, src); err != nil {
+		return nil, nil, fmt.Errorf("cannot convert %s to a type parameter: %v", types.TypeString(param.typ, typesutil.FileQualifier(pgf.File, pkg.GetTypes(), info)), err)
+	}
+
+	return pkg.FileSet(), &analysis.SuggestedFix{TextEdits: edits}, nil
+}
+
+// findGeneralizableParam returns the parameter declaration enclosing
+// [start, end) if it is a named, non-variadic parameter of a function
+// (not a method) declaration with a body, whose type is not a type
+// parameter, in a file whose Go version permits type parameters.
+func findGeneralizableParam(file *ast.File, info *types.Info, start, end token.Pos) *generalizableParam {
+	if v := versions.FileVersion(info, file); v != "" && versions.Before(v, "go1.18") {
+		return nil
+	}
+	path, _ := astutil.PathEnclosingInterval(file, start, end)
+	for i, n := range path {
+		field, ok := n.(*ast.Field)
+		if !ok {
+			continue
+		}
+		// (PathEnclosingInterval omits the FuncType of a FuncDecl.)
+		if i+2 >= len(path) || len(field.Names) == 0 {
+			return nil
+		}
+		decl, ok := path[i+2].(*ast.FuncDecl)
+		if !ok || decl.Recv != nil || decl.Body == nil || path[i+1] != decl.Type.Params {
+			return nil
+		}
+		if _, ok := field.Type.(*ast.Ellipsis); ok {
+			return nil
+		}
+		typ := info.TypeOf(field.Type)
+		if typ == nil || typeparams.IsTypeParam(typ) {
+			return nil
+		}
+		if basic, ok := typ.(*types.Basic); ok && basic.Info()&types.IsUntyped != 0 || typ == types.Typ[types.Invalid] {
+			return nil
+		}
+		vars := make(map[*types.Var]bool)
+		for _, name := range field.Names {
+			if v, ok := info.Defs[name].(*types.Var); ok {
+				vars[v] = true
+			}
+		}
+		return &generalizableParam{decl: decl, field: field, vars: vars, typ: typ}
+	}
+	return nil
+}
+
+// paramConstraint returns the smallest constraint, as source text, that
+// permits the uses of the parameters in the function body.
+func paramConstraint(param *generalizableParam, file *ast.File, pkg *types.Package, info *types.Info) (string, error) {
+	var (
+		methods    = make(map[string]*types.Func)
+		comparable bool // parameters are compared
+		core       bool // operators apply to parameters
+		err        error
+	)
+	// addMethods records the methods of an interface to which a
+	// parameter is converted.
+	addMethods := func(t types.Type) {
+		if iface, ok := t.Underlying().(*types.Interface); ok {
+			for i := 0; i < iface.NumMethods(); i++ {
+				methods[iface.Method(i).Name()] = iface.Method(i)
+			}
+		}
+	}
+	isParam := func(e ast.Expr) bool {
+		id, ok := astutil.Unparen(e).(*ast.Ident)
+		if !ok {
+			return false
+		}
+		v, ok := info.Uses[id].(*types.Var)
+		return ok && param.vars[v]
+	}
+	analysisinternal.WalkASTWithParent(param.decl.Body, func(n, parent ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || !isParam(id) {
+			return err == nil
+		}
+		switch parent := parent.(type) {
+		case *ast.SelectorExpr:
+			sel, ok := info.Selections[parent]
+			if !ok {
+				break
+			}
+			if sel.Kind() != types.MethodVal {
+				err = fmt.Errorf("cannot convert %s to a type parameter: the function uses its field %s", id.Name, parent.Sel.Name)
+				break
+			}
+			methods[sel.Obj().Name()] = sel.Obj().(*types.Func)
+		case *ast.BinaryExpr:
+			if parent.Op == token.EQL || parent.Op == token.NEQ {
+				comparable = true
+			} else {
+				core = true
+			}
+		case *ast.UnaryExpr:
+			if parent.Op != token.AND {
+				core = true
+			}
+		case *ast.IndexExpr, *ast.SliceExpr, *ast.StarExpr, *ast.IncDecStmt:
+			core = true
+		case *ast.SendStmt:
+			core = parent.Chan == id || core
+		case *ast.RangeStmt:
+			core = parent.X == id || core
+		case *ast.AssignStmt:
+			if parent.Tok != token.ASSIGN && parent.Tok != token.DEFINE {
+				core = true
+			}
+		case *ast.CallExpr:
+			if parent.Fun == id {
+				core = true
+				break
+			}
+			if fn, ok := typeutil.Callee(info, parent).(*types.Builtin); ok {
+				if fn.Name() == "len" || fn.Name() == "cap" {
+					core = true
+				}
+				break
+			}
+			if sig, ok := info.TypeOf(parent.Fun).Underlying().(*types.Signature); ok {
+				for i, arg := range parent.Args {
+					if arg != id {
+						continue
+					}
+					params := sig.Params()
+					switch {
+					case sig.Variadic() && i >= params.Len()-1:
+						if slice, ok := params.At(params.Len() - 1).Type().(*types.Slice); ok {
+							addMethods(slice.Elem())
+						}
+					case i < params.Len():
+						addMethods(params.At(i).Type())
+					}
+				}
+			}
+		}
+		return err == nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// Format the constraint.
+	qual := typesutil.FileQualifier(file, pkg, info)
+	var elems []string
+	if core {
+		if _, ok := param.typ.Underlying().(*types.Interface); !ok {
+			elems = append(elems, "~"+types.TypeString(param.typ.Underlying(), qual))
+		}
+	}
+	if comparable && len(elems) == 0 {
+		elems = append(elems, "comparable")
+	}
+	var names []string
+	for name := range methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sig := types.TypeString(methods[name].Type(), qual)
+		elems = append(elems, name+strings.TrimPrefix(sig, "func"))
+	}
+	switch {
+	case len(elems) == 0:
+		return "any", nil
+	case len(elems) == 1 && len(names) == 0:
+		return elems[0], nil
+	default:
+		return "interface{ " + strings.Join(elems, "; ") + " }", nil
+	}
+}
+
+// checkGeneralized type-checks the package pkg with the content src,
+// which generalizes the parameter param, in place of that of the file
+// pgf. It returns the first new error of the package, or, as the
+// infertypeargs analyzer checks of simplified calls, an error if a
+// call of the function would infer a type argument other than the
+// parameter's original type.
+func checkGeneralized(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.Package, pgf *parsego.File, param *generalizableParam, src []byte) error {
+	_, file, err := parser.ParseFile(pkg.FileSet(), pgf.URI.Path(), src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for _, err := range pkg.GetTypeErrors() {
+		existing[err.Msg] = true
+	}
+	logf := logger(ctx, "generalize parameter", snapshot.Options().VerboseOutput)
+	_, info, err := reTypeCheck(logf, pkg, map[protocol.DocumentURI]*ast.File{pgf.URI: file}, func(err types.Error) bool {
+		return existing[err.Msg]
+	})
+	if err != nil {
+		return err
+	}
+
+	// The edits follow the function name, so its offset is unchanged.
+	offset, err := safetoken.Offset(pgf.Tok, param.decl.Name.Pos())
+	if err != nil {
+		return err
+	}
+	var fn *types.Func
+	for _, decl := range file.Decls {
+		if decl, ok := decl.(*ast.FuncDecl); ok && pkg.FileSet().File(decl.Pos()).Offset(decl.Name.Pos()) == offset {
+			fn, _ = info.Defs[decl.Name].(*types.Func)
+		}
+	}
+	if fn == nil {
+		return fmt.Errorf("generalized function not found")
+	}
+	tparams := fn.Type().(*types.Signature).TypeParams()
+	want := types.TypeString(param.typ, nil)
+	for id, inst := range info.Instances {
+		if info.Uses[id] != fn {
+			continue
+		}
+		targ := inst.TypeArgs.At(tparams.Len() - 1)
+		if got := types.TypeString(targ, nil); !typeparams.IsTypeParam(targ) && got != want {
+			return fmt.Errorf("the call at %s would infer %s", safetoken.StartPosition(pkg.FileSet(), id.Pos()), got)
+		}
+	}
+	return nil
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
@@ -0,0 +1,514 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file defines the "generate test" source action, which adds a
+// table-driven test of a function to the corresponding _test.go file.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/metadata"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/util/bug"
+	"golang.org/x/tools/gopls/internal/util/slices"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/imports"
+	"golang.org/x/tools/parser"
+)
+
+// GenerateTest returns the changes that add a table-driven test of
+// the function or method declared at rng to the _test.go file
+// corresponding to the file that declares it, creating the test file
+// if it does not exist. The test has a field for the receiver and
+// each parameter and result of the function.
+//
+// The test package is type-checked with the new test, and an error is
+// returned if the test introduces type errors.
+func GenerateTest(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, rng protocol.Range) ([]protocol.DocumentChanges, error) {
+	ctx, done := event.Start(ctx, "golang.GenerateTest")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := testableFunc(pkg, pgf, start, end)
+	if err != nil {
+		return nil, err
+	}
+	sig := fn.Type().(*types.Signature)
+	testName := generatedTestName(fn)
+
+	// Find the test file, if it exists.
+	testURI := testFileURI(pgf.URI)
+	testFh, err := snapshot.ReadFile(ctx, testURI)
+	if err != nil {
+		return nil, err
+	}
+	var testPGF *parsego.File
+	if _, err := testFh.Content(); err == nil {
+		testPGF, err = snapshot.ParseGo(ctx, testFh, parsego.Full)
+		if err != nil {
+			return nil, err
+		}
+	} else if !slices.Contains(snapshot.Options().SupportedResourceOperations, protocol.Create) {
+		return nil, fmt.Errorf("can't create %s: LSP client does not support file creation", testURI.Path())
+	}
+
+	// An external test package (p_test) may refer only to the
+	// exported names of the package under test.
+	external := testPGF != nil && testPGF.File.Name.Name != pkg.GetTypes().Name()
+	if external {
+		if !fn.Exported() {
+			return nil, fmt.Errorf("%s is not exported, so %s cannot test it", fn.Name(), testURI.Path())
+		}
+		if obj := inaccessibleType(sig, nil); obj != nil {
+			return nil, fmt.Errorf("%s cannot refer to unexported type %s", testURI.Path(), obj.Name())
+		}
+	} else if obj := inaccessibleType(sig, pkg.GetTypes()); obj != nil {
+		return nil, fmt.Errorf("%s cannot refer to unexported type %s.%s", testURI.Path(), obj.Pkg().Name(), obj.Name())
+	}
+
+	if exists, err := testExists(ctx, snapshot, pkg, testURI, testPGF, testName); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("test %s already exists", testName)
+	}
+
+	// Qualify names relative to the imports of the test file.
+	var fileImports []*ast.ImportSpec
+	if testPGF != nil {
+		fileImports = testPGF.File.Imports
+	}
+	qual, newImports := newFileQualifier(snapshot, pkg.Metadata(), testPGF)
+	if external {
+		// Unlike newFileQualifier, qualify names of the package under test.
+		fileQual := qual
+		qual = func(p *types.Package) string {
+			if p == pkg.GetTypes() {
+				if name, ok := importedName(fileImports, p.Path(), p.Name()); ok {
+					return name
+				}
+				imp := newImport{importPath: p.Path()}
+				if p.Name() != pathpkg.Base(trimVersionSuffix(imp.importPath)) {
+					imp.name = p.Name()
+				}
+				if !slices.Contains(*newImports, imp) {
+					*newImports = append(*newImports, imp)
+				}
+				return p.Name()
+			}
+			return fileQual(p)
+		}
+	}
+	// stdImport returns the local name of the standard package path
+	// in the test file, adding an import if necessary.
+	stdImport := func(path string) string {
+		if name, ok := importedName(fileImports, path, path); ok {
+			return name
+		}
+		if !slices.Contains(*newImports, newImport{importPath: path}) {
+			*newImports = append(*newImports, newImport{importPath: path})
+		}
+		return path
+	}
+
+	text, err := formatTest(testName, fn, qual, stdImport)
+	if err != nil {
+		return nil, err
+	}
+
+	// Append the test to an existing file.
+	if testPGF != nil {
+		var edits []protocol.TextEdit
+		if len(*newImports) > 0 {
+			var fixes []*imports.ImportFix
+			for _, imp := range *newImports {
+				fixes = append(fixes, &imports.ImportFix{
+					StmtInfo: imports.ImportInfo{ImportPath: imp.importPath, Name: imp.name},
+					FixType:  imports.AddImport,
+				})
+			}
+			edits, err = computeImportFixEdits(snapshot, testPGF, fixes)
+			if err != nil {
+				return nil, err
+			}
+		}
+		eof := len(testPGF.Src)
+		eofRange, err := testPGF.Mapper.OffsetRange(eof, eof)
+		if err != nil {
+			return nil, err
+		}
+		newText := "\n" + text
+		if eof > 0 && testPGF.Src[eof-1] != '\n' {
+			newText = "\n" + newText
+		}
+		edits = append(edits, protocol.TextEdit{Range: eofRange, NewText: newText})
+		src, _, err := protocol.ApplyEdits(testPGF.Mapper, edits)
+		if err != nil {
+			return nil, err
+		}
+		testPkg, _, err := NarrowestPackageForFile(ctx, snapshot, testURI)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkGeneratedTest(ctx, snapshot, testPkg, testURI, src); err != nil {
+			return nil, err
+		}
+		return documentChanges(testFh, edits), nil
+	}
+
+	// Otherwise create the test file.
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n\n", pgf.File.Name.Name)
+	sort.Slice(*newImports, func(i, j int) bool {
+		x, y := (*newImports)[i], (*newImports)[j]
+		if xstd, ystd := !strings.Contains(x.importPath, "."), !strings.Contains(y.importPath, "."); xstd != ystd {
+			return xstd // standard packages first
+		}
+		return x.importPath < y.importPath
+	})
+	buf.WriteString("import (\n")
+	for i, imp := range *newImports {
+		if i > 0 && strings.Contains(imp.importPath, ".") && !strings.Contains((*newImports)[i-1].importPath, ".") {
+			buf.WriteString("\n")
+		}
+		if imp.name != "" {
+			fmt.Fprintf(&buf, "\t%s %q\n", imp.name, imp.importPath)
+		} else {
+			fmt.Fprintf(&buf, "\t%q\n", imp.importPath)
+		}
+	}
+	buf.WriteString(")\n\n")
+	buf.WriteString(text)
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGeneratedTest(ctx, snapshot, pkg, testURI, src); err != nil {
+		return nil, err
+	}
+	return []protocol.DocumentChanges{
+		{
+			CreateFile: &protocol.CreateFile{Kind: "create", URI: testURI},
+		},
+		{
+			TextDocumentEdit: &protocol.TextDocumentEdit{
+				TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+					TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: testURI},
+				},
+				Edits: protocol.AsAnnotatedTextEdits([]protocol.TextEdit{{NewText: string(src)}}),
+			},
+		},
+	}, nil
+}
+
+// checkGeneratedTest type-checks the package pkg with the content src
+// of the test file testURI, which contains the generated test, and
+// returns an error if it has errors that pkg does not.
+//
+// Packages are imported only from the transitive imports of pkg, so
+// errors importing other packages (such as testing, in a package
+// without tests) are ignored, as are the references to them.
+func checkGeneratedTest(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.Package, testURI protocol.DocumentURI, src []byte) error {
+	_, file, err := parser.ParseFile(pkg.FileSet(), testURI.Path(), src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return bug.Errorf("generated test failed to parse: %v", err)
+	}
+	existing := make(map[string]bool)
+	for _, err := range pkg.GetTypeErrors() {
+		existing[err.Msg] = true
+	}
+	logf := logger(ctx, "generate test", snapshot.Options().VerboseOutput)
+	_, _, err = reTypeCheck(logf, pkg, map[protocol.DocumentURI]*ast.File{testURI: file}, func(err types.Error) bool {
+		return existing[err.Msg] || strings.HasPrefix(err.Msg, "could not import ")
+	})
+	if err != nil {
+		return fmt.Errorf("generated test has errors: %v", err)
+	}
+	return nil
+}
+
+// testableFunc returns the function or method whose declaration
+// (other than its body) encloses the selection [start, end), if a test
+// may be generated for it.
+func testableFunc(pkg *cache.Package, pgf *parsego.File, start, end token.Pos) (*types.Func, error) {
+	if strings.HasSuffix(pgf.URI.Path(), "_test.go") {
+		return nil, errors.New("cannot generate a test for a function of a test file")
+	}
+	var decl *ast.FuncDecl
+	for _, d := range pgf.File.Decls {
+		if d, ok := d.(*ast.FuncDecl); ok && d.Pos() <= start && end <= d.Type.End() {
+			decl = d
+			break
+		}
+	}
+	if decl == nil {
+		return nil, errors.New("no function declaration at selection")
+	}
+	fn, ok := pkg.GetTypesInfo().Defs[decl.Name].(*types.Func)
+	if !ok {
+		return nil, fmt.Errorf("no type information for %s", decl.Name.Name)
+	}
+	sig := fn.Type().(*types.Signature)
+	switch {
+	case fn.Name() == "_":
+		return nil, errors.New("cannot generate a test for a blank function")
+	case sig.Recv() == nil && (fn.Name() == "init" || fn.Name() == "main" && pkg.GetTypes().Name() == "main"):
+		return nil, fmt.Errorf("cannot generate a test for %s", fn.Name())
+	case sig.TypeParams().Len() > 0 || sig.RecvTypeParams().Len() > 0:
+		return nil, fmt.Errorf("cannot generate a test for generic function %s", fn.Name())
+	}
+	return fn, nil
+}
+
+// generatedTestName returns the name of the test of fn: TestF for a
+// function F, and TestT_M for a method M of type T.
+func generatedTestName(fn *types.Func) string {
+	name := fn.Name()
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		t := recv.Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if named, ok := t.(*types.Named); ok {
+			name = named.Obj().Name() + "_" + name
+		}
+	}
+	if first := name[0]; 'a' <= first && first <= 'z' {
+		name = string(first-'a'+'A') + name[1:]
+	}
+	return "Test" + name
+}
+
+// testFileURI returns the URI of the _test.go file that corresponds
+// to the Go file uri.
+func testFileURI(uri protocol.DocumentURI) protocol.DocumentURI {
+	return protocol.URIFromPath(strings.TrimSuffix(uri.Path(), ".go") + "_test.go")
+}
+
+// testExists reports whether a function named testName is declared
+// by a test file of the same package as the test file at testURI
+// (parsed as testPGF, if it exists), which tests package pkg.
+func testExists(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.Package, testURI protocol.DocumentURI, testPGF *parsego.File, testName string) (bool, error) {
+	pkgPath := pkg.Metadata().PkgPath
+	if testPGF != nil && testPGF.File.Name.Name != pkg.GetTypes().Name() {
+		pkgPath += "_test"
+	}
+	uris := []protocol.DocumentURI{testURI}
+	for _, mp := range snapshot.MetadataGraph().Packages {
+		if mp.ForTest == pkg.Metadata().PkgPath && mp.PkgPath == pkgPath {
+			uris = append(uris, mp.CompiledGoFiles...)
+		}
+	}
+	seen := make(map[protocol.DocumentURI]bool)
+	for _, uri := range uris {
+		if seen[uri] || !strings.HasSuffix(uri.Path(), "_test.go") {
+			continue
+		}
+		seen[uri] = true
+		pgf := testPGF
+		if uri != testURI {
+			fh, err := snapshot.ReadFile(ctx, uri)
+			if err != nil {
+				return false, err
+			}
+			pgf, err = snapshot.ParseGo(ctx, fh, parsego.Full)
+			if err != nil {
+				return false, err
+			}
+		}
+		if pgf == nil {
+			continue // test file does not exist
+		}
+		for _, decl := range pgf.File.Decls {
+			if decl, ok := decl.(*ast.FuncDecl); ok && decl.Recv == nil && decl.Name.Name == testName {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// importedName returns the local name of the import of the package
+// (path, name) among fileImports, if any.
+func importedName(fileImports []*ast.ImportSpec, path, name string) (string, bool) {
+	for _, spec := range fileImports {
+		if metadata.UnquoteImportPath(spec) != metadata.ImportPath(path) {
+			continue
+		}
+		if spec.Name == nil {
+			return name, true
+		}
+		if spec.Name.Name != "_" && spec.Name.Name != "." {
+			return spec.Name.Name, true
+		}
+	}
+	return "", false
+}
+
+// formatTest returns the formatted declaration of the table-driven
+// test testName of fn. The qualifier qual qualifies the names of
+// types, and stdImport returns the local name of a standard package.
+func formatTest(testName string, fn *types.Func, qual types.Qualifier, stdImport func(path string) string) (string, error) {
+	sig := fn.Type().(*types.Signature)
+
+	// Choose distinct names for the fields of a test case.
+	used := map[string]bool{"name": true}
+	fieldName := func(name string) string {
+		for i := 1; used[name]; i++ {
+			name = strings.TrimRight(name, "0123456789") + strconv.Itoa(i)
+		}
+		used[name] = true
+		return name
+	}
+	type field struct{ name, typ string }
+	var recv *field
+	if v := sig.Recv(); v != nil {
+		name := v.Name()
+		if name == "" || name == "_" {
+			name = "recv"
+		}
+		recv = &field{fieldName(name), types.TypeString(v.Type(), qual)}
+	}
+	var params []field
+	for i := 0; i < sig.Params().Len(); i++ {
+		v := sig.Params().At(i)
+		name := v.Name()
+		if name == "" || name == "_" {
+			name = "arg" + strconv.Itoa(i)
+		}
+		params = append(params, field{fieldName(name), types.TypeString(v.Type(), qual)})
+	}
+	results := sig.Results()
+	wantErr := results.Len() > 0 && types.Identical(results.At(results.Len()-1).Type(), types.Universe.Lookup("error").Type())
+	nwant := results.Len()
+	if wantErr {
+		nwant--
+	}
+	var wants []field
+	for i := 0; i < nwant; i++ {
+		name := "want"
+		if i > 0 {
+			name += strconv.Itoa(i)
+		}
+		wants = append(wants, field{fieldName(name), types.TypeString(results.At(i).Type(), qual)})
+	}
+	var wantErrField string
+	if wantErr {
+		wantErrField = fieldName("wantErr")
+	}
+
+	// Format the call of the function.
+	var call strings.Builder
+	funcName := fn.Name()
+	if recv != nil {
+		fmt.Fprintf(&call, "tt.%s.%s(", recv.name, fn.Name())
+		t := sig.Recv().Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		if named, ok := t.(*types.Named); ok {
+			funcName = named.Obj().Name() + "." + funcName
+		}
+	} else {
+		if pkgName := qual(fn.Pkg()); pkgName != "" {
+			fmt.Fprintf(&call, "%s.", pkgName)
+		}
+		fmt.Fprintf(&call, "%s(", fn.Name())
+	}
+	for i, param := range params {
+		if i > 0 {
+			call.WriteString(", ")
+		}
+		fmt.Fprintf(&call, "tt.%s", param.name)
+		if sig.Variadic() && i == len(params)-1 {
+			call.WriteString("...")
+		}
+	}
+	call.WriteString(")")
+
+	testing := stdImport("testing")
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "func %s(t *%s.T) {\n", testName, testing)
+	buf.WriteString("tests := []struct {\nname string\n")
+	if recv != nil {
+		fmt.Fprintf(&buf, "%s %s\n", recv.name, recv.typ)
+	}
+	for _, f := range params {
+		fmt.Fprintf(&buf, "%s %s\n", f.name, f.typ)
+	}
+	for _, f := range wants {
+		fmt.Fprintf(&buf, "%s %s\n", f.name, f.typ)
+	}
+	if wantErr {
+		fmt.Fprintf(&buf, "%s bool\n", wantErrField)
+	}
+	buf.WriteString("}{\n// TODO: Add test cases.\n}\n")
+	fmt.Fprintf(&buf, "for _, tt := range tests {\nt.Run(tt.name, func(t *%s.T) {\n", testing)
+
+	// Call the function, and compare the results with those wanted.
+	var gots []string
+	for i := range wants {
+		got := "got"
+		if i > 0 {
+			got += strconv.Itoa(i)
+		}
+		gots = append(gots, got)
+	}
+	lhs := gots
+	if wantErr {
+		lhs = append(lhs[:len(lhs):len(lhs)], "err")
+	}
+	if len(lhs) > 0 {
+		fmt.Fprintf(&buf, "%s := ", strings.Join(lhs, ", "))
+	}
+	fmt.Fprintf(&buf, "%s\n", call.String())
+	if wantErr {
+		fmt.Fprintf(&buf, "if (err != nil) != tt.%s {\n", wantErrField)
+		fmt.Fprintf(&buf, "t.Fatalf(\"%s() error = %%v, wantErr %%v\", err, tt.%s)\n}\n", funcName, wantErrField)
+		if len(wants) > 0 {
+			buf.WriteString("if err != nil {\nreturn\n}\n")
+		}
+	}
+	if len(wants) > 0 {
+		reflect := stdImport("reflect")
+		for i, got := range gots {
+			want := wants[i].name
+			fmt.Fprintf(&buf, "if !%s.DeepEqual(%s, tt.%s) {\n", reflect, got, want)
+			if len(gots) == 1 {
+				fmt.Fprintf(&buf, "t.Errorf(\"%s() = %%v, want %%v\", %s, tt.%s)\n}\n", funcName, got, want)
+			} else {
+				fmt.Fprintf(&buf, "t.Errorf(\"%s() %s = %%v, want %%v\", %s, tt.%s)\n}\n", funcName, got, got, want)
+			}
+		}
+	}
+	buf.WriteString("})\n}\n}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("formatting test: %v", err)
+	}
+	return string(src), nil
+}
@@ -40,6 +40,7 @@ const (
 	CompositeLiteralTypes      = "compositeLiteralTypes"
 	CompositeLiteralFieldNames = "compositeLiteralFields"
 	FunctionTypeParameters     = "functionTypeParameters"
+	GCDetails                  = "gcDetails"
 )
 
 var AllInlayHints = map[string]*Hint{
@@ -78,6 +79,12 @@ var AllInlayHints = map[string]*Hint{
 		Doc:  "Enable/disable inlay hints for implicit type parameters on generic functions:\n```go\n\tmyFoo/*[int, string]*/(1, \"hello\")\n```",
 		Run:  funcTypeParams,
 	},
+	GCDetails: {
+		Name: GCDetails,
+		Doc:  "Enable/disable inlay hints for the optimization decisions of the compiler, such as escape analysis,\ninlining, bounds checks, and nil checks, as selected by the annotations setting:\n```go\n\tfunc f(x int) int {/* canInline(cost: 4)*/\n```\nThe package is compiled to compute them, so they are shown only for files without unsaved changes.",
+		// Run is nil: the hints are computed by GCDetailsInlayHints
+		// for the whole package.
+	},
 }
 
 func InlayHint(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, pRng protocol.Range) ([]protocol.InlayHint, error) {
@@ -96,11 +103,12 @@ func InlayHint(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, pR
 		if !enabled {
 			continue
 		}
-		if h, ok := AllInlayHints[hint]; ok {
+		if h, ok := AllInlayHints[hint]; ok && h.Run != nil {
 			enabledHints = append(enabledHints, h.Run)
 		}
 	}
-	if len(enabledHints) == 0 {
+	gcDetails := inlayHintOptions.Hints[GCDetails]
+	if len(enabledHints) == 0 && !gcDetails {
 		return nil, nil
 	}
 
@@ -129,6 +137,14 @@ func InlayHint(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, pR
 		}
 		return true
 	})
+
+	if gcDetails {
+		gcHints, err := GCDetailsInlayHints(ctx, snapshot, pkg.Metadata(), pgf, start, end)
+		if err != nil {
+			return nil, err
+		}
+		hints = append(hints, gcHints...)
+	}
 	return hints, nil
 }
 
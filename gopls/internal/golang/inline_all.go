@@ -231,7 +231,7 @@ func inlineAllCalls(ctx context.Context, logf func(string, ...any), snapshot *ca
 			// anything in the surrounding scope.
 			//
 			// TODO(rfindley): improve this.
-			tpkg, tinfo, err = reTypeCheck(logf, callInfo.pkg, map[protocol.DocumentURI]*ast.File{uri: file}, true)
+			tpkg, tinfo, err = reTypeCheck(logf, callInfo.pkg, map[protocol.DocumentURI]*ast.File{uri: file}, func(types.Error) bool { return true })
 			if err != nil {
 				return nil, bug.Errorf("type checking after inlining failed: %v", err)
 			}
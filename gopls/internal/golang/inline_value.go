@@ -0,0 +1,103 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"context"
+	"go/ast"
+	"go/types"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/internal/event"
+)
+
+// InlineValues returns the variables whose values a debugger should
+// display inline, within the given range of the file, while stopped at
+// the given location.
+//
+// A variable is reported at each reference to it, in the function
+// containing the stopped location, that lies within rng and no later
+// than the stopped line, provided that it is a local variable,
+// parameter, named result or range variable, and that the reference
+// denotes the same variable that the name denotes at the stopped
+// location (so variables that are out of scope or shadowed there are
+// excluded).
+func InlineValues(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, rng, stopped protocol.Range) ([]protocol.InlineValue, error) {
+	ctx, done := event.Start(ctx, "golang.InlineValues")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	start, end, err := pgf.RangePos(rng)
+	if err != nil {
+		return nil, err
+	}
+	stoppedPos, err := pgf.PositionPos(stopped.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the function in which execution is stopped.
+	path, _ := astutil.PathEnclosingInterval(pgf.File, stoppedPos, stoppedPos)
+	var fn ast.Node
+	for _, n := range path {
+		if _, ok := n.(*ast.FuncDecl); ok {
+			fn = n
+			break
+		}
+		if _, ok := n.(*ast.FuncLit); ok {
+			fn = n
+			break
+		}
+	}
+	if fn == nil {
+		return nil, nil // not in a function
+	}
+
+	info := pkg.GetTypesInfo()
+	scope := pkg.GetTypes().Scope().Innermost(stoppedPos)
+	if scope == nil {
+		return nil, nil
+	}
+	lastLine := stopped.End.Line
+
+	var values []protocol.InlineValue
+	ast.Inspect(fn, func(n ast.Node) bool {
+		if n == nil || n.End() < start || n.Pos() > end {
+			return false
+		}
+		id, ok := n.(*ast.Ident)
+		if !ok || id.Name == "_" || id.Pos() < start || id.End() > end {
+			return true
+		}
+		v, ok := info.ObjectOf(id).(*types.Var)
+		if !ok || v.IsField() || v.Parent() == nil || v.Parent() == pkg.GetTypes().Scope() {
+			return true
+		}
+		// Exclude variables not in scope at the stopped location,
+		// or shadowed there by another declaration.
+		if _, obj := scope.LookupParent(id.Name, stoppedPos); obj != v {
+			return true
+		}
+		idRng, err := pgf.NodeRange(id)
+		if err != nil || idRng.Start.Line > lastLine {
+			return true
+		}
+		values = append(values, protocol.InlineValue{
+			Value: protocol.InlineValueVariableLookup{
+				Range:               idRng,
+				VariableName:        id.Name,
+				CaseSensitiveLookup: true,
+			},
+		})
+		return true
+	})
+	return values, nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/internal/event"
+)
+
+// LinkedEditingRanges returns the ranges of the file that should be
+// edited together with the token at the given position, so that an
+// editor may rename as you type without a round trip through Rename.
+//
+// Linked ranges are reported for:
+//   - local identifiers (variables, constants, types, parameters and
+//     results), whose declaration and references all lie within the
+//     enclosing function;
+//   - labels and the goto, break, and continue statements that refer
+//     to them; and
+//   - the name portions of struct tag values that agree across keys,
+//     such as both occurrences of "id" in `json:"id" db:"id"`.
+//
+// It returns nil if there is nothing at the position that can be
+// safely edited in this way.
+func LinkedEditingRanges(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, position protocol.Position) (*protocol.LinkedEditingRanges, error) {
+	ctx, done := event.Start(ctx, "golang.LinkedEditingRanges")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(position)
+	if err != nil {
+		return nil, err
+	}
+	path, _ := astutil.PathEnclosingInterval(pgf.File, pos, pos)
+	if len(path) == 0 {
+		return nil, fmt.Errorf("no enclosing position found for %v:%v", position.Line, position.Character)
+	}
+	// As in Highlight, if the cursor is just after an identifier,
+	// prefer it to whatever follows.
+	if _, ok := path[0].(*ast.Ident); !ok {
+		if p, _ := astutil.PathEnclosingInterval(pgf.File, pos-1, pos-1); p != nil {
+			if _, ok := p[0].(*ast.Ident); ok {
+				path = p
+			}
+		}
+	}
+
+	var spans []posRange
+	switch node := path[0].(type) {
+	case *ast.Ident:
+		spans = linkedIdents(pgf.File, pkg.GetTypesInfo(), pkg.GetTypes(), node)
+	case *ast.BasicLit:
+		if len(path) > 1 {
+			if field, ok := path[1].(*ast.Field); ok && field.Tag == node {
+				spans = linkedTagNames(node, pos)
+			}
+		}
+	}
+	if len(spans) < 2 {
+		return nil, nil
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	result := &protocol.LinkedEditingRanges{}
+	for _, span := range spans {
+		rng, err := pgf.PosRange(span.start, span.end)
+		if err != nil {
+			return nil, err
+		}
+		result.Ranges = append(result.Ranges, rng)
+	}
+	return result, nil
+}
+
+// linkedIdents returns the spans of all identifiers in the file that
+// denote the same local object or label as id, or nil if id does not
+// denote one, or if its references cannot all be edited as one.
+func linkedIdents(file *ast.File, info *types.Info, pkg *types.Package, id *ast.Ident) []posRange {
+	// The symbolic variable of a type switch (x in "switch x := y.(type)")
+	// has no object of its own; each case clause declares an implicit
+	// variable. Treat them all as one.
+	objs := make(map[types.Object]bool)
+	var headers []*ast.Ident
+	ast.Inspect(file, func(n ast.Node) bool {
+		sw, ok := n.(*ast.TypeSwitchStmt)
+		if !ok {
+			return true
+		}
+		assign, ok := sw.Assign.(*ast.AssignStmt)
+		if !ok || len(assign.Lhs) != 1 {
+			return true
+		}
+		header, ok := assign.Lhs[0].(*ast.Ident)
+		if !ok {
+			return true
+		}
+		var implicits []types.Object
+		match := header == id
+		for _, stmt := range sw.Body.List {
+			if obj := info.Implicits[stmt]; obj != nil {
+				implicits = append(implicits, obj)
+				if obj == info.Uses[id] {
+					match = true
+				}
+			}
+		}
+		if match {
+			headers = append(headers, header)
+			for _, obj := range implicits {
+				objs[obj] = true
+			}
+		}
+		return true
+	})
+	if len(headers) == 0 {
+		obj := info.ObjectOf(id)
+		if !isLocalObject(obj, pkg) {
+			return nil
+		}
+		objs[obj] = true
+	}
+
+	var spans []posRange
+	for _, header := range headers {
+		spans = append(spans, posRange{header.Pos(), header.End()})
+	}
+	for ident, obj := range info.Defs {
+		if obj != nil && objs[obj] {
+			spans = append(spans, posRange{ident.Pos(), ident.End()})
+		}
+	}
+	for ident, obj := range info.Uses {
+		if objs[obj] {
+			if field, ok := info.Defs[ident].(*types.Var); ok && field.IsField() {
+				// An embedded field named after a local type:
+				// renaming the type would also rename the field,
+				// which may be selected elsewhere.
+				return nil
+			}
+			spans = append(spans, posRange{ident.Pos(), ident.End()})
+		}
+	}
+	return spans
+}
+
+// isLocalObject reports whether obj is a label or an object declared
+// within a function body or signature of pkg, and is therefore
+// referenced only within the enclosing function.
+func isLocalObject(obj types.Object, pkg *types.Package) bool {
+	if obj == nil || obj.Pkg() != pkg {
+		return false
+	}
+	switch obj := obj.(type) {
+	case *types.Label:
+		return true
+	case *types.Var:
+		if obj.IsField() {
+			return false
+		}
+	case *types.Const, *types.TypeName:
+	default:
+		return false // e.g. func, package name
+	}
+	parent := obj.Parent()
+	return parent != nil && parent != pkg.Scope() && parent != types.Universe
+}
+
+// linkedTagNames returns the spans of the name portions of the values
+// in a struct tag that are equal to the one at pos. For example, in
+// the tag `json:"id,omitempty" db:"id"`, a position within either id
+// yields the spans of both.
+func linkedTagNames(lit *ast.BasicLit, pos token.Pos) []posRange {
+	// Only raw strings, and interpreted strings without escapes,
+	// have offsets that correspond to the source text.
+	if len(lit.Value) < 2 || (lit.Value[0] != '`' && strings.ContainsRune(lit.Value, '\\')) {
+		return nil
+	}
+	tag := lit.Value[1 : len(lit.Value)-1]
+	offset := int(pos - lit.Pos() - 1) // offset of pos within tag
+
+	names := structTagNames(tag)
+	var want string
+	for _, name := range names {
+		if name.start <= offset && offset <= name.end {
+			want = tag[name.start:name.end]
+			break
+		}
+	}
+	if want == "" || want == "-" {
+		return nil
+	}
+	var spans []posRange
+	for _, name := range names {
+		if tag[name.start:name.end] == want {
+			start := lit.Pos() + 1 + token.Pos(name.start)
+			spans = append(spans, posRange{start, start + token.Pos(len(want))})
+		}
+	}
+	return spans
+}
+
+// structTagNames returns the offsets of the name portion (before any
+// comma) of each value in a conventional struct tag (see
+// [reflect.StructTag]). It stops at the first malformed key:"value"
+// pair.
+func structTagNames(tag string) []struct{ start, end int } {
+	var names []struct{ start, end int }
+	i := 0
+	for i < len(tag) {
+		// Skip leading space.
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		j := i
+		for j < len(tag) && tag[j] > ' ' && tag[j] != ':' && tag[j] != '"' && tag[j] != 0x7f {
+			j++
+		}
+		if j == i || j+1 >= len(tag) || tag[j] != ':' || tag[j+1] != '"' {
+			break
+		}
+		// Scan quoted string to find value.
+		start := j + 2
+		k := start
+		for k < len(tag) && tag[k] != '"' {
+			if tag[k] == '\\' {
+				k++
+			}
+			k++
+		}
+		if k >= len(tag) {
+			break
+		}
+		end := start
+		for end < k && tag[end] != ',' {
+			end++
+		}
+		names = append(names, struct{ start, end int }{start, end})
+		i = k + 1
+	}
+	return names
+}
@@ -0,0 +1,497 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file defines WriteLSIF, which exports the cross-reference
+// information gopls computes for a workspace in the Language Server
+// Index Format (LSIF), for use by code browsing tools that have no
+// language server. See:
+// https://microsoft.github.io/language-server-protocol/specifications/lsif/0.6.0/specification/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/types/objectpath"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/metadata"
+	"golang.org/x/tools/gopls/internal/cache/methodsets"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/gopls/internal/version"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// WriteLSIF writes an LSIF dump of the workspace packages of the
+// snapshot to w, as a sequence of JSON vertices and edges, one per line.
+//
+// The dump records, for each identifier in the workspace, the
+// definitions and references of the symbol it denotes, its hover
+// text, its moniker (see [Monikers]), and, for package-level types,
+// the types that implement it or that it implements.
+//
+// Symbols are identified across packages by their package path and
+// object path, so references from one package to a symbol in another
+// share a single result set. Implementations are found using the
+// method-set indexes of the workspace packages.
+func WriteLSIF(ctx context.Context, snapshot *cache.Snapshot, w io.Writer) error {
+	ctx, done := event.Start(ctx, "golang.WriteLSIF")
+	defer done()
+
+	mps, err := snapshot.WorkspaceMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	metadata.RemoveIntermediateTestVariants(&mps)
+	// Index each package before its test variants, so that each file
+	// is indexed once, as part of its least augmented package.
+	sort.Slice(mps, func(i, j int) bool {
+		if ti, tj := mps[i].ForTest != "", mps[j].ForTest != ""; ti != tj {
+			return tj
+		}
+		return mps[i].ID < mps[j].ID
+	})
+	ids := make([]PackageID, len(mps))
+	for i, mp := range mps {
+		ids[i] = mp.ID
+	}
+	pkgs, err := snapshot.TypeCheck(ctx, ids...)
+	if err != nil {
+		return err
+	}
+	indexes, err := snapshot.MethodSets(ctx, ids...)
+	if err != nil {
+		return err
+	}
+
+	x := &lsifIndexer{
+		snapshot:  snapshot,
+		enc:       json.NewEncoder(w),
+		workspace: make(map[string]bool),
+		symbols:   make(map[string]*lsifSymbol),
+		members:   make(map[*types.Package]map[types.Object]string),
+		packages:  make(map[string]int),
+		modules:   make(map[string]int),
+		defs:      make(map[lsifOffset]lsifItem),
+	}
+	for _, mp := range mps {
+		x.workspace[string(mp.PkgPath)] = true
+	}
+
+	x.emit(&lsifVertex{
+		Label:            "metaData",
+		Version:          "0.6.0",
+		ProjectRoot:      snapshot.View().Folder().Dir,
+		PositionEncoding: "utf-16",
+		ToolInfo:         &lsifToolInfo{Name: "gopls", Version: version.Version()},
+	})
+	project := x.emit(&lsifVertex{Label: "project", Kind: "go"})
+
+	var (
+		documents []int
+		seen      = make(map[protocol.DocumentURI]bool)
+		defined   []lsifType // package-level types defined in the workspace
+	)
+	for i, pkg := range pkgs {
+		goFiles := make(map[protocol.DocumentURI]bool)
+		for _, uri := range mps[i].GoFiles {
+			goFiles[uri] = true
+		}
+		encoder := new(objectpath.Encoder)
+		for _, pgf := range pkg.CompiledGoFiles() {
+			if seen[pgf.URI] || !goFiles[pgf.URI] {
+				continue // already indexed, or generated by cgo
+			}
+			seen[pgf.URI] = true
+			doc, types, err := x.indexFile(pkg, pgf, encoder)
+			if err != nil {
+				return err
+			}
+			documents = append(documents, doc)
+			defined = append(defined, types...)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	// Record the types that each workspace type implements,
+	// or is implemented by.
+	for _, t := range defined {
+		key, ok := methodsets.KeyOf(t.tname.Type())
+		if !ok {
+			continue // no methods
+		}
+		sym := t.sym
+		found := make(map[int]bool)
+		for _, index := range indexes {
+			for _, res := range index.Search(key, "") {
+				loc := res.Location
+				item, ok := x.defs[lsifOffset{protocol.URIFromPath(loc.Filename), loc.Start}]
+				if ok && !found[item.rng] {
+					found[item.rng] = true
+					sym.impls = append(sym.impls, item)
+				}
+			}
+		}
+	}
+
+	// Emit the results of each symbol, in order of first appearance.
+	symbols := make([]*lsifSymbol, 0, len(x.symbols))
+	for _, sym := range x.symbols {
+		symbols = append(symbols, sym)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].resultSet < symbols[j].resultSet })
+	for _, sym := range symbols {
+		hover := x.emit(&lsifVertex{
+			Label:  "hoverResult",
+			Result: &lsifHover{Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: sym.hover}},
+		})
+		x.edge("textDocument/hover", sym.resultSet, hover)
+		if len(sym.defs) > 0 {
+			result := x.emit(&lsifVertex{Label: "definitionResult"})
+			x.edge("textDocument/definition", sym.resultSet, result)
+			x.items(result, sym.defs, "")
+		}
+		if len(sym.defs)+len(sym.refs) > 0 {
+			result := x.emit(&lsifVertex{Label: "referenceResult"})
+			x.edge("textDocument/references", sym.resultSet, result)
+			x.items(result, sym.defs, "definitions")
+			x.items(result, sym.refs, "references")
+		}
+		if len(sym.impls) > 0 {
+			result := x.emit(&lsifVertex{Label: "implementationResult"})
+			x.edge("textDocument/implementation", sym.resultSet, result)
+			x.items(result, sym.impls, "")
+		}
+	}
+	if len(documents) > 0 {
+		x.emit(&lsifEdge{Label: "contains", OutV: project, InVs: documents})
+	}
+	return x.err
+}
+
+// An lsifIndexer holds the state of a call to WriteLSIF.
+type lsifIndexer struct {
+	snapshot *cache.Snapshot
+	enc      *json.Encoder
+	lastID   int
+	err      error // first encoding error
+
+	workspace map[string]bool                            // paths of workspace packages
+	symbols   map[string]*lsifSymbol                     // keyed by symbolKey
+	members   map[*types.Package]map[types.Object]string // memoized memberNames
+	packages  map[string]int                             // packageInformation vertices, by package path
+	modules   map[string]int                             // packageInformation vertices, by module
+	defs      map[lsifOffset]lsifItem                    // ranges of defining identifiers
+}
+
+// An lsifSymbol holds the results for a symbol, which are emitted
+// once all documents have been indexed.
+type lsifSymbol struct {
+	resultSet         int
+	hover             string
+	defs, refs, impls []lsifItem
+}
+
+// An lsifType is a package-level type and its symbol.
+type lsifType struct {
+	tname *types.TypeName
+	sym   *lsifSymbol
+}
+
+// An lsifItem is a range vertex and the document that contains it.
+type lsifItem struct {
+	rng, doc int
+}
+
+// An lsifOffset identifies the start of an identifier.
+type lsifOffset struct {
+	uri    protocol.DocumentURI
+	offset int
+}
+
+// indexFile emits the vertices and edges for a document and each
+// identifier in it, and returns the ID of the document vertex and the
+// package-level types that the file defines.
+func (x *lsifIndexer) indexFile(pkg *cache.Package, pgf *parsego.File, encoder *objectpath.Encoder) (int, []lsifType, error) {
+	info := pkg.GetTypesInfo()
+	doc := x.emit(&lsifVertex{Label: "document", URI: pgf.URI, LanguageID: "go"})
+
+	var (
+		ranges  []int
+		defined []lsifType
+		err     error
+	)
+	ast.Inspect(pgf.File, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || err != nil {
+			return err == nil
+		}
+		obj := identObject(info, id)
+		if obj == nil {
+			return true
+		}
+		switch obj.(type) {
+		case *types.PkgName, *types.Nil, *types.Builtin:
+			return true
+		}
+		if obj.Pkg() == nil {
+			return true // e.g. error, or the error.Error method
+		}
+		var rng protocol.Range
+		rng, err = pgf.NodeRange(id)
+		if err != nil {
+			return false
+		}
+		isDef := info.Defs[id] == obj
+
+		key := symbolKey(pkg.FileSet(), obj, encoder)
+		sym := x.symbols[key]
+		if sym == nil {
+			sym = x.newSymbol(obj)
+			x.symbols[key] = sym
+		}
+		start, end := rng.Start, rng.End
+		rangeID := x.emit(&lsifVertex{Label: "range", Start: &start, End: &end})
+		ranges = append(ranges, rangeID)
+		x.edge("next", rangeID, sym.resultSet)
+
+		item := lsifItem{rangeID, doc}
+		if isDef {
+			sym.defs = append(sym.defs, item)
+			offset, _ := safetoken.Offset(pgf.Tok, id.Pos()) // can't fail
+			x.defs[lsifOffset{pgf.URI, offset}] = item
+			// Include the doc comment in the hover text.
+			decl, spec, field := findDeclInfo([]*ast.File{pgf.File}, id.Pos())
+			sym.hover = lsifHoverText(obj, pgf, spec, chooseDocComment(decl, spec, field))
+			if tname, ok := obj.(*types.TypeName); ok && obj.Parent() == obj.Pkg().Scope() {
+				defined = append(defined, lsifType{tname, sym})
+			}
+		} else {
+			sym.refs = append(sym.refs, item)
+		}
+		return true
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(ranges) > 0 {
+		x.emit(&lsifEdge{Label: "contains", OutV: doc, InVs: ranges})
+	}
+	return doc, defined, nil
+}
+
+// symbolKey returns the key identifying the symbol obj in the dump.
+// Objects that can be reached from the scope of their package are
+// identified by package path and object path; others, such as local
+// variables, by their position.
+func symbolKey(fset *token.FileSet, obj types.Object, encoder *objectpath.Encoder) string {
+	if fn, ok := obj.(*types.Func); ok {
+		obj = typeparams.OriginMethod(fn)
+	} else if v, ok := obj.(*types.Var); ok {
+		obj = v.Origin()
+	}
+	if path, err := encoder.For(obj); err == nil {
+		return obj.Pkg().Path() + " " + string(path)
+	}
+	posn := safetoken.StartPosition(fset, obj.Pos())
+	return fmt.Sprintf("%s:%d:%d %s", posn.Filename, posn.Line, posn.Column, obj.Name())
+}
+
+// newSymbol emits the result set (and moniker, if any) for a new
+// symbol, and returns it.
+func (x *lsifIndexer) newSymbol(obj types.Object) *lsifSymbol {
+	sym := &lsifSymbol{
+		resultSet: x.emit(&lsifVertex{Label: "resultSet"}),
+		hover:     lsifHoverText(obj, nil, nil, nil),
+	}
+	members, ok := x.members[obj.Pkg()]
+	if !ok {
+		members = memberNames(obj.Pkg())
+		x.members[obj.Pkg()] = members
+	}
+	if identifier, ok := monikerIdentifier(obj, members); ok {
+		m := newMoniker(identifier, x.workspace[obj.Pkg().Path()])
+		moniker := x.emit(&lsifVertex{
+			Label:      "moniker",
+			Scheme:     m.Scheme,
+			Identifier: m.Identifier,
+			Unique:     m.Unique,
+			Kind:       string(*m.Kind),
+		})
+		x.edge("moniker", sym.resultSet, moniker)
+		if info := x.packageInformation(obj.Pkg().Path()); info != 0 {
+			x.edge("packageInformation", moniker, info)
+		}
+	}
+	return sym
+}
+
+// packageInformation returns the ID of the packageInformation vertex
+// describing the module that provides the package with the given path,
+// emitting it if necessary. It returns zero if the module is unknown.
+func (x *lsifIndexer) packageInformation(pkgPath string) int {
+	if id, ok := x.packages[pkgPath]; ok {
+		return id
+	}
+	name, version := "", ""
+	for _, mp := range x.snapshot.MetadataGraph().Packages {
+		if string(mp.PkgPath) != pkgPath {
+			continue
+		}
+		if mp.Module != nil {
+			name, version = mp.Module.Path, mp.Module.Version
+		} else if !strings.Contains(strings.Split(pkgPath, "/")[0], ".") {
+			name, version = "std", x.snapshot.View().GoVersionString()
+		}
+		break
+	}
+	var id int
+	if name != "" {
+		key := name + "@" + version
+		var ok bool
+		if id, ok = x.modules[key]; !ok {
+			id = x.emit(&lsifVertex{Label: "packageInformation", Name: name, Manager: "gomod", Version: version})
+			x.modules[key] = id
+		}
+	}
+	x.packages[pkgPath] = id
+	return id
+}
+
+// lsifHoverText returns the hover text for obj. If obj is defined in
+// pgf, spec is the declaration syntax of obj, if any, and doc its doc
+// comment.
+func lsifHoverText(obj types.Object, pgf *parsego.File, spec ast.Spec, doc *ast.CommentGroup) string {
+	qf := func(p *types.Package) string {
+		if p == obj.Pkg() {
+			return ""
+		}
+		return p.Name()
+	}
+	var signature string
+	if pgf != nil {
+		signature = objectString(obj, qf, obj.Pos(), pgf.Tok, spec)
+	} else {
+		signature = objectString(obj, qf, obj.Pos(), nil, nil)
+	}
+	text := "```go\n" + signature + "\n```"
+	if doc != nil {
+		text += "\n\n" + doc.Text()
+	}
+	return text
+}
+
+// emit encodes a vertex or edge, assigning it the next ID, which it
+// returns.
+func (x *lsifIndexer) emit(elem interface{ setID(int) }) int {
+	x.lastID++
+	elem.setID(x.lastID)
+	if err := x.enc.Encode(elem); err != nil && x.err == nil {
+		x.err = err
+	}
+	return x.lastID
+}
+
+// edge emits a one-to-one edge.
+func (x *lsifIndexer) edge(label string, out, in int) {
+	x.emit(&lsifEdge{Label: label, OutV: out, InV: in})
+}
+
+// items emits the "item" edges from a result to its ranges, one per
+// document.
+func (x *lsifIndexer) items(result int, items []lsifItem, property string) {
+	var docs []int
+	byDoc := make(map[int][]int)
+	for _, item := range items {
+		if _, ok := byDoc[item.doc]; !ok {
+			docs = append(docs, item.doc)
+		}
+		byDoc[item.doc] = append(byDoc[item.doc], item.rng)
+	}
+	for _, doc := range docs {
+		x.emit(&lsifEdge{Label: "item", OutV: result, InVs: byDoc[doc], Document: doc, Property: property})
+	}
+}
+
+// An lsifVertex is a vertex of an LSIF dump. Its fields are the union
+// of those of all the kinds of vertex used by WriteLSIF.
+type lsifVertex struct {
+	ID    int    `json:"id"`
+	Type  string `json:"type"` // "vertex"
+	Label string `json:"label"`
+
+	// metaData
+	ProjectRoot      protocol.DocumentURI `json:"projectRoot,omitempty"`
+	PositionEncoding string               `json:"positionEncoding,omitempty"`
+	ToolInfo         *lsifToolInfo        `json:"toolInfo,omitempty"`
+
+	// metaData, packageInformation
+	Version string `json:"version,omitempty"`
+
+	// project, moniker
+	Kind string `json:"kind,omitempty"`
+
+	// document
+	URI        protocol.DocumentURI `json:"uri,omitempty"`
+	LanguageID string               `json:"languageId,omitempty"`
+
+	// range
+	Start *protocol.Position `json:"start,omitempty"`
+	End   *protocol.Position `json:"end,omitempty"`
+
+	// hoverResult
+	Result *lsifHover `json:"result,omitempty"`
+
+	// moniker
+	Scheme     string                   `json:"scheme,omitempty"`
+	Identifier string                   `json:"identifier,omitempty"`
+	Unique     protocol.UniquenessLevel `json:"unique,omitempty"`
+
+	// packageInformation
+	Name    string `json:"name,omitempty"`
+	Manager string `json:"manager,omitempty"`
+}
+
+func (v *lsifVertex) setID(id int) { v.ID, v.Type = id, "vertex" }
+
+// An lsifEdge is an edge of an LSIF dump. A one-to-one edge sets InV;
+// a one-to-many edge sets InVs.
+type lsifEdge struct {
+	ID    int    `json:"id"`
+	Type  string `json:"type"` // "edge"
+	Label string `json:"label"`
+
+	OutV int   `json:"outV"`
+	InV  int   `json:"inV,omitempty"`
+	InVs []int `json:"inVs,omitempty"`
+
+	// item edges
+	Document int    `json:"document,omitempty"`
+	Property string `json:"property,omitempty"`
+}
+
+func (e *lsifEdge) setID(id int) { e.ID, e.Type = id, "edge" }
+
+type lsifToolInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// lsifHover is the result of a hoverResult vertex.
+// (Unlike protocol.Hover, it has no range.)
+type lsifHover struct {
+	Contents protocol.MarkupContent `json:"contents"`
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/typeparams"
+)
+
+// monikerScheme is the scheme of the monikers reported by gopls.
+// It is the scheme used by other Go indexers, so that their indexes
+// and those of gopls may be linked.
+const monikerScheme = "gomod"
+
+// Monikers returns the monikers of the symbol denoted by the
+// identifier at the given position. A moniker names a symbol
+// independent of any workspace, allowing an index of one module to be
+// linked to the indexes of the modules it depends on.
+//
+// Only package-level objects, and the methods and fields of
+// package-level named types, have monikers; see [monikerIdentifier].
+func Monikers(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, pp protocol.Position) ([]protocol.Moniker, error) {
+	ctx, done := event.Start(ctx, "golang.Monikers")
+	defer done()
+
+	pkg, pgf, err := NarrowestPackageForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(pp)
+	if err != nil {
+		return nil, err
+	}
+	path, _ := astutil.PathEnclosingInterval(pgf.File, pos, pos)
+	if len(path) == 0 {
+		return nil, nil
+	}
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return nil, nil
+	}
+	obj := identObject(pkg.GetTypesInfo(), id)
+	if obj == nil || obj.Pkg() == nil {
+		return nil, nil // e.g. a built-in
+	}
+	identifier, ok := monikerIdentifier(obj, memberNames(obj.Pkg()))
+	if !ok {
+		return nil, nil
+	}
+
+	mps, err := snapshot.WorkspaceMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inWorkspace := false
+	for _, mp := range mps {
+		if string(mp.PkgPath) == obj.Pkg().Path() {
+			inWorkspace = true
+			break
+		}
+	}
+	return []protocol.Moniker{newMoniker(identifier, inWorkspace)}, nil
+}
+
+// identObject returns the object denoted by id, if any. For an
+// embedded field, it returns the embedded type, not the field.
+func identObject(info *types.Info, id *ast.Ident) types.Object {
+	if obj, ok := info.Uses[id]; ok {
+		return obj
+	}
+	return info.Defs[id]
+}
+
+// newMoniker returns the moniker for a symbol with the given
+// identifier, according to whether it is declared in the workspace.
+func newMoniker(identifier string, inWorkspace bool) protocol.Moniker {
+	kind := protocol.Import
+	if inWorkspace {
+		kind = protocol.Export
+		_, name, _ := strings.Cut(identifier, ":")
+		for _, part := range strings.Split(name, ".") {
+			if !token.IsExported(part) {
+				kind = protocol.Local
+				break
+			}
+		}
+	}
+	return protocol.Moniker{
+		Scheme:     monikerScheme,
+		Identifier: identifier,
+		Unique:     protocol.Scheme,
+		Kind:       &kind,
+	}
+}
+
+// monikerIdentifier returns the moniker identifier for obj, which is
+// of the form "pkgpath:Name" for a package-level object, or
+// "pkgpath:Type.Name" for a method or field of a package-level named
+// type. It reports false for all other objects, such as local
+// variables and the fields of anonymous structs.
+//
+// The members mapping must have been computed by [memberNames] for
+// the package of obj.
+func monikerIdentifier(obj types.Object, members map[types.Object]string) (string, bool) {
+	pkg := obj.Pkg()
+	if pkg == nil {
+		return "", false // built-in
+	}
+	switch obj := obj.(type) {
+	case *types.Func:
+		if origin := typeparams.OriginMethod(obj); origin != obj {
+			return monikerIdentifier(origin, members)
+		}
+	case *types.Var:
+		if origin := obj.Origin(); origin != obj {
+			return monikerIdentifier(origin, members)
+		}
+	case *types.PkgName, *types.Label:
+		return "", false
+	}
+	if obj.Parent() == pkg.Scope() {
+		return pkg.Path() + ":" + obj.Name(), true
+	}
+	if name, ok := members[obj]; ok {
+		return pkg.Path() + ":" + name, true
+	}
+	return "", false
+}
+
+// memberNames returns a mapping from each method and field of each
+// package-level named type of pkg (including interface methods) to
+// its name qualified by that of its type, such as "T.M".
+func memberNames(pkg *types.Package) map[types.Object]string {
+	members := make(map[types.Object]string)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tname, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || tname.IsAlias() {
+			continue
+		}
+		named, ok := tname.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		for i := 0; i < named.NumMethods(); i++ {
+			m := named.Method(i)
+			members[m] = name + "." + m.Name()
+		}
+		switch u := named.Underlying().(type) {
+		case *types.Struct:
+			for i := 0; i < u.NumFields(); i++ {
+				f := u.Field(i)
+				members[f] = name + "." + f.Name()
+			}
+		case *types.Interface:
+			for i := 0; i < u.NumExplicitMethods(); i++ {
+				m := u.ExplicitMethod(i)
+				members[m] = name + "." + m.Name()
+			}
+		}
+	}
+	return members
+}
@@ -0,0 +1,1091 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file defines the "move to package" refactoring, which moves a
+// package-level function or type, along with the unexported
+// declarations that only it uses, to another package.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/util/bug"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/gopls/internal/util/slices"
+	"golang.org/x/tools/internal/diff"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/imports"
+	"golang.org/x/tools/refactor/importgraph"
+)
+
+// MoveToPackage moves the package-level function or type whose name is
+// at the start of rng (with its methods) to the package destPath. The
+// unexported declarations of its package that are used only by the
+// moved declarations move with it; the others remain, and are
+// exported if the moved declarations refer to them. Likewise, a moved
+// declaration is exported if it is used by declarations that remain.
+// Every reference to a moved declaration is qualified by the
+// destination package, and imports are added and removed as needed.
+//
+// The move is refused if it would create an import cycle, if a
+// declaration that remains and one that moves would each refer to
+// the other's package, or if an unexported field or method would be
+// used across the two packages.
+func MoveToPackage(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, rng protocol.Range, destPath PackagePath) ([]protocol.DocumentChanges, error) {
+	ctx, done := event.Start(ctx, "golang.MoveToPackage")
+	defer done()
+
+	if strings.HasSuffix(fh.URI().Path(), "_test.go") {
+		return nil, errors.New("cannot move declarations of a test file")
+	}
+	narrowest, err := NarrowestMetadataForFile(ctx, snapshot, fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	srcPath := narrowest.PkgPath
+	if destPath == srcPath {
+		return nil, fmt.Errorf("declaration is already in package %s", destPath)
+	}
+	var destMeta *cache.Package
+	for _, mp := range snapshot.MetadataGraph().Packages {
+		if mp.PkgPath == destPath && mp.ForTest == "" && len(mp.CompiledGoFiles) > 0 {
+			pkgs, err := snapshot.TypeCheck(ctx, mp.ID)
+			if err != nil {
+				return nil, err
+			}
+			destMeta = pkgs[0]
+			break
+		}
+	}
+	if destMeta == nil {
+		return nil, fmt.Errorf("no package %s in the workspace", destPath)
+	}
+
+	// Type-check the package and its direct importers, in which
+	// references must be updated.
+	pkgs, err := typeCheckReverseDependencies(ctx, snapshot, fh.URI(), false)
+	if err != nil {
+		return nil, err
+	}
+	m := &mover{
+		snapshot: snapshot,
+		srcPath:  srcPath,
+		destPath: destPath,
+		destPkg:  destMeta,
+		pkgs:     pkgs,
+		moved:    make(map[string]bool),
+		stay:     make(map[string]bool),
+		newNames: make(map[string]string),
+		spans:    make(map[protocol.DocumentURI][][2]int),
+		changes:  make(map[protocol.DocumentURI]*fileChange),
+	}
+	for _, p := range pkgs {
+		if p.Metadata().PkgPath == srcPath {
+			m.srcPkgs = append(m.srcPkgs, p)
+			if p.Metadata().ID == narrowest.ID {
+				m.srcPkg = p
+			}
+		}
+	}
+	if m.srcPkg == nil {
+		return nil, fmt.Errorf("can't find package %s", srcPath) // can't happen
+	}
+	pgf, err := m.srcPkg.File(fh.URI())
+	if err != nil {
+		return nil, err
+	}
+	pos, err := pgf.PositionPos(rng.Start)
+	if err != nil {
+		return nil, err
+	}
+	path, _ := astutil.PathEnclosingInterval(pgf.File, pos, pos)
+	id, ok := path[0].(*ast.Ident)
+	if !ok {
+		return nil, errors.New("no identifier at selection")
+	}
+	obj := identObject(m.srcPkg.GetTypesInfo(), id)
+	if obj == nil || obj.Parent() != m.srcPkg.GetTypes().Scope() {
+		return nil, fmt.Errorf("%s is not a package-level declaration of this package", id.Name)
+	}
+	if err := m.plan(obj); err != nil {
+		return nil, err
+	}
+	return m.edits(ctx, pgf.URI)
+}
+
+// A mover holds the state of a "move to package" refactoring.
+type mover struct {
+	snapshot          *cache.Snapshot
+	srcPath, destPath PackagePath
+	srcPkg            *cache.Package   // the non-test variant of the source package
+	srcPkgs           []*cache.Package // all non-intermediate variants of the source package
+	destPkg           *cache.Package
+	pkgs              []*cache.Package // source package variants and direct importers
+
+	decls      map[string]ast.Decl               // movable declarations of the source package
+	methods    map[string][]*ast.FuncDecl        // method declarations, by receiver type name
+	uses       map[string][]srcPos               // uses of package-level names, by name
+	moved      map[string]bool                   // names of moved declarations
+	movedDecls []ast.Decl                        // moved declarations, in order
+	stay       map[string]bool                   // names that remain but are used by moved declarations
+	newNames   map[string]string                 // names of exported declarations
+	spans      map[protocol.DocumentURI][][2]int // offsets of moved declarations
+	importers  map[PackagePath]bool              // packages that must import the destination
+
+	changes map[protocol.DocumentURI]*fileChange
+}
+
+// A srcPos is a position in a file.
+type srcPos struct {
+	uri    protocol.DocumentURI
+	offset int
+}
+
+// A fileChange accumulates the changes to a file.
+type fileChange struct {
+	pgf   *parsego.File
+	edits map[diff.Edit]bool
+	add   []newImport
+	del   []*ast.ImportSpec
+}
+
+// plan determines the declarations to move with the declaration of
+// obj, and those to export, and checks that the move is safe.
+func (m *mover) plan(obj types.Object) error {
+	switch obj := obj.(type) {
+	case *types.Func:
+		if obj.Name() == "init" || obj.Name() == "main" && m.srcPkg.GetTypes().Name() == "main" {
+			return fmt.Errorf("cannot move %s", obj.Name())
+		}
+	case *types.TypeName:
+	default:
+		return fmt.Errorf("can only move a function or a type, not %s", obj.Name())
+	}
+	if err := m.indexDecls(); err != nil {
+		return err
+	}
+	if m.decls[obj.Name()] == nil {
+		return fmt.Errorf("cannot move %s, which is declared in a group", obj.Name())
+	}
+
+	// Move the declaration, and each unexported declaration
+	// that is used only by moved declarations.
+	if err := m.move(obj.Name()); err != nil {
+		return err
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, name := range m.references() {
+			if m.moved[name] || token.IsExported(name) || m.decls[name] == nil {
+				continue
+			}
+			only, err := m.usedOnlyBy(name)
+			if err != nil {
+				return err
+			}
+			if only {
+				if err := m.move(name); err != nil {
+					return err
+				}
+				changed = true
+			}
+		}
+	}
+	for _, name := range m.references() {
+		if !m.moved[name] {
+			m.stay[name] = true
+		}
+	}
+
+	// Export the declarations that are used across the two packages.
+	srcScope := m.srcPkg.GetTypes().Scope()
+	destScope := m.destPkg.GetTypes().Scope()
+	final := make(map[string]string)
+	for name := range m.moved {
+		if !token.IsExported(name) && m.usedOutside(name) {
+			m.newNames[name] = exportedName(name)
+		}
+		newName := m.name(name)
+		if destScope.Lookup(newName) != nil {
+			return fmt.Errorf("package %s already declares %s", m.destPath, newName)
+		}
+		if other, ok := final[newName]; ok {
+			return fmt.Errorf("%s and %s would both be called %s", other, name, newName)
+		}
+		final[newName] = name
+	}
+	for name := range m.stay {
+		if !token.IsExported(name) {
+			newName := exportedName(name)
+			for _, p := range m.srcPkgs {
+				if p.GetTypes().Scope().Lookup(newName) != nil {
+					return fmt.Errorf("cannot export %s: package %s already declares %s", name, m.srcPath, newName)
+				}
+			}
+			m.newNames[name] = newName
+		}
+		if srcScope.Lookup(name) == nil {
+			return fmt.Errorf("no declaration of %s", name) // can't happen
+		}
+	}
+
+	if err := m.checkMembers(); err != nil {
+		return err
+	}
+	return m.checkImports()
+}
+
+// indexDecls records the movable package-level declarations of the
+// source package, and the uses of package-level names by all its
+// variants.
+func (m *mover) indexDecls() error {
+	m.decls = make(map[string]ast.Decl)
+	m.methods = make(map[string][]*ast.FuncDecl)
+	for _, pgf := range m.srcPkg.CompiledGoFiles() {
+		for _, decl := range pgf.File.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					m.decls[decl.Name.Name] = decl
+				} else if len(decl.Recv.List) == 1 {
+					if name := recvTypeName(decl.Recv.List[0].Type); name != "" {
+						m.methods[name] = append(m.methods[name], decl)
+					}
+				}
+			case *ast.GenDecl:
+				if decl.Tok == token.IMPORT || len(decl.Specs) != 1 {
+					continue
+				}
+				switch spec := decl.Specs[0].(type) {
+				case *ast.TypeSpec:
+					m.decls[spec.Name.Name] = decl
+				case *ast.ValueSpec:
+					if len(spec.Names) == 1 {
+						m.decls[spec.Names[0].Name] = decl
+					}
+				}
+			}
+		}
+	}
+	delete(m.decls, "_")
+
+	m.uses = make(map[string][]srcPos)
+	seen := make(map[srcPos]bool)
+	for _, p := range m.srcPkgs {
+		scope := p.GetTypes().Scope()
+		info := p.GetTypesInfo()
+		for _, pgf := range p.CompiledGoFiles() {
+			var err error
+			ast.Inspect(pgf.File, func(n ast.Node) bool {
+				if err != nil {
+					return false
+				}
+				if id, ok := n.(*ast.Ident); ok {
+					if obj := info.Uses[id]; obj != nil && obj.Parent() == scope {
+						var offset int
+						offset, err = offsetOf(pgf, id.Pos())
+						pos := srcPos{pgf.URI, offset}
+						if err == nil && !seen[pos] {
+							seen[pos] = true
+							m.uses[id.Name] = append(m.uses[id.Name], pos)
+						}
+					}
+				}
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recvTypeName returns the name of the type of a method receiver.
+func recvTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return recvTypeName(expr.X)
+	case *ast.IndexExpr:
+		return recvTypeName(expr.X)
+	case *ast.IndexListExpr:
+		return recvTypeName(expr.X)
+	case *ast.ParenExpr:
+		return recvTypeName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+	return ""
+}
+
+// move records that the declaration of name, and its methods, move.
+func (m *mover) move(name string) error {
+	m.moved[name] = true
+	decls := []ast.Decl{m.decls[name]}
+	for _, method := range m.methods[name] {
+		decls = append(decls, method)
+	}
+	for _, decl := range decls {
+		m.movedDecls = append(m.movedDecls, decl)
+		pgf, start, end, err := m.declSpan(decl)
+		if err != nil {
+			return err
+		}
+		m.spans[pgf.URI] = append(m.spans[pgf.URI], [2]int{start, end})
+	}
+	return nil
+}
+
+// declFile returns the file of the source package that declares decl.
+func (m *mover) declFile(decl ast.Decl) (*parsego.File, error) {
+	for _, pgf := range m.srcPkg.CompiledGoFiles() {
+		if pgf.File.Pos() <= decl.Pos() && decl.End() <= pgf.File.End() {
+			return pgf, nil
+		}
+	}
+	return nil, bug.Errorf("declaration not in package %s", m.srcPath)
+}
+
+// declSpan returns the file of the source package that declares decl,
+// and the offsets of decl in it.
+func (m *mover) declSpan(decl ast.Decl) (*parsego.File, int, int, error) {
+	pgf, err := m.declFile(decl)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	start, err := offsetOf(pgf, decl.Pos())
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	end, err := offsetOf(pgf, decl.End())
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return pgf, start, end, nil
+}
+
+// inMoved reports whether pos is within a moved declaration.
+func (m *mover) inMoved(pos srcPos) bool {
+	for _, span := range m.spans[pos.uri] {
+		if span[0] <= pos.offset && pos.offset < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// references returns the sorted package-level names of the source
+// package used by the moved declarations.
+func (m *mover) references() []string {
+	info := m.srcPkg.GetTypesInfo()
+	scope := m.srcPkg.GetTypes().Scope()
+	refs := make(map[string]bool)
+	for _, decl := range m.movedDecls {
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if obj := info.Uses[id]; obj != nil && obj.Parent() == scope {
+					refs[id.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	var names []string
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// usedOnlyBy reports whether the declaration of name (and its
+// methods) is used only by the moved declarations.
+func (m *mover) usedOnlyBy(name string) (bool, error) {
+	own := []ast.Decl{m.decls[name]}
+	for _, method := range m.methods[name] {
+		own = append(own, method)
+	}
+	for _, use := range m.uses[name] {
+		if m.inMoved(use) {
+			continue
+		}
+		inOwn := false
+		for _, decl := range own {
+			pgf, start, end, err := m.declSpan(decl)
+			if err != nil {
+				return false, err
+			}
+			if use.uri == pgf.URI && start <= use.offset && use.offset < end {
+				inOwn = true
+			}
+		}
+		if !inOwn {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// usedOutside reports whether a moved name is used outside the moved
+// declarations.
+func (m *mover) usedOutside(name string) bool {
+	for _, use := range m.uses[name] {
+		if !m.inMoved(use) {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the name of a declaration after the move.
+func (m *mover) name(name string) string {
+	if newName, ok := m.newNames[name]; ok {
+		return newName
+	}
+	return name
+}
+
+// checkMembers checks that no unexported field or method of a type
+// is used across the two packages.
+func (m *mover) checkMembers() error {
+	for _, p := range m.srcPkgs {
+		scope := p.GetTypes().Scope()
+		for _, pgf := range p.CompiledGoFiles() {
+			for id, obj := range p.GetTypesInfo().Uses {
+				if obj.Exported() || obj.Pkg() != p.GetTypes() || obj.Parent() == scope || !obj.Pos().IsValid() {
+					continue
+				}
+				if _, ok := obj.(*types.PkgName); ok {
+					continue
+				}
+				if id.Pos() < pgf.File.Pos() || id.Pos() > pgf.File.End() {
+					continue
+				}
+				declPGF, ok := enclosingFile(p, obj.Pos())
+				if !ok {
+					continue
+				}
+				useOffset, err := offsetOf(pgf, id.Pos())
+				if err != nil {
+					return err
+				}
+				declOffset, err := offsetOf(declPGF, obj.Pos())
+				if err != nil {
+					return err
+				}
+				use := srcPos{pgf.URI, useOffset}
+				decl := srcPos{declPGF.URI, declOffset}
+				if m.inMoved(use) != m.inMoved(decl) {
+					return fmt.Errorf("cannot move: unexported %s would be used across packages at %s:%d",
+						obj.Name(), filepath.Base(pgf.URI.Path()), safetoken.Line(pgf.Tok, id.Pos()))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkImports checks that the imports needed after the move do not
+// create a cycle in the import graph of the workspace.
+func (m *mover) checkImports() error {
+	// Which packages must import the destination package?
+	m.importers = make(map[PackagePath]bool)
+	for name := range m.moved {
+		if m.usedOutside(name) {
+			m.importers[m.srcPath] = true
+		}
+	}
+	for _, p := range m.pkgs {
+		path := p.Metadata().PkgPath
+		if path == m.srcPath || path == m.destPath {
+			continue
+		}
+		if srcTypes := p.DependencyTypes(m.srcPath); srcTypes != nil {
+			for _, obj := range p.GetTypesInfo().Uses {
+				if obj.Pkg() == srcTypes && m.moved[obj.Name()] && obj.Parent() == srcTypes.Scope() {
+					m.importers[path] = true
+					break
+				}
+			}
+		}
+	}
+	if m.importers[m.srcPath] && len(m.stay) > 0 {
+		var names []string
+		for name := range m.stay {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("cannot move: packages %s and %s would import each other, as moved declarations use %s",
+			m.srcPath, m.destPath, strings.Join(names, ", "))
+	}
+
+	// Which packages must the destination package import?
+	deps := make(map[PackagePath]bool)
+	if len(m.stay) > 0 {
+		deps[m.srcPath] = true
+	}
+	info := m.srcPkg.GetTypesInfo()
+	for _, decl := range m.movedDecls {
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok {
+				if pkgName, ok := info.Uses[id].(*types.PkgName); ok {
+					deps[PackagePath(pkgName.Imported().Path())] = true
+				}
+			}
+			return true
+		})
+	}
+	delete(deps, m.destPath)
+
+	// Build the forward import graph of the workspace.
+	graph := make(importgraph.Graph)
+	for _, mp := range m.snapshot.MetadataGraph().Packages {
+		if mp.ForTest != "" {
+			continue
+		}
+		edges := graph[string(mp.PkgPath)]
+		if edges == nil {
+			edges = make(map[string]bool)
+			graph[string(mp.PkgPath)] = edges
+		}
+		for dep := range mp.DepsByPkgPath {
+			edges[string(dep)] = true
+		}
+	}
+	reachable := graph.Search(string(m.destPath))
+	for path := range m.importers {
+		if reachable[string(path)] {
+			return fmt.Errorf("cannot move: %s would import %s, which imports it", path, m.destPath)
+		}
+	}
+	for path := range deps {
+		if graph.Search(string(path))[string(m.destPath)] {
+			return fmt.Errorf("cannot move: %s would import %s, which imports it", m.destPath, path)
+		}
+	}
+	return nil
+}
+
+// edits returns the changes that perform the move.
+func (m *mover) edits(ctx context.Context, target protocol.DocumentURI) ([]protocol.DocumentChanges, error) {
+	// The moved declarations go in the file of the destination
+	// package with the same name as that of the target.
+	destDir := filepath.Dir(m.destPkg.Metadata().CompiledGoFiles[0].Path())
+	destURI := protocol.URIFromPath(filepath.Join(destDir, filepath.Base(target.Path())))
+	destPGF, err := m.destPkg.File(destURI)
+	if err != nil {
+		if !slices.Contains(m.snapshot.Options().SupportedResourceOperations, protocol.Create) {
+			return nil, fmt.Errorf("can't create %s: LSP client does not support file creation", destURI.Path())
+		}
+		if fh, err := m.snapshot.ReadFile(ctx, destURI); err != nil {
+			return nil, err
+		} else if _, err := fh.Content(); err == nil {
+			return nil, fmt.Errorf("%s is not a file of package %s", destURI.Path(), m.destPath)
+		}
+		destPGF = nil
+	}
+
+	// Format the moved declarations, qualified for the destination file.
+	var destImports []*ast.ImportSpec
+	if destPGF != nil {
+		destImports = destPGF.File.Imports
+	}
+	var added []newImport
+	destQual := func(path PackagePath, declared, preferred string) string {
+		if name, ok := importedName(destImports, string(path), declared); ok {
+			return name
+		}
+		imp := newImport{importPath: string(path)}
+		if preferred != pathpkg.Base(trimVersionSuffix(imp.importPath)) {
+			imp.name = preferred
+		}
+		if !slices.Contains(added, imp) {
+			added = append(added, imp)
+		}
+		return preferred
+	}
+	var text bytes.Buffer
+	if err := m.formatMoved(&text, destQual); err != nil {
+		return nil, err
+	}
+
+	// Remove the moved declarations, and the imports that only they use.
+	spans := make(map[*parsego.File][][2]int)
+	for _, decl := range m.movedDecls {
+		pgf, err := m.declFile(decl)
+		if err != nil {
+			return nil, err
+		}
+		_, start, end, err := selectedDecls(pgf, decl.Pos(), decl.End())
+		if err != nil {
+			return nil, err
+		}
+		spans[pgf] = append(spans[pgf], [2]int{start, end})
+	}
+	for pgf, spans := range spans {
+		sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+		var merged [][2]int
+		for _, span := range spans {
+			if n := len(merged); n > 0 && span[0] <= merged[n-1][1] {
+				if span[1] > merged[n-1][1] {
+					merged[n-1][1] = span[1]
+				}
+				continue
+			}
+			merged = append(merged, span)
+		}
+		change := m.change(pgf)
+		for _, span := range merged {
+			change.edits[diff.Edit{Start: span[0], End: span[1]}] = true
+		}
+		unused, err := m.unusedImports(pgf)
+		if err != nil {
+			return nil, err
+		}
+		change.del = append(change.del, unused...)
+	}
+
+	// Update the references to moved and exported declarations.
+	for _, p := range m.pkgs {
+		if err := m.updateReferences(p); err != nil {
+			return nil, err
+		}
+	}
+
+	// Add the moved declarations to the destination package.
+	var changes []protocol.DocumentChanges
+	if destPGF != nil {
+		change := m.change(destPGF)
+		change.add = append(change.add, added...)
+		eof := len(destPGF.Src)
+		newText := "\n" + text.String()
+		if eof > 0 && destPGF.Src[eof-1] != '\n' {
+			newText = "\n" + newText
+		}
+		change.edits[diff.Edit{Start: eof, End: eof, New: newText}] = true
+	} else {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "package %s\n\n", m.destPkg.GetTypes().Name())
+		var specs []string
+		for _, imp := range added {
+			spec := strconv.Quote(imp.importPath)
+			if imp.name != "" {
+				spec = imp.name + " " + spec
+			}
+			specs = append(specs, spec)
+		}
+		switch len(specs) {
+		case 0:
+		case 1:
+			fmt.Fprintf(&buf, "import %s\n\n", specs[0])
+		default:
+			fmt.Fprintf(&buf, "import (\n\t%s\n)\n\n", strings.Join(specs, "\n\t"))
+		}
+		buf.Write(text.Bytes())
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes,
+			protocol.DocumentChanges{
+				CreateFile: &protocol.CreateFile{Kind: "create", URI: destURI},
+			},
+			protocol.DocumentChanges{
+				TextDocumentEdit: &protocol.TextDocumentEdit{
+					TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+						TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: destURI},
+					},
+					Edits: protocol.AsAnnotatedTextEdits([]protocol.TextEdit{{NewText: string(src)}}),
+				},
+			})
+	}
+
+	// Convert the changes of each file to edits.
+	var uris []protocol.DocumentURI
+	for uri := range m.changes {
+		uris = append(uris, uri)
+	}
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	for _, uri := range uris {
+		change := m.changes[uri]
+		var edits []protocol.TextEdit
+		var fixes []*imports.ImportFix
+		for _, imp := range change.add {
+			fixes = append(fixes, &imports.ImportFix{
+				StmtInfo: imports.ImportInfo{ImportPath: imp.importPath, Name: imp.name},
+				FixType:  imports.AddImport,
+			})
+		}
+		for _, spec := range change.del {
+			fix := importSpecFix(spec)
+			fix.FixType = imports.DeleteImport
+			fixes = append(fixes, fix)
+		}
+		if len(fixes) > 0 {
+			edits, err = computeImportFixEdits(m.snapshot, change.pgf, fixes)
+			if err != nil {
+				return nil, err
+			}
+		}
+		var offsetEdits []diff.Edit
+		for edit := range change.edits {
+			offsetEdits = append(offsetEdits, edit)
+		}
+		diff.SortEdits(offsetEdits)
+		for _, edit := range offsetEdits {
+			rng, err := change.pgf.Mapper.OffsetRange(edit.Start, edit.End)
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, protocol.TextEdit{Range: rng, NewText: edit.New})
+		}
+		fh, err := m.snapshot.ReadFile(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, documentChanges(fh, edits)...)
+	}
+	return changes, nil
+}
+
+// change returns the accumulated changes to the file pgf.
+func (m *mover) change(pgf *parsego.File) *fileChange {
+	change, ok := m.changes[pgf.URI]
+	if !ok {
+		change = &fileChange{pgf: pgf, edits: make(map[diff.Edit]bool)}
+		m.changes[pgf.URI] = change
+	}
+	return change
+}
+
+// formatMoved writes the text of the moved declarations to buf, in
+// which names are qualified relative to the destination file:
+// destQual returns the local name of the specified package (with the
+// specified declared and preferred names) in that file.
+func (m *mover) formatMoved(buf *bytes.Buffer, destQual func(path PackagePath, declared, preferred string) string) error {
+	info := m.srcPkg.GetTypesInfo()
+	srcTypes := m.srcPkg.GetTypes()
+	scope := srcTypes.Scope()
+
+	// Import specs of the source files, by package name object.
+	specs := make(map[*types.PkgName]*ast.ImportSpec)
+	for _, pgf := range m.srcPkg.CompiledGoFiles() {
+		for _, spec := range pgf.File.Imports {
+			obj := info.Implicits[spec]
+			if obj == nil && spec.Name != nil {
+				obj = info.Defs[spec.Name]
+			}
+			if pkgName, ok := obj.(*types.PkgName); ok {
+				specs[pkgName] = spec
+			}
+		}
+	}
+
+	for i, decl := range m.movedDecls {
+		pgf, err := m.declFile(decl)
+		if err != nil {
+			return err
+		}
+		_, start, end, err := selectedDecls(pgf, decl.Pos(), decl.End())
+		if err != nil {
+			return err
+		}
+		var edits []diff.Edit
+		edit := func(from, to token.Pos, text string) {
+			fromOffset, toOffset, offsetErr := offsetsOf(pgf, from, to)
+			if offsetErr != nil {
+				err = offsetErr
+				return
+			}
+			edits = append(edits, diff.Edit{Start: fromOffset - start, End: toOffset - start, New: text})
+		}
+		ast.Inspect(decl, func(n ast.Node) bool {
+			if err != nil {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				x, ok := n.X.(*ast.Ident)
+				if !ok {
+					break
+				}
+				pkgName, ok := info.Uses[x].(*types.PkgName)
+				if !ok {
+					break
+				}
+				imported := pkgName.Imported()
+				if PackagePath(imported.Path()) == m.destPath {
+					edit(x.Pos(), n.Sel.Pos(), "") // no longer qualified
+					return false
+				}
+				preferred := imported.Name()
+				if spec := specs[pkgName]; spec != nil && spec.Name != nil {
+					preferred = spec.Name.Name
+				}
+				if name := destQual(PackagePath(imported.Path()), imported.Name(), preferred); name != x.Name {
+					edit(x.Pos(), x.End(), name)
+				}
+				return false
+
+			case *ast.Ident:
+				obj := identObject(info, n)
+				if obj == nil {
+					break
+				}
+				if obj.Parent() == scope {
+					switch {
+					case m.moved[n.Name]:
+						if newName, ok := m.newNames[n.Name]; ok {
+							edit(n.Pos(), n.End(), newName)
+						}
+					case m.stay[n.Name]:
+						qual := destQual(m.srcPath, srcTypes.Name(), srcTypes.Name())
+						edit(n.Pos(), n.End(), qual+"."+m.name(n.Name))
+					}
+				} else if p := obj.Pkg(); p != nil && p != srcTypes && obj.Parent() == p.Scope() {
+					err = fmt.Errorf("cannot move declarations that use dot-imported %s", n.Name)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		text, err := diff.Apply(string(pgf.Src[start:end]), edits)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(strings.TrimRight(text, "\n") + "\n")
+	}
+	return nil
+}
+
+// unusedImports returns the import specs of a source file whose uses
+// are all within moved declarations.
+func (m *mover) unusedImports(pgf *parsego.File) ([]*ast.ImportSpec, error) {
+	info := m.srcPkg.GetTypesInfo()
+	used := make(map[types.Object]bool)   // used outside moved declarations
+	inside := make(map[types.Object]bool) // used inside moved declarations
+	var err error
+	ast.Inspect(pgf.File, func(n ast.Node) bool {
+		if err != nil {
+			return false
+		}
+		if id, ok := n.(*ast.Ident); ok {
+			if pkgName, ok := info.Uses[id].(*types.PkgName); ok {
+				var offset int
+				offset, err = offsetOf(pgf, id.Pos())
+				if err != nil {
+					return false
+				}
+				if m.inMoved(srcPos{pgf.URI, offset}) {
+					inside[pkgName] = true
+				} else {
+					used[pkgName] = true
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	var unused []*ast.ImportSpec
+	for _, spec := range pgf.File.Imports {
+		obj := info.Implicits[spec]
+		if obj == nil && spec.Name != nil {
+			obj = info.Defs[spec.Name]
+		}
+		if obj != nil && inside[obj] && !used[obj] {
+			unused = append(unused, spec)
+		}
+	}
+	return unused, nil
+}
+
+// updateReferences records the edits to the references in package p
+// to moved and exported declarations, outside the moved declarations.
+func (m *mover) updateReferences(p *cache.Package) error {
+	info := p.GetTypesInfo()
+	path := p.Metadata().PkgPath
+	destName := m.destPkg.GetTypes().Name()
+	for _, pgf := range p.CompiledGoFiles() {
+		// destQual returns the name of the destination package in the
+		// file, adding an import if needed.
+		destQual := func() string {
+			if name, ok := importedName(pgf.File.Imports, string(m.destPath), destName); ok {
+				return name
+			}
+			imp := newImport{importPath: string(m.destPath)}
+			if destName != pathpkg.Base(trimVersionSuffix(imp.importPath)) {
+				imp.name = destName
+			}
+			change := m.change(pgf)
+			if !slices.Contains(change.add, imp) {
+				change.add = append(change.add, imp)
+			}
+			return destName
+		}
+
+		if path == m.srcPath {
+			// References within the source package are unqualified.
+			scope := p.GetTypes().Scope()
+			var (
+				edits []diff.Edit
+				err   error
+			)
+			ast.Inspect(pgf.File, func(n ast.Node) bool {
+				if err != nil {
+					return false
+				}
+				id, ok := n.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				obj := identObject(info, id)
+				if obj == nil || obj.Parent() != scope {
+					return true
+				}
+				var start, end int
+				start, end, err = offsetsOf(pgf, id.Pos(), id.End())
+				if err != nil || m.inMoved(srcPos{pgf.URI, start}) {
+					return true
+				}
+				switch {
+				case m.moved[id.Name]:
+					edits = append(edits, diff.Edit{Start: start, End: end, New: destQual() + "." + m.name(id.Name)})
+				case m.newNames[id.Name] != "":
+					edits = append(edits, diff.Edit{Start: start, End: end, New: m.newNames[id.Name]})
+				}
+				return true
+			})
+			if err != nil {
+				return err
+			}
+			if len(edits) > 0 {
+				change := m.change(pgf)
+				for _, edit := range edits {
+					change.edits[edit] = true
+				}
+			}
+			continue
+		}
+
+		// References from other packages are qualified.
+		srcTypes := p.DependencyTypes(m.srcPath)
+		if srcTypes == nil {
+			continue
+		}
+		var (
+			edits    []diff.Edit
+			uses     = make(map[types.Object]int) // uses of source package names
+			replaced = make(map[types.Object]int) // replaced uses of source package names
+			err      error
+		)
+		ast.Inspect(pgf.File, func(n ast.Node) bool {
+			if err != nil {
+				return false
+			}
+			switch n := n.(type) {
+			case *ast.SelectorExpr:
+				x, ok := n.X.(*ast.Ident)
+				if !ok {
+					break
+				}
+				pkgName, ok := info.Uses[x].(*types.PkgName)
+				if !ok || pkgName.Imported() != srcTypes {
+					break
+				}
+				uses[pkgName]++
+				if m.moved[n.Sel.Name] {
+					replaced[pkgName]++
+					newText := m.name(n.Sel.Name)
+					if path != m.destPath {
+						newText = destQual() + "." + newText
+					}
+					var start, end int
+					start, end, err = offsetsOf(pgf, n.Pos(), n.End())
+					edits = append(edits, diff.Edit{Start: start, End: end, New: newText})
+				}
+				return false
+			case *ast.Ident:
+				if obj := info.Uses[n]; obj != nil && obj.Pkg() == srcTypes && obj.Parent() == srcTypes.Scope() && m.moved[n.Name] {
+					err = fmt.Errorf("cannot move %s, which is dot-imported by %s", n.Name, pgf.URI.Path())
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return err
+		}
+		if len(edits) == 0 {
+			continue
+		}
+		change := m.change(pgf)
+		for _, edit := range edits {
+			change.edits[edit] = true
+		}
+		for _, spec := range pgf.File.Imports {
+			obj := info.Implicits[spec]
+			if obj == nil && spec.Name != nil {
+				obj = info.Defs[spec.Name]
+			}
+			if obj != nil && replaced[obj] > 0 && replaced[obj] == uses[obj] {
+				change.del = append(change.del, spec)
+			}
+		}
+	}
+	return nil
+}
+
+// offsetOf returns the offset of pos in pgf.
+func offsetOf(pgf *parsego.File, pos token.Pos) (int, error) {
+	offset, err := safetoken.Offset(pgf.Tok, pos)
+	if err != nil {
+		return 0, bug.Errorf("position not in %s: %v", pgf.URI.Path(), err)
+	}
+	return offset, nil
+}
+
+// offsetsOf returns the offsets of start and end in pgf.
+func offsetsOf(pgf *parsego.File, start, end token.Pos) (int, int, error) {
+	startOffset, err := offsetOf(pgf, start)
+	if err != nil {
+		return 0, 0, err
+	}
+	endOffset, err := offsetOf(pgf, end)
+	if err != nil {
+		return 0, 0, err
+	}
+	return startOffset, endOffset, nil
+}
+
+// exportedName returns the exported form of an unexported name.
+func exportedName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
@@ -0,0 +1,169 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file defines the "Convert anonymous struct to named type"
+// code action.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+)
+
+// CanNameStructType reports whether the innermost type expression
+// enclosing [start, end) is an anonymous struct type that can be
+// declared as a named type at package level.
+func CanNameStructType(file *ast.File, pkg *types.Package, info *types.Info, start, end token.Pos) bool {
+	node, _ := findAnonymousStruct(file, pkg, info, start, end)
+	return node != nil
+}
+
+// nameStructType is a singleFileFixer that declares a named type for
+// the anonymous struct type enclosing [start, end), before the
+// enclosing declaration, and uses it in place of each identical
+// anonymous struct type of the file.
+func nameStructType(fset *token.FileSet, start, end token.Pos, src []byte, file *ast.File, pkg *types.Package, info *types.Info) (*token.FileSet, *analysis.SuggestedFix, error) {
+	node, decl := findAnonymousStruct(file, pkg, info, start, end)
+	if node == nil {
+		return nil, nil, fmt.Errorf("no anonymous struct type to convert")
+	}
+	typ := info.TypeOf(node)
+	tok := fset.File(file.Pos())
+
+	// Find the identical struct types of the file, excluding those
+	// nested within another.
+	var sites []*ast.StructType
+	defined := definedStructs(file)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n, ok := n.(*ast.StructType); ok && !defined[n] {
+			if n == node || types.Identical(info.TypeOf(n), typ) {
+				sites = append(sites, n)
+				return false
+			}
+		}
+		return true
+	})
+
+	// Choose a name that is not in scope at any use.
+	name, _ := generateIdentifier(0, "newStruct", func(name string) bool {
+		if pkg.Scope().Lookup(name) != nil {
+			return true
+		}
+		for _, site := range sites {
+			if scope := pkg.Scope().Innermost(site.Pos()); scope != nil {
+				if _, obj := scope.LookupParent(name, site.Pos()); obj != nil {
+					return true
+				}
+			}
+		}
+		return false
+	})
+
+	// Format the declaration of the named type.
+	startOffset, endOffset, err := safetoken.Offsets(tok, node.Pos(), node.End())
+	if err != nil {
+		return nil, nil, err
+	}
+	const prefix = "package p\n\n"
+	declSrc, err := format.Source([]byte(prefix + "type " + name + " " + string(src[startOffset:endOffset]) + "\n"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Insert it before the enclosing declaration and its doc comment.
+	insert := decl.Pos()
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		if decl.Doc != nil {
+			insert = decl.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if decl.Doc != nil {
+			insert = decl.Doc.Pos()
+		}
+	}
+	insert = tok.LineStart(safetoken.Line(tok, insert))
+	edits := []analysis.TextEdit{{
+		Pos:     insert,
+		End:     insert,
+		NewText: []byte(strings.TrimPrefix(string(declSrc), prefix) + "\n"),
+	}}
+	for _, site := range sites {
+		edits = append(edits, analysis.TextEdit{
+			Pos:     site.Pos(),
+			End:     site.End(),
+			NewText: []byte(name),
+		})
+	}
+	return fset, &analysis.SuggestedFix{TextEdits: edits}, nil
+}
+
+// findAnonymousStruct returns the innermost anonymous struct type
+// enclosing [start, end) and the enclosing package-level declaration,
+// if the struct type refers only to package-level declarations, so
+// that it can be declared at package level.
+func findAnonymousStruct(file *ast.File, pkg *types.Package, info *types.Info, start, end token.Pos) (*ast.StructType, ast.Decl) {
+	path, _ := astutil.PathEnclosingInterval(file, start, end)
+	if len(path) < 2 {
+		return nil, nil
+	}
+	var node *ast.StructType
+	for i, n := range path {
+		if n, ok := n.(*ast.StructType); ok {
+			if spec, ok := path[i+1].(*ast.TypeSpec); ok && spec.Type == n {
+				return nil, nil // not anonymous
+			}
+			node = n
+			break
+		}
+	}
+	if node == nil {
+		return nil, nil
+	}
+	if _, ok := info.TypeOf(node).(*types.Struct); !ok {
+		return nil, nil
+	}
+	decl, ok := path[len(path)-2].(ast.Decl)
+	if !ok {
+		return nil, nil
+	}
+	local := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Ident); ok {
+			// Imported package names belong to the file scope.
+			if obj := info.Uses[id]; obj != nil && obj.Pkg() == pkg && !is[*types.PkgName](obj) && obj.Parent() != pkg.Scope() {
+				local = true // e.g. a local type or a type parameter
+			}
+		}
+		return !local
+	})
+	if local {
+		return nil, nil
+	}
+	return node, decl
+}
+
+// definedStructs returns the struct types of the file that are the
+// types of type declarations.
+func definedStructs(file *ast.File) map[*ast.StructType]bool {
+	defined := make(map[*ast.StructType]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok {
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				defined[st] = true
+			}
+		}
+		return true
+	})
+	return defined
+}
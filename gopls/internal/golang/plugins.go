@@ -0,0 +1,515 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file runs the analyzer plugins configured by the
+// analyzerPlugins setting, using the vet tool protocol of the go
+// command: each plugin is built as an executable and run by "go vet
+// -vettool=plugin -json", whose output is a JSON tree of diagnostics.
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/metadata"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/filecache"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/gocommand"
+)
+
+// PluginDiagnostics returns the diagnostics of the analyzer plugins of
+// the analyzerPlugins setting for the packages pkgs, excluding those
+// of analyzers disabled by the analyses setting.
+//
+// As the plugins analyze the files saved on disk, packages with
+// unsaved changes are skipped. The diagnostics of each package are
+// stored in the file cache, keyed by the sources of the plugins, the
+// build configuration, and the files of the package and of its
+// non-standard dependencies.
+func PluginDiagnostics(ctx context.Context, snapshot *cache.Snapshot, pkgs map[PackageID]*metadata.Package) (map[protocol.DocumentURI][]*cache.Diagnostic, error) {
+	plugins := snapshot.Options().AnalyzerPlugins
+	if len(plugins) == 0 {
+		return nil, nil
+	}
+	ctx, done := event.Start(ctx, "golang.PluginDiagnostics")
+	defer done()
+
+	pluginsHash, err := hashPlugins(ctx, snapshot, plugins)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the packages whose diagnostics are not cached.
+	var (
+		reports = make(map[protocol.DocumentURI][]*cache.Diagnostic)
+		missing = make(map[PackageID]file.Hash)
+		paths   []string // to vet, for the missing packages
+		seen    = make(map[string]bool)
+	)
+	for id, mp := range pkgs {
+		key, saved, err := pluginKey(ctx, snapshot, pluginsHash, mp)
+		if err != nil {
+			return nil, err
+		}
+		if !saved {
+			continue
+		}
+		data, err := filecache.Get(pluginsKind, key)
+		if err == nil {
+			addPluginDiagnostics(reports, groupByURI(cache.DecodeDiagnostics(data)))
+			continue
+		} else if err != filecache.ErrNotFound {
+			return nil, err
+		}
+		missing[id] = key
+		if path := vetPath(mp); !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	if len(missing) == 0 {
+		return filterPluginDiagnostics(snapshot, reports), nil
+	}
+	sort.Strings(paths)
+
+	// Vet the missing packages with each plugin.
+	results, err := runPlugins(ctx, snapshot, plugins, paths)
+	if err != nil {
+		return nil, err
+	}
+	byPackage := make(map[PackageID][]*cache.Diagnostic)
+	for _, diag := range results {
+		// Attribute each diagnostic to the missing packages containing
+		// its file, such as a package and its test variant.
+		for id := range missing {
+			if containsFile(pkgs[id], diag.URI) {
+				byPackage[id] = append(byPackage[id], diag)
+			}
+		}
+	}
+	for id, key := range missing {
+		if err := filecache.Set(pluginsKind, key, cache.EncodeDiagnostics(byPackage[id])); err != nil {
+			event.Error(ctx, "storing plugin diagnostics in filecache", err)
+		}
+		addPluginDiagnostics(reports, groupByURI(byPackage[id]))
+	}
+
+	return filterPluginDiagnostics(snapshot, reports), nil
+}
+
+// The file cache kind of plugin diagnostics.
+const pluginsKind = "plugins"
+
+// pluginKey returns the file cache key of the plugin diagnostics of
+// package mp, given the hash of the plugins. It reports false if any
+// file of mp has unsaved changes.
+func pluginKey(ctx context.Context, snapshot *cache.Snapshot, pluginsHash file.Hash, mp *metadata.Package) (file.Hash, bool, error) {
+	hasher := sha256.New()
+	fmt.Fprintf(hasher, "plugins: %s %s\n", pluginsHash, mp.ID)
+	saved, err := writeBuildKey(ctx, snapshot, mp, hasher)
+	if err != nil {
+		return file.Hash{}, false, err
+	}
+	var key file.Hash
+	hasher.Sum(key[:0])
+	return key, saved, nil
+}
+
+// hashPlugins returns a hash of the sources of the specified analyzer
+// plugins: the contents of the files of the packages they are built
+// from, outside the standard library, and the go.mod and go.sum files
+// of their modules. It is computed once per snapshot.
+func hashPlugins(ctx context.Context, snapshot *cache.Snapshot, plugins []string) (file.Hash, error) {
+	key := fmt.Sprintf("plugins %q", plugins)
+	v, err := snapshot.ProgramResult(ctx, key, func(ctx context.Context, snapshot *cache.Snapshot) (interface{}, error) {
+		inv := &gocommand.Invocation{
+			Verb:       "list",
+			Args:       append([]string{"-deps", "-json"}, plugins...),
+			WorkingDir: snapshot.View().Root().Path(),
+		}
+		stdout, err := snapshot.RunGoCommandDirect(ctx, cache.Normal, inv)
+		if err != nil {
+			return nil, fmt.Errorf("listing analyzer plugins: %v", err)
+		}
+		hasher := sha256.New()
+		fmt.Fprintf(hasher, "%s\n", key)
+		hashFile := func(filename string) error {
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(hasher, "%s %s\n", filename, file.HashOf(data))
+			return nil
+		}
+		seenMod := make(map[string]bool)
+		for dec := json.NewDecoder(stdout); dec.More(); {
+			var pkg struct {
+				Dir                           string
+				Standard                      bool
+				GoFiles, CgoFiles, EmbedFiles []string
+				Module                        *struct{ GoMod string }
+			}
+			if err := dec.Decode(&pkg); err != nil {
+				return nil, err
+			}
+			if pkg.Standard {
+				continue
+			}
+			for _, names := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.EmbedFiles} {
+				for _, name := range names {
+					if err := hashFile(filepath.Join(pkg.Dir, name)); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if pkg.Module != nil && pkg.Module.GoMod != "" && !seenMod[pkg.Module.GoMod] {
+				seenMod[pkg.Module.GoMod] = true
+				if err := hashFile(pkg.Module.GoMod); err != nil {
+					return nil, err
+				}
+				// A module may lack a go.sum file.
+				if err := hashFile(filepath.Join(filepath.Dir(pkg.Module.GoMod), "go.sum")); err != nil && !os.IsNotExist(err) {
+					return nil, err
+				}
+			}
+		}
+		var hash file.Hash
+		hasher.Sum(hash[:0])
+		return hash, nil
+	})
+	if err != nil {
+		return file.Hash{}, err
+	}
+	return v.(file.Hash), nil
+}
+
+// vetPath returns the package path by which "go vet" analyzes package
+// mp. The go command cannot load test variants and external test
+// packages (such as "a_test") by path, but vets them along with the
+// package they test.
+func vetPath(mp *metadata.Package) string {
+	if mp.ForTest != "" {
+		return string(mp.ForTest)
+	}
+	return string(mp.PkgPath)
+}
+
+// groupByURI groups diagnostics by file.
+func groupByURI(diags []*cache.Diagnostic) map[protocol.DocumentURI][]*cache.Diagnostic {
+	byURI := make(map[protocol.DocumentURI][]*cache.Diagnostic)
+	for _, diag := range diags {
+		byURI[diag.URI] = append(byURI[diag.URI], diag)
+	}
+	return byURI
+}
+
+// containsFile reports whether uri is a file of package mp.
+func containsFile(mp *metadata.Package, uri protocol.DocumentURI) bool {
+	for _, f := range mp.CompiledGoFiles {
+		if f == uri {
+			return true
+		}
+	}
+	for _, f := range mp.GoFiles {
+		if f == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// addPluginDiagnostics adds the diagnostics of a package to reports,
+// omitting duplicates, such as the diagnostics of a file reported for
+// both a package and its test variant.
+func addPluginDiagnostics(reports, diagnostics map[protocol.DocumentURI][]*cache.Diagnostic) {
+	for uri, diags := range diagnostics {
+	outer:
+		for _, diag := range diags {
+			for _, prev := range reports[uri] {
+				if prev.Source == diag.Source && prev.Range == diag.Range && prev.Message == diag.Message {
+					continue outer
+				}
+			}
+			reports[uri] = append(reports[uri], diag)
+		}
+	}
+}
+
+// filterPluginDiagnostics returns the diagnostics of reports whose
+// analyzers are not disabled by the analyses setting.
+func filterPluginDiagnostics(snapshot *cache.Snapshot, reports map[protocol.DocumentURI][]*cache.Diagnostic) map[protocol.DocumentURI][]*cache.Diagnostic {
+	analyses := snapshot.Options().Analyses
+	filtered := make(map[protocol.DocumentURI][]*cache.Diagnostic)
+	for uri, diags := range reports {
+		for _, diag := range diags {
+			if enabled, ok := analyses[string(diag.Source)]; ok && !enabled {
+				continue
+			}
+			diag := *diag // the diagnostics are shared
+			filtered[uri] = append(filtered[uri], &diag)
+		}
+	}
+	return filtered
+}
+
+// runPlugins builds each plugin, runs it on the packages of the
+// specified paths using "go vet", and returns its diagnostics.
+func runPlugins(ctx context.Context, snapshot *cache.Snapshot, plugins []string, paths []string) ([]*cache.Diagnostic, error) {
+	tmpDir, err := os.MkdirTemp("", "gopls-plugins")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	root := snapshot.View().Root().Path()
+	var diagnostics []*cache.Diagnostic
+	for i, plugin := range plugins {
+		tool := filepath.Join(tmpDir, fmt.Sprintf("plugin%d", i))
+		build := &gocommand.Invocation{
+			Verb:       "build",
+			Args:       []string{"-o", tool, plugin},
+			WorkingDir: root,
+		}
+		if _, err := snapshot.RunGoCommandDirect(ctx, cache.Normal, build); err != nil {
+			return nil, fmt.Errorf("building analyzer plugin %s: %v", plugin, err)
+		}
+
+		// The vet tool writes its output to the standard error of go
+		// vet, which fails if a package has errors, in which case any
+		// output it did produce is still reported.
+		var stdout, stderr bytes.Buffer
+		vet := &gocommand.Invocation{
+			Verb:       "vet",
+			Args:       append([]string{"-vettool=" + tool, "-json"}, paths...),
+			WorkingDir: root,
+		}
+		vetErr := snapshot.RunGoCommandPiped(ctx, cache.Normal, vet, &stdout, &stderr)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		diags, err := parseVetJSON(ctx, snapshot, stderr.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("reading output of analyzer plugin %s: %v", plugin, err)
+		}
+		if vetErr != nil && len(diags) == 0 {
+			return nil, fmt.Errorf("running analyzer plugin %s: %v", plugin, vetErr)
+		}
+		diagnostics = append(diagnostics, diags...)
+	}
+	return diagnostics, nil
+}
+
+// These types mirror the JSON output of the vet tool protocol, as
+// produced by the unitchecker package.
+type (
+	// A vetTree maps each package ID to the results of each analyzer:
+	// either a list of diagnostics, or an error.
+	vetTree map[string]map[string]json.RawMessage
+
+	vetDiagnostic struct {
+		Category       string               `json:"category,omitempty"`
+		Posn           string               `json:"posn"` // e.g. "file.go:line:column"
+		Message        string               `json:"message"`
+		SuggestedFixes []vetSuggestedFix    `json:"suggested_fixes,omitempty"`
+		Related        []vetRelatedLocation `json:"related,omitempty"`
+	}
+
+	vetSuggestedFix struct {
+		Message string        `json:"message"`
+		Edits   []vetTextEdit `json:"edits"`
+	}
+
+	vetTextEdit struct {
+		Filename string `json:"filename"`
+		Start    int    `json:"start"` // byte offset
+		End      int    `json:"end"`
+		New      string `json:"new"`
+	}
+
+	vetRelatedLocation struct {
+		Posn    string `json:"posn"`
+		Message string `json:"message"`
+	}
+)
+
+// parseVetJSON parses the output of "go vet -json", a sequence of
+// JSON trees, each preceded by a "# package" line, and returns its
+// diagnostics.
+func parseVetJSON(ctx context.Context, snapshot *cache.Snapshot, output []byte) ([]*cache.Diagnostic, error) {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(output, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("#")) {
+			buf.Write(line)
+		}
+	}
+
+	var diagnostics []*cache.Diagnostic
+	dec := json.NewDecoder(&buf)
+	for {
+		var tree vetTree
+		if err := dec.Decode(&tree); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		for id, results := range tree {
+			for analyzer, result := range results {
+				var diags []vetDiagnostic
+				if err := json.Unmarshal(result, &diags); err != nil {
+					var failure struct {
+						Err string `json:"error"`
+					}
+					if json.Unmarshal(result, &failure) == nil && failure.Err != "" {
+						event.Error(ctx, fmt.Sprintf("analyzer %s failed on %s", analyzer, id), fmt.Errorf("%s", failure.Err))
+						continue
+					}
+					return nil, err
+				}
+				for _, d := range diags {
+					diag, err := toPluginDiagnostic(ctx, snapshot, analyzer, d)
+					if err != nil {
+						event.Error(ctx, fmt.Sprintf("diagnostic of analyzer %s", analyzer), err)
+						continue
+					}
+					diagnostics = append(diagnostics, diag)
+				}
+			}
+		}
+	}
+	return diagnostics, nil
+}
+
+// toPluginDiagnostic converts a diagnostic of the named analyzer from
+// its JSON form.
+func toPluginDiagnostic(ctx context.Context, snapshot *cache.Snapshot, analyzer string, d vetDiagnostic) (*cache.Diagnostic, error) {
+	loc, err := vetLocation(ctx, snapshot, d.Posn)
+	if err != nil {
+		return nil, err
+	}
+	code := "default"
+	if d.Category != "" {
+		code = d.Category
+	}
+	diag := &cache.Diagnostic{
+		URI:      loc.URI,
+		Range:    loc.Range,
+		Severity: protocol.SeverityWarning,
+		Code:     code,
+		Source:   cache.DiagnosticSource(analyzer),
+		Message:  d.Message,
+	}
+	for _, r := range d.Related {
+		loc, err := vetLocation(ctx, snapshot, r.Posn)
+		if err != nil {
+			return nil, err
+		}
+		diag.Related = append(diag.Related, protocol.DiagnosticRelatedInformation{
+			Location: loc,
+			Message:  r.Message,
+		})
+	}
+	for _, fix := range d.SuggestedFixes {
+		edits := make(map[protocol.DocumentURI][]protocol.TextEdit)
+		for _, edit := range fix.Edits {
+			uri := protocol.URIFromPath(edit.Filename)
+			m, err := fileMapper(ctx, snapshot, uri)
+			if err != nil {
+				return nil, err
+			}
+			rng, err := m.OffsetRange(edit.Start, edit.End)
+			if err != nil {
+				return nil, err
+			}
+			edits[uri] = append(edits[uri], protocol.TextEdit{Range: rng, NewText: edit.New})
+		}
+		diag.SuggestedFixes = append(diag.SuggestedFixes, cache.SuggestedFix{
+			Title:      fix.Message,
+			Edits:      edits,
+			ActionKind: protocol.QuickFix,
+		})
+	}
+	return diag, nil
+}
+
+// vetLocation returns the location of a position of the form
+// "file:line:column" or "file:line" in the vet tool output. The
+// location spans the identifier at the position, if any.
+func vetLocation(ctx context.Context, snapshot *cache.Snapshot, posn string) (protocol.Location, error) {
+	var (
+		filename = posn
+		nums     []int // column and line, in reverse order
+	)
+	for len(nums) < 2 {
+		colon := strings.LastIndexByte(filename, ':')
+		if colon < 0 {
+			break
+		}
+		n, err := strconv.Atoi(filename[colon+1:])
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+		filename = filename[:colon]
+	}
+	line, col := 0, 1
+	switch len(nums) {
+	case 1:
+		line = nums[0]
+	case 2:
+		line, col = nums[1], nums[0]
+	}
+	if line <= 0 || col <= 0 {
+		return protocol.Location{}, fmt.Errorf("invalid position %q", posn)
+	}
+	uri := protocol.URIFromPath(filename)
+	m, err := fileMapper(ctx, snapshot, uri)
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	start, err := m.LineCol8Position(line, col)
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	offset, err := m.PositionOffset(start)
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	end := offset
+	for end < len(m.Content) {
+		r, size := utf8.DecodeRune(m.Content[end:])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+		end += size
+	}
+	return m.OffsetLocation(offset, end)
+}
+
+// fileMapper returns a mapper for the content of the file uri.
+func fileMapper(ctx context.Context, snapshot *cache.Snapshot, uri protocol.DocumentURI) (*protocol.Mapper, error) {
+	fh, err := snapshot.ReadFile(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	content, err := fh.Content()
+	if err != nil {
+		return nil, err
+	}
+	return protocol.NewMapper(uri, content), nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package golang
+
+// This file defines the "Reorder fields" code action, which
+// rearranges the fields of a struct type into the order that
+// minimizes its size, as reported by the fieldalignment analyzer.
+// Unlike the analyzer's fix, it preserves comments, tags, and fields
+// declared together.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/util/bug"
+	"golang.org/x/tools/gopls/internal/util/safetoken"
+	"golang.org/x/tools/internal/structlayout"
+)
+
+// A fieldOrder is a better order for the fields of a struct type.
+type fieldOrder struct {
+	node             *ast.StructType
+	fields           []*ast.Field // in the new order
+	size, newSize    int64
+	ptrdata, newPtrs int64
+}
+
+// CanReorderFields reports whether the fields of the struct type
+// enclosing [start, end) can be reordered to reduce its size or
+// pointer bytes, and if so returns the title of the code action.
+func CanReorderFields(pkg *cache.Package, pgf *parsego.File, start, end token.Pos) (string, bool) {
+	order := findFieldOrder(pkg, pgf, start, end)
+	if order == nil {
+		return "", false
+	}
+	return fmt.Sprintf("Reorder fields (size %d -> %d, pointer bytes %d -> %d)",
+		order.size, order.newSize, order.ptrdata, order.newPtrs), true
+}
+
+// reorderFields is a fixer that reorders the fields of the struct type
+// enclosing [start, end) to minimize its size.
+func reorderFields(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.Package, pgf *parsego.File, start, end token.Pos) (*token.FileSet, *analysis.SuggestedFix, error) {
+	order := findFieldOrder(pkg, pgf, start, end)
+	if order == nil {
+		return nil, nil, fmt.Errorf("no struct type to reorder")
+	}
+
+	// Format the struct with the fields, and their comments, in the
+	// new order. A field with a doc comment is preceded by a blank
+	// line.
+	var buf bytes.Buffer
+	buf.WriteString("package p\n\ntype _ struct {\n")
+	for i, field := range order.fields {
+		from, to := field.Pos(), field.End()
+		if field.Doc != nil {
+			from = field.Doc.Pos()
+			if i > 0 {
+				buf.WriteString("\n")
+			}
+		}
+		if field.Comment != nil {
+			to = field.Comment.End()
+		}
+		startOffset, endOffset, err := safetoken.Offsets(pgf.Tok, from, to)
+		if err != nil {
+			return nil, nil, err
+		}
+		fmt.Fprintf(&buf, "\t%s\n", pgf.Src[startOffset:endOffset])
+	}
+	buf.WriteString("}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	const prefix = "package p\n\ntype _ "
+	text := strings.TrimSuffix(strings.TrimPrefix(string(src), prefix), "\n")
+
+	// Indent the struct like its declaration.
+	line := safetoken.Line(pgf.Tok, order.node.Pos())
+	lineStart, err := safetoken.Offset(pgf.Tok, pgf.Tok.LineStart(line))
+	if err != nil {
+		return nil, nil, err
+	}
+	indent := pgf.Src[lineStart:]
+	indent = indent[:len(indent)-len(bytes.TrimLeft(indent, " \t"))]
+	text = strings.ReplaceAll(text, "\n", "\n"+string(indent))
+
+	return pkg.FileSet(), &analysis.SuggestedFix{
+		TextEdits: []analysis.TextEdit{{
+			Pos:     order.node.Pos(),
+			End:     order.node.End(),
+			NewText: []byte(text),
+		}},
+	}, nil
+}
+
+// findFieldOrder returns the optimal order of the fields of the
+// innermost struct type enclosing [start, end), or nil if its current
+// order is already optimal or cannot be changed without losing
+// comments.
+//
+// Fields declared together, such as "x, y int", are kept together,
+// and embedded fields come first among fields whose order makes no
+// difference to the layout.
+func findFieldOrder(pkg *cache.Package, pgf *parsego.File, start, end token.Pos) *fieldOrder {
+	path, _ := astutil.PathEnclosingInterval(pgf.File, start, end)
+	var node *ast.StructType
+	for _, n := range path {
+		if n, ok := n.(*ast.StructType); ok {
+			node = n
+			break
+		}
+	}
+	if node == nil || len(node.Fields.List) < 2 || !node.Fields.Opening.IsValid() {
+		return nil
+	}
+	typ, ok := pkg.GetTypesInfo().TypeOf(node).(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	// Comments that are not attached to a field would be lost.
+	attached := make(map[*ast.CommentGroup]bool)
+	for _, field := range node.Fields.List {
+		attached[field.Doc] = true
+		attached[field.Comment] = true
+	}
+	for _, cg := range pgf.File.Comments {
+		if node.Fields.Opening < cg.Pos() && cg.End() <= node.Fields.Closing && !attached[cg] {
+			return nil
+		}
+	}
+
+	// Compute the optimal order of the fields of typ, arranged so
+	// that embedded fields come first.
+	var (
+		units  []*ast.Field // fields of the syntax, embedded first
+		vars   []*types.Var // fields of the type, embedded first
+		unitOf []int        // index in units of each element of vars
+	)
+	index := 0 // index in typ of the first field of the current ast.Field
+	offsets := make(map[*ast.Field]int)
+	for _, field := range node.Fields.List {
+		offsets[field] = index
+		index += fieldCount(field)
+	}
+	if index != typ.NumFields() {
+		return nil // can't happen
+	}
+	for _, embedded := range []bool{true, false} {
+		for _, field := range node.Fields.List {
+			if (len(field.Names) == 0) != embedded {
+				continue
+			}
+			for i := 0; i < fieldCount(field); i++ {
+				vars = append(vars, typ.Field(offsets[field]+i))
+				unitOf = append(unitOf, len(units))
+			}
+			units = append(units, field)
+		}
+	}
+	sizes := pkg.Metadata().TypesSizes
+	s := structlayout.Sizes{
+		WordSize: sizes.Sizeof(unsafePointer),
+		MaxAlign: sizes.Alignof(unsafePointer),
+	}
+	optimal, indexes := structlayout.StableOptimalOrder(types.NewStruct(vars, nil), &s)
+	order := &fieldOrder{
+		node:    node,
+		size:    s.Sizeof(typ),
+		newSize: s.Sizeof(optimal),
+		ptrdata: s.PtrData(typ),
+		newPtrs: s.PtrData(optimal),
+	}
+	if order.size == order.newSize && order.ptrdata == order.newPtrs {
+		return nil // already optimal
+	}
+
+	// Fields declared together have the same layout and are adjacent
+	// in vars, so the stable sort keeps them adjacent in the optimal
+	// order.
+	seen := make(map[int]bool)
+	last := -1
+	for _, i := range indexes {
+		unit := unitOf[i]
+		if unit == last {
+			continue
+		}
+		if seen[unit] {
+			bug.Reportf("fields declared together are not adjacent in the optimal order")
+			return nil
+		}
+		seen[unit] = true
+		last = unit
+		order.fields = append(order.fields, units[unit])
+	}
+	return order
+}
+
+// fieldCount returns the number of fields declared by field.
+func fieldCount(field *ast.Field) int {
+	if len(field.Names) == 0 {
+		return 1 // embedded
+	}
+	return len(field.Names)
+}
+
+var unsafePointer = types.Unsafe.Scope().Lookup("Pointer").(*types.TypeName).Type()
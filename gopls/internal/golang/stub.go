@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"go/ast"
 	"go/format"
 	"go/token"
 	"go/types"
@@ -123,75 +124,10 @@ func stubMethodsFixer(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.
 		return nil, nil, fmt.Errorf("no missing methods found")
 	}
 
-	// Build import environment for the declaring file.
-	// (typesutil.FileQualifier works only for complete
-	// import mappings, and requires types.)
-	importEnv := make(map[ImportPath]string) // value is local name
-	for _, imp := range declPGF.File.Imports {
-		importPath := metadata.UnquoteImportPath(imp)
-		var name string
-		if imp.Name != nil {
-			name = imp.Name.Name
-			if name == "_" {
-				continue
-			} else if name == "." {
-				name = "" // see types.Qualifier
-			}
-		} else {
-			// Use the correct name from the metadata of the imported
-			// package---not a guess based on the import path.
-			mp := snapshot.Metadata(declMeta.DepsByImpPath[importPath])
-			if mp == nil {
-				continue // can't happen?
-			}
-			name = string(mp.Name)
-		}
-		importEnv[importPath] = name // latest alias wins
-	}
-
 	// Create a package name qualifier that uses the
 	// locally appropriate imported package name.
 	// It records any needed new imports.
-	// TODO(adonovan): factor with golang.FormatVarType?
-	//
-	// Prior to CL 469155 this logic preserved any renaming
-	// imports from the file that declares the interface
-	// method--ostensibly the preferred name for imports of
-	// frequently renamed packages such as protobufs.
-	// Now we use the package's declared name. If this turns out
-	// to be a mistake, then use parseHeader(si.iface.Pos()).
-	//
-	type newImport struct{ name, importPath string }
-	var newImports []newImport // for AddNamedImport
-	qual := func(pkg *types.Package) string {
-		// TODO(adonovan): don't ignore vendor prefix.
-		//
-		// Ignore the current package import.
-		if pkg.Path() == conc.Pkg().Path() {
-			return ""
-		}
-
-		importPath := ImportPath(pkg.Path())
-		name, ok := importEnv[importPath]
-		if !ok {
-			// Insert new import using package's declared name.
-			//
-			// TODO(adonovan): resolve conflict between declared
-			// name and existing file-level (declPGF.File.Imports)
-			// or package-level (si.Concrete.Pkg.Scope) decls by
-			// generating a fresh name.
-			name = pkg.Name()
-			importEnv[importPath] = name
-			new := newImport{importPath: string(importPath)}
-			// For clarity, use a renaming import whenever the
-			// local name does not match the path's last segment.
-			if name != pathpkg.Base(trimVersionSuffix(new.importPath)) {
-				new.name = name
-			}
-			newImports = append(newImports, new)
-		}
-		return name
-	}
+	qual, newImports := newFileQualifier(snapshot, declMeta, declPGF)
 
 	// Format interface name (used only in a comment).
 	iface := si.Interface.Name()
@@ -289,7 +225,7 @@ func stubMethodsFixer(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.
 	}
 
 	// Splice the new imports into the syntax tree.
-	for _, imp := range newImports {
+	for _, imp := range *newImports {
 		astutil.AddNamedImport(fset, newF, imp.name, imp.importPath)
 	}
 
@@ -306,6 +242,87 @@ func stubMethodsFixer(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.
 		nil
 }
 
+// A newImport is an import that must be added to a file.
+type newImport struct{ name, importPath string }
+
+// newFileQualifier returns a qualifier for the names of types in code
+// to be inserted into the file pgf of the package mp, which uses the
+// local names of the file's imports. It records in *newImports any
+// imports that must be added to the file for the qualified names to
+// be valid. A nil pgf denotes a file that does not yet exist.
+//
+// TODO(adonovan): factor with golang.FormatVarType?
+//
+// Prior to CL 469155 this logic preserved any renaming
+// imports from the file that declares the interface
+// method--ostensibly the preferred name for imports of
+// frequently renamed packages such as protobufs.
+// Now we use the package's declared name. If this turns out
+// to be a mistake, then use parseHeader(si.iface.Pos()).
+func newFileQualifier(snapshot *cache.Snapshot, mp *metadata.Package, pgf *parsego.File) (_ types.Qualifier, newImports *[]newImport) {
+	// Build import environment for the file.
+	// (typesutil.FileQualifier works only for complete
+	// import mappings, and requires types.)
+	importEnv := make(map[ImportPath]string) // value is local name
+	var fileImports []*ast.ImportSpec
+	if pgf != nil {
+		fileImports = pgf.File.Imports
+	}
+	for _, imp := range fileImports {
+		importPath := metadata.UnquoteImportPath(imp)
+		var name string
+		if imp.Name != nil {
+			name = imp.Name.Name
+			if name == "_" {
+				continue
+			} else if name == "." {
+				name = "" // see types.Qualifier
+			}
+		} else {
+			// Use the correct name from the metadata of the imported
+			// package---not a guess based on the import path.
+			dep := snapshot.Metadata(mp.DepsByImpPath[importPath])
+			if dep == nil {
+				continue // can't happen?
+			}
+			name = string(dep.Name)
+		}
+		importEnv[importPath] = name // latest alias wins
+	}
+
+	newImports = new([]newImport) // for AddNamedImport
+	qual := func(pkg *types.Package) string {
+		// TODO(adonovan): don't ignore vendor prefix.
+		//
+		// Ignore the current package import.
+		if pkg.Path() == string(mp.PkgPath) {
+			return ""
+		}
+
+		importPath := ImportPath(pkg.Path())
+		name, ok := importEnv[importPath]
+		if !ok {
+			// Insert new import using package's declared name.
+			//
+			// TODO(adonovan): resolve conflict between declared
+			// name and existing file-level (pgf.File.Imports)
+			// or package-level (pkg.Scope) decls by
+			// generating a fresh name.
+			name = pkg.Name()
+			importEnv[importPath] = name
+			new := newImport{importPath: string(importPath)}
+			// For clarity, use a renaming import whenever the
+			// local name does not match the path's last segment.
+			if name != pathpkg.Base(trimVersionSuffix(new.importPath)) {
+				new.name = name
+			}
+			*newImports = append(*newImports, new)
+		}
+		return name
+	}
+	return qual, newImports
+}
+
 // diffToTextEdits converts diff (offset-based) edits to analysis (token.Pos) form.
 func diffToTextEdits(tok *token.File, diffs []diff.Edit) []analysis.TextEdit {
 	edits := make([]analysis.TextEdit, 0, len(diffs))
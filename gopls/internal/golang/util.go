@@ -6,11 +6,14 @@ package golang
 
 import (
 	"context"
+	"fmt"
 	"go/ast"
 	"go/printer"
 	"go/token"
 	"go/types"
+	"io"
 	"regexp"
+	"sort"
 	"strings"
 
 	"golang.org/x/tools/gopls/internal/cache"
@@ -364,3 +367,52 @@ func embeddedIdent(x ast.Expr) *ast.Ident {
 type ImporterFunc func(path string) (*types.Package, error)
 
 func (f ImporterFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// writeBuildKey writes to w a description of the build of package mp
+// by the go command, for use in the key of a cached result of the
+// build: the go version and build configuration of the snapshot, and
+// the identities of the files of mp and of its dependencies outside
+// the standard library, whose changes may affect the build of mp (for
+// example by inlining). It reports whether the files of mp are saved,
+// that is, whether the go command sees them as the snapshot does.
+func writeBuildKey(ctx context.Context, snapshot *cache.Snapshot, mp *metadata.Package, w io.Writer) (saved bool, err error) {
+	view := snapshot.View()
+	opts := snapshot.Options()
+	env := opts.EnvSlice()
+	sort.Strings(env)
+	fmt.Fprintf(w, "go: %s\n", view.Folder().Env.GoVersionOutput)
+	fmt.Fprintf(w, "port: %s/%s\n", view.GOOS(), view.GOARCH())
+	fmt.Fprintf(w, "GOFLAGS: %s\n", view.Folder().Env.GOFLAGS)
+	fmt.Fprintf(w, "env: %q %q\n", env, view.EnvOverlay())
+	fmt.Fprintf(w, "buildFlags: %q\n", opts.BuildFlags)
+
+	saved = true
+	seen := make(map[PackageID]bool)
+	var visit func(dep *metadata.Package) error
+	visit = func(dep *metadata.Package) error {
+		if dep == nil || seen[dep.ID] || (dep != mp && dep.Module == nil) {
+			return nil
+		}
+		seen[dep.ID] = true
+		for _, uri := range dep.CompiledGoFiles {
+			fh, err := snapshot.ReadFile(ctx, uri)
+			if err != nil {
+				return err
+			}
+			if dep == mp && !fh.SameContentsOnDisk() {
+				saved = false
+			}
+			fmt.Fprintf(w, "%s %s\n", uri, fh.Identity().Hash)
+		}
+		for _, id := range dep.DepsByPkgPath {
+			if err := visit(snapshot.Metadata(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(mp); err != nil {
+		return false, err
+	}
+	return saved, nil
+}
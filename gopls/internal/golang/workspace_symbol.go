@@ -307,19 +307,34 @@ func collectSymbols(ctx context.Context, snapshots []*cache.Snapshot, matcherTyp
 		if snapshot.Options().SymbolScope == settings.AllSymbolScope {
 			workspaceOnly = false
 		}
+		allow := func(uri protocol.DocumentURI) bool {
+			norm := filepath.ToSlash(uri.Path())
+			nm := strings.TrimPrefix(norm, folder)
+			// Only scan each file once.
+			return !filterer.Disallow(nm) && !seen[uri]
+		}
+
+		// While the workspace is loading, answer from the index
+		// persisted by an earlier session, if any.
+		if indexed, ok := snapshot.IndexedSymbols(ctx, workspaceOnly); ok {
+			for _, f := range indexed {
+				if !allow(f.URI) {
+					continue
+				}
+				mp := &metadata.Package{ID: f.PkgID, PkgPath: f.PkgPath, Name: f.PkgName}
+				seen[f.URI] = true
+				work = append(work, symbolFile{f.URI, mp, f.Symbols})
+			}
+			continue
+		}
+
 		symbols, err := snapshot.Symbols(ctx, workspaceOnly)
 		if err != nil {
 			return nil, err
 		}
 
 		for uri, syms := range symbols {
-			norm := filepath.ToSlash(uri.Path())
-			nm := strings.TrimPrefix(norm, folder)
-			if filterer.Disallow(nm) {
-				continue
-			}
-			// Only scan each file once.
-			if seen[uri] {
+			if !allow(uri) {
 				continue
 			}
 			meta, err := NarrowestMetadataForFile(ctx, snapshot, uri)
@@ -0,0 +1,598 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mod
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/internal/versions"
+)
+
+// ModuleGraph returns the module requirement graph of the workspace
+// modules of the snapshot's view, and, if args.Target is set, a plan to
+// upgrade the target module.
+//
+// The graph is computed offline: the go.mod files of dependencies are
+// read from args.ProxyDir, the directories of file:// entries of
+// GOPROXY, and the download cache of the module cache, in that order.
+// As with the go command, the graph is pruned (go.dev/ref/mod#graph-pruning)
+// when the workspace is defined by a go.work file or all its modules
+// are at go 1.17 or later.
+func ModuleGraph(ctx context.Context, snapshot *cache.Snapshot, args command.ModuleGraphArgs) (command.ModuleGraphResult, error) {
+	var result command.ModuleGraphResult
+
+	ld := &loader{
+		replace: make(map[module.Version]replacement),
+		files:   make(map[module.Version]*modfile.File),
+	}
+	if args.ProxyDir != "" {
+		ld.dirs = append(ld.dirs, args.ProxyDir)
+	}
+	ld.dirs = append(ld.dirs, proxyDirs(goenv(snapshot, "GOPROXY"))...)
+	if gomodcache := snapshot.View().Folder().Env.GOMODCACHE; gomodcache != "" {
+		ld.dirs = append(ld.dirs, filepath.Join(gomodcache, "cache", "download"))
+	}
+
+	// Collect the workspace modules and their replacements. As with the
+	// go command, replacements in go.work take precedence over those in
+	// go.mod files.
+	var mains []mainModule
+	for _, uri := range snapshot.View().ModFiles() {
+		fh, err := snapshot.ReadFile(ctx, uri)
+		if err != nil {
+			return result, err
+		}
+		pm, err := snapshot.ParseMod(ctx, fh)
+		if err != nil {
+			return result, err
+		}
+		if pm.File == nil || pm.File.Module == nil {
+			return result, fmt.Errorf("%s: missing module declaration", uri.Path())
+		}
+		m := mainModule{uri: uri, path: pm.File.Module.Mod.Path, pruned: prunes(pm.File)}
+		for _, req := range pm.File.Require {
+			m.reqs = append(m.reqs, req.Mod)
+		}
+		mains = append(mains, m)
+		ld.addReplace(filepath.Dir(uri.Path()), pm.File.Replace)
+	}
+	if len(mains) == 0 {
+		return result, fmt.Errorf("no workspace modules")
+	}
+	sort.Slice(mains, func(i, j int) bool { return mains[i].path < mains[j].path })
+	pruned := true
+	for _, m := range mains {
+		pruned = pruned && m.pruned
+	}
+	if gowork := snapshot.View().GoWork(); gowork != "" {
+		pruned = true // as in the go command's workspace mode
+		fh, err := snapshot.ReadFile(ctx, gowork)
+		if err != nil {
+			return result, err
+		}
+		pw, err := snapshot.ParseWork(ctx, fh)
+		if err != nil {
+			return result, err
+		}
+		if pw.File != nil {
+			ld.addReplace(filepath.Dir(gowork.Path()), pw.File.Replace)
+		}
+	}
+
+	g, err := ld.build(ctx, mains, nil, pruned)
+	if err != nil {
+		return result, err
+	}
+	result.Modules = g.nodes()
+
+	if args.Target != "" {
+		plan, err := ld.planUpgrade(ctx, g, args.Target, snapshot.GoModForFile(args.URI))
+		if err != nil {
+			return result, err
+		}
+		result.Upgrade = plan
+	}
+	return result, nil
+}
+
+// goenv returns the value of the environment variable key, as configured
+// by the "env" setting or else the process environment.
+func goenv(snapshot *cache.Snapshot, key string) string {
+	if v, ok := snapshot.Options().Env[key]; ok {
+		return v
+	}
+	return os.Getenv(key)
+}
+
+// proxyDirs returns the directories of the file:// entries of the
+// GOPROXY list.
+func proxyDirs(goproxy string) []string {
+	var dirs []string
+	for _, entry := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		u, err := url.Parse(strings.TrimSpace(entry))
+		if err != nil || u.Scheme != "file" {
+			continue
+		}
+		path := u.Path
+		if runtime.GOOS == "windows" {
+			path = strings.TrimPrefix(path, "/") // file:///C:/dir
+		}
+		dirs = append(dirs, filepath.FromSlash(path))
+	}
+	return dirs
+}
+
+// A mainModule is a workspace module.
+type mainModule struct {
+	uri    protocol.DocumentURI // of its go.mod file
+	path   string
+	reqs   []module.Version
+	pruned bool // whether its go.mod file supports graph pruning
+}
+
+// prunes reports whether the go.mod file f supports module graph
+// pruning, that is, whether it is at go 1.17 or later.
+func prunes(f *modfile.File) bool {
+	return f.Go != nil && versions.AtLeast("go"+f.Go.Version, "go1.17")
+}
+
+// A replacement is the target of a replace directive: either a module
+// version, or a local directory.
+type replacement struct {
+	mod module.Version
+	dir string
+}
+
+// A loader reads the go.mod files of the modules of a graph.
+type loader struct {
+	dirs    []string // directories laid out as module proxies
+	replace map[module.Version]replacement
+	files   map[module.Version]*modfile.File // nil for missing go.mod files
+}
+
+// addReplace records the replace directives of the go.mod or go.work
+// file in dir, overriding any previous replacements of the same modules.
+func (ld *loader) addReplace(dir string, replaces []*modfile.Replace) {
+	for _, r := range replaces {
+		var repl replacement
+		if r.New.Version == "" {
+			repl.dir = r.New.Path
+			if !filepath.IsAbs(repl.dir) {
+				repl.dir = filepath.Join(dir, repl.dir)
+			}
+		} else {
+			repl.mod = r.New
+		}
+		ld.replace[r.Old] = repl
+	}
+}
+
+// goMod returns the parsed go.mod file of the module version m, or nil
+// if it is not available offline.
+func (ld *loader) goMod(m module.Version) *modfile.File {
+	if f, ok := ld.files[m]; ok {
+		return f
+	}
+	var f *modfile.File
+	repl, ok := ld.replace[m]
+	if !ok {
+		repl, ok = ld.replace[module.Version{Path: m.Path}]
+	}
+	switch {
+	case !ok:
+		f = ld.readProxyMod(m)
+	case repl.dir != "":
+		filename := filepath.Join(repl.dir, "go.mod")
+		if data, err := os.ReadFile(filename); err == nil {
+			f, _ = modfile.ParseLax(filename, data, nil)
+		}
+	default:
+		f = ld.readProxyMod(repl.mod)
+	}
+	ld.files[m] = f
+	return f
+}
+
+// readProxyMod reads the go.mod file of m from the proxy directories.
+func (ld *loader) readProxyMod(m module.Version) *modfile.File {
+	escPath, err := module.EscapePath(m.Path)
+	if err != nil {
+		return nil
+	}
+	escVersion, err := module.EscapeVersion(m.Version)
+	if err != nil {
+		return nil
+	}
+	for _, dir := range ld.dirs {
+		filename := filepath.Join(dir, filepath.FromSlash(escPath), "@v", escVersion+".mod")
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		if f, err := modfile.ParseLax(filename, data, nil); err == nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// latest returns the latest version of the module path available in the
+// proxy directories, preferring releases to pre-releases, or "" if
+// there is none.
+func (ld *loader) latest(path string) string {
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return ""
+	}
+	seen := make(map[string]bool)
+	var versions []string
+	add := func(v string) {
+		if semver.IsValid(v) && !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	for _, dir := range ld.dirs {
+		vdir := filepath.Join(dir, filepath.FromSlash(escPath), "@v")
+		if data, err := os.ReadFile(filepath.Join(vdir, "list")); err == nil {
+			for _, line := range strings.Split(string(data), "\n") {
+				if fields := strings.Fields(line); len(fields) > 0 {
+					add(fields[0])
+				}
+			}
+		}
+		entries, _ := os.ReadDir(vdir)
+		for _, e := range entries {
+			if name := e.Name(); strings.HasSuffix(name, ".mod") {
+				if v, err := module.UnescapeVersion(strings.TrimSuffix(name, ".mod")); err == nil {
+					add(v)
+				}
+			}
+		}
+	}
+	semver.Sort(versions)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if semver.Prerelease(versions[i]) == "" {
+			return versions[i]
+		}
+	}
+	if len(versions) > 0 {
+		return versions[len(versions)-1]
+	}
+	return ""
+}
+
+// A graph is a module requirement graph, rooted at the workspace
+// modules, whose nodes have an empty version.
+type graph struct {
+	mains    []mainModule
+	pruned   bool
+	order    []module.Version // nodes in breadth-first order
+	reqs     map[module.Version][]module.Version
+	loaded   map[module.Version]bool // nodes whose requirements are in the graph
+	missing  map[module.Version]bool // nodes whose go.mod is unavailable
+	parent   map[module.Version]module.Version
+	selected map[string]string // module path -> version selected by MVS
+}
+
+// build returns the graph of the requirements of mains, and of the
+// additional root requirements extra, if any.
+//
+// Requirements of workspace modules are satisfied by the workspace
+// modules themselves, as with go.work.
+//
+// If pruned is set, the graph is pruned as by the go command: the
+// requirements of a module at go 1.17 or later are in the graph only if
+// it is a root requirement, or if it is itself required by a module
+// whose requirements are not pruned. Otherwise, as for modules before go
+// 1.17, the transitive requirements of every module are in the graph.
+func (ld *loader) build(ctx context.Context, mains []mainModule, extra []module.Version, pruned bool) (*graph, error) {
+	g := &graph{
+		mains:    mains,
+		pruned:   pruned,
+		reqs:     make(map[module.Version][]module.Version),
+		loaded:   make(map[module.Version]bool),
+		missing:  make(map[module.Version]bool),
+		parent:   make(map[module.Version]module.Version),
+		selected: make(map[string]string),
+	}
+	isMain := make(map[string]bool)
+	for _, m := range mains {
+		isMain[m.path] = true
+	}
+
+	// Each item of the queue is a node whose requirements are to be
+	// loaded. Those of a node reached through modules that do not
+	// support pruning are loaded transitively (unpruned).
+	type item struct {
+		m        module.Version
+		unpruned bool
+	}
+	var queue []item
+	queued := make(map[item]bool)
+	enqueue := func(m module.Version, unpruned bool) {
+		if it := (item{m, unpruned}); !queued[it] {
+			queued[it] = true
+			queue = append(queue, it)
+		}
+	}
+	seen := make(map[module.Version]bool)
+	add := func(m module.Version, parent *module.Version) {
+		if !seen[m] {
+			seen[m] = true
+			if parent != nil {
+				g.parent[m] = *parent
+			}
+			g.order = append(g.order, m)
+		}
+	}
+	for _, m := range mains {
+		root := module.Version{Path: m.path}
+		add(root, nil)
+		g.reqs[root] = m.reqs
+		g.loaded[root] = true
+	}
+	for _, m := range mains {
+		root := module.Version{Path: m.path}
+		for _, req := range m.reqs {
+			if !isMain[req.Path] {
+				add(req, &root)
+				enqueue(req, !pruned)
+			}
+		}
+	}
+	for _, m := range extra {
+		add(m, nil)
+		enqueue(m, !pruned)
+	}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		it := queue[0]
+		queue = queue[1:]
+		m := it.m
+		f := ld.goMod(m)
+		if f == nil {
+			g.missing[m] = true
+			continue
+		}
+		if !g.loaded[m] {
+			g.loaded[m] = true
+			for _, req := range f.Require {
+				g.reqs[m] = append(g.reqs[m], req.Mod)
+			}
+		}
+		// The requirements of m are loaded in turn, unless they are
+		// pruned out: m supports pruning, and was reached through
+		// modules that do too.
+		unpruned := it.unpruned || !prunes(f)
+		for _, req := range g.reqs[m] {
+			if isMain[req.Path] {
+				continue
+			}
+			add(req, &m)
+			if unpruned {
+				enqueue(req, true)
+			}
+		}
+	}
+	for _, m := range g.order {
+		if isMain[m.Path] {
+			g.selected[m.Path] = ""
+		} else if v, ok := g.selected[m.Path]; !ok || semver.Compare(m.Version, v) > 0 {
+			g.selected[m.Path] = m.Version
+		}
+	}
+	return g, nil
+}
+
+// why returns the shortest chain of requirements from a workspace module
+// to m, inclusive.
+func (g *graph) why(m module.Version) []string {
+	var chain []string
+	for {
+		chain = append(chain, modString(m))
+		p, ok := g.parent[m]
+		if !ok {
+			break
+		}
+		m = p
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// nodes returns the nodes of the graph, workspace modules first, then
+// the others sorted by path and version.
+func (g *graph) nodes() []command.ModuleGraphNode {
+	order := append([]module.Version(nil), g.order...)
+	sort.SliceStable(order, func(i, j int) bool {
+		x, y := order[i], order[j]
+		if (x.Version == "") != (y.Version == "") {
+			return x.Version == ""
+		}
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		return semver.Compare(x.Version, y.Version) < 0
+	})
+	var nodes []command.ModuleGraphNode
+	for _, m := range order {
+		node := command.ModuleGraphNode{
+			Path:     m.Path,
+			Version:  m.Version,
+			Main:     m.Version == "",
+			Selected: g.selected[m.Path] == m.Version,
+			Missing:  g.missing[m],
+			Pruned:   !g.loaded[m] && !g.missing[m],
+			Why:      g.why(m),
+		}
+		for _, req := range g.reqs[m] {
+			node.Requires = append(node.Requires, modString(req))
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// planUpgrade returns a plan to upgrade the module denoted by target
+// ("path" or "path@version") in the workspace of graph g. If no version
+// is specified, the latest one available offline is used.
+//
+// The plan consists of the upgrades that minimal version selection
+// implies when target is added to the requirements of the workspace,
+// and of the requirements to add to each workspace module so that, on
+// its own, it selects the same versions of the upgraded modules as the
+// workspace. New requirements of the target are added to the module of
+// the go.mod file gomod, if no workspace module requires it yet.
+func (ld *loader) planUpgrade(ctx context.Context, g *graph, target string, gomod protocol.DocumentURI) (*command.ModuleUpgrade, error) {
+	path, version, _ := strings.Cut(target, "@")
+	if err := module.CheckPath(path); err != nil {
+		return nil, err
+	}
+	for _, m := range g.mains {
+		if m.path == path {
+			return nil, fmt.Errorf("%s is a workspace module", path)
+		}
+	}
+	if version == "" {
+		if version = ld.latest(path); version == "" {
+			return nil, fmt.Errorf("no versions of %s are available offline", path)
+		}
+	} else if !semver.IsValid(version) {
+		return nil, fmt.Errorf("invalid version %q of %s", version, path)
+	}
+	target = path + "@" + version
+	if err := module.Check(path, version); err != nil {
+		return nil, err
+	}
+
+	plan := &command.ModuleUpgrade{Target: target}
+	if old, ok := g.selected[path]; ok && semver.Compare(version, old) <= 0 {
+		return plan, nil // already selected
+	}
+
+	upgraded, err := ld.build(ctx, g.mains, []module.Version{{Path: path, Version: version}}, g.pruned)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for p, to := range upgraded.selected {
+		if from, ok := g.selected[p]; !ok || from != to {
+			paths = append(paths, p)
+			plan.Upgrades = append(plan.Upgrades, command.ModuleVersionChange{
+				Path: p,
+				From: from,
+				To:   to,
+			})
+		}
+	}
+	sort.Slice(plan.Upgrades, func(i, j int) bool { return plan.Upgrades[i].Path < plan.Upgrades[j].Path })
+	sort.Strings(paths)
+	for _, m := range upgraded.order {
+		if upgraded.missing[m] {
+			plan.Missing = append(plan.Missing, modString(m))
+		}
+	}
+	sort.Strings(plan.Missing)
+
+	// The target becomes a requirement of the module of gomod, or of the
+	// first workspace module, unless a workspace module depends on it.
+	host := g.mains[0].uri
+	for _, m := range g.mains {
+		if m.uri == gomod {
+			host = m.uri
+		}
+	}
+	added := make([][]module.Version, len(g.mains))
+	required := false
+	for _, m := range g.mains {
+		alone, err := ld.build(ctx, []mainModule{m}, nil, m.pruned)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := alone.selected[path]; ok {
+			required = true
+		}
+	}
+	for i, m := range g.mains {
+		if !required && m.uri == host {
+			added[i] = []module.Version{{Path: path, Version: version}}
+		}
+	}
+
+	// Raise the requirements of each workspace module, considered on
+	// its own, until it selects the upgraded versions of the modules it
+	// depends on. Its direct requirements are raised first, as the
+	// upgrades of the others may follow from them.
+	for i, m := range g.mains {
+		for {
+			self := m
+			self.reqs = append(append([]module.Version(nil), m.reqs...), added[i]...)
+			direct := make(map[string]bool)
+			for _, req := range self.reqs {
+				direct[req.Path] = true
+			}
+			alone, err := ld.build(ctx, []mainModule{self}, nil, m.pruned)
+			if err != nil {
+				return nil, err
+			}
+			var behind []module.Version
+			for _, p := range paths {
+				v, ok := alone.selected[p]
+				if to := upgraded.selected[p]; ok && semver.Compare(v, to) < 0 {
+					behind = append(behind, module.Version{Path: p, Version: to})
+				}
+			}
+			var raise []module.Version
+			for _, req := range behind {
+				if direct[req.Path] {
+					raise = append(raise, req)
+				}
+			}
+			if len(raise) == 0 {
+				raise = behind
+			}
+			if len(raise) == 0 {
+				break
+			}
+			added[i] = append(added[i], raise...)
+		}
+		if len(added[i]) > 0 {
+			edit := command.ModuleUpgradeEdit{URI: m.uri}
+			for _, req := range added[i] {
+				edit.Require = append(edit.Require, modString(req))
+			}
+			sort.Strings(edit.Require)
+			plan.Edits = append(plan.Edits, edit)
+		}
+	}
+	return plan, nil
+}
+
+// modString returns the "path@version" form of m, or its path if it is
+// a workspace module.
+func modString(m module.Version) string {
+	if m.Version == "" {
+		return m.Path
+	}
+	return m.Path + "@" + m.Version
+}
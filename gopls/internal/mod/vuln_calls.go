@@ -0,0 +1,239 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mod
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/semver"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/parsego"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/gopls/internal/vulncheck"
+	"golang.org/x/tools/gopls/internal/vulncheck/govulncheck"
+)
+
+// VulnerableCallDiagnostics returns diagnostics at the call sites in the
+// workspace's Go files that reach vulnerable symbols, according to the
+// call stacks of the govulncheck results of the snapshot.
+//
+// Each call in a call stack is reported, from the entry point down to
+// the call of the vulnerable symbol. Calls that no longer match the
+// source, and vulnerabilities fixed by the current requirements, are
+// assumed to be stale and are not reported.
+func VulnerableCallDiagnostics(ctx context.Context, snapshot *cache.Snapshot) (map[protocol.DocumentURI][]*cache.Diagnostic, error) {
+	reports := make(map[protocol.DocumentURI][]*cache.Diagnostic)
+	seen := make(map[string]bool) // of file:range:message
+	for modURI, vs := range snapshot.Vulnerabilities() {
+		if vs == nil || vs.Mode != vulncheck.ModeGovulncheck {
+			continue
+		}
+		required, err := requiredVersions(ctx, snapshot, modURI)
+		if err != nil {
+			return nil, err
+		}
+		reset, err := suggestGovulncheckAction(true, modURI)
+		if err != nil {
+			return nil, err
+		}
+		for _, finding := range vs.Findings {
+			vuln, typ := foundVuln(finding)
+			if typ != vulnCalled {
+				continue
+			}
+			if v := required[vuln.Module]; finding.FixedVersion != "" && semver.IsValid(v) && semver.Compare(finding.FixedVersion, v) <= 0 {
+				continue
+			}
+			showPath, err := command.NewShowVulnPathCommand("Show call path to "+frameSymbol(vuln), command.ShowVulnPathArgs{
+				URI:   modURI,
+				OSV:   finding.OSV,
+				Trace: finding.Trace,
+			})
+			if err != nil {
+				return nil, err
+			}
+			related := relatedFrames(ctx, snapshot, finding.Trace)
+
+			// Frame i holds the position of its call to frame i-1.
+			for i := 1; i < len(finding.Trace); i++ {
+				frame := finding.Trace[i]
+				if frame.Position == nil || frame.Position.Line <= 0 {
+					continue
+				}
+				uri := protocol.URIFromPath(frame.Position.Filename)
+				if snapshot.GoModForFile(uri) == "" {
+					continue // not a workspace file
+				}
+				rng, ok, err := callRange(ctx, snapshot, uri, frame.Position)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+				var msg string
+				if i == 1 {
+					msg = fmt.Sprintf("call to vulnerable function %s (%s)", frameSymbol(vuln), finding.OSV)
+				} else {
+					msg = fmt.Sprintf("call to %s reaches vulnerable function %s (%s)", frameSymbol(finding.Trace[i-1]), frameSymbol(vuln), finding.OSV)
+				}
+				key := fmt.Sprintf("%s:%v:%s", uri, rng, msg)
+				if seen[key] {
+					continue // another call stack through the same call
+				}
+				seen[key] = true
+				reports[uri] = append(reports[uri], &cache.Diagnostic{
+					URI:      uri,
+					Range:    rng,
+					Severity: protocol.SeverityWarning,
+					Source:   cache.Govulncheck,
+					Code:     finding.OSV,
+					CodeHref: href(finding.OSV),
+					Message:  msg,
+					Related:  related,
+					SuggestedFixes: []cache.SuggestedFix{
+						cache.SuggestedFixFromCommand(showPath, protocol.QuickFix),
+						reset,
+					},
+				})
+			}
+		}
+	}
+	return reports, nil
+}
+
+// requiredVersions returns the versions of the modules required by the
+// go.mod file modURI, by module path.
+func requiredVersions(ctx context.Context, snapshot *cache.Snapshot, modURI protocol.DocumentURI) (map[string]string, error) {
+	fh, err := snapshot.ReadFile(ctx, modURI)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[string]string)
+	pm, err := snapshot.ParseMod(ctx, fh)
+	if err != nil || pm.File == nil {
+		return versions, nil // reported elsewhere
+	}
+	for _, req := range pm.File.Require {
+		versions[req.Mod.Path] = req.Mod.Version
+	}
+	return versions, nil
+}
+
+// callRange returns the range of the function expression of the call
+// whose opening parenthesis is at pos in the Go file uri. It reports
+// false if there is no such call, as when the file has changed since
+// pos was computed.
+func callRange(ctx context.Context, snapshot *cache.Snapshot, uri protocol.DocumentURI, pos *govulncheck.Position) (protocol.Range, bool, error) {
+	fh, err := snapshot.ReadFile(ctx, uri)
+	if err != nil {
+		return protocol.Range{}, false, err
+	}
+	pgf, err := snapshot.ParseGo(ctx, fh, parsego.Full)
+	if err != nil {
+		return protocol.Range{}, false, err
+	}
+	lparen, ok := framePos(pgf, pos)
+	if !ok {
+		return protocol.Range{}, false, nil
+	}
+	path, _ := astutil.PathEnclosingInterval(pgf.File, lparen, lparen+1)
+	for _, n := range path {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || call.Lparen != lparen {
+			continue
+		}
+		var fun ast.Node = astutil.Unparen(call.Fun)
+		if sel, ok := fun.(*ast.SelectorExpr); ok {
+			fun = sel.Sel
+		}
+		rng, err := pgf.NodeRange(fun)
+		if err != nil {
+			return protocol.Range{}, false, err
+		}
+		return rng, true, nil
+	}
+	return protocol.Range{}, false, nil
+}
+
+// framePos returns the position in pgf of the 1-based line and column
+// of pos, if valid.
+func framePos(pgf *parsego.File, pos *govulncheck.Position) (token.Pos, bool) {
+	if pos.Line > pgf.Tok.LineCount() || pos.Column <= 0 {
+		return token.NoPos, false
+	}
+	start := pgf.Tok.LineStart(pos.Line)
+	if offset := pgf.Tok.Offset(start) + pos.Column - 1; offset >= pgf.Tok.Size() {
+		return token.NoPos, false
+	}
+	return start + token.Pos(pos.Column-1), true
+}
+
+// relatedFrames returns the locations of the calls of the call stack
+// trace, from the entry point down to the vulnerable symbol.
+func relatedFrames(ctx context.Context, snapshot *cache.Snapshot, trace []*govulncheck.Frame) []protocol.DiagnosticRelatedInformation {
+	var related []protocol.DiagnosticRelatedInformation
+	for i := len(trace) - 1; i > 0; i-- {
+		frame := trace[i]
+		if frame.Position == nil || frame.Position.Line <= 0 {
+			continue
+		}
+		uri := protocol.URIFromPath(frame.Position.Filename)
+		rng, ok, err := callRange(ctx, snapshot, uri, frame.Position)
+		if err != nil || !ok {
+			continue
+		}
+		related = append(related, protocol.DiagnosticRelatedInformation{
+			Location: protocol.Location{URI: uri, Range: rng},
+			Message:  fmt.Sprintf("%s calls %s", frameSymbol(frame), frameSymbol(trace[i-1])),
+		})
+	}
+	return related
+}
+
+// FormatVulnPath returns a description of the call stack trace of a
+// finding of vulnerability osv, from the entry point down to the
+// vulnerable symbol, with file names relative to dir.
+func FormatVulnPath(osv string, trace []*govulncheck.Frame, dir string) string {
+	var b strings.Builder
+	if len(trace) > 0 {
+		fmt.Fprintf(&b, "%s: call path to %s:", osv, frameSymbol(trace[0]))
+	}
+	for i := len(trace) - 1; i >= 0; i-- {
+		frame := trace[i]
+		fmt.Fprintf(&b, "\n\t%s", frameSymbol(frame))
+		if pos := frame.Position; pos != nil && pos.Line > 0 {
+			filename := pos.Filename
+			if rel, err := filepath.Rel(dir, filename); err == nil && !strings.HasPrefix(rel, "..") {
+				filename = rel
+			}
+			fmt.Fprintf(&b, " (%s:%d:%d)", filename, pos.Line, pos.Column)
+		}
+	}
+	return b.String()
+}
+
+// frameSymbol returns the qualified name of the function of frame, in
+// the form used by govulncheck.
+func frameSymbol(frame *govulncheck.Frame) string {
+	var b strings.Builder
+	if frame.Package != "" {
+		b.WriteString(frame.Package)
+		b.WriteString(".")
+	}
+	if frame.Receiver != "" {
+		b.WriteString(strings.TrimPrefix(frame.Receiver, "*"))
+		b.WriteString(".")
+	}
+	b.WriteString(strings.Split(frame.Function, "$")[0])
+	return b.String()
+}
@@ -8,4 +8,8 @@ package protocol
 const (
 	GoTest CodeActionKind = "goTest"
 	// TODO: Add GoGenerate, RegenerateCgo etc.
+
+	// SourceGenerateTest adds a test of the selected function to the
+	// corresponding _test.go file.
+	SourceGenerateTest CodeActionKind = "source.generateTest"
 )
@@ -25,25 +25,34 @@ const (
 	AddDependency           Command = "add_dependency"
 	AddImport               Command = "add_import"
 	AddTelemetryCounters    Command = "add_telemetry_counters"
+	ApplyEdits              Command = "apply_edits"
 	ApplyFix                Command = "apply_fix"
 	ChangeSignature         Command = "change_signature"
 	CheckUpgrades           Command = "check_upgrades"
 	DiagnoseFiles           Command = "diagnose_files"
 	EditGoDirective         Command = "edit_go_directive"
+	ExtractInterface        Command = "extract_interface"
+	ExtractToFile           Command = "extract_to_file"
 	FetchVulncheckResult    Command = "fetch_vulncheck_result"
 	GCDetails               Command = "gc_details"
 	Generate                Command = "generate"
+	GenerateTest            Command = "generate_test"
 	GoGetPackage            Command = "go_get_package"
+	Index                   Command = "index"
 	ListImports             Command = "list_imports"
 	ListKnownPackages       Command = "list_known_packages"
 	MaybePromptForTelemetry Command = "maybe_prompt_for_telemetry"
 	MemStats                Command = "mem_stats"
+	ModuleGraph             Command = "module_graph"
+	MoveToPackage           Command = "move_to_package"
+	PackageFiles            Command = "package_files"
 	RegenerateCgo           Command = "regenerate_cgo"
 	RemoveDependency        Command = "remove_dependency"
 	ResetGoModDiagnostics   Command = "reset_go_mod_diagnostics"
 	RunGoWorkCommand        Command = "run_go_work_command"
 	RunGovulncheck          Command = "run_govulncheck"
 	RunTests                Command = "run_tests"
+	ShowVulnPath            Command = "show_vuln_path"
 	StartDebugging          Command = "start_debugging"
 	StartProfile            Command = "start_profile"
 	StopProfile             Command = "stop_profile"
@@ -61,25 +70,34 @@ var Commands = []Command{
 	AddDependency,
 	AddImport,
 	AddTelemetryCounters,
+	ApplyEdits,
 	ApplyFix,
 	ChangeSignature,
 	CheckUpgrades,
 	DiagnoseFiles,
 	EditGoDirective,
+	ExtractInterface,
+	ExtractToFile,
 	FetchVulncheckResult,
 	GCDetails,
 	Generate,
+	GenerateTest,
 	GoGetPackage,
+	Index,
 	ListImports,
 	ListKnownPackages,
 	MaybePromptForTelemetry,
 	MemStats,
+	ModuleGraph,
+	MoveToPackage,
+	PackageFiles,
 	RegenerateCgo,
 	RemoveDependency,
 	ResetGoModDiagnostics,
 	RunGoWorkCommand,
 	RunGovulncheck,
 	RunTests,
+	ShowVulnPath,
 	StartDebugging,
 	StartProfile,
 	StopProfile,
@@ -113,6 +131,12 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 			return nil, err
 		}
 		return nil, s.AddTelemetryCounters(ctx, a0)
+	case "gopls.apply_edits":
+		var a0 ApplyEditsArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return nil, s.ApplyEdits(ctx, a0)
 	case "gopls.apply_fix":
 		var a0 ApplyFixArgs
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -143,6 +167,18 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 			return nil, err
 		}
 		return nil, s.EditGoDirective(ctx, a0)
+	case "gopls.extract_interface":
+		var a0 ExtractInterfaceArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.ExtractInterface(ctx, a0)
+	case "gopls.extract_to_file":
+		var a0 ExtractToFileArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.ExtractToFile(ctx, a0)
 	case "gopls.fetch_vulncheck_result":
 		var a0 URIArg
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -161,12 +197,24 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 			return nil, err
 		}
 		return nil, s.Generate(ctx, a0)
+	case "gopls.generate_test":
+		var a0 GenerateTestArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.GenerateTest(ctx, a0)
 	case "gopls.go_get_package":
 		var a0 GoGetPackageArgs
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
 			return nil, err
 		}
 		return nil, s.GoGetPackage(ctx, a0)
+	case "gopls.index":
+		var a0 IndexArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.Index(ctx, a0)
 	case "gopls.list_imports":
 		var a0 URIArg
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -183,6 +231,24 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 		return nil, s.MaybePromptForTelemetry(ctx)
 	case "gopls.mem_stats":
 		return s.MemStats(ctx)
+	case "gopls.module_graph":
+		var a0 ModuleGraphArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.ModuleGraph(ctx, a0)
+	case "gopls.move_to_package":
+		var a0 MoveToPackageArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.MoveToPackage(ctx, a0)
+	case "gopls.package_files":
+		var a0 PackageFilesArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return s.PackageFiles(ctx, a0)
 	case "gopls.regenerate_cgo":
 		var a0 URIArg
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -219,6 +285,12 @@ func Dispatch(ctx context.Context, params *protocol.ExecuteCommandParams, s Inte
 			return nil, err
 		}
 		return nil, s.RunTests(ctx, a0)
+	case "gopls.show_vuln_path":
+		var a0 ShowVulnPathArgs
+		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
+			return nil, err
+		}
+		return nil, s.ShowVulnPath(ctx, a0)
 	case "gopls.start_debugging":
 		var a0 DebuggingArgs
 		if err := UnmarshalArgs(params.Arguments, &a0); err != nil {
@@ -319,6 +391,18 @@ func NewAddTelemetryCountersCommand(title string, a0 AddTelemetryCountersArgs) (
 	}, nil
 }
 
+func NewApplyEditsCommand(title string, a0 ApplyEditsArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.apply_edits",
+		Arguments: args,
+	}, nil
+}
+
 func NewApplyFixCommand(title string, a0 ApplyFixArgs) (protocol.Command, error) {
 	args, err := MarshalArgs(a0)
 	if err != nil {
@@ -379,6 +463,30 @@ func NewEditGoDirectiveCommand(title string, a0 EditGoDirectiveArgs) (protocol.C
 	}, nil
 }
 
+func NewExtractInterfaceCommand(title string, a0 ExtractInterfaceArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.extract_interface",
+		Arguments: args,
+	}, nil
+}
+
+func NewExtractToFileCommand(title string, a0 ExtractToFileArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.extract_to_file",
+		Arguments: args,
+	}, nil
+}
+
 func NewFetchVulncheckResultCommand(title string, a0 URIArg) (protocol.Command, error) {
 	args, err := MarshalArgs(a0)
 	if err != nil {
@@ -415,6 +523,18 @@ func NewGenerateCommand(title string, a0 GenerateArgs) (protocol.Command, error)
 	}, nil
 }
 
+func NewGenerateTestCommand(title string, a0 GenerateTestArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.generate_test",
+		Arguments: args,
+	}, nil
+}
+
 func NewGoGetPackageCommand(title string, a0 GoGetPackageArgs) (protocol.Command, error) {
 	args, err := MarshalArgs(a0)
 	if err != nil {
@@ -427,6 +547,18 @@ func NewGoGetPackageCommand(title string, a0 GoGetPackageArgs) (protocol.Command
 	}, nil
 }
 
+func NewIndexCommand(title string, a0 IndexArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.index",
+		Arguments: args,
+	}, nil
+}
+
 func NewListImportsCommand(title string, a0 URIArg) (protocol.Command, error) {
 	args, err := MarshalArgs(a0)
 	if err != nil {
@@ -475,6 +607,42 @@ func NewMemStatsCommand(title string) (protocol.Command, error) {
 	}, nil
 }
 
+func NewModuleGraphCommand(title string, a0 ModuleGraphArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.module_graph",
+		Arguments: args,
+	}, nil
+}
+
+func NewMoveToPackageCommand(title string, a0 MoveToPackageArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.move_to_package",
+		Arguments: args,
+	}, nil
+}
+
+func NewPackageFilesCommand(title string, a0 PackageFilesArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.package_files",
+		Arguments: args,
+	}, nil
+}
+
 func NewRegenerateCgoCommand(title string, a0 URIArg) (protocol.Command, error) {
 	args, err := MarshalArgs(a0)
 	if err != nil {
@@ -547,6 +715,18 @@ func NewRunTestsCommand(title string, a0 RunTestsArgs) (protocol.Command, error)
 	}, nil
 }
 
+func NewShowVulnPathCommand(title string, a0 ShowVulnPathArgs) (protocol.Command, error) {
+	args, err := MarshalArgs(a0)
+	if err != nil {
+		return protocol.Command{}, err
+	}
+	return protocol.Command{
+		Title:     title,
+		Command:   "gopls.show_vuln_path",
+		Arguments: args,
+	}, nil
+}
+
 func NewStartDebuggingCommand(title string, a0 DebuggingArgs) (protocol.Command, error) {
 	args, err := MarshalArgs(a0)
 	if err != nil {
@@ -19,6 +19,7 @@ import (
 
 	"golang.org/x/tools/gopls/internal/protocol"
 	"golang.org/x/tools/gopls/internal/vulncheck"
+	"golang.org/x/tools/gopls/internal/vulncheck/govulncheck"
 )
 
 // Interface defines the interface gopls exposes for the
@@ -46,6 +47,13 @@ type Interface interface {
 	// Applies a fix to a region of source code.
 	ApplyFix(context.Context, ApplyFixArgs) (*protocol.WorkspaceEdit, error)
 
+	// ApplyEdits: Apply edits to other documents
+	//
+	// Asks the client to apply edits to documents other than that of a
+	// completion item, which the item itself cannot express, such as the
+	// insertion of an import into another cell of a notebook.
+	ApplyEdits(context.Context, ApplyEditsArgs) error
+
 	// Test: Run test(s) (legacy)
 	//
 	// Runs `go test` for a specific set of test or benchmark functions.
@@ -93,6 +101,15 @@ type Interface interface {
 	// Checks for module upgrades.
 	CheckUpgrades(context.Context, CheckUpgradesArgs) error
 
+	// ModuleGraph: Compute the module graph of the workspace
+	//
+	// Computes the module requirement graph of the workspace modules,
+	// reporting why each version was selected, and optionally plans the
+	// minimal set of upgrades required by a new version of a module.
+	// The graph is computed offline, from the go.mod files of the module
+	// cache and of local module proxy directories.
+	ModuleGraph(context.Context, ModuleGraphArgs) (ModuleGraphResult, error)
+
 	// AddDependency: Add a dependency
 	//
 	// Adds a dependency to the go.mod file for a module.
@@ -179,6 +196,12 @@ type Interface interface {
 	// Fetch the result of latest vulnerability check (`govulncheck`).
 	FetchVulncheckResult(context.Context, URIArg) (map[protocol.DocumentURI]*vulncheck.Result, error)
 
+	// ShowVulnPath: Show the call path to a vulnerable symbol
+	//
+	// Shows the call stack of a govulncheck finding, from the entry
+	// point down to the vulnerable symbol, in a message.
+	ShowVulnPath(context.Context, ShowVulnPathArgs) error
+
 	// MemStats: Fetch memory statistics
 	//
 	// Call runtime.GC multiple times and return memory statistics as reported by
@@ -195,6 +218,16 @@ type Interface interface {
 	// command.
 	WorkspaceStats(context.Context) (WorkspaceStatsResult, error)
 
+	// Index: Write an LSIF index of the workspace
+	//
+	// Writes an LSIF dump of the workspace packages of the view
+	// containing the given directory to a temporary file, and returns
+	// the file name. The dump records the definitions, references,
+	// hover text, implementations and monikers of the workspace symbols.
+	//
+	// This command is intended for use by the gopls index command.
+	Index(context.Context, IndexArgs) (IndexResult, error)
+
 	// RunGoWorkCommand: Run `go work [args...]`, and apply the resulting go.work
 	// edits to the current go.work file
 	RunGoWorkCommand(context.Context, RunGoWorkArgs) error
@@ -218,11 +251,54 @@ type Interface interface {
 	// Its signature will certainly change in the future (pun intended).
 	ChangeSignature(context.Context, ChangeSignatureArgs) (*protocol.WorkspaceEdit, error)
 
+	// ExtractToFile: Move declarations to another file
+	//
+	// Moves the selected top-level declarations to another file of the
+	// same package, creating it if necessary, and updates the imports
+	// of both files.
+	ExtractToFile(context.Context, ExtractToFileArgs) (*protocol.WorkspaceEdit, error)
+
+	// ExtractInterface: Extract an interface from a type
+	//
+	// Declares an interface with the selected exported methods of a
+	// named type, next to the type's declaration. Optionally, it also
+	// replaces the type by the interface in the parameters of the
+	// package's functions that use only those methods.
+	ExtractInterface(context.Context, ExtractInterfaceArgs) (*protocol.WorkspaceEdit, error)
+
+	// GenerateTest: Generate a test for a function
+	//
+	// Adds a table-driven test of the selected function or method to
+	// the corresponding _test.go file, creating the file if necessary.
+	// The test has a field for the receiver and for each parameter and
+	// result of the function.
+	GenerateTest(context.Context, GenerateTestArgs) (*protocol.WorkspaceEdit, error)
+
+	// MoveToPackage: Move a declaration to another package
+	//
+	// Moves the selected package-level function or type, with its
+	// methods and the unexported declarations that only it uses, to
+	// another package. Declarations used across the two packages are
+	// exported, and references to the moved declarations are qualified
+	// by the destination package. The move is refused if it would
+	// create an import cycle.
+	MoveToPackage(context.Context, MoveToPackageArgs) (*protocol.WorkspaceEdit, error)
+
 	// DiagnoseFiles: Cause server to publish diagnostics for the specified files.
 	//
 	// This command is needed by the 'gopls {check,fix}' CLI subcommands.
 	DiagnoseFiles(context.Context, DiagnoseFilesArgs) error
 
+	// PackageFiles: List the files of the packages matching patterns
+	//
+	// Lists the packages matching the given patterns, as the go command
+	// does in the given directory, with the environment and build flags
+	// of the view containing it, and returns the Go files of the
+	// packages, including their test files.
+	//
+	// This command is needed by the 'gopls check' CLI subcommand.
+	PackageFiles(context.Context, PackageFilesArgs) (PackageFilesResult, error)
+
 	// Views: List current Views on the server.
 	//
 	// This command is intended for use by gopls tests only.
@@ -250,6 +326,11 @@ type GenerateArgs struct {
 
 // TODO(rFindley): document the rest of these once the docgen is fleshed out.
 
+type ApplyEditsArgs struct {
+	// The edits to apply, by document.
+	Edits []protocol.TextDocumentEdit
+}
+
 type ApplyFixArgs struct {
 	// The name of the fix to apply.
 	//
@@ -286,6 +367,83 @@ type CheckUpgradesArgs struct {
 	Modules []string
 }
 
+// ModuleGraphArgs holds the arguments to the ModuleGraph command.
+type ModuleGraphArgs struct {
+	// A file or directory within the view.
+	URI protocol.DocumentURI
+	// The module to plan an upgrade of, as "path" or "path@version".
+	// Without a version, the latest one available offline is used.
+	// If empty, no upgrade is planned.
+	Target string
+	// A directory laid out as a module proxy, from which to read go.mod
+	// files before the module cache.
+	ProxyDir string
+}
+
+// ModuleGraphResult holds the result of the ModuleGraph command.
+type ModuleGraphResult struct {
+	// The modules of the graph: the workspace modules, then the others
+	// sorted by path and version.
+	Modules []ModuleGraphNode
+	// The upgrade plan for the target module, if any.
+	Upgrade *ModuleUpgrade
+}
+
+// A ModuleGraphNode is a module version of the module graph.
+type ModuleGraphNode struct {
+	Path string
+	// The version of the module, empty for workspace modules.
+	Version string
+	// Whether the module is a workspace module.
+	Main bool
+	// Whether the version is the one selected by minimal version
+	// selection.
+	Selected bool
+	// Whether the go.mod file of the module is not available offline,
+	// making its requirements unknown.
+	Missing bool
+	// Whether the requirements of the module are pruned out of the
+	// graph (go.dev/ref/mod#graph-pruning), so that its go.mod file is
+	// not read.
+	Pruned bool
+	// The requirements of the module, as "path@version".
+	Requires []string
+	// The shortest chain of requirements from a workspace module to
+	// this one, inclusive.
+	Why []string
+}
+
+// A ModuleUpgrade is a plan to upgrade a module in the workspace.
+type ModuleUpgrade struct {
+	// The module version to upgrade to, as "path@version".
+	Target string
+	// The selected versions that change, including that of the target.
+	Upgrades []ModuleVersionChange
+	// The requirements to add to each workspace module, so that each
+	// selects the upgraded versions of its dependencies on its own.
+	Edits []ModuleUpgradeEdit
+	// The modules whose go.mod file is not available offline, which may
+	// make the plan incomplete.
+	Missing []string
+}
+
+// A ModuleVersionChange is a change of the selected version of a module.
+type ModuleVersionChange struct {
+	Path string
+	// The previously selected version, empty if the module is new to
+	// the graph.
+	From string
+	To   string
+}
+
+// A ModuleUpgradeEdit holds the requirements to add to a go.mod file.
+type ModuleUpgradeEdit struct {
+	// The go.mod file URI.
+	URI protocol.DocumentURI
+	// The requirements to add or raise, as "path@version".
+	Require []string
+}
+
 type DependencyArgs struct {
 	// The go.mod file URI.
 	URI protocol.DocumentURI
@@ -414,6 +572,18 @@ type StopProfileResult struct {
 	File string
 }
 
+// IndexArgs holds the arguments to the Index command.
+type IndexArgs struct {
+	// A directory within the view to index.
+	URI protocol.DocumentURI
+}
+
+// IndexResult holds the result of the Index command.
+type IndexResult struct {
+	// File is the name of the file containing the LSIF dump.
+	File string
+}
+
 type ResetGoModDiagnosticsArgs struct {
 	URIArg
 
@@ -432,6 +602,16 @@ type VulncheckArgs struct {
 	// TODO: -tests
 }
 
+// ShowVulnPathArgs holds the arguments to the ShowVulnPath command.
+type ShowVulnPathArgs struct {
+	// The go.mod file of the govulncheck result.
+	URI protocol.DocumentURI
+	// The ID of the vulnerability.
+	OSV string
+	// The call stack, from the vulnerable symbol to the entry point.
+	Trace []*govulncheck.Frame
+}
+
 // RunVulncheckResult holds the result of asynchronously starting the vulncheck
 // command.
 type RunVulncheckResult struct {
@@ -514,11 +694,72 @@ type ChangeSignatureArgs struct {
 	ResolveEdits bool
 }
 
+// ExtractToFileArgs specifies an "extract to file" refactoring to perform.
+type ExtractToFileArgs struct {
+	// The range of the declarations to move.
+	Location protocol.Location
+	// The file to which the declarations are moved. If it is empty, a
+	// new file named after the first declaration is created.
+	Dest protocol.DocumentURI
+	// Whether to resolve and return the edits.
+	ResolveEdits bool
+}
+
+// ExtractInterfaceArgs specifies an "extract interface" refactoring to perform.
+type ExtractInterfaceArgs struct {
+	// The location of the name of the type.
+	Location protocol.Location
+	// The name of the interface. If it is empty, a name is derived
+	// from that of the type.
+	Name string
+	// The names of the methods of the interface. If it is empty, the
+	// interface has all the exported methods of the type.
+	Methods []string
+	// Whether to use the interface in place of the type in the
+	// parameters of the package's functions that use only its methods.
+	ReplaceParams bool
+	// Whether to resolve and return the edits.
+	ResolveEdits bool
+}
+
+// GenerateTestArgs specifies a function for which to generate a test.
+type GenerateTestArgs struct {
+	// The location of the declaration of the function.
+	Location protocol.Location
+	// Whether to resolve and return the edits.
+	ResolveEdits bool
+}
+
+// MoveToPackageArgs specifies a declaration to move to another package.
+type MoveToPackageArgs struct {
+	// The location of the name of the declaration.
+	Location protocol.Location
+	// The path of the destination package.
+	PkgPath string
+	// Whether to resolve and return the edits.
+	ResolveEdits bool
+}
+
 // DiagnoseFilesArgs specifies a set of files for which diagnostics are wanted.
 type DiagnoseFilesArgs struct {
 	Files []protocol.DocumentURI
 }
 
+type PackageFilesArgs struct {
+	// The directory in which the patterns are interpreted.
+	URI protocol.DocumentURI
+
+	// The package patterns, such as "./...".
+	Patterns []string
+}
+
+// PackageFilesResult holds the result of the PackageFiles command.
+type PackageFilesResult struct {
+	// The Go files of the matching packages, including their test
+	// files, without duplicates.
+	Files []protocol.DocumentURI
+}
+
 // A View holds summary information about a cache.View.
 type View struct {
 	Type       string               // view type (via cache.ViewType.String)
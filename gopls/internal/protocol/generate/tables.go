@@ -50,6 +50,9 @@ var goplsStar = map[prop]int{
 	{"WorkDoneProgressParams", "workDoneToken"}:               wantOpt,     // test failures
 	{"WorkspaceClientCapabilities", "didChangeConfiguration"}: wantOpt,     // A.B.C.D
 	{"WorkspaceClientCapabilities", "didChangeWatchedFiles"}:  wantOpt,     // A.B.C.D
+
+	{"WorkspaceFullDocumentDiagnosticReport", "version"}:      wantStar, // null if not open
+	{"WorkspaceUnchangedDocumentDiagnosticReport", "version"}: wantStar, // null if not open
 }
 
 // keep track of which entries in goplsStar are used
@@ -120,8 +123,6 @@ var usedDisambiguate = make(map[string]bool)
 var goplsType = map[string]string{
 	"And_RegOpt_textDocument_colorPresentation": "WorkDoneProgressOptionsAndTextDocumentRegistrationOptions",
 	"ConfigurationParams":                       "ParamConfiguration",
-	"DocumentDiagnosticParams":                  "string",
-	"DocumentDiagnosticReport":                  "string",
 	"DocumentUri":                               "DocumentURI",
 	"InitializeParams":                          "ParamInitialize",
 	"LSPAny":                                    "interface{}",
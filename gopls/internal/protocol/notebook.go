@@ -0,0 +1,161 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protocol
+
+// This file defines NotebookMapper, which maps between positions in
+// the cells of a notebook and positions in the single Go file formed
+// by concatenating the cells.
+
+import (
+	"fmt"
+	"strings"
+)
+
+// notebookCellScheme is the URI scheme used by VS Code for the text
+// documents of notebook cells, e.g.
+//
+//	vscode-notebook-cell:/home/me/notes.ipynb#W0sZmlsZQ%3D%3D
+//
+// Such URIs are accepted as DocumentURIs, but since they do not
+// denote files, they must be mapped to the file formed by their
+// notebook (see NotebookMapper) before use.
+const notebookCellScheme = "vscode-notebook-cell"
+
+// IsNotebookCell reports whether uri denotes the text document of a
+// notebook cell, as opposed to a file.
+func (uri DocumentURI) IsNotebookCell() bool {
+	return strings.HasPrefix(string(uri), notebookCellScheme+":")
+}
+
+// A NotebookCellText holds the URI and text of a cell of a notebook.
+type NotebookCellText struct {
+	URI  DocumentURI
+	Text string
+}
+
+// A NotebookMapper maps between positions in the cells of a notebook
+// and positions in a file whose content is a header followed by the
+// text of each cell, in order. Each cell starts on a new line, so a
+// position in a cell maps to the file by a change of line number
+// alone.
+//
+// The embedded Mapper maps positions within the file.
+type NotebookMapper struct {
+	*Mapper
+	cells []notebookCellLines
+}
+
+// notebookCellLines records the lines [start, end) of a cell in the file.
+type notebookCellLines struct {
+	uri        DocumentURI
+	start, end uint32
+}
+
+// NewNotebookMapper returns a mapper for the file uri formed by the
+// header and the specified cells. The header, if non-empty, must end
+// with a newline.
+func NewNotebookMapper(uri DocumentURI, header string, cells []NotebookCellText) *NotebookMapper {
+	var b strings.Builder
+	b.WriteString(header)
+	m := new(NotebookMapper)
+	line := uint32(strings.Count(header, "\n"))
+	for _, cell := range cells {
+		text := cell.Text
+		if text != "" && !strings.HasSuffix(text, "\n") {
+			text += "\n"
+		}
+		b.WriteString(text)
+		n := uint32(strings.Count(text, "\n"))
+		if n == 0 {
+			// An empty cell still occupies a line, so
+			// that positions within it are valid.
+			b.WriteString("\n")
+			n = 1
+		}
+		m.cells = append(m.cells, notebookCellLines{cell.URI, line, line + n})
+		line += n
+	}
+	m.Mapper = NewMapper(uri, []byte(b.String()))
+	return m
+}
+
+// Cells returns the URIs of the cells of the notebook, in order.
+func (m *NotebookMapper) Cells() []DocumentURI {
+	uris := make([]DocumentURI, len(m.cells))
+	for i, cell := range m.cells {
+		uris[i] = cell.uri
+	}
+	return uris
+}
+
+// Contains reports whether uri denotes a cell of the notebook.
+func (m *NotebookMapper) Contains(uri DocumentURI) bool {
+	_, ok := m.cell(uri)
+	return ok
+}
+
+func (m *NotebookMapper) cell(uri DocumentURI) (notebookCellLines, bool) {
+	for _, cell := range m.cells {
+		if cell.uri == uri {
+			return cell, true
+		}
+	}
+	return notebookCellLines{}, false
+}
+
+// CellPosition converts a position in the specified cell to a
+// position in the file. The end of a cell's final line is a valid
+// position in the cell.
+func (m *NotebookMapper) CellPosition(uri DocumentURI, pos Position) (Position, error) {
+	cell, ok := m.cell(uri)
+	if !ok {
+		return Position{}, fmt.Errorf("%s is not a cell of notebook file %s", uri, m.URI)
+	}
+	if n := cell.end - cell.start; pos.Line > n || pos.Line == n && pos.Character > 0 {
+		return Position{}, fmt.Errorf("line number %d out of range 0-%d of cell %s", pos.Line, cell.end-cell.start-1, uri)
+	}
+	return Position{Line: cell.start + pos.Line, Character: pos.Character}, nil
+}
+
+// PositionCell converts a position in the file to a position in one of
+// its cells. It reports false if the position lies in the header.
+func (m *NotebookMapper) PositionCell(pos Position) (DocumentURI, Position, bool) {
+	for _, cell := range m.cells {
+		if cell.start <= pos.Line && pos.Line < cell.end {
+			return cell.uri, Position{Line: pos.Line - cell.start, Character: pos.Character}, true
+		}
+	}
+	return "", Position{}, false
+}
+
+// CellLocation converts a range in the file to a location in one of
+// its cells. It reports false if the range starts in the header.
+// A range that extends beyond its starting cell is truncated to an
+// empty range at its start.
+func (m *NotebookMapper) CellLocation(rng Range) (Location, bool) {
+	uri, start, ok := m.PositionCell(rng.Start)
+	if !ok {
+		return Location{}, false
+	}
+	end := start
+	if euri, epos, ok := m.PositionCell(rng.End); ok && euri == uri {
+		end = epos
+	}
+	return Location{URI: uri, Range: Range{Start: start, End: end}}, true
+}
+
+// CellRange converts a range in the file to a range in the specified
+// cell. It reports false if the range does not lie within the cell.
+func (m *NotebookMapper) CellRange(uri DocumentURI, rng Range) (Range, bool) {
+	suri, start, ok := m.PositionCell(rng.Start)
+	if !ok || suri != uri {
+		return Range{}, false
+	}
+	euri, end, ok := m.PositionCell(rng.End)
+	if !ok || euri != uri {
+		return Range{}, false
+	}
+	return Range{Start: start, End: end}, true
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package protocol_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+)
+
+func TestNotebookMapper(t *testing.T) {
+	const (
+		file  = protocol.DocumentURI("file:///notes.ipynb.go")
+		cell1 = protocol.DocumentURI("vscode-notebook-cell:/notes.ipynb#a")
+		cell2 = protocol.DocumentURI("vscode-notebook-cell:/notes.ipynb#b")
+		cell3 = protocol.DocumentURI("vscode-notebook-cell:/notes.ipynb#c")
+	)
+	m := protocol.NewNotebookMapper(file, "package main\n", []protocol.NotebookCellText{
+		{URI: cell1, Text: "var x = 1\nvar y = 2"},
+		{URI: cell2, Text: ""},
+		{URI: cell3, Text: "func f() {}\n"},
+	})
+	const want = "package main\nvar x = 1\nvar y = 2\n\nfunc f() {}\n"
+	if got := string(m.Content); got != want {
+		t.Fatalf("Content = %q, want %q", got, want)
+	}
+
+	pos := func(line, char uint32) protocol.Position {
+		return protocol.Position{Line: line, Character: char}
+	}
+	for _, test := range []struct {
+		cell      protocol.DocumentURI
+		pos, want protocol.Position
+	}{
+		{cell1, pos(0, 4), pos(1, 4)},
+		{cell1, pos(1, 9), pos(2, 9)},
+		{cell2, pos(0, 0), pos(3, 0)},
+		{cell3, pos(0, 5), pos(4, 5)},
+		{cell3, pos(1, 0), pos(5, 0)}, // end of cell
+	} {
+		got, err := m.CellPosition(test.cell, test.pos)
+		if err != nil {
+			t.Errorf("CellPosition(%s, %v) failed: %v", test.cell, test.pos, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("CellPosition(%s, %v) = %v, want %v", test.cell, test.pos, got, test.want)
+		}
+		uri, back, ok := m.PositionCell(got)
+		if test.pos.Line == 1 && test.cell == cell3 {
+			continue // EOF is not within a cell
+		}
+		if !ok || uri != test.cell || back != test.pos {
+			t.Errorf("PositionCell(%v) = %s, %v, %t, want %s, %v", got, uri, back, ok, test.cell, test.pos)
+		}
+	}
+
+	if _, err := m.CellPosition(cell1, pos(3, 0)); err == nil {
+		t.Errorf("CellPosition beyond end of cell succeeded")
+	}
+	if _, _, ok := m.PositionCell(pos(0, 3)); ok {
+		t.Errorf("PositionCell in header succeeded")
+	}
+
+	rng := protocol.Range{Start: pos(1, 4), End: pos(1, 5)}
+	if got, ok := m.CellRange(cell1, rng); !ok || got != (protocol.Range{Start: pos(0, 4), End: pos(0, 5)}) {
+		t.Errorf("CellRange(cell1, %v) = %v, %t", rng, got, ok)
+	}
+	if _, ok := m.CellRange(cell3, rng); ok {
+		t.Errorf("CellRange(cell3, %v) succeeded", rng)
+	}
+
+	// A range that spans cells is truncated to its start.
+	rng = protocol.Range{Start: pos(2, 4), End: pos(4, 0)}
+	want2 := protocol.Location{URI: cell1, Range: protocol.Range{Start: pos(1, 4), End: pos(1, 4)}}
+	if got, ok := m.CellLocation(rng); !ok || got != want2 {
+		t.Errorf("CellLocation(%v) = %v, %t, want %v", rng, got, ok, want2)
+	}
+}
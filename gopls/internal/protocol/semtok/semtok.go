@@ -5,7 +5,11 @@
 // The semtok package provides an encoder for LSP's semantic tokens.
 package semtok
 
-import "sort"
+import (
+	"sort"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+)
 
 // A Token provides the extent and semantics of a token.
 type Token struct {
@@ -106,3 +110,39 @@ func Encode(
 	}
 	return x[:j]
 }
+
+// Diff returns the edits that transform prev, a previous encoding of a
+// file's tokens (as returned by Encode), into next.
+//
+// The result is at most a single edit replacing the span between the
+// longest common prefix and the longest common suffix of the two
+// arrays. Typing within a large file typically changes only a handful
+// of tokens, so this single edit is usually much smaller than next.
+// If the arrays are equal, Diff returns no edits.
+func Diff(prev, next []uint32) []protocol.SemanticTokensEdit {
+	// Common prefix.
+	n := len(prev)
+	if len(next) < n {
+		n = len(next)
+	}
+	start := 0
+	for start < n && prev[start] == next[start] {
+		start++
+	}
+	if start == len(prev) && start == len(next) {
+		return []protocol.SemanticTokensEdit{}
+	}
+
+	// Common suffix, not overlapping the prefix.
+	end1, end2 := len(prev), len(next)
+	for end1 > start && end2 > start && prev[end1-1] == next[end2-1] {
+		end1--
+		end2--
+	}
+
+	return []protocol.SemanticTokensEdit{{
+		Start:       uint32(start),
+		DeleteCount: uint32(end1 - start),
+		Data:        next[start:end2],
+	}}
+}
@@ -0,0 +1,53 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semtok
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+)
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		prev, next []uint32
+		want       []protocol.SemanticTokensEdit
+	}{
+		{"equal", []uint32{1, 2, 3}, []uint32{1, 2, 3}, []protocol.SemanticTokensEdit{}},
+		{"empty", nil, nil, []protocol.SemanticTokensEdit{}},
+		{"insert", []uint32{1, 2, 3}, []uint32{1, 2, 9, 3}, []protocol.SemanticTokensEdit{{Start: 2, DeleteCount: 0, Data: []uint32{9}}}},
+		{"delete", []uint32{1, 2, 9, 3}, []uint32{1, 2, 3}, []protocol.SemanticTokensEdit{{Start: 2, DeleteCount: 1}}},
+		{"replace", []uint32{1, 2, 3, 4}, []uint32{1, 7, 8, 4}, []protocol.SemanticTokensEdit{{Start: 1, DeleteCount: 2, Data: []uint32{7, 8}}}},
+		{"append", []uint32{1}, []uint32{1, 2}, []protocol.SemanticTokensEdit{{Start: 1, DeleteCount: 0, Data: []uint32{2}}}},
+		{"repeated", []uint32{1, 1}, []uint32{1, 1, 1}, []protocol.SemanticTokensEdit{{Start: 2, DeleteCount: 0, Data: []uint32{1}}}},
+		{"all", []uint32{1, 2}, []uint32{3}, []protocol.SemanticTokensEdit{{Start: 0, DeleteCount: 2, Data: []uint32{3}}}},
+	} {
+		got := Diff(test.prev, test.next)
+		// Normalize empty Data for comparison.
+		for i := range got {
+			if len(got[i].Data) == 0 {
+				got[i].Data = nil
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Diff(%v, %v) = %v, want %v", test.name, test.prev, test.next, got, test.want)
+		}
+		if applied := apply(test.prev, got); !reflect.DeepEqual(applied, test.next) {
+			t.Errorf("%s: applying Diff(%v, %v) = %v", test.name, test.prev, test.next, applied)
+		}
+	}
+}
+
+// apply applies edits to data, as a client would.
+func apply(data []uint32, edits []protocol.SemanticTokensEdit) []uint32 {
+	result := append([]uint32(nil), data...)
+	for _, edit := range edits {
+		tail := append([]uint32(nil), result[edit.Start+edit.DeleteCount:]...)
+		result = append(append(result[:edit.Start], edit.Data...), tail...)
+	}
+	return result
+}
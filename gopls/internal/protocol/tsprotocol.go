@@ -5132,7 +5132,7 @@ type WorkspaceFullDocumentDiagnosticReport struct {
 	URI DocumentURI `json:"uri"`
 	// The version number for which the diagnostics are reported.
 	// If the document is not marked as open `null` can be provided.
-	Version int32 `json:"version"`
+	Version *int32 `json:"version"`
 	FullDocumentDiagnosticReport
 }
 
@@ -5220,7 +5220,7 @@ type WorkspaceUnchangedDocumentDiagnosticReport struct {
 	URI DocumentURI `json:"uri"`
 	// The version number for which the diagnostics are reported.
 	// If the document is not marked as open `null` can be provided.
-	Version int32 `json:"version"`
+	Version *int32 `json:"version"`
 	UnchangedDocumentDiagnosticReport
 }
 
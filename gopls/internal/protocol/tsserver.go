@@ -40,7 +40,7 @@ type Server interface {
 	Completion(context.Context, *CompletionParams) (*CompletionList, error)                                      // textDocument/completion
 	Declaration(context.Context, *DeclarationParams) (*Or_textDocument_declaration, error)                       // textDocument/declaration
 	Definition(context.Context, *DefinitionParams) ([]Location, error)                                           // textDocument/definition
-	Diagnostic(context.Context, *string) (*string, error)                                                        // textDocument/diagnostic
+	Diagnostic(context.Context, *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error)                    // textDocument/diagnostic
 	DidChange(context.Context, *DidChangeTextDocumentParams) error                                               // textDocument/didChange
 	DidClose(context.Context, *DidCloseTextDocumentParams) error                                                 // textDocument/didClose
 	DidOpen(context.Context, *DidOpenTextDocumentParams) error                                                   // textDocument/didOpen
@@ -315,7 +315,7 @@ func serverDispatch(ctx context.Context, server Server, reply jsonrpc2.Replier,
 		return true, reply(ctx, resp, nil)
 
 	case "textDocument/diagnostic":
-		var params string
+		var params DocumentDiagnosticParams
 		if err := UnmarshalJSON(r.Params(), &params); err != nil {
 			return true, sendParseError(ctx, reply, err)
 		}
@@ -958,8 +958,8 @@ func (s *serverDispatcher) Definition(ctx context.Context, params *DefinitionPar
 	}
 	return result, nil
 }
-func (s *serverDispatcher) Diagnostic(ctx context.Context, params *string) (*string, error) {
-	var result *string
+func (s *serverDispatcher) Diagnostic(ctx context.Context, params *DocumentDiagnosticParams) (*DocumentDiagnosticReport, error) {
+	var result *DocumentDiagnosticReport
 	if err := s.sender.Call(ctx, "textDocument/diagnostic", params, &result); err != nil {
 		return nil, err
 	}
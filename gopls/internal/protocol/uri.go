@@ -60,7 +60,8 @@ type URI = string
 // where there is no pointer of type *K or *V on which to call
 // UnmarshalJSON. (See Go issue #28189 for more detail.)
 //
-// Non-empty DocumentURIs are valid "file"-scheme URIs.
+// Non-empty DocumentURIs are valid "file"-scheme URIs, or the URIs
+// of notebook cells (see [DocumentURI.IsNotebookCell]).
 // The empty DocumentURI is valid.
 func (uri *DocumentURI) UnmarshalText(data []byte) (err error) {
 	*uri, err = ParseDocumentURI(string(data))
@@ -146,6 +147,10 @@ func ParseDocumentURI(s string) (DocumentURI, error) {
 		return "", nil
 	}
 
+	if DocumentURI(s).IsNotebookCell() {
+		return DocumentURI(s), nil // see NotebookMapper
+	}
+
 	if !strings.HasPrefix(s, "file://") {
 		return "", fmt.Errorf("DocumentURI scheme is not 'file': %s", s)
 	}
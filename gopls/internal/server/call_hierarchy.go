@@ -19,7 +19,7 @@ func (s *server) PrepareCallHierarchy(ctx context.Context, params *protocol.Call
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	if snapshot.FileKind(fh) != file.Go {
@@ -34,7 +34,7 @@ func (s *server) IncomingCalls(ctx context.Context, params *protocol.CallHierarc
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.Item.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	if snapshot.FileKind(fh) != file.Go {
@@ -49,7 +49,7 @@ func (s *server) OutgoingCalls(ctx context.Context, params *protocol.CallHierarc
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.Item.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	if snapshot.FileKind(fh) != file.Go {
@@ -25,7 +25,7 @@ func (s *server) CodeAction(ctx context.Context, params *protocol.CodeActionPara
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	uri := fh.URI()
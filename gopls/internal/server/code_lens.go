@@ -24,7 +24,7 @@ func (s *server) CodeLens(ctx context.Context, params *protocol.CodeLensParams)
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 
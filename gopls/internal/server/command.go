@@ -29,6 +29,7 @@ import (
 	"golang.org/x/tools/gopls/internal/debug"
 	"golang.org/x/tools/gopls/internal/file"
 	"golang.org/x/tools/gopls/internal/golang"
+	"golang.org/x/tools/gopls/internal/mod"
 	"golang.org/x/tools/gopls/internal/progress"
 	"golang.org/x/tools/gopls/internal/protocol"
 	"golang.org/x/tools/gopls/internal/protocol/command"
@@ -205,6 +206,13 @@ func (c *commandHandler) run(ctx context.Context, cfg commandConfig, run command
 	return runcmd()
 }
 
+func (c *commandHandler) ApplyEdits(ctx context.Context, args command.ApplyEditsArgs) error {
+	return c.run(ctx, commandConfig{}, func(ctx context.Context, _ commandDeps) error {
+		_, err := c.resolveOrApplyEdits(ctx, false, protocol.TextDocumentEditsToDocumentChanges(args.Edits))
+		return err
+	})
+}
+
 func (c *commandHandler) ApplyFix(ctx context.Context, args command.ApplyFixArgs) (*protocol.WorkspaceEdit, error) {
 	var result *protocol.WorkspaceEdit
 	err := c.run(ctx, commandConfig{
@@ -222,27 +230,34 @@ func (c *commandHandler) ApplyFix(ctx context.Context, args command.ApplyFixArgs
 				TextDocumentEdit: &edit,
 			})
 		}
-		edit := protocol.WorkspaceEdit{
-			DocumentChanges: changes,
-		}
-		if args.ResolveEdits {
-			result = &edit
-			return nil
-		}
-		r, err := c.s.client.ApplyEdit(ctx, &protocol.ApplyWorkspaceEditParams{
-			Edit: edit,
-		})
-		if err != nil {
-			return err
-		}
-		if !r.Applied {
-			return errors.New(r.FailureReason)
-		}
-		return nil
+		result, err = c.resolveOrApplyEdits(ctx, args.ResolveEdits, changes)
+		return err
 	})
 	return result, err
 }
 
+// resolveOrApplyEdits returns the workspace edit formed by changes if
+// resolve is set, as when the client resolves the edits of a code
+// action; otherwise it asks the client to apply the edit.
+func (c *commandHandler) resolveOrApplyEdits(ctx context.Context, resolve bool, changes []protocol.DocumentChanges) (*protocol.WorkspaceEdit, error) {
+	edit := protocol.WorkspaceEdit{
+		DocumentChanges: changes,
+	}
+	if resolve {
+		return &edit, nil
+	}
+	r, err := c.s.client.ApplyEdit(ctx, &protocol.ApplyWorkspaceEditParams{
+		Edit: edit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !r.Applied {
+		return nil, errors.New(r.FailureReason)
+	}
+	return nil, nil
+}
+
 func (c *commandHandler) RegenerateCgo(ctx context.Context, args command.URIArg) error {
 	return c.run(ctx, commandConfig{
 		progress: "Regenerating Cgo",
@@ -304,6 +319,18 @@ func (c *commandHandler) CheckUpgrades(ctx context.Context, args command.CheckUp
 	})
 }
 
+func (c *commandHandler) ModuleGraph(ctx context.Context, args command.ModuleGraphArgs) (result command.ModuleGraphResult, _ error) {
+	err := c.run(ctx, commandConfig{
+		progress: "Computing module graph",
+		forURI:   args.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		var err error
+		result, err = mod.ModuleGraph(ctx, deps.snapshot, args)
+		return err
+	})
+	return result, err
+}
+
 func (c *commandHandler) AddDependency(ctx context.Context, args command.DependencyArgs) error {
 	return c.GoGetModule(ctx, args)
 }
@@ -936,6 +963,29 @@ func (c *commandHandler) StopProfile(ctx context.Context, args command.StopProfi
 	return result, nil
 }
 
+func (c *commandHandler) Index(ctx context.Context, args command.IndexArgs) (result command.IndexResult, _ error) {
+	err := c.run(ctx, commandConfig{
+		progress: "Indexing workspace",
+		forURI:   args.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		file, err := os.CreateTemp("", "gopls-index-*.lsif")
+		if err != nil {
+			return fmt.Errorf("creating temp index file: %v", err)
+		}
+		if err := golang.WriteLSIF(ctx, deps.snapshot, file); err != nil {
+			file.Close() // ignore error
+			os.Remove(file.Name())
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("closing index file: %v", err)
+		}
+		result.File = file.Name()
+		return nil
+	})
+	return result, err
+}
+
 func (c *commandHandler) FetchVulncheckResult(ctx context.Context, arg command.URIArg) (map[protocol.DocumentURI]*vulncheck.Result, error) {
 	ret := map[protocol.DocumentURI]*vulncheck.Result{}
 	err := c.run(ctx, commandConfig{forURI: arg.URI}, func(ctx context.Context, deps commandDeps) error {
@@ -957,6 +1007,15 @@ func (c *commandHandler) FetchVulncheckResult(ctx context.Context, arg command.U
 	return ret, err
 }
 
+func (c *commandHandler) ShowVulnPath(ctx context.Context, args command.ShowVulnPathArgs) error {
+	return c.run(ctx, commandConfig{
+		forURI: args.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		showMessage(ctx, c.s.client, protocol.Info, mod.FormatVulnPath(args.OSV, args.Trace, deps.snapshot.View().Folder().Dir.Path()))
+		return nil
+	})
+}
+
 func (c *commandHandler) RunGovulncheck(ctx context.Context, args command.VulncheckArgs) (command.RunVulncheckResult, error) {
 	if args.URI == "" {
 		return command.RunVulncheckResult{}, errors.New("VulncheckArgs is missing URI field")
@@ -1270,21 +1329,68 @@ func (c *commandHandler) ChangeSignature(ctx context.Context, args command.Chang
 		if err != nil {
 			return err
 		}
-		edit := protocol.WorkspaceEdit{
-			DocumentChanges: changes,
+		result, err = c.resolveOrApplyEdits(ctx, args.ResolveEdits, changes)
+		return err
+	})
+	return result, err
+}
+
+func (c *commandHandler) GenerateTest(ctx context.Context, args command.GenerateTestArgs) (*protocol.WorkspaceEdit, error) {
+	var result *protocol.WorkspaceEdit
+	err := c.run(ctx, commandConfig{
+		forURI: args.Location.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		changes, err := golang.GenerateTest(ctx, deps.snapshot, deps.fh, args.Location.Range)
+		if err != nil {
+			return err
 		}
-		if args.ResolveEdits {
-			result = &edit
-			return nil
+		result, err = c.resolveOrApplyEdits(ctx, args.ResolveEdits, changes)
+		return err
+	})
+	return result, err
+}
+
+func (c *commandHandler) MoveToPackage(ctx context.Context, args command.MoveToPackageArgs) (*protocol.WorkspaceEdit, error) {
+	var result *protocol.WorkspaceEdit
+	err := c.run(ctx, commandConfig{
+		forURI: args.Location.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		changes, err := golang.MoveToPackage(ctx, deps.snapshot, deps.fh, args.Location.Range, golang.PackagePath(args.PkgPath))
+		if err != nil {
+			return err
 		}
-		r, err := c.s.client.ApplyEdit(ctx, &protocol.ApplyWorkspaceEditParams{
-			Edit: edit,
-		})
-		if !r.Applied {
-			return fmt.Errorf("failed to apply edits: %v", r.FailureReason)
+		result, err = c.resolveOrApplyEdits(ctx, args.ResolveEdits, changes)
+		return err
+	})
+	return result, err
+}
+
+func (c *commandHandler) ExtractInterface(ctx context.Context, args command.ExtractInterfaceArgs) (*protocol.WorkspaceEdit, error) {
+	var result *protocol.WorkspaceEdit
+	err := c.run(ctx, commandConfig{
+		forURI: args.Location.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		changes, err := golang.ExtractInterface(ctx, deps.snapshot, deps.fh, args.Location.Range, args.Name, args.Methods, args.ReplaceParams)
+		if err != nil {
+			return err
 		}
+		result, err = c.resolveOrApplyEdits(ctx, args.ResolveEdits, changes)
+		return err
+	})
+	return result, err
+}
 
-		return nil
+func (c *commandHandler) ExtractToFile(ctx context.Context, args command.ExtractToFileArgs) (*protocol.WorkspaceEdit, error) {
+	var result *protocol.WorkspaceEdit
+	err := c.run(ctx, commandConfig{
+		forURI: args.Location.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		changes, err := golang.ExtractToFile(ctx, deps.snapshot, deps.fh, args.Location.Range, args.Dest)
+		if err != nil {
+			return err
+		}
+		result, err = c.resolveOrApplyEdits(ctx, args.ResolveEdits, changes)
+		return err
 	})
 	return result, err
 }
@@ -1330,6 +1436,43 @@ func (c *commandHandler) DiagnoseFiles(ctx context.Context, args command.Diagnos
 	})
 }
 
+func (c *commandHandler) PackageFiles(ctx context.Context, args command.PackageFilesArgs) (result command.PackageFilesResult, _ error) {
+	err := c.run(ctx, commandConfig{
+		forURI: args.URI,
+	}, func(ctx context.Context, deps commandDeps) error {
+		inv := &gocommand.Invocation{
+			Verb:       "list",
+			Args:       append([]string{"-json"}, args.Patterns...),
+			WorkingDir: args.URI.Path(),
+		}
+		stdout, err := deps.snapshot.RunGoCommandDirect(ctx, cache.Normal, inv)
+		if err != nil {
+			return err
+		}
+		seen := make(map[protocol.DocumentURI]bool)
+		for dec := json.NewDecoder(stdout); dec.More(); {
+			var pkg struct {
+				Dir                                          string
+				GoFiles, CgoFiles, TestGoFiles, XTestGoFiles []string
+			}
+			if err := dec.Decode(&pkg); err != nil {
+				return err
+			}
+			for _, names := range [][]string{pkg.GoFiles, pkg.CgoFiles, pkg.TestGoFiles, pkg.XTestGoFiles} {
+				for _, name := range names {
+					uri := protocol.URIFromPath(filepath.Join(pkg.Dir, name))
+					if !seen[uri] {
+						seen[uri] = true
+						result.Files = append(result.Files, uri)
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
 func (c *commandHandler) Views(ctx context.Context) ([]command.View, error) {
 	var summaries []command.View
 	for _, view := range c.s.session.Views() {
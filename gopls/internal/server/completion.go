@@ -6,6 +6,7 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -16,12 +17,13 @@ import (
 	"golang.org/x/tools/gopls/internal/settings"
 	"golang.org/x/tools/gopls/internal/telemetry"
 	"golang.org/x/tools/gopls/internal/template"
+	"golang.org/x/tools/gopls/internal/util/slices"
 	"golang.org/x/tools/gopls/internal/work"
 	"golang.org/x/tools/internal/event"
 	"golang.org/x/tools/internal/event/tag"
 )
 
-func (s *server) Completion(ctx context.Context, params *protocol.CompletionParams) (_ *protocol.CompletionList, rerr error) {
+func (s *server) Completion(ctx context.Context, params *protocol.CompletionParams) (list *protocol.CompletionList, rerr error) {
 	recordLatency := telemetry.StartLatencyTimer("completion")
 	defer func() {
 		recordLatency(ctx, rerr)
@@ -30,6 +32,14 @@ func (s *server) Completion(ctx context.Context, params *protocol.CompletionPara
 	ctx, done := event.Start(ctx, "lsp.Server.completion", tag.URI.Of(params.TextDocument.URI))
 	defer done()
 
+	nm, cell, err := s.toNotebookFile(&params.TextDocumentPositionParams)
+	if err != nil {
+		return nil, err
+	}
+	if nm != nil {
+		defer func() { s.cellCompletionList(nm, cell, list) }()
+	}
+
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
@@ -113,15 +123,6 @@ func toProtocolCompletionItems(candidates []completion.CompletionItem, rng proto
 			continue
 		}
 
-		doc := &protocol.Or_CompletionItem_documentation{
-			Value: protocol.MarkupContent{
-				Kind:  protocol.Markdown,
-				Value: golang.CommentToMarkdown(candidate.Documentation, options),
-			},
-		}
-		if options.PreferredContentFormat != protocol.Markdown {
-			doc.Value = candidate.Documentation
-		}
 		item := protocol.CompletionItem{
 			Label:  candidate.Label,
 			Detail: candidate.Detail,
@@ -142,11 +143,111 @@ func toProtocolCompletionItems(candidates []completion.CompletionItem, rng proto
 			FilterText: strings.TrimLeft(candidate.InsertText, "&*"),
 
 			Preselect:     i == 0,
-			Documentation: doc,
+			Documentation: completionDocumentation(candidate.Documentation, options),
 			Tags:          protocol.NonNilSlice(candidate.Tags),
 			Deprecated:    candidate.Deprecated,
 		}
+		if candidate.Resolve != nil {
+			item.Data = candidate.Resolve
+			if candidate.Resolve.Documentation {
+				item.Documentation = nil // leave it to ResolveCompletionItem
+			}
+		}
 		items = append(items, item)
 	}
 	return items
 }
+
+// completionDocumentation returns the documentation of a completion
+// item in the client's preferred format.
+func completionDocumentation(text string, options *settings.Options) *protocol.Or_CompletionItem_documentation {
+	doc := &protocol.Or_CompletionItem_documentation{
+		Value: protocol.MarkupContent{
+			Kind:  protocol.Markdown,
+			Value: golang.CommentToMarkdown(text, options),
+		},
+	}
+	if options.PreferredContentFormat != protocol.Markdown {
+		doc.Value = text
+	}
+	return doc
+}
+
+// ResolveCompletionItem computes the properties of a completion item
+// whose computation was deferred by Completion; see
+// [completion.ResolveData].
+func (s *server) ResolveCompletionItem(ctx context.Context, item *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+	ctx, done := event.Start(ctx, "lsp.Server.resolveCompletionItem")
+	defer done()
+
+	if item.Data == nil {
+		return item, nil // nothing was deferred
+	}
+	// The data round-trips through the client as arbitrary JSON.
+	raw, err := json.Marshal(item.Data)
+	if err != nil {
+		return nil, err
+	}
+	var data completion.ResolveData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	// Items completed in a notebook cell are resolved in its notebook file.
+	var nm *protocol.NotebookMapper
+	cell := data.URI
+	if cell.IsNotebookCell() {
+		if nm = s.notebookMapper(cell); nm == nil {
+			return item, nil // notebook was closed
+		}
+		data.URI = nm.URI
+	}
+	fh, snapshot, release, err := s.fileOf(ctx, data.URI)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if snapshot.FileKind(fh) != file.Go {
+		return item, nil
+	}
+
+	resolved, err := completion.Resolve(ctx, snapshot, fh, data)
+	if err != nil {
+		return nil, err
+	}
+	options := snapshot.Options()
+	if data.Detail && resolved.Detail != "" {
+		item.Detail = resolved.Detail
+	}
+	if data.Documentation {
+		item.Documentation = completionDocumentation(resolved.Documentation, options)
+		if len(resolved.Tags) > 0 {
+			item.Tags = resolved.Tags
+		}
+		item.Deprecated = item.Deprecated || resolved.Deprecated
+	}
+	edits := resolved.AdditionalTextEdits
+	if nm != nil {
+		var cmd *protocol.Command
+		edits, cmd = s.cellEdits(nm, cell, edits)
+		if cmd != nil && item.Command == nil {
+			item.Command = cmd
+		}
+	}
+	if len(edits) > 0 {
+		// A client may resolve an item more than once, so replace the
+		// import edits of any previous resolution instead of repeating them.
+		item.AdditionalTextEdits = append(withoutEdits(item.AdditionalTextEdits, edits), edits...)
+	}
+	return item, nil
+}
+
+// withoutEdits returns the elements of edits that are not in remove.
+func withoutEdits(edits, remove []protocol.TextEdit) []protocol.TextEdit {
+	var result []protocol.TextEdit
+	for _, edit := range edits {
+		if !slices.Contains(remove, edit) {
+			result = append(result, edit)
+		}
+	}
+	return result
+}
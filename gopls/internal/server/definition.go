@@ -29,12 +29,12 @@ func (s *server) Definition(ctx context.Context, params *protocol.DefinitionPara
 	// TODO(rfindley): definition requests should be multiplexed across all views.
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	switch kind := snapshot.FileKind(fh); kind {
 	case file.Tmpl:
-		return template.Definition(snapshot, fh, params.Position)
+		return template.Definition(ctx, snapshot, fh, params.Position)
 	case file.Go:
 		return golang.Definition(ctx, snapshot, fh, params.Position)
 	default:
@@ -49,7 +49,7 @@ func (s *server) TypeDefinition(ctx context.Context, params *protocol.TypeDefini
 	// TODO(rfindley): type definition requests should be multiplexed across all views.
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	switch kind := snapshot.FileKind(fh); kind {
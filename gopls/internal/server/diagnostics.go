@@ -30,6 +30,7 @@ import (
 	"golang.org/x/tools/internal/event"
 	"golang.org/x/tools/internal/event/keys"
 	"golang.org/x/tools/internal/event/tag"
+	"golang.org/x/tools/internal/xcontext"
 )
 
 // fileDiagnostics holds the current state of published diagnostics for a file.
@@ -229,7 +230,57 @@ func (s *server) diagnoseSnapshot(snapshot *cache.Snapshot, changedURIs []protoc
 		}
 		return
 	}
+
+	// Dead code is reported last, unless it is already known, as
+	// computing it requires the SSA form of the whole program, which
+	// would delay the other diagnostics.
+	deadcodeKnown := false
+	if snapshot.Options().Deadcode {
+		var deadcode diagMap
+		deadcode, deadcodeKnown = golang.CachedDeadcodeDiagnostics(snapshot)
+		addDiagnostics(diagnostics, deadcode)
+	}
+	// Likewise for the references of templates to the fields of their
+	// data, as inferring its type requires type-checking the packages
+	// that execute them.
+	dotTypesKnown := template.DotTypesKnown(snapshot)
 	s.updateDiagnostics(ctx, snapshot, diagnostics, true)
+
+	if !dotTypesKnown {
+		if err := template.ComputeDotTypes(ctx, snapshot); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			event.Error(ctx, "warning: while computing template types", err, snapshot.Labels()...)
+		} else {
+			// Template files have no other diagnostics.
+			for uri, diags := range template.Diagnostics(ctx, snapshot) {
+				diagnostics[uri] = diags
+			}
+			s.updateDiagnostics(ctx, snapshot, diagnostics, true)
+		}
+	}
+
+	if snapshot.Options().Deadcode && !deadcodeKnown {
+		deadcode, err := golang.DeadcodeDiagnostics(ctx, snapshot)
+		if err != nil {
+			if ctx.Err() == nil {
+				event.Error(ctx, "warning: while computing dead code", err, snapshot.Labels()...)
+			}
+			return
+		}
+		if len(deadcode) > 0 {
+			addDiagnostics(diagnostics, deadcode)
+			s.updateDiagnostics(ctx, snapshot, diagnostics, true)
+		}
+	}
+}
+
+// addDiagnostics adds the diagnostics of each file in src to dst.
+func addDiagnostics(dst, src diagMap) {
+	for uri, diags := range src {
+		dst[uri] = append(dst[uri], diags...)
+	}
 }
 
 func (s *server) diagnoseChangedFiles(ctx context.Context, snapshot *cache.Snapshot, uris []protocol.DocumentURI) (diagMap, error) {
@@ -365,6 +416,13 @@ func (s *server) diagnose(ctx context.Context, snapshot *cache.Snapshot) (diagMa
 	}
 	store("diagnosing vulnerabilities", vulnReports, vulnErr)
 
+	// Diagnose calls that reach vulnerable symbols.
+	vulnCallReports, vulnCallErr := mod.VulnerableCallDiagnostics(ctx, snapshot)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	store("diagnosing vulnerable calls", vulnCallReports, vulnCallErr)
+
 	workspacePkgs, err := snapshot.WorkspaceMetadata(ctx)
 	if s.shouldIgnoreError(snapshot, err) {
 		return diagnostics, ctx.Err()
@@ -393,7 +451,7 @@ func (s *server) diagnose(ctx context.Context, snapshot *cache.Snapshot) (diagMa
 	s.updateCriticalErrorStatus(ctx, snapshot, statusErr)
 
 	// Diagnose template (.tmpl) files.
-	tmplReports := template.Diagnostics(snapshot)
+	tmplReports := template.Diagnostics(ctx, snapshot)
 	// NOTE(rfindley): typeCheckSource is not accurate here.
 	// (but this will be gone soon anyway).
 	store("diagnosing templates", tmplReports, nil)
@@ -476,6 +534,16 @@ func (s *server) diagnose(ctx context.Context, snapshot *cache.Snapshot) (diagMa
 		store("collecting gc_details", gcDetailsReports, err)
 	}()
 
+	// Run analyzer plugins on the packages to analyze, if any.
+	if len(snapshot.Options().AnalyzerPlugins) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pluginReports, err := golang.PluginDiagnostics(ctx, snapshot, toAnalyze)
+			store("running analyzer plugins", pluginReports, err)
+		}()
+	}
+
 	// Package diagnostics and analysis diagnostics must both be computed and
 	// merged before they can be reported.
 	var pkgDiags, analysisDiags diagMap
@@ -769,6 +837,7 @@ func (s *server) updateDiagnostics(ctx context.Context, snapshot *cache.Snapshot
 			}
 		}
 	}
+	s.refreshDiagnosticsLocked(ctx)
 }
 
 // updateOrphanedFileDiagnostics records and publishes orphaned file
@@ -819,9 +888,27 @@ func (s *server) updateOrphanedFileDiagnostics(ctx context.Context, modID uint64
 			return err
 		}
 	}
+	s.refreshDiagnosticsLocked(ctx)
 	return nil
 }
 
+// refreshDiagnosticsLocked asks a client that pulls diagnostics to pull
+// them again, if they have changed since it was last asked, while
+// holding s.diagnosticsMu.
+func (s *server) refreshDiagnosticsLocked(ctx context.Context) {
+	if !s.diagnosticsChanged || !s.refreshDiagnostics {
+		return
+	}
+	s.diagnosticsChanged = false
+	// The client may respond to the request by pulling diagnostics,
+	// so don't wait for it while holding the lock.
+	go func() {
+		if err := s.client.DiagnosticRefresh(xcontext.Detach(ctx)); err != nil {
+			event.Error(ctx, "failed to refresh diagnostics", err)
+		}
+	}()
+}
+
 // publishFileDiagnosticsLocked publishes a fileDiagnostics value, while holding s.diagnosticsMu.
 //
 // If the publication succeeds, it updates f.publishedHash and f.mustPublish.
@@ -903,9 +990,21 @@ func (s *server) publishFileDiagnosticsLocked(ctx context.Context, views viewSet
 	}
 	sortDiagnostics(unique)
 
+	// Clients that pull diagnostics must not also receive them by
+	// publication, which would report each one twice. Instead, note
+	// the change so that the client may be asked to pull again.
+	if s.pullDiagnostics {
+		if hash != f.publishedHash {
+			f.publishedHash = hash
+			s.diagnosticsChanged = true
+		}
+		f.mustPublish = false
+		return nil
+	}
+
 	// Publish, if necessary.
 	if hash != f.publishedHash || f.mustPublish {
-		if err := s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+		if err := s.publishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
 			Diagnostics: toProtocolDiagnostics(unique),
 			URI:         uri,
 			Version:     version,
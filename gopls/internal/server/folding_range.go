@@ -20,7 +20,7 @@ func (s *server) FoldingRange(ctx context.Context, params *protocol.FoldingRange
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	if snapshot.FileKind(fh) != file.Go {
@@ -22,7 +22,7 @@ func (s *server) Formatting(ctx context.Context, params *protocol.DocumentFormat
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 
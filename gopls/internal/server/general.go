@@ -10,6 +10,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go/build"
 	"log"
@@ -129,6 +130,24 @@ func (s *server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 		}
 	}
 
+	if h := params.Capabilities.Workspace.InlayHint; h != nil && h.RefreshSupport {
+		s.refreshInlayHints = true
+	}
+
+	var diagnosticProvider *protocol.Or_ServerCapabilities_diagnosticProvider
+	if options.PullDiagnostics && params.Capabilities.TextDocument.Diagnostic != nil {
+		s.pullDiagnostics = true
+		if d := params.Capabilities.Workspace.Diagnostics; d != nil && d.RefreshSupport {
+			s.refreshDiagnostics = true
+		}
+		diagnosticProvider = &protocol.Or_ServerCapabilities_diagnosticProvider{
+			Value: protocol.DiagnosticOptions{
+				InterFileDependencies: true,
+				WorkspaceDiagnostics:  true,
+			},
+		}
+	}
+
 	versionInfo := debug.VersionInfo()
 
 	goplsVersion, err := json.Marshal(versionInfo)
@@ -141,10 +160,13 @@ func (s *server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 			CallHierarchyProvider: &protocol.Or_ServerCapabilities_callHierarchyProvider{Value: true},
 			CodeActionProvider:    codeActionProvider,
 			CodeLensProvider:      &protocol.CodeLensOptions{}, // must be non-nil to enable the code lens capability
+			ColorProvider:         &protocol.Or_ServerCapabilities_colorProvider{Value: true},
 			CompletionProvider: &protocol.CompletionOptions{
 				TriggerCharacters: []string{"."},
+				ResolveProvider:   true,
 			},
 			DefinitionProvider:         &protocol.Or_ServerCapabilities_definitionProvider{Value: true},
+			DiagnosticProvider:         diagnosticProvider,
 			TypeDefinitionProvider:     &protocol.Or_ServerCapabilities_typeDefinitionProvider{Value: true},
 			ImplementationProvider:     &protocol.Or_ServerCapabilities_implementationProvider{Value: true},
 			DocumentFormattingProvider: &protocol.Or_ServerCapabilities_documentFormattingProvider{Value: true},
@@ -153,17 +175,29 @@ func (s *server) Initialize(ctx context.Context, params *protocol.ParamInitializ
 			ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
 				Commands: protocol.NonNilSlice(options.SupportedCommands),
 			},
-			FoldingRangeProvider:      &protocol.Or_ServerCapabilities_foldingRangeProvider{Value: true},
-			HoverProvider:             &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
-			DocumentHighlightProvider: &protocol.Or_ServerCapabilities_documentHighlightProvider{Value: true},
-			DocumentLinkProvider:      &protocol.DocumentLinkOptions{},
-			InlayHintProvider:         protocol.InlayHintOptions{},
-			ReferencesProvider:        &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
-			RenameProvider:            renameOpts,
-			SelectionRangeProvider:    &protocol.Or_ServerCapabilities_selectionRangeProvider{Value: true},
+			FoldingRangeProvider:       &protocol.Or_ServerCapabilities_foldingRangeProvider{Value: true},
+			HoverProvider:              &protocol.Or_ServerCapabilities_hoverProvider{Value: true},
+			DocumentHighlightProvider:  &protocol.Or_ServerCapabilities_documentHighlightProvider{Value: true},
+			DocumentLinkProvider:       &protocol.DocumentLinkOptions{},
+			InlayHintProvider:          protocol.InlayHintOptions{},
+			InlineValueProvider:        &protocol.Or_ServerCapabilities_inlineValueProvider{Value: true},
+			LinkedEditingRangeProvider: &protocol.Or_ServerCapabilities_linkedEditingRangeProvider{Value: true},
+			MonikerProvider:            &protocol.Or_ServerCapabilities_monikerProvider{Value: true},
+			NotebookDocumentSync: &protocol.Or_ServerCapabilities_notebookDocumentSync{
+				Value: protocol.NotebookDocumentSyncOptions{
+					NotebookSelector: []protocol.Or_NotebookDocumentSyncOptions_notebookSelector_Elem{{
+						Value: protocol.NotebookDocumentFilterWithCells{
+							Cells: []protocol.NotebookCellLanguage{{Language: "go"}},
+						},
+					}},
+				},
+			},
+			ReferencesProvider:     &protocol.Or_ServerCapabilities_referencesProvider{Value: true},
+			RenameProvider:         renameOpts,
+			SelectionRangeProvider: &protocol.Or_ServerCapabilities_selectionRangeProvider{Value: true},
 			SemanticTokensProvider: protocol.SemanticTokensOptions{
 				Range: &protocol.Or_SemanticTokensOptions_range{Value: true},
-				Full:  &protocol.Or_SemanticTokensOptions_full{Value: true},
+				Full:  &protocol.Or_SemanticTokensOptions_full{Value: protocol.SemanticTokensFullDelta{Delta: true}},
 				Legend: protocol.SemanticTokensLegend{
 					TokenTypes:     protocol.NonNilSlice(options.SemanticTypes),
 					TokenModifiers: protocol.NonNilSlice(options.SemanticMods),
@@ -583,9 +617,18 @@ func (s *server) handleOptionResults(ctx context.Context, results settings.Optio
 	return nil
 }
 
+// errNotebookCell is the error of fileOf for the cells of notebook
+// documents, which are not files of any view. Only the requests that
+// translate their positions to the notebook file, such as completion
+// and hover, are supported in cells.
+var errNotebookCell = errors.New("operation not supported in notebook cells")
+
 // fileOf returns the file for a given URI and its snapshot.
 // On success, the returned function must be called to release the snapshot.
 func (s *server) fileOf(ctx context.Context, uri protocol.DocumentURI) (file.Handle, *cache.Snapshot, func(), error) {
+	if uri.IsNotebookCell() {
+		return nil, nil, nil, fmt.Errorf("%w: %s", errNotebookCell, uri)
+	}
 	snapshot, release, err := s.session.SnapshotOf(ctx, uri)
 	if err != nil {
 		return nil, nil, nil, err
@@ -598,6 +641,17 @@ func (s *server) fileOf(ctx context.Context, uri protocol.DocumentURI) (file.Han
 	return fh, snapshot, release, nil
 }
 
+// notebookCellErr returns nil if err is errNotebookCell, and err
+// otherwise. Handlers of requests that are not supported in notebook
+// cells use it to report an empty result for them, as they do for
+// unsupported kinds of files.
+func notebookCellErr(err error) error {
+	if errors.Is(err, errNotebookCell) {
+		return nil // empty result
+	}
+	return err
+}
+
 // shutdown implements the 'shutdown' LSP handler. It releases resources
 // associated with the server and waits for all ongoing work to complete.
 func (s *server) Shutdown(ctx context.Context) error {
@@ -21,7 +21,7 @@ func (s *server) DocumentHighlight(ctx context.Context, params *protocol.Documen
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 
@@ -18,7 +18,7 @@ import (
 	"golang.org/x/tools/internal/event/tag"
 )
 
-func (s *server) Hover(ctx context.Context, params *protocol.HoverParams) (_ *protocol.Hover, rerr error) {
+func (s *server) Hover(ctx context.Context, params *protocol.HoverParams) (hover *protocol.Hover, rerr error) {
 	recordLatency := telemetry.StartLatencyTimer("hover")
 	defer func() {
 		recordLatency(ctx, rerr)
@@ -27,6 +27,14 @@ func (s *server) Hover(ctx context.Context, params *protocol.HoverParams) (_ *pr
 	ctx, done := event.Start(ctx, "lsp.Server.hover", tag.URI.Of(params.TextDocument.URI))
 	defer done()
 
+	nm, cell, err := s.toNotebookFile(&params.TextDocumentPositionParams)
+	if err != nil {
+		return nil, err
+	}
+	if nm != nil {
+		defer func() { cellHover(nm, cell, hover) }()
+	}
+
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
 		return nil, err
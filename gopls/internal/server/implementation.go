@@ -26,7 +26,7 @@ func (s *server) Implementation(ctx context.Context, params *protocol.Implementa
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	if snapshot.FileKind(fh) != file.Go {
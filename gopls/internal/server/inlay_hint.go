@@ -7,6 +7,7 @@ package server
 import (
 	"context"
 
+	"golang.org/x/tools/gopls/internal/cache"
 	"golang.org/x/tools/gopls/internal/file"
 	"golang.org/x/tools/gopls/internal/golang"
 	"golang.org/x/tools/gopls/internal/mod"
@@ -21,7 +22,7 @@ func (s *server) InlayHint(ctx context.Context, params *protocol.InlayHintParams
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 
@@ -29,7 +30,49 @@ func (s *server) InlayHint(ctx context.Context, params *protocol.InlayHintParams
 	case file.Mod:
 		return mod.InlayHint(ctx, snapshot, fh, params.Range)
 	case file.Go:
+		if snapshot.Options().Hints[golang.GCDetails] {
+			s.computeGCDetails(ctx, snapshot, fh.URI())
+		}
 		return golang.InlayHint(ctx, snapshot, fh, params.Range)
 	}
 	return nil, nil // empty result
 }
+
+// GCDetailsWorkTitle is the title of the progress notification of the
+// compilation of a package for the gcDetails inlay hints, for awaiting
+// in tests.
+const GCDetailsWorkTitle = "Computing gc details"
+
+// computeGCDetails starts compiling the package of the Go file uri in
+// the background, if its optimization details, which the gcDetails
+// inlay hints show, are missing. When the details are computed, the
+// client is asked to refresh its inlay hints.
+func (s *server) computeGCDetails(ctx context.Context, snapshot *cache.Snapshot, uri protocol.DocumentURI) {
+	mp, err := golang.MissingGCDetails(ctx, snapshot, uri)
+	if err != nil {
+		event.Error(ctx, "checking gc details", err)
+		return
+	}
+	if mp == nil {
+		return
+	}
+	// The work starts before the response, so that a client awaiting
+	// the details knows to wait.
+	work := s.progress.Start(ctx, GCDetailsWorkTitle, "Compiling "+string(mp.PkgPath)+"...", nil, nil)
+	release := snapshot.Acquire()
+	go func() {
+		defer release()
+		ctx := snapshot.BackgroundContext()
+		if _, err := golang.GCOptimizationDetails(ctx, snapshot, mp); err != nil {
+			work.End(ctx, "Failed to compute gc details")
+			event.Error(ctx, "computing gc details", err)
+			return
+		}
+		work.End(ctx, "Done.")
+		if s.refreshInlayHints {
+			if err := s.client.InlayHintRefresh(ctx); err != nil {
+				event.Error(ctx, "failed to refresh inlay hints", err)
+			}
+		}
+	}()
+}
@@ -33,7 +33,7 @@ func (s *server) DocumentLink(ctx context.Context, params *protocol.DocumentLink
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 
@@ -0,0 +1,481 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+// This file defines the LSP handlers for notebook documents.
+//
+// gopls presents the Go code cells of an open notebook as a single
+// virtual Go file, the "notebook file", whose content is the text of
+// the cells in order, preceded by a package clause if the first cell
+// lacks one. The notebook file is an overlay in the directory of the
+// notebook, named after it: notes.ipynb has the notebook file
+// notes.ipynb.go. It therefore belongs to the package of that
+// directory, whose name the package clause uses: that of the other Go
+// files of the directory, or main if there are none.
+//
+// Diagnostics of the notebook file are published for the cells, and
+// requests for the completion and hover of a cell are translated to
+// requests on the notebook file, using a [protocol.NotebookMapper].
+
+import (
+	"context"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/golang/completion"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/event/tag"
+)
+
+// A notebook holds the state of an open notebook document.
+type notebook struct {
+	version  int32                           // version of the notebook file, incremented by each change
+	pkgName  string                          // package name of the notebook file's directory
+	cells    []protocol.NotebookCell         // all cells of the notebook, in order
+	text     map[protocol.DocumentURI]string // text of each open cell document
+	lang     map[protocol.DocumentURI]string // language ID of each cell document
+	versions map[protocol.DocumentURI]int32  // version of each open cell document
+	mapper   *protocol.NotebookMapper        // of the notebook file; immutable
+}
+
+func (s *server) DidOpenNotebookDocument(ctx context.Context, params *protocol.DidOpenNotebookDocumentParams) error {
+	ctx, done := event.Start(ctx, "lsp.Server.didOpenNotebookDocument", tag.URI.Of(params.NotebookDocument.URI))
+	defer done()
+
+	uri, err := notebookFileURI(params.NotebookDocument.URI)
+	if err != nil {
+		return err
+	}
+	// As with DidOpen, ensure that there is a view for the notebook file.
+	dir := filepath.Dir(uri.Path())
+	if len(s.session.Views()) == 0 {
+		s.addFolders(ctx, []protocol.WorkspaceFolder{{
+			URI:  string(protocol.URIFromPath(dir)),
+			Name: filepath.Base(dir),
+		}})
+	}
+
+	nb := &notebook{
+		version:  1,
+		pkgName:  s.dirPackageName(ctx, dir),
+		cells:    params.NotebookDocument.Cells,
+		text:     make(map[protocol.DocumentURI]string),
+		lang:     make(map[protocol.DocumentURI]string),
+		versions: make(map[protocol.DocumentURI]int32),
+	}
+	for _, doc := range params.CellTextDocuments {
+		nb.open(doc)
+	}
+	nb.update(uri)
+
+	s.notebooksMu.Lock()
+	s.notebooks[params.NotebookDocument.URI] = nb
+	s.notebooksMu.Unlock()
+
+	return s.didModifyFiles(ctx, []file.Modification{{
+		URI:        uri,
+		Action:     file.Open,
+		Version:    nb.version,
+		Text:       nb.mapper.Content,
+		LanguageID: "go",
+	}}, FromDidOpen)
+}
+
+func (s *server) DidChangeNotebookDocument(ctx context.Context, params *protocol.DidChangeNotebookDocumentParams) error {
+	ctx, done := event.Start(ctx, "lsp.Server.didChangeNotebookDocument", tag.URI.Of(params.NotebookDocument.URI))
+	defer done()
+
+	s.notebooksMu.Lock()
+	nb, ok := s.notebooks[params.NotebookDocument.URI]
+	if !ok {
+		s.notebooksMu.Unlock()
+		return fmt.Errorf("notebook %s is not open", params.NotebookDocument.URI)
+	}
+	if err := nb.apply(params.Change.Cells); err != nil {
+		s.notebooksMu.Unlock()
+		return err
+	}
+	nb.version++
+	nb.update(nb.mapper.URI)
+	mod := file.Modification{
+		URI:     nb.mapper.URI,
+		Action:  file.Change,
+		Version: nb.version,
+		Text:    nb.mapper.Content,
+	}
+	s.notebooksMu.Unlock()
+
+	return s.didModifyFiles(ctx, []file.Modification{mod}, FromDidChange)
+}
+
+func (s *server) DidSaveNotebookDocument(ctx context.Context, params *protocol.DidSaveNotebookDocumentParams) error {
+	_, done := event.Start(ctx, "lsp.Server.didSaveNotebookDocument", tag.URI.Of(params.NotebookDocument.URI))
+	defer done()
+
+	return nil // the notebook file exists only as an overlay
+}
+
+func (s *server) DidCloseNotebookDocument(ctx context.Context, params *protocol.DidCloseNotebookDocumentParams) error {
+	ctx, done := event.Start(ctx, "lsp.Server.didCloseNotebookDocument", tag.URI.Of(params.NotebookDocument.URI))
+	defer done()
+
+	s.notebooksMu.Lock()
+	nb, ok := s.notebooks[params.NotebookDocument.URI]
+	delete(s.notebooks, params.NotebookDocument.URI)
+	s.notebooksMu.Unlock()
+	if !ok {
+		return fmt.Errorf("notebook %s is not open", params.NotebookDocument.URI)
+	}
+
+	// Clear the diagnostics of the cells.
+	for _, cell := range nb.mapper.Cells() {
+		if err := s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+			URI:         cell,
+			Diagnostics: []protocol.Diagnostic{},
+		}); err != nil {
+			return err
+		}
+	}
+	return s.didModifyFiles(ctx, []file.Modification{{
+		URI:     nb.mapper.URI,
+		Action:  file.Close,
+		Version: -1,
+	}}, FromDidClose)
+}
+
+// notebookFileURI returns the URI of the notebook file of the notebook
+// with the specified URI, which must be a file URI.
+func notebookFileURI(notebook protocol.URI) (protocol.DocumentURI, error) {
+	uri, err := protocol.ParseDocumentURI(notebook)
+	if err != nil {
+		return "", err
+	}
+	if uri == "" || uri.IsNotebookCell() {
+		return "", fmt.Errorf("invalid notebook URI %q", notebook)
+	}
+	return protocol.URIFromPath(uri.Path() + ".go"), nil
+}
+
+// dirPackageName returns the name of the package of the Go files of
+// the directory dir, other than test and notebook files, or "main" if
+// there are none.
+func (s *server) dirPackageName(ctx context.Context, dir string) string {
+	snapshot, release, err := s.session.SnapshotOf(ctx, protocol.URIFromPath(dir))
+	if err != nil {
+		return "main"
+	}
+	defer release()
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, ".ipynb.go") {
+			continue
+		}
+		fh, err := snapshot.ReadFile(ctx, protocol.URIFromPath(filepath.Join(dir, name)))
+		if err != nil {
+			continue
+		}
+		content, err := fh.Content()
+		if err != nil {
+			continue
+		}
+		if f, err := parser.ParseFile(token.NewFileSet(), "", content, parser.PackageClauseOnly); err == nil {
+			return f.Name.Name
+		}
+	}
+	return "main"
+}
+
+// open records the text, language, and version of an open cell document.
+func (nb *notebook) open(doc protocol.TextDocumentItem) {
+	nb.text[doc.URI] = doc.Text
+	nb.lang[doc.URI] = string(doc.LanguageID)
+	nb.versions[doc.URI] = doc.Version
+}
+
+// apply applies changes to the cells of the notebook.
+func (nb *notebook) apply(changes *protocol.NotebookDocumentCellChanges) error {
+	if changes == nil {
+		return nil
+	}
+	if structure := changes.Structure; structure != nil {
+		start, count := int(structure.Array.Start), int(structure.Array.DeleteCount)
+		if start+count > len(nb.cells) {
+			return fmt.Errorf("invalid change of cells [%d:%d] of %d", start, start+count, len(nb.cells))
+		}
+		var cells []protocol.NotebookCell
+		cells = append(cells, nb.cells[:start]...)
+		cells = append(cells, structure.Array.Cells...)
+		cells = append(cells, nb.cells[start+count:]...)
+		nb.cells = cells
+		for _, doc := range structure.DidClose {
+			delete(nb.text, doc.URI)
+			delete(nb.lang, doc.URI)
+			delete(nb.versions, doc.URI)
+		}
+		for _, doc := range structure.DidOpen {
+			nb.open(doc)
+		}
+	}
+	for _, data := range changes.Data {
+		for i := range nb.cells {
+			if nb.cells[i].Document == data.Document {
+				nb.cells[i] = data
+			}
+		}
+	}
+	for _, content := range changes.TextContent {
+		uri := content.Document.URI
+		text, ok := nb.text[uri]
+		if !ok {
+			return fmt.Errorf("cell %s is not open", uri)
+		}
+		nb.versions[uri] = content.Document.Version
+		changes := content.Changes
+		// As in changedText, accept a change of the full content.
+		if len(changes) == 1 && changes[0].Range == nil && changes[0].RangeLength == 0 {
+			nb.text[uri] = changes[0].Text
+			continue
+		}
+		updated, err := applyContentChanges(uri, []byte(text), changes)
+		if err != nil {
+			return err
+		}
+		nb.text[uri] = string(updated)
+	}
+	return nil
+}
+
+// update recomputes the notebook file, whose URI is uri, from the Go
+// code cells of the notebook.
+func (nb *notebook) update(uri protocol.DocumentURI) {
+	var cells []protocol.NotebookCellText
+	for _, cell := range nb.cells {
+		text, ok := nb.text[cell.Document]
+		if ok && cell.Kind == protocol.Code && nb.lang[cell.Document] == "go" {
+			cells = append(cells, protocol.NotebookCellText{URI: cell.Document, Text: text})
+		}
+	}
+	header := "package " + nb.pkgName + "\n"
+	if len(cells) > 0 && startsWithPackage(cells[0].Text) {
+		header = ""
+	}
+	nb.mapper = protocol.NewNotebookMapper(uri, header, cells)
+}
+
+// startsWithPackage reports whether the first token of the Go source
+// text is the package keyword.
+func startsWithPackage(text string) bool {
+	var sc scanner.Scanner
+	src := []byte(text)
+	sc.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	_, tok, _ := sc.Scan()
+	return tok == token.PACKAGE
+}
+
+// notebookMapper returns the mapper of the open notebook that has the
+// specified cell or notebook file, or nil if there is none.
+func (s *server) notebookMapper(uri protocol.DocumentURI) *protocol.NotebookMapper {
+	s.notebooksMu.Lock()
+	defer s.notebooksMu.Unlock()
+	for _, nb := range s.notebooks {
+		if nb.mapper.URI == uri || nb.mapper.Contains(uri) {
+			return nb.mapper
+		}
+	}
+	return nil
+}
+
+// toNotebookFile translates params, if they denote a position in a
+// cell of an open notebook, to the corresponding position in its
+// notebook file, and returns the notebook's mapper and the cell.
+// Otherwise it returns a nil mapper and leaves params unchanged.
+func (s *server) toNotebookFile(params *protocol.TextDocumentPositionParams) (*protocol.NotebookMapper, protocol.DocumentURI, error) {
+	cell := params.TextDocument.URI
+	if !cell.IsNotebookCell() {
+		return nil, "", nil
+	}
+	m := s.notebookMapper(cell)
+	if m == nil {
+		return nil, "", fmt.Errorf("%s is not a cell of an open notebook", cell)
+	}
+	pos, err := m.CellPosition(cell, params.Position)
+	if err != nil {
+		return nil, "", err
+	}
+	params.TextDocument.URI = m.URI
+	params.Position = pos
+	return m, cell, nil
+}
+
+// publishDiagnostics publishes diagnostics to the client. The
+// diagnostics of a notebook file are published for its cells instead,
+// with every cell receiving a (possibly empty) list.
+func (s *server) publishDiagnostics(ctx context.Context, params *protocol.PublishDiagnosticsParams) error {
+	m := s.notebookMapper(params.URI)
+	if m == nil {
+		return s.client.PublishDiagnostics(ctx, params)
+	}
+	byCell := cellDiagnostics(m, params.Diagnostics)
+	for _, cell := range m.Cells() {
+		diags := byCell[cell]
+		if diags == nil {
+			diags = []protocol.Diagnostic{} // non-nil for JSON
+		}
+		if err := s.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+			URI:         cell,
+			Diagnostics: diags,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cellDiagnostics translates the diagnostics of the notebook file of m
+// to its cells, and groups them by cell.
+func cellDiagnostics(m *protocol.NotebookMapper, diags []protocol.Diagnostic) map[protocol.DocumentURI][]protocol.Diagnostic {
+	cells := m.Cells()
+	byCell := make(map[protocol.DocumentURI][]protocol.Diagnostic)
+	for _, diag := range diags {
+		loc, ok := m.CellLocation(diag.Range)
+		if !ok {
+			if len(cells) == 0 {
+				continue
+			}
+			// The diagnostic is in the header: report it at
+			// the start of the first cell.
+			loc = protocol.Location{URI: cells[0]}
+		}
+		diag.Range = loc.Range
+		var related []protocol.DiagnosticRelatedInformation
+		for _, rel := range diag.RelatedInformation {
+			if rel.Location.URI == m.URI {
+				cellLoc, ok := m.CellLocation(rel.Location.Range)
+				if !ok {
+					continue
+				}
+				rel.Location = cellLoc
+			}
+			related = append(related, rel)
+		}
+		diag.RelatedInformation = related
+		diag.Data = nil // fixes are expressed in terms of the notebook file
+		byCell[loc.URI] = append(byCell[loc.URI], diag)
+	}
+	return byCell
+}
+
+// cellHover translates the range of a hover in the notebook file of
+// m to the specified cell.
+func cellHover(m *protocol.NotebookMapper, cell protocol.DocumentURI, hover *protocol.Hover) {
+	if hover == nil {
+		return
+	}
+	rng, ok := m.CellRange(cell, hover.Range)
+	if !ok {
+		rng = protocol.Range{}
+	}
+	hover.Range = rng
+}
+
+// cellCompletionList translates the edits of the items of a completion
+// list in the notebook file of m to the specified cell. The additional
+// edits of an item in other cells, such as the insertion of an import
+// into the first cell, become the edits of its command.
+func (s *server) cellCompletionList(m *protocol.NotebookMapper, cell protocol.DocumentURI, list *protocol.CompletionList) {
+	if list == nil {
+		return
+	}
+	items := list.Items[:0]
+	for _, item := range list.Items {
+		if item.TextEdit != nil {
+			rng, ok := m.CellRange(cell, item.TextEdit.Range)
+			if !ok {
+				continue
+			}
+			edit := *item.TextEdit
+			edit.Range = rng
+			item.TextEdit = &edit
+		}
+		item.AdditionalTextEdits, item.Command = s.cellEdits(m, cell, item.AdditionalTextEdits)
+		if data, ok := item.Data.(*completion.ResolveData); ok {
+			// Resolve the item in terms of the cell.
+			data2 := *data
+			data2.URI = cell
+			item.Data = &data2
+		}
+		items = append(items, item)
+	}
+	list.Items = items
+}
+
+// cellEdits translates edits in the notebook file of m to its cells. It
+// returns those within the specified cell, and a command to apply those
+// within other cells, if any. Edits in the header of the notebook file,
+// or that span several cells, cannot be expressed and are dropped.
+func (s *server) cellEdits(m *protocol.NotebookMapper, cell protocol.DocumentURI, edits []protocol.TextEdit) ([]protocol.TextEdit, *protocol.Command) {
+	var (
+		result []protocol.TextEdit
+		others = make(map[protocol.DocumentURI][]protocol.TextEdit)
+	)
+	for _, edit := range edits {
+		uri, _, ok := m.PositionCell(edit.Range.Start)
+		if !ok {
+			continue // in the header
+		}
+		rng, ok := m.CellRange(uri, edit.Range)
+		if !ok {
+			continue // spans several cells
+		}
+		edit := protocol.TextEdit{Range: rng, NewText: edit.NewText}
+		if uri == cell {
+			result = append(result, edit)
+		} else {
+			others[uri] = append(others[uri], edit)
+		}
+	}
+	if len(others) == 0 {
+		return result, nil
+	}
+	var docEdits []protocol.TextDocumentEdit
+	for _, uri := range m.Cells() {
+		if edits := others[uri]; len(edits) > 0 {
+			docEdits = append(docEdits, protocol.TextDocumentEdit{
+				TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+					Version:                s.cellVersion(uri),
+					TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: uri},
+				},
+				Edits: protocol.AsAnnotatedTextEdits(edits),
+			})
+		}
+	}
+	cmd, err := command.NewApplyEditsCommand("Apply edits to other cells", command.ApplyEditsArgs{Edits: docEdits})
+	if err != nil {
+		return result, nil // can't happen
+	}
+	return result, &cmd
+}
+
+// cellVersion returns the version of the specified cell document of an
+// open notebook.
+func (s *server) cellVersion(cell protocol.DocumentURI) int32 {
+	s.notebooksMu.Lock()
+	defer s.notebooksMu.Unlock()
+	for _, nb := range s.notebooks {
+		if v, ok := nb.versions[cell]; ok {
+			return v
+		}
+	}
+	return 0
+}
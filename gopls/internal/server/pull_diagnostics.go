@@ -0,0 +1,305 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package server
+
+// This file defines the LSP 3.17 "pull" diagnostics handlers,
+// textDocument/diagnostic and workspace/diagnostic.
+//
+// Unlike published diagnostics (see diagnostics.go), pull diagnostics
+// are computed on demand, synchronously, from the current snapshot.
+// Each report carries a result ID derived from the content of its
+// diagnostics, so that when the client presents the ID of its previous
+// result for a file whose diagnostics have not changed, the server can
+// reply "unchanged" instead of re-sending them. Result IDs are thus
+// stateless: no per-client bookkeeping is required.
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/cache/metadata"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/golang"
+	"golang.org/x/tools/gopls/internal/mod"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/template"
+	"golang.org/x/tools/gopls/internal/util/maps"
+	"golang.org/x/tools/gopls/internal/work"
+	"golang.org/x/tools/internal/event"
+	"golang.org/x/tools/internal/event/tag"
+)
+
+func (s *server) Diagnostic(ctx context.Context, params *protocol.DocumentDiagnosticParams) (*protocol.DocumentDiagnosticReport, error) {
+	ctx, done := event.Start(ctx, "lsp.Server.diagnostic", tag.URI.Of(params.TextDocument.URI))
+	defer done()
+
+	if cell := params.TextDocument.URI; cell.IsNotebookCell() {
+		return s.cellDiagnostic(ctx, cell)
+	}
+
+	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	diags, err := s.diagnoseFile(ctx, snapshot, fh)
+	if err != nil {
+		return nil, err
+	}
+	resultID := diagnosticsResultID(diags)
+	if params.PreviousResultID == resultID {
+		return &protocol.DocumentDiagnosticReport{
+			Value: protocol.RelatedUnchangedDocumentDiagnosticReport{
+				UnchangedDocumentDiagnosticReport: protocol.UnchangedDocumentDiagnosticReport{
+					Kind:     string(protocol.DiagnosticUnchanged),
+					ResultID: resultID,
+				},
+			},
+		}, nil
+	}
+	return &protocol.DocumentDiagnosticReport{
+		Value: protocol.RelatedFullDocumentDiagnosticReport{
+			FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
+				Kind:     string(protocol.DiagnosticFull),
+				ResultID: resultID,
+				Items:    toProtocolDiagnostics(diags),
+			},
+		},
+	}, nil
+}
+
+// cellDiagnostic returns the diagnostics of a cell of a notebook: those
+// of its notebook file that lie in the cell. They are always reported
+// in full, without a result ID, and are empty if the notebook is not
+// open.
+func (s *server) cellDiagnostic(ctx context.Context, cell protocol.DocumentURI) (*protocol.DocumentDiagnosticReport, error) {
+	items := []protocol.Diagnostic{} // non-nil for JSON
+	if m := s.notebookMapper(cell); m != nil {
+		fh, snapshot, release, err := s.fileOf(ctx, m.URI)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		diags, err := s.diagnoseFile(ctx, snapshot, fh)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, cellDiagnostics(m, toProtocolDiagnostics(diags))[cell]...)
+	}
+	return &protocol.DocumentDiagnosticReport{
+		Value: protocol.RelatedFullDocumentDiagnosticReport{
+			FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
+				Kind:  string(protocol.DiagnosticFull),
+				Items: items,
+			},
+		},
+	}, nil
+}
+
+func (s *server) DiagnosticWorkspace(ctx context.Context, params *protocol.WorkspaceDiagnosticParams) (*protocol.WorkspaceDiagnosticReport, error) {
+	ctx, done := event.Start(ctx, "lsp.Server.diagnosticWorkspace")
+	defer done()
+
+	previous := make(map[protocol.DocumentURI]string)
+	for _, prev := range params.PreviousResultIds {
+		previous[prev.URI] = prev.Value
+	}
+
+	// Diagnose each view, as for published diagnostics,
+	// and merge the results.
+	var (
+		mu     sync.Mutex
+		merged = make(diagMap)
+		errs   []error
+	)
+	views := s.session.Views()
+	var wg sync.WaitGroup
+	for _, v := range views {
+		snapshot, release, err := v.Snapshot()
+		if err != nil {
+			continue // view is shut down
+		}
+		wg.Add(1)
+		go func(snapshot *cache.Snapshot, release func()) {
+			defer release()
+			defer wg.Done()
+			diagnostics, err := s.diagnose(ctx, snapshot)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			addDiagnostics(merged, diagnostics)
+			// Dead code is computed after the other diagnostics;
+			// see diagnoseSnapshot.
+			if snapshot.Options().Deadcode {
+				deadcode, _ := golang.CachedDeadcodeDiagnostics(snapshot)
+				addDiagnostics(merged, deadcode)
+			}
+		}(snapshot, release)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(errs) > 0 && len(errs) == len(views) {
+		return nil, errs[0]
+	}
+
+	// The client forgets about files that are absent from the report,
+	// so files that previously had diagnostics but now have none must
+	// be reported explicitly, with an empty result.
+	for uri := range previous {
+		if _, ok := merged[uri]; !ok {
+			merged[uri] = nil
+		}
+	}
+
+	versions := make(map[protocol.DocumentURI]int32)
+	for _, o := range s.session.Overlays() {
+		versions[o.URI()] = o.Version()
+	}
+
+	report := &protocol.WorkspaceDiagnosticReport{
+		Items: []protocol.WorkspaceDocumentDiagnosticReport{},
+	}
+	uris := maps.Keys(merged)
+	sort.Slice(uris, func(i, j int) bool { return uris[i] < uris[j] })
+	for _, uri := range uris {
+		if s.notebookMapper(uri) != nil {
+			continue // reported for the cells of the notebook; see cellDiagnostic
+		}
+		diags := dedupDiagnostics(merged[uri])
+		resultID := diagnosticsResultID(diags)
+
+		// The version of a file that is not open is null.
+		var version *int32
+		if v, ok := versions[uri]; ok {
+			version = &v
+		}
+		if prev, ok := previous[uri]; ok && prev == resultID {
+			report.Items = append(report.Items, protocol.WorkspaceDocumentDiagnosticReport{
+				Value: protocol.WorkspaceUnchangedDocumentDiagnosticReport{
+					URI:     uri,
+					Version: version,
+					UnchangedDocumentDiagnosticReport: protocol.UnchangedDocumentDiagnosticReport{
+						Kind:     string(protocol.DiagnosticUnchanged),
+						ResultID: resultID,
+					},
+				},
+			})
+			continue
+		}
+		report.Items = append(report.Items, protocol.WorkspaceDocumentDiagnosticReport{
+			Value: protocol.WorkspaceFullDocumentDiagnosticReport{
+				URI:     uri,
+				Version: version,
+				FullDocumentDiagnosticReport: protocol.FullDocumentDiagnosticReport{
+					Kind:     string(protocol.DiagnosticFull),
+					ResultID: resultID,
+					Items:    toProtocolDiagnostics(diags),
+				},
+			},
+		})
+	}
+	return report, nil
+}
+
+// diagnoseFile computes the current diagnostics for a single file in
+// the given snapshot.
+//
+// For Go files, these are the list, parse, and type errors of the
+// narrowest package containing the file, combined with the analysis
+// diagnostics of the widest such package, mirroring the logic used for
+// published diagnostics.
+func (s *server) diagnoseFile(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle) ([]*cache.Diagnostic, error) {
+	uri := fh.URI()
+	switch snapshot.FileKind(fh) {
+	case file.Go:
+		if snapshot.IsBuiltin(uri) || snapshot.IgnoredFile(uri) {
+			return nil, nil
+		}
+		mps, err := snapshot.MetadataForFile(ctx, uri)
+		if err != nil {
+			return nil, err
+		}
+		metadata.RemoveIntermediateTestVariants(&mps)
+		if len(mps) == 0 {
+			return nil, fmt.Errorf("no package metadata for file %s", uri)
+		}
+		narrowest, widest := mps[0], mps[len(mps)-1]
+		pkgDiags, err := snapshot.PackageDiagnostics(ctx, narrowest.ID)
+		if err != nil {
+			return nil, err
+		}
+		analysisDiags, err := golang.Analyze(ctx, snapshot, map[golang.PackageID]*metadata.Package{widest.ID: widest}, nil)
+		if err != nil {
+			return nil, err
+		}
+		vulnDiags, err := mod.VulnerableCallDiagnostics(ctx, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		var diags []*cache.Diagnostic
+		combineDiagnostics(pkgDiags[uri], analysisDiags[uri], &diags, &diags)
+		diags = append(diags, vulnDiags[uri]...)
+		sortDiagnostics(diags)
+		return diags, nil
+
+	case file.Mod:
+		byURI, err := mod.ParseDiagnostics(ctx, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		return byURI[uri], nil
+
+	case file.Work:
+		byURI, err := work.Diagnostics(ctx, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		return byURI[uri], nil
+
+	case file.Tmpl:
+		return template.Diagnostics(ctx, snapshot)[uri], nil
+
+	default:
+		return nil, fmt.Errorf("pull diagnostics not supported for %s", uri)
+	}
+}
+
+// dedupDiagnostics returns the unique elements of diags (by hash), in
+// sorted order. Diagnostics computed by more than one view for the
+// same file may be duplicates.
+func dedupDiagnostics(diags []*cache.Diagnostic) []*cache.Diagnostic {
+	seen := make(map[file.Hash]bool)
+	var unique []*cache.Diagnostic
+	for _, diag := range diags {
+		h := hashDiagnostic(diag)
+		if !seen[h] {
+			seen[h] = true
+			unique = append(unique, diag)
+		}
+	}
+	sortDiagnostics(unique)
+	return unique
+}
+
+// diagnosticsResultID returns a result ID that identifies the content
+// of the given set of diagnostics, which must not contain duplicates.
+// Two sets of diagnostics have the same result ID if and only if
+// (modulo hash collisions) they would be reported identically.
+func diagnosticsResultID(diags []*cache.Diagnostic) string {
+	var hash file.Hash
+	for _, diag := range diags {
+		hash.XORWith(hashDiagnostic(diag))
+	}
+	return hash.String()
+}
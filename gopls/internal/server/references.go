@@ -27,7 +27,7 @@ func (s *server) References(ctx context.Context, params *protocol.ReferenceParam
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 	switch snapshot.FileKind(fh) {
@@ -22,7 +22,7 @@ func (s *server) Rename(ctx context.Context, params *protocol.RenameParams) (*pr
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 
@@ -75,7 +75,7 @@ func (s *server) PrepareRename(ctx context.Context, params *protocol.PrepareRena
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 
@@ -32,7 +32,7 @@ func (s *server) SelectionRange(ctx context.Context, params *protocol.SelectionR
 
 	fh, snapshot, release, err := s.fileOf(ctx, params.TextDocument.URI)
 	if err != nil {
-		return nil, err
+		return nil, notebookCellErr(err)
 	}
 	defer release()
 
@@ -6,48 +6,136 @@ package server
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"golang.org/x/tools/gopls/internal/file"
 	"golang.org/x/tools/gopls/internal/golang"
 	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/semtok"
 	"golang.org/x/tools/gopls/internal/template"
 	"golang.org/x/tools/internal/event"
 	"golang.org/x/tools/internal/event/tag"
 )
 
 func (s *server) SemanticTokensFull(ctx context.Context, params *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
-	return s.semanticTokens(ctx, params.TextDocument, nil)
+	tokens, version, err := s.semanticTokens(ctx, params.TextDocument, nil)
+	if err != nil || tokens == nil {
+		return tokens, err
+	}
+	s.saveSemanticTokens(params.TextDocument.URI, version, tokens, "")
+	return tokens, nil
+}
+
+func (s *server) SemanticTokensFullDelta(ctx context.Context, params *protocol.SemanticTokensDeltaParams) (interface{}, error) {
+	tokens, version, err := s.semanticTokens(ctx, params.TextDocument, nil)
+	if err != nil || tokens == nil {
+		return nil, err
+	}
+	prev := s.saveSemanticTokens(params.TextDocument.URI, version, tokens, params.PreviousResultID)
+	if prev == nil {
+		// The client's previous result is unknown
+		// (or evicted): send the full result.
+		return tokens, nil
+	}
+	return &protocol.SemanticTokensDelta{
+		ResultID: tokens.ResultID,
+		Edits:    semtok.Diff(prev.Data, tokens.Data),
+	}, nil
 }
 
 func (s *server) SemanticTokensRange(ctx context.Context, params *protocol.SemanticTokensRangeParams) (*protocol.SemanticTokens, error) {
-	return s.semanticTokens(ctx, params.TextDocument, &params.Range)
+	tokens, _, err := s.semanticTokens(ctx, params.TextDocument, &params.Range)
+	return tokens, err
 }
 
-func (s *server) semanticTokens(ctx context.Context, td protocol.TextDocumentIdentifier, rng *protocol.Range) (*protocol.SemanticTokens, error) {
+// semanticTokens returns the semantic tokens of the specified
+// document, and the version of the document from which they were
+// computed.
+func (s *server) semanticTokens(ctx context.Context, td protocol.TextDocumentIdentifier, rng *protocol.Range) (*protocol.SemanticTokens, int32, error) {
 	ctx, done := event.Start(ctx, "lsp.Server.semanticTokens", tag.URI.Of(td.URI))
 	defer done()
 
 	fh, snapshot, release, err := s.fileOf(ctx, td.URI)
 	if err != nil {
-		return nil, err
+		return nil, 0, notebookCellErr(err)
 	}
 	defer release()
 	if !snapshot.Options().SemanticTokens {
 		// return an error, so if the option changes
 		// the client won't remember the wrong answer
-		return nil, fmt.Errorf("semantictokens are disabled")
+		return nil, 0, fmt.Errorf("semantictokens are disabled")
 	}
 
+	var tokens *protocol.SemanticTokens
 	switch snapshot.FileKind(fh) {
 	case file.Tmpl:
-		return template.SemanticTokens(ctx, snapshot, fh.URI())
+		tokens, err = template.SemanticTokens(ctx, snapshot, fh.URI())
 
 	case file.Go:
-		return golang.SemanticTokens(ctx, snapshot, fh, rng)
+		tokens, err = golang.SemanticTokens(ctx, snapshot, fh, rng)
 
 	default:
 		// TODO(adonovan): should return an error!
-		return nil, nil // empty result
+		return nil, 0, nil // empty result
+	}
+	return tokens, fh.Version(), err
+}
+
+// semanticTokensKey identifies a version of a file.
+type semanticTokensKey struct {
+	uri     protocol.DocumentURI
+	version int32
+}
+
+// saveSemanticTokens assigns a result ID to the full semantic tokens of
+// the specified version of a file, and records them as the basis for
+// subsequent delta requests, replacing those of other versions of the
+// file. It returns the tokens of the specified previous result, if they
+// were recorded.
+//
+// The result ID identifies both the version of the file and the tokens
+// themselves, which may change without a change to the file, for
+// example when a declaration in another file changes kind.
+func (s *server) saveSemanticTokens(uri protocol.DocumentURI, version int32, tokens *protocol.SemanticTokens, previousResultID string) *protocol.SemanticTokens {
+	data := make([]byte, 4*len(tokens.Data))
+	for i, v := range tokens.Data {
+		binary.LittleEndian.PutUint32(data[4*i:], v)
+	}
+	hash := file.HashOf(data)
+	tokens.ResultID = fmt.Sprintf("%d-%x", version, hash[:8])
+
+	s.semanticTokensMu.Lock()
+	defer s.semanticTokensMu.Unlock()
+
+	var prev *protocol.SemanticTokens
+	if v, _, ok := strings.Cut(previousResultID, "-"); ok {
+		if prevVersion, err := strconv.ParseInt(v, 10, 32); err == nil {
+			if saved := s.lastSemanticTokens[semanticTokensKey{uri, int32(prevVersion)}]; saved != nil && saved.ResultID == previousResultID {
+				prev = saved
+			}
+		}
+	}
+	// A client computes the next delta relative to the last result it
+	// received, so only the most recent version of each file is retained.
+	for key := range s.lastSemanticTokens {
+		if key.uri == uri {
+			delete(s.lastSemanticTokens, key)
+		}
+	}
+	s.lastSemanticTokens[semanticTokensKey{uri, version}] = tokens
+	return prev
+}
+
+// forgetSemanticTokens discards the recorded semantic tokens of a file.
+func (s *server) forgetSemanticTokens(uri protocol.DocumentURI) {
+	s.semanticTokensMu.Lock()
+	defer s.semanticTokensMu.Unlock()
+	for key := range s.lastSemanticTokens {
+		if key.uri == uri {
+			delete(s.lastSemanticTokens, key)
+		}
 	}
 }
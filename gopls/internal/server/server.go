@@ -36,6 +36,8 @@ func New(session *cache.Session, client protocol.ClientCloser, options *settings
 		progress:            progress.NewTracker(client),
 		options:             options,
 		viewsToDiagnose:     make(map[*cache.View]uint64),
+		lastSemanticTokens:  make(map[semanticTokensKey]*protocol.SemanticTokens),
+		notebooks:           make(map[protocol.URI]*notebook),
 	}
 }
 
@@ -95,6 +97,20 @@ type server struct {
 	diagnosticsMu sync.Mutex
 	diagnostics   map[protocol.DocumentURI]*fileDiagnostics
 
+	// pullDiagnostics reports whether the client pulls diagnostics
+	// (textDocument/diagnostic), in which case they are not published.
+	// If the client also supports workspace/diagnostic/refresh,
+	// refreshDiagnostics is set, and the client is asked to pull again
+	// whenever diagnostics change. Both are set during initialization.
+	pullDiagnostics, refreshDiagnostics bool
+	diagnosticsChanged                  bool // guarded by diagnosticsMu; a refresh is pending
+
+	// refreshInlayHints reports whether the client supports
+	// workspace/inlayHint/refresh, which is sent when the details shown
+	// by the gcDetails inlay hints have been computed. It is set during
+	// initialization.
+	refreshInlayHints bool
+
 	// diagnosticsSema limits the concurrency of diagnostics runs, which can be
 	// expensive.
 	diagnosticsSema chan unit
@@ -115,6 +131,16 @@ type server struct {
 	optionsMu sync.Mutex
 	options   *settings.Options
 
+	// lastSemanticTokens records the most recent full semantic tokens
+	// result for each open file, and the file version for which it was
+	// computed, so that subsequent requests may be answered with a delta.
+	semanticTokensMu   sync.Mutex
+	lastSemanticTokens map[semanticTokensKey]*protocol.SemanticTokens
+
+	// notebooks holds the open notebook documents, by notebook URI.
+	notebooksMu sync.Mutex
+	notebooks   map[protocol.URI]*notebook
+
 	// # Modification tracking and diagnostics
 	//
 	// For the purpose of tracking diagnostics, we need a monotonically
@@ -206,6 +206,8 @@ func (s *server) DidClose(ctx context.Context, params *protocol.DidCloseTextDocu
 	ctx, done := event.Start(ctx, "lsp.Server.didClose", tag.URI.Of(params.TextDocument.URI))
 	defer done()
 
+	s.forgetSemanticTokens(params.TextDocument.URI)
+
 	return s.didModifyFiles(ctx, []file.Modification{
 		{
 			URI:     params.TextDocument.URI,
@@ -327,6 +329,12 @@ func (s *server) applyIncrementalChanges(ctx context.Context, uri protocol.Docum
 	if err != nil {
 		return nil, fmt.Errorf("%w: file not found (%v)", jsonrpc2.ErrInternal, err)
 	}
+	return applyContentChanges(uri, content, changes)
+}
+
+// applyContentChanges applies a sequence of incremental changes to the
+// content of the document uri.
+func applyContentChanges(uri protocol.DocumentURI, content []byte, changes []protocol.TextDocumentContentChangeEvent) ([]byte, error) {
 	for _, change := range changes {
 		// TODO(adonovan): refactor to use diff.Apply, which is robust w.r.t.
 		// out-of-order or overlapping changes---and much more efficient.
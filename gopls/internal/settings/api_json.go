@@ -139,6 +139,14 @@ var GeneratedAPIJSON = &APIJSON{
 				Status:    "experimental",
 				Hierarchy: "ui.completion",
 			},
+			{
+				Name:      "postfixSnippets",
+				Type:      "[]PostfixSnippet",
+				Doc:       "postfixSnippets defines additional postfix snippets, offered along\nwith the predefined ones when experimentalPostfixCompletions is\nenabled. Each snippet is an object with a \"label\", which completes\n\"x.label!\", optional \"details\" shown by the client, and a \"body\",\nwhich is a text/template producing the snippet text, or nothing if\nthe snippet does not apply to the selector expression.\n\nThe template has access to the same facilities as the predefined\nsnippets, such as .X, the text of the expression, .Type and .Kind,\nits type and the kind of its underlying type, .StmtOK, .Import,\n.VarName, .Placeholder, and .Cursor. For example:\n\n```json5\n\"postfixSnippets\": [{\n  \"label\": \"must\",\n  \"details\": \"panic on error\",\n  \"body\": \"{{if and .StmtOK (eq (.TypeName .Type) \\\"error\\\")}}if err := {{.X}}; err != nil {\\n\\tpanic(err)\\n}{{end}}\",\n}]\n```\n",
+				Default:   "[]",
+				Status:    "experimental",
+				Hierarchy: "ui.completion",
+			},
 			{
 				Name:      "completeFunctionCalls",
 				Type:      "bool",
@@ -211,6 +219,28 @@ var GeneratedAPIJSON = &APIJSON{
 				Default:   "\"all\"",
 				Hierarchy: "ui.navigation",
 			},
+			{
+				Name: "dynamicCallHierarchy",
+				Type: "enum",
+				Doc:  "dynamicCallHierarchy controls whether call hierarchy requests\nreport dynamic calls, that is, calls through interface methods and\nfunction values, in addition to static calls. The default value,\n\"off\", reports only static calls. The other values build the SSA\nform of the workspace packages, which is expensive, and compute a\ncall graph with the specified algorithm. Dynamic calls are marked\nas such in the details of the reported items.\n",
+				EnumValues: []EnumValue{
+					{
+						Value: "\"off\"",
+						Doc:   "`\"off\"` reports only static calls.\n",
+					},
+					{
+						Value: "\"rta\"",
+						Doc:   "`\"rta\"` finds dynamic calls using Rapid Type Analysis,\nwhich is faster but less precise than VTA.\n",
+					},
+					{
+						Value: "\"vta\"",
+						Doc:   "`\"vta\"` finds dynamic calls using Variable Type Analysis.\n",
+					},
+				},
+				Default:   "\"off\"",
+				Status:    "experimental",
+				Hierarchy: "ui.navigation",
+			},
 			{
 				Name: "analyses",
 				Type: "map[string]bool",
@@ -486,10 +516,18 @@ var GeneratedAPIJSON = &APIJSON{
 				Status:    "experimental",
 				Hierarchy: "ui.diagnostic",
 			},
+			{
+				Name:      "analyzerPlugins",
+				Type:      "[]string",
+				Doc:       "analyzerPlugins specifies the main packages of additional analyzers\nto run on the open packages of the workspace, such as checkers built\nwith the multichecker or unitchecker packages of go/analysis. Each\nentry is a package path or a relative path such as \"./tools/check\",\nresolved in the root directory of the view. Gopls builds each package\nand runs it as the vet tool of \"go vet -json\", so it must support the\nvet protocol, as multichecker and unitchecker do.\n\nThe analyzers of a plugin may be disabled by name using the analyses\nsetting. Their diagnostics are computed from the files saved on disk,\nso they are not reported for packages with unsaved changes.\n",
+				Default:   "[]",
+				Status:    "experimental",
+				Hierarchy: "ui.diagnostic",
+			},
 			{
 				Name: "annotations",
 				Type: "map[string]bool",
-				Doc:  "annotations specifies the various kinds of optimization diagnostics\nthat should be reported by the gc_details command.\n",
+				Doc:  "annotations specifies the various kinds of optimization diagnostics\nthat should be reported by the gc_details command and the gcDetails\ninlay hints.\n",
 				EnumKeys: EnumKeys{
 					ValueType: "bool",
 					Keys: []EnumKey{
@@ -537,6 +575,14 @@ var GeneratedAPIJSON = &APIJSON{
 				Status:    "experimental",
 				Hierarchy: "ui.diagnostic",
 			},
+			{
+				Name:      "deadcode",
+				Type:      "bool",
+				Doc:       "deadcode enables diagnostics for functions of the workspace that\nare unreachable from the main function of any main package of the\nworkspace, as computed by the deadcode command using Rapid Type\nAnalysis. Each diagnostic offers a quick fix to delete the\nfunction. Only packages imported, directly or indirectly, by a\nmain package of the workspace are reported.\n",
+				Default:   "false",
+				Status:    "experimental",
+				Hierarchy: "ui.diagnostic",
+			},
 			{
 				Name:      "diagnosticsDelay",
 				Type:      "time.Duration",
@@ -600,6 +646,11 @@ var GeneratedAPIJSON = &APIJSON{
 						Doc:     "Enable/disable inlay hints for implicit type parameters on generic functions:\n```go\n\tmyFoo/*[int, string]*/(1, \"hello\")\n```",
 						Default: "false",
 					},
+					{
+						Name:    "\"gcDetails\"",
+						Doc:     "Enable/disable inlay hints for the optimization decisions of the compiler, such as escape analysis,\ninlining, bounds checks, and nil checks, as selected by the annotations setting:\n```go\n\tfunc f(x int) int {/* canInline(cost: 4)*/\n```\nThe package is compiled to compute them, so they are shown only for files without unsaved changes.",
+						Default: "false",
+					},
 					{
 						Name:    "\"parameterNames\"",
 						Doc:     "Enable/disable inlay hints for parameter names:\n```go\n\tparseInt(/* str: */ \"123\", /* radix: */ 8)\n```",
@@ -733,19 +784,25 @@ var GeneratedAPIJSON = &APIJSON{
 			Doc:     "Gopls will prepend \"fwd/\" to all the counters updated using this command\nto avoid conflicts with other counters gopls collects.",
 			ArgDoc:  "{\n\t// Names and Values must have the same length.\n\t\"Names\": []string,\n\t\"Values\": []int64,\n}",
 		},
+		{
+			Command: "gopls.apply_edits",
+			Title:   "Apply edits to other documents",
+			Doc:     "Asks the client to apply edits to documents other than that of a\ncompletion item, which the item itself cannot express, such as the\ninsertion of an import into another cell of a notebook.",
+			ArgDoc:  "{\n\t// The edits to apply, by document.\n\t\"Edits\": []{\n\t\t\"textDocument\": {\n\t\t\t\"version\": int32,\n\t\t\t\"uri\": string,\n\t\t},\n\t\t\"edits\": []golang.org/x/tools/gopls/internal/protocol.Or_TextDocumentEdit_edits_Elem,\n\t},\n}",
+		},
 		{
 			Command:   "gopls.apply_fix",
 			Title:     "Apply a fix",
 			Doc:       "Applies a fix to a region of source code.",
 			ArgDoc:    "{\n\t// The name of the fix to apply.\n\t//\n\t// For fixes suggested by analyzers, this is a string constant\n\t// advertised by the analyzer that matches the Category of\n\t// the analysis.Diagnostic with a SuggestedFix containing no edits.\n\t//\n\t// For fixes suggested by code actions, this is a string agreed\n\t// upon by the code action and golang.ApplyFix.\n\t\"Fix\": string,\n\t// The file URI for the document to fix.\n\t\"URI\": string,\n\t// The document range to scan for fixes.\n\t\"Range\": {\n\t\t\"start\": {\n\t\t\t\"line\": uint32,\n\t\t\t\"character\": uint32,\n\t\t},\n\t\t\"end\": {\n\t\t\t\"line\": uint32,\n\t\t\t\"character\": uint32,\n\t\t},\n\t},\n\t// Whether to resolve and return the edits.\n\t\"ResolveEdits\": bool,\n}",
-			ResultDoc: "{\n\t// Holds changes to existing resources.\n\t\"changes\": map[golang.org/x/tools/gopls/internal/protocol.DocumentURI][]golang.org/x/tools/gopls/internal/protocol.TextEdit,\n\t// Depending on the client capability `workspace.workspaceEdit.resourceOperations` document changes\n\t// are either an array of `TextDocumentEdit`s to express changes to n different text documents\n\t// where each text document edit addresses a specific version of a text document. Or it can contain\n\t// above `TextDocumentEdit`s mixed with create, rename and delete file / folder operations.\n\t//\n\t// Whether a client supports versioned document edits is expressed via\n\t// `workspace.workspaceEdit.documentChanges` client capability.\n\t//\n\t// If a client neither supports `documentChanges` nor `workspace.workspaceEdit.resourceOperations` then\n\t// only plain `TextEdit`s using the `changes` property are supported.\n\t\"documentChanges\": []{\n\t\t\"TextDocumentEdit\": {\n\t\t\t\"textDocument\": { ... },\n\t\t\t\"edits\": { ... },\n\t\t},\n\t\t\"RenameFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"oldUri\": string,\n\t\t\t\"newUri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t},\n\t// A map of change annotations that can be referenced in `AnnotatedTextEdit`s or create, rename and\n\t// delete file / folder operations.\n\t//\n\t// Whether clients honor this property depends on the client capability `workspace.changeAnnotationSupport`.\n\t//\n\t// @since 3.16.0\n\t\"changeAnnotations\": map[string]golang.org/x/tools/gopls/internal/protocol.ChangeAnnotation,\n}",
+			ResultDoc: "{\n\t// Holds changes to existing resources.\n\t\"changes\": map[golang.org/x/tools/gopls/internal/protocol.DocumentURI][]golang.org/x/tools/gopls/internal/protocol.TextEdit,\n\t// Depending on the client capability `workspace.workspaceEdit.resourceOperations` document changes\n\t// are either an array of `TextDocumentEdit`s to express changes to n different text documents\n\t// where each text document edit addresses a specific version of a text document. Or it can contain\n\t// above `TextDocumentEdit`s mixed with create, rename and delete file / folder operations.\n\t//\n\t// Whether a client supports versioned document edits is expressed via\n\t// `workspace.workspaceEdit.documentChanges` client capability.\n\t//\n\t// If a client neither supports `documentChanges` nor `workspace.workspaceEdit.resourceOperations` then\n\t// only plain `TextEdit`s using the `changes` property are supported.\n\t\"documentChanges\": []{\n\t\t\"TextDocumentEdit\": {\n\t\t\t\"textDocument\": { ... },\n\t\t\t\"edits\": { ... },\n\t\t},\n\t\t\"RenameFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"oldUri\": string,\n\t\t\t\"newUri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t\t\"CreateFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"uri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t},\n\t// A map of change annotations that can be referenced in `AnnotatedTextEdit`s or create, rename and\n\t// delete file / folder operations.\n\t//\n\t// Whether clients honor this property depends on the client capability `workspace.changeAnnotationSupport`.\n\t//\n\t// @since 3.16.0\n\t\"changeAnnotations\": map[string]golang.org/x/tools/gopls/internal/protocol.ChangeAnnotation,\n}",
 		},
 		{
 			Command:   "gopls.change_signature",
 			Title:     "Perform a \"change signature\" refactoring",
 			Doc:       "This command is experimental, currently only supporting parameter removal.\nIts signature will certainly change in the future (pun intended).",
 			ArgDoc:    "{\n\t\"RemoveParameter\": {\n\t\t\"uri\": string,\n\t\t\"range\": {\n\t\t\t\"start\": { ... },\n\t\t\t\"end\": { ... },\n\t\t},\n\t},\n\t// Whether to resolve and return the edits.\n\t\"ResolveEdits\": bool,\n}",
-			ResultDoc: "{\n\t// Holds changes to existing resources.\n\t\"changes\": map[golang.org/x/tools/gopls/internal/protocol.DocumentURI][]golang.org/x/tools/gopls/internal/protocol.TextEdit,\n\t// Depending on the client capability `workspace.workspaceEdit.resourceOperations` document changes\n\t// are either an array of `TextDocumentEdit`s to express changes to n different text documents\n\t// where each text document edit addresses a specific version of a text document. Or it can contain\n\t// above `TextDocumentEdit`s mixed with create, rename and delete file / folder operations.\n\t//\n\t// Whether a client supports versioned document edits is expressed via\n\t// `workspace.workspaceEdit.documentChanges` client capability.\n\t//\n\t// If a client neither supports `documentChanges` nor `workspace.workspaceEdit.resourceOperations` then\n\t// only plain `TextEdit`s using the `changes` property are supported.\n\t\"documentChanges\": []{\n\t\t\"TextDocumentEdit\": {\n\t\t\t\"textDocument\": { ... },\n\t\t\t\"edits\": { ... },\n\t\t},\n\t\t\"RenameFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"oldUri\": string,\n\t\t\t\"newUri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t},\n\t// A map of change annotations that can be referenced in `AnnotatedTextEdit`s or create, rename and\n\t// delete file / folder operations.\n\t//\n\t// Whether clients honor this property depends on the client capability `workspace.changeAnnotationSupport`.\n\t//\n\t// @since 3.16.0\n\t\"changeAnnotations\": map[string]golang.org/x/tools/gopls/internal/protocol.ChangeAnnotation,\n}",
+			ResultDoc: "{\n\t// Holds changes to existing resources.\n\t\"changes\": map[golang.org/x/tools/gopls/internal/protocol.DocumentURI][]golang.org/x/tools/gopls/internal/protocol.TextEdit,\n\t// Depending on the client capability `workspace.workspaceEdit.resourceOperations` document changes\n\t// are either an array of `TextDocumentEdit`s to express changes to n different text documents\n\t// where each text document edit addresses a specific version of a text document. Or it can contain\n\t// above `TextDocumentEdit`s mixed with create, rename and delete file / folder operations.\n\t//\n\t// Whether a client supports versioned document edits is expressed via\n\t// `workspace.workspaceEdit.documentChanges` client capability.\n\t//\n\t// If a client neither supports `documentChanges` nor `workspace.workspaceEdit.resourceOperations` then\n\t// only plain `TextEdit`s using the `changes` property are supported.\n\t\"documentChanges\": []{\n\t\t\"TextDocumentEdit\": {\n\t\t\t\"textDocument\": { ... },\n\t\t\t\"edits\": { ... },\n\t\t},\n\t\t\"RenameFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"oldUri\": string,\n\t\t\t\"newUri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t\t\"CreateFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"uri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t},\n\t// A map of change annotations that can be referenced in `AnnotatedTextEdit`s or create, rename and\n\t// delete file / folder operations.\n\t//\n\t// Whether clients honor this property depends on the client capability `workspace.changeAnnotationSupport`.\n\t//\n\t// @since 3.16.0\n\t\"changeAnnotations\": map[string]golang.org/x/tools/gopls/internal/protocol.ChangeAnnotation,\n}",
 		},
 		{
 			Command: "gopls.check_upgrades",
@@ -765,6 +822,20 @@ var GeneratedAPIJSON = &APIJSON{
 			Doc:     "Runs `go mod edit -go=version` for a module.",
 			ArgDoc:  "{\n\t// Any document URI within the relevant module.\n\t\"URI\": string,\n\t// The version to pass to `go mod edit -go`.\n\t\"Version\": string,\n}",
 		},
+		{
+			Command:   "gopls.extract_interface",
+			Title:     "Extract an interface from a type",
+			Doc:       "Declares an interface with the selected exported methods of a\nnamed type, next to the type's declaration. Optionally, it also\nreplaces the type by the interface in the parameters of the\npackage's functions that use only those methods.",
+			ArgDoc:    "{\n\t// The location of the name of the type.\n\t\"Location\": {\n\t\t\"uri\": string,\n\t\t\"range\": {\n\t\t\t\"start\": { ... },\n\t\t\t\"end\": { ... },\n\t\t},\n\t},\n\t// The name of the interface. If it is empty, a name is derived\n\t// from that of the type.\n\t\"Name\": string,\n\t// The names of the methods of the interface. If it is empty, the\n\t// interface has all the exported methods of the type.\n\t\"Methods\": []string,\n\t// Whether to use the interface in place of the type in the\n\t// parameters of the package's functions that use only its methods.\n\t\"ReplaceParams\": bool,\n\t// Whether to resolve and return the edits.\n\t\"ResolveEdits\": bool,\n}",
+			ResultDoc: "{\n\t// Holds changes to existing resources.\n\t\"changes\": map[golang.org/x/tools/gopls/internal/protocol.DocumentURI][]golang.org/x/tools/gopls/internal/protocol.TextEdit,\n\t// Depending on the client capability `workspace.workspaceEdit.resourceOperations` document changes\n\t// are either an array of `TextDocumentEdit`s to express changes to n different text documents\n\t// where each text document edit addresses a specific version of a text document. Or it can contain\n\t// above `TextDocumentEdit`s mixed with create, rename and delete file / folder operations.\n\t//\n\t// Whether a client supports versioned document edits is expressed via\n\t// `workspace.workspaceEdit.documentChanges` client capability.\n\t//\n\t// If a client neither supports `documentChanges` nor `workspace.workspaceEdit.resourceOperations` then\n\t// only plain `TextEdit`s using the `changes` property are supported.\n\t\"documentChanges\": []{\n\t\t\"TextDocumentEdit\": {\n\t\t\t\"textDocument\": { ... },\n\t\t\t\"edits\": { ... },\n\t\t},\n\t\t\"RenameFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"oldUri\": string,\n\t\t\t\"newUri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t\t\"CreateFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"uri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t},\n\t// A map of change annotations that can be referenced in `AnnotatedTextEdit`s or create, rename and\n\t// delete file / folder operations.\n\t//\n\t// Whether clients honor this property depends on the client capability `workspace.changeAnnotationSupport`.\n\t//\n\t// @since 3.16.0\n\t\"changeAnnotations\": map[string]golang.org/x/tools/gopls/internal/protocol.ChangeAnnotation,\n}",
+		},
+		{
+			Command:   "gopls.extract_to_file",
+			Title:     "Move declarations to another file",
+			Doc:       "Moves the selected top-level declarations to another file of the\nsame package, creating it if necessary, and updates the imports\nof both files.",
+			ArgDoc:    "{\n\t// The range of the declarations to move.\n\t\"Location\": {\n\t\t\"uri\": string,\n\t\t\"range\": {\n\t\t\t\"start\": { ... },\n\t\t\t\"end\": { ... },\n\t\t},\n\t},\n\t// The file to which the declarations are moved. If it is empty, a\n\t// new file named after the first declaration is created.\n\t\"Dest\": string,\n\t// Whether to resolve and return the edits.\n\t\"ResolveEdits\": bool,\n}",
+			ResultDoc: "{\n\t// Holds changes to existing resources.\n\t\"changes\": map[golang.org/x/tools/gopls/internal/protocol.DocumentURI][]golang.org/x/tools/gopls/internal/protocol.TextEdit,\n\t// Depending on the client capability `workspace.workspaceEdit.resourceOperations` document changes\n\t// are either an array of `TextDocumentEdit`s to express changes to n different text documents\n\t// where each text document edit addresses a specific version of a text document. Or it can contain\n\t// above `TextDocumentEdit`s mixed with create, rename and delete file / folder operations.\n\t//\n\t// Whether a client supports versioned document edits is expressed via\n\t// `workspace.workspaceEdit.documentChanges` client capability.\n\t//\n\t// If a client neither supports `documentChanges` nor `workspace.workspaceEdit.resourceOperations` then\n\t// only plain `TextEdit`s using the `changes` property are supported.\n\t\"documentChanges\": []{\n\t\t\"TextDocumentEdit\": {\n\t\t\t\"textDocument\": { ... },\n\t\t\t\"edits\": { ... },\n\t\t},\n\t\t\"RenameFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"oldUri\": string,\n\t\t\t\"newUri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t\t\"CreateFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"uri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t},\n\t// A map of change annotations that can be referenced in `AnnotatedTextEdit`s or create, rename and\n\t// delete file / folder operations.\n\t//\n\t// Whether clients honor this property depends on the client capability `workspace.changeAnnotationSupport`.\n\t//\n\t// @since 3.16.0\n\t\"changeAnnotations\": map[string]golang.org/x/tools/gopls/internal/protocol.ChangeAnnotation,\n}",
+		},
 		{
 			Command:   "gopls.fetch_vulncheck_result",
 			Title:     "Get known vulncheck result",
@@ -784,12 +855,26 @@ var GeneratedAPIJSON = &APIJSON{
 			Doc:     "Runs `go generate` for a given directory.",
 			ArgDoc:  "{\n\t// URI for the directory to generate.\n\t\"Dir\": string,\n\t// Whether to generate recursively (go generate ./...)\n\t\"Recursive\": bool,\n}",
 		},
+		{
+			Command:   "gopls.generate_test",
+			Title:     "Generate a test for a function",
+			Doc:       "Adds a table-driven test of the selected function or method to\nthe corresponding _test.go file, creating the file if necessary.\nThe test has a field for the receiver and for each parameter and\nresult of the function.",
+			ArgDoc:    "{\n\t// The location of the declaration of the function.\n\t\"Location\": {\n\t\t\"uri\": string,\n\t\t\"range\": {\n\t\t\t\"start\": { ... },\n\t\t\t\"end\": { ... },\n\t\t},\n\t},\n\t// Whether to resolve and return the edits.\n\t\"ResolveEdits\": bool,\n}",
+			ResultDoc: "{\n\t// Holds changes to existing resources.\n\t\"changes\": map[golang.org/x/tools/gopls/internal/protocol.DocumentURI][]golang.org/x/tools/gopls/internal/protocol.TextEdit,\n\t// Depending on the client capability `workspace.workspaceEdit.resourceOperations` document changes\n\t// are either an array of `TextDocumentEdit`s to express changes to n different text documents\n\t// where each text document edit addresses a specific version of a text document. Or it can contain\n\t// above `TextDocumentEdit`s mixed with create, rename and delete file / folder operations.\n\t//\n\t// Whether a client supports versioned document edits is expressed via\n\t// `workspace.workspaceEdit.documentChanges` client capability.\n\t//\n\t// If a client neither supports `documentChanges` nor `workspace.workspaceEdit.resourceOperations` then\n\t// only plain `TextEdit`s using the `changes` property are supported.\n\t\"documentChanges\": []{\n\t\t\"TextDocumentEdit\": {\n\t\t\t\"textDocument\": { ... },\n\t\t\t\"edits\": { ... },\n\t\t},\n\t\t\"RenameFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"oldUri\": string,\n\t\t\t\"newUri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t\t\"CreateFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"uri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t},\n\t// A map of change annotations that can be referenced in `AnnotatedTextEdit`s or create, rename and\n\t// delete file / folder operations.\n\t//\n\t// Whether clients honor this property depends on the client capability `workspace.changeAnnotationSupport`.\n\t//\n\t// @since 3.16.0\n\t\"changeAnnotations\": map[string]golang.org/x/tools/gopls/internal/protocol.ChangeAnnotation,\n}",
+		},
 		{
 			Command: "gopls.go_get_package",
 			Title:   "'go get' a package",
 			Doc:     "Runs `go get` to fetch a package.",
 			ArgDoc:  "{\n\t// Any document URI within the relevant module.\n\t\"URI\": string,\n\t// The package to go get.\n\t\"Pkg\": string,\n\t\"AddRequire\": bool,\n}",
 		},
+		{
+			Command:   "gopls.index",
+			Title:     "Write an LSIF index of the workspace",
+			Doc:       "Writes an LSIF dump of the workspace packages of the view\ncontaining the given directory to a temporary file, and returns\nthe file name. The dump records the definitions, references,\nhover text, implementations and monikers of the workspace symbols.\n\nThis command is intended for use by the gopls index command.",
+			ArgDoc:    "{\n\t// A directory within the view to index.\n\t\"URI\": string,\n}",
+			ResultDoc: "{\n\t// File is the name of the file containing the LSIF dump.\n\t\"File\": string,\n}",
+		},
 		{
 			Command:   "gopls.list_imports",
 			Title:     "List imports of a file and its package",
@@ -815,6 +900,27 @@ var GeneratedAPIJSON = &APIJSON{
 			Doc:       "Call runtime.GC multiple times and return memory statistics as reported by\nruntime.MemStats.\n\nThis command is used for benchmarking, and may change in the future.",
 			ResultDoc: "{\n\t\"HeapAlloc\": uint64,\n\t\"HeapInUse\": uint64,\n\t\"TotalAlloc\": uint64,\n}",
 		},
+		{
+			Command:   "gopls.module_graph",
+			Title:     "Compute the module graph of the workspace",
+			Doc:       "Computes the module requirement graph of the workspace modules,\nreporting why each version was selected, and optionally plans the\nminimal set of upgrades required by a new version of a module.\nThe graph is computed offline, from the go.mod files of the module\ncache and of local module proxy directories.",
+			ArgDoc:    "{\n\t// A file or directory within the view.\n\t\"URI\": string,\n\t// The module to plan an upgrade of, as \"path\" or \"path@version\".\n\t// Without a version, the latest one available offline is used.\n\t// If empty, no upgrade is planned.\n\t\"Target\": string,\n\t// A directory laid out as a module proxy, from which to read go.mod\n\t// files before the module cache.\n\t\"ProxyDir\": string,\n}",
+			ResultDoc: "{\n\t// The modules of the graph: the workspace modules, then the others\n\t// sorted by path and version.\n\t\"Modules\": []{\n\t\t\"Path\": string,\n\t\t\"Version\": string,\n\t\t\"Main\": bool,\n\t\t\"Selected\": bool,\n\t\t\"Missing\": bool,\n\t\t\"Requires\": []string,\n\t\t\"Why\": []string,\n\t},\n\t// The upgrade plan for the target module, if any.\n\t\"Upgrade\": {\n\t\t\"Target\": string,\n\t\t\"Upgrades\": []{\n\t\t\t\"Path\": string,\n\t\t\t\"From\": string,\n\t\t\t\"To\": string,\n\t\t},\n\t\t\"Edits\": []{\n\t\t\t\"URI\": string,\n\t\t\t\"Require\": []string,\n\t\t},\n\t\t\"Missing\": []string,\n\t},\n}",
+		},
+		{
+			Command:   "gopls.move_to_package",
+			Title:     "Move a declaration to another package",
+			Doc:       "Moves the selected package-level function or type, with its\nmethods and the unexported declarations that only it uses, to\nanother package. Declarations used across the two packages are\nexported, and references to the moved declarations are qualified\nby the destination package. The move is refused if it would\ncreate an import cycle.",
+			ArgDoc:    "{\n\t// The location of the name of the declaration.\n\t\"Location\": {\n\t\t\"uri\": string,\n\t\t\"range\": {\n\t\t\t\"start\": { ... },\n\t\t\t\"end\": { ... },\n\t\t},\n\t},\n\t// The path of the destination package.\n\t\"PkgPath\": string,\n\t// Whether to resolve and return the edits.\n\t\"ResolveEdits\": bool,\n}",
+			ResultDoc: "{\n\t// Holds changes to existing resources.\n\t\"changes\": map[golang.org/x/tools/gopls/internal/protocol.DocumentURI][]golang.org/x/tools/gopls/internal/protocol.TextEdit,\n\t// Depending on the client capability `workspace.workspaceEdit.resourceOperations` document changes\n\t// are either an array of `TextDocumentEdit`s to express changes to n different text documents\n\t// where each text document edit addresses a specific version of a text document. Or it can contain\n\t// above `TextDocumentEdit`s mixed with create, rename and delete file / folder operations.\n\t//\n\t// Whether a client supports versioned document edits is expressed via\n\t// `workspace.workspaceEdit.documentChanges` client capability.\n\t//\n\t// If a client neither supports `documentChanges` nor `workspace.workspaceEdit.resourceOperations` then\n\t// only plain `TextEdit`s using the `changes` property are supported.\n\t\"documentChanges\": []{\n\t\t\"TextDocumentEdit\": {\n\t\t\t\"textDocument\": { ... },\n\t\t\t\"edits\": { ... },\n\t\t},\n\t\t\"RenameFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"oldUri\": string,\n\t\t\t\"newUri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t\t\"CreateFile\": {\n\t\t\t\"kind\": string,\n\t\t\t\"uri\": string,\n\t\t\t\"options\": { ... },\n\t\t\t\"ResourceOperation\": { ... },\n\t\t},\n\t},\n\t// A map of change annotations that can be referenced in `AnnotatedTextEdit`s or create, rename and\n\t// delete file / folder operations.\n\t//\n\t// Whether clients honor this property depends on the client capability `workspace.changeAnnotationSupport`.\n\t//\n\t// @since 3.16.0\n\t\"changeAnnotations\": map[string]golang.org/x/tools/gopls/internal/protocol.ChangeAnnotation,\n}",
+		},
+		{
+			Command:   "gopls.package_files",
+			Title:     "List the files of the packages matching patterns",
+			Doc:       "Lists the packages matching the given patterns, as the go command\ndoes in the given directory, with the environment and build flags\nof the view containing it, and returns the Go files of the\npackages, including their test files.\n\nThis command is needed by the 'gopls check' CLI subcommand.",
+			ArgDoc:    "{\n\t// The directory in which the patterns are interpreted.\n\t\"URI\": string,\n\t// The package patterns, such as \"./...\".\n\t\"Patterns\": []string,\n}",
+			ResultDoc: "{\n\t// The Go files of the matching packages, including their test\n\t// files, without duplicates.\n\t\"Files\": []string,\n}",
+		},
 		{
 			Command: "gopls.regenerate_cgo",
 			Title:   "Regenerate cgo",
@@ -852,6 +958,12 @@ var GeneratedAPIJSON = &APIJSON{
 			Doc:     "Runs `go test` for a specific set of test or benchmark functions.",
 			ArgDoc:  "{\n\t// The test file containing the tests to run.\n\t\"URI\": string,\n\t// Specific test names to run, e.g. TestFoo.\n\t\"Tests\": []string,\n\t// Specific benchmarks to run, e.g. BenchmarkFoo.\n\t\"Benchmarks\": []string,\n}",
 		},
+		{
+			Command: "gopls.show_vuln_path",
+			Title:   "Show the call path to a vulnerable symbol",
+			Doc:     "Shows the call stack of a govulncheck finding, from the entry\npoint down to the vulnerable symbol, in a message.",
+			ArgDoc:  "{\n\t// The go.mod file of the govulncheck result.\n\t\"URI\": string,\n\t// The ID of the vulnerability.\n\t\"OSV\": string,\n\t// The call stack, from the vulnerable symbol to the entry point.\n\t\"Trace\": []*golang.org/x/tools/gopls/internal/vulncheck/govulncheck.Frame,\n}",
+		},
 		{
 			Command:   "gopls.start_debugging",
 			Title:     "Start the gopls debug server",
@@ -1288,6 +1400,10 @@ var GeneratedAPIJSON = &APIJSON{
 			Name: "functionTypeParameters",
 			Doc:  "Enable/disable inlay hints for implicit type parameters on generic functions:\n```go\n\tmyFoo/*[int, string]*/(1, \"hello\")\n```",
 		},
+		{
+			Name: "gcDetails",
+			Doc:  "Enable/disable inlay hints for the optimization decisions of the compiler, such as escape analysis,\ninlining, bounds checks, and nil checks, as selected by the annotations setting:\n```go\n\tfunc f(x int) int {/* canInline(cost: 4)*/\n```\nThe package is compiled to compute them, so they are shown only for files without unsaved changes.",
+		},
 		{
 			Name: "parameterNames",
 			Doc:  "Enable/disable inlay hints for parameter names:\n```go\n\tparseInt(/* str: */ \"123\", /* radix: */ 8)\n```",
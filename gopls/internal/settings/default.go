@@ -47,6 +47,7 @@ func DefaultOptions(overrides ...func(*Options)) *Options {
 						protocol.RefactorRewrite:       true,
 						protocol.RefactorInline:        true,
 						protocol.RefactorExtract:       true,
+						protocol.SourceGenerateTest:    true,
 					},
 					file.Mod: {
 						protocol.SourceOrganizeImports: true,
@@ -85,10 +86,11 @@ func DefaultOptions(overrides ...func(*Options)) *Options {
 						LinksInHover: true,
 					},
 					NavigationOptions: NavigationOptions{
-						ImportShortcut: BothShortcuts,
-						SymbolMatcher:  SymbolFastFuzzy,
-						SymbolStyle:    DynamicSymbols,
-						SymbolScope:    AllSymbolScope,
+						ImportShortcut:       BothShortcuts,
+						SymbolMatcher:        SymbolFastFuzzy,
+						SymbolStyle:          DynamicSymbols,
+						SymbolScope:          AllSymbolScope,
+						DynamicCallHierarchy: DynamicCallsOff,
 					},
 					CompletionOptions: CompletionOptions{
 						Matcher:                        Fuzzy,
@@ -117,6 +119,7 @@ func DefaultOptions(overrides ...func(*Options)) *Options {
 				LinkifyShowMessage:          false,
 				IncludeReplaceInWorkspace:   false,
 				ZeroConfig:                  true,
+				PullDiagnostics:             false,
 			},
 			Hooks: Hooks{
 				URLRegexp:            urlRegexp(),
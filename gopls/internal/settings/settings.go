@@ -7,10 +7,12 @@ package settings
 import (
 	"context"
 	"fmt"
+	"go/token"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
+	"text/template"
 	"time"
 
 	"golang.org/x/tools/go/analysis"
@@ -132,6 +134,7 @@ type ClientOptions struct {
 	CompletionDeprecated                       bool
 	SupportedResourceOperations                []protocol.ResourceOperationKind
 	CodeActionResolveOptions                   []string
+	CompletionResolveOptions                   []string
 }
 
 // ServerOptions holds LSP-specific configuration that is provided by the
@@ -275,6 +278,27 @@ type CompletionOptions struct {
 	// such as "someSlice.sort!".
 	ExperimentalPostfixCompletions bool `status:"experimental"`
 
+	// PostfixSnippets defines additional postfix snippets, offered along
+	// with the predefined ones when experimentalPostfixCompletions is
+	// enabled. Each snippet is an object with a "label", which completes
+	// "x.label!", optional "details" shown by the client, and a "body",
+	// which is a text/template producing the snippet text, or nothing if
+	// the snippet does not apply to the selector expression.
+	//
+	// The template has access to the same facilities as the predefined
+	// snippets, such as .X, the text of the expression, .Type and .Kind,
+	// its type and the kind of its underlying type, .StmtOK, .Import,
+	// .VarName, .Placeholder, and .Cursor. For example:
+	//
+	// ```json5
+	// "postfixSnippets": [{
+	//   "label": "must",
+	//   "details": "panic on error",
+	//   "body": "{{if and .StmtOK (eq (.TypeName .Type) \"error\")}}if err := {{.X}}; err != nil {\n\tpanic(err)\n}{{end}}",
+	// }]
+	// ```
+	PostfixSnippets []PostfixSnippet `status:"experimental"`
+
 	// CompleteFunctionCalls enables function call completion.
 	//
 	// When completing a statement, or when a function return type matches the
@@ -283,6 +307,28 @@ type CompletionOptions struct {
 	CompleteFunctionCalls bool
 }
 
+// A PostfixSnippet is a user-defined postfix completion snippet.
+type PostfixSnippet struct {
+	Label   string `json:"label"`
+	Details string `json:"details,omitempty"`
+	Body    string `json:"body"`
+
+	tmpl *template.Template // parsed body
+}
+
+// Template returns the parsed template of the snippet's body.
+func (s PostfixSnippet) Template() *template.Template {
+	return s.tmpl
+}
+
+// ParsePostfixTemplate parses the body of a postfix snippet, providing
+// the functions available to all postfix snippet templates.
+func ParsePostfixTemplate(name, body string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"inc": func(i int) int { return i + 1 },
+	}).Parse(body)
+}
+
 type DocumentationOptions struct {
 	// HoverKind controls the information that appears in the hover text.
 	// SingleLine and Structured are intended for use only by authors of editor plugins.
@@ -338,13 +384,35 @@ type DiagnosticOptions struct {
 	// [Staticcheck's website](https://staticcheck.io/docs/checks/).
 	Staticcheck bool `status:"experimental"`
 
+	// AnalyzerPlugins specifies the main packages of additional analyzers
+	// to run on the open packages of the workspace, such as checkers built
+	// with the multichecker or unitchecker packages of go/analysis. Each
+	// entry is a package path or a relative path such as "./tools/check",
+	// resolved in the root directory of the view. Gopls builds each package
+	// and runs it as the vet tool of "go vet -json", so it must support the
+	// vet protocol, as multichecker and unitchecker do.
+	//
+	// The analyzers of a plugin may be disabled by name using the analyses
+	// setting. Their diagnostics are computed from the files saved on disk,
+	// so they are not reported for packages with unsaved changes.
+	AnalyzerPlugins []string `status:"experimental"`
+
 	// Annotations specifies the various kinds of optimization diagnostics
-	// that should be reported by the gc_details command.
+	// that should be reported by the gc_details command and the gcDetails
+	// inlay hints.
 	Annotations map[Annotation]bool `status:"experimental"`
 
 	// Vulncheck enables vulnerability scanning.
 	Vulncheck VulncheckMode `status:"experimental"`
 
+	// Deadcode enables diagnostics for functions of the workspace that
+	// are unreachable from the main function of any main package of the
+	// workspace, as computed by the deadcode command using Rapid Type
+	// Analysis. Each diagnostic offers a quick fix to delete the
+	// function. Only packages imported, directly or indirectly, by a
+	// main package of the workspace are reported.
+	Deadcode bool `status:"experimental"`
+
 	// DiagnosticsDelay controls the amount of time that gopls waits
 	// after the most recent file modification before computing deep diagnostics.
 	// Simple diagnostics (parsing and type-checking) are always run immediately
@@ -402,6 +470,15 @@ type NavigationOptions struct {
 	// searched, including dependencies; this is more expensive and may return
 	// unwanted results.
 	SymbolScope SymbolScope
+
+	// DynamicCallHierarchy controls whether call hierarchy requests
+	// report dynamic calls, that is, calls through interface methods and
+	// function values, in addition to static calls. The default value,
+	// "off", reports only static calls. The other values build the SSA
+	// form of the workspace packages, which is expensive, and compute a
+	// call graph with the specified algorithm. Dynamic calls are marked
+	// as such in the details of the reported items.
+	DynamicCallHierarchy DynamicCallHierarchy `status:"experimental"`
 }
 
 // UserOptions holds custom Gopls configuration (not part of the LSP) that is
@@ -564,6 +641,15 @@ type InternalOptions struct {
 	// dynamically creating build configurations for different modules,
 	// directories, and GOOS/GOARCH combinations to cover open files.
 	ZeroConfig bool
+
+	// PullDiagnostics enables support for pull diagnostics.
+	//
+	// When set, and the client declares support for pull diagnostics,
+	// gopls advertises the LSP 3.17 textDocument/diagnostic and
+	// workspace/diagnostic requests, which compute diagnostics on demand
+	// and report result IDs so that clients can avoid re-transferring
+	// unchanged results. Diagnostics are then no longer published.
+	PullDiagnostics bool
 }
 
 type SubdirWatchPatterns string
@@ -637,6 +723,20 @@ const (
 	AllSymbolScope SymbolScope = "all"
 )
 
+// A DynamicCallHierarchy selects the call graph algorithm used to find
+// dynamic calls for call hierarchy requests.
+type DynamicCallHierarchy string
+
+const (
+	// DynamicCallsOff reports only static calls.
+	DynamicCallsOff DynamicCallHierarchy = "off"
+	// DynamicCallsRTA finds dynamic calls using Rapid Type Analysis,
+	// which is faster but less precise than VTA.
+	DynamicCallsRTA DynamicCallHierarchy = "rta"
+	// DynamicCallsVTA finds dynamic calls using Variable Type Analysis.
+	DynamicCallsVTA DynamicCallHierarchy = "vta"
+)
+
 type HoverKind string
 
 const (
@@ -760,6 +860,11 @@ func (o *Options) ForClientCapabilities(clientName *protocol.ClientInfo, caps pr
 		o.CompletionDeprecated = true
 	}
 
+	// Check which completion item properties the client can resolve lazily.
+	if rs := caps.TextDocument.Completion.CompletionItem.ResolveSupport; rs != nil {
+		o.CompletionResolveOptions = rs.Properties
+	}
+
 	// Check if the client supports code actions resolving.
 	if caps.TextDocument.CodeAction.DataSupport && caps.TextDocument.CodeAction.ResolveSupport != nil {
 		o.CodeActionResolveOptions = caps.TextDocument.CodeAction.ResolveSupport.Properties
@@ -801,6 +906,8 @@ func (o *Options) Clone() *Options {
 	result.BuildFlags = copySlice(o.BuildFlags)
 	result.DirectoryFilters = copySlice(o.DirectoryFilters)
 	result.StandaloneTags = copySlice(o.StandaloneTags)
+	result.AnalyzerPlugins = copySlice(o.AnalyzerPlugins)
+	result.PostfixSnippets = append([]PostfixSnippet(nil), o.PostfixSnippets...)
 
 	copyAnalyzerMap := func(src map[string]*Analyzer) map[string]*Analyzer {
 		dst := make(map[string]*Analyzer)
@@ -942,6 +1049,9 @@ func (o *Options) set(name string, value interface{}, seen map[string]struct{})
 			o.Matcher = Matcher(s)
 		}
 
+	case "postfixSnippets":
+		result.setPostfixSnippets(&o.PostfixSnippets)
+
 	case "symbolMatcher":
 		if s, ok := result.asOneOf(
 			string(SymbolFuzzy),
@@ -969,6 +1079,15 @@ func (o *Options) set(name string, value interface{}, seen map[string]struct{})
 			o.SymbolScope = SymbolScope(s)
 		}
 
+	case "dynamicCallHierarchy":
+		if s, ok := result.asOneOf(
+			string(DynamicCallsOff),
+			string(DynamicCallsRTA),
+			string(DynamicCallsVTA),
+		); ok {
+			o.DynamicCallHierarchy = DynamicCallHierarchy(s)
+		}
+
 	case "hoverKind":
 		if s, ok := result.asOneOf(
 			string(NoDocumentation),
@@ -1008,6 +1127,9 @@ func (o *Options) set(name string, value interface{}, seen map[string]struct{})
 			o.Vulncheck = VulncheckMode(s)
 		}
 
+	case "deadcode":
+		result.setBool(&o.Deadcode)
+
 	case "codelenses", "codelens":
 		var lensOverrides map[string]bool
 		result.setBoolMap(&lensOverrides)
@@ -1035,6 +1157,9 @@ func (o *Options) set(name string, value interface{}, seen map[string]struct{})
 			}
 		}
 
+	case "analyzerPlugins":
+		result.setStringSlice(&o.AnalyzerPlugins)
+
 	case "local":
 		result.setString(&o.Local)
 
@@ -1167,6 +1292,9 @@ func (o *Options) set(name string, value interface{}, seen map[string]struct{})
 	case "zeroConfig":
 		result.setBool(&o.ZeroConfig)
 
+	case "pullDiagnostics":
+		result.setBool(&o.PullDiagnostics)
+
 	// Replaced settings.
 	case "experimentalDisabledAnalyses":
 		result.deprecated("analyses")
@@ -1317,6 +1445,53 @@ func (r *OptionResult) setAnnotationMap(bm *map[Annotation]bool) {
 	*bm = m
 }
 
+func (r *OptionResult) setPostfixSnippets(snippets *[]PostfixSnippet) {
+	list, ok := r.Value.([]interface{})
+	if !ok {
+		r.parseErrorf("invalid type %T, expect list", r.Value)
+		return
+	}
+	var result []PostfixSnippet
+	for i, elem := range list {
+		fields, ok := elem.(map[string]interface{})
+		if !ok {
+			r.parseErrorf("invalid element type %T, expect object", elem)
+			return
+		}
+		var snippet PostfixSnippet
+		for name, value := range fields {
+			s, ok := value.(string)
+			if !ok {
+				r.parseErrorf("snippet %d: invalid type %T for %q, expect string", i, value, name)
+				return
+			}
+			switch name {
+			case "label":
+				snippet.Label = s
+			case "details":
+				snippet.Details = s
+			case "body":
+				snippet.Body = s
+			default:
+				r.parseErrorf("snippet %d: unexpected field %q", i, name)
+				return
+			}
+		}
+		if !token.IsIdentifier(snippet.Label) {
+			r.parseErrorf("snippet %d: label %q is not an identifier", i, snippet.Label)
+			return
+		}
+		tmpl, err := ParsePostfixTemplate(snippet.Label, snippet.Body)
+		if err != nil {
+			r.parseErrorf("snippet %q: %v", snippet.Label, err)
+			return
+		}
+		snippet.tmpl = tmpl
+		result = append(result, snippet)
+	}
+	*snippets = result
+}
+
 func (r *OptionResult) asBoolMap() map[string]bool {
 	all, ok := r.Value.(map[string]interface{})
 	if !ok {
@@ -189,6 +189,33 @@ func TestSetOption(t *testing.T) {
 				return o.Vulncheck == ModeVulncheckImports
 			},
 		},
+		{
+			name: "postfixSnippets",
+			value: []interface{}{
+				map[string]interface{}{"label": "must", "details": "panic on error", "body": "{{.X}}"},
+			},
+			check: func(o Options) bool {
+				return len(o.PostfixSnippets) == 1 &&
+					o.PostfixSnippets[0].Label == "must" &&
+					o.PostfixSnippets[0].Template() != nil
+			},
+		},
+		{
+			name: "postfixSnippets",
+			value: []interface{}{
+				map[string]interface{}{"label": "must", "body": "{{if .X}}"},
+			},
+			wantError: true,
+			check:     func(o Options) bool { return o.PostfixSnippets == nil },
+		},
+		{
+			name: "postfixSnippets",
+			value: []interface{}{
+				map[string]interface{}{"label": "must!", "body": "{{.X}}"},
+			},
+			wantError: true,
+			check:     func(o Options) bool { return o.PostfixSnippets == nil },
+		},
 	}
 
 	for _, test := range tests {
@@ -10,6 +10,8 @@ import (
 	"fmt"
 	"go/scanner"
 	"go/token"
+	"go/types"
+	"regexp"
 	"strings"
 
 	"golang.org/x/tools/gopls/internal/cache"
@@ -24,6 +26,11 @@ type completer struct {
 	offset int // offset of the start of the Token
 	ctx    protocol.CompletionContext
 	syms   map[string]symbol
+
+	// types holds the types of the fields and methods referenced by
+	// the template, if the type of its data is known.
+	types *typeInfo
+	qf    types.Qualifier
 }
 
 func Completion(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, pos protocol.Position, context protocol.CompletionContext) (*protocol.CompletionList, error) {
@@ -53,6 +60,10 @@ func Completion(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, p
 		ctx:    context,
 		syms:   syms,
 	}
+	tp := completionParse(p, start, p.FromPosition(pos))
+	if info, dot := typeCheck(ctx, snapshot, fh, tp); info != nil {
+		c.types, c.qf = info, dot.qualifier()
+	}
 	return c.complete()
 }
 
@@ -142,6 +153,10 @@ func (c *completer) complete() (*protocol.CompletionList, error) {
 		}
 		return ans, nil
 	}
+	if items, ok := c.members(sofar, start); ok {
+		ans.Items = items
+		return ans, nil
+	}
 	if pattern[0] == '.' {
 		for _, s := range c.syms {
 			if s.kind == protocol.Method && weakMatch("."+s.name, pattern) > 0 {
@@ -190,6 +205,66 @@ func (c *completer) complete() (*protocol.CompletionList, error) {
 	return ans, nil
 }
 
+// selectorRe matches the selector expression before the cursor, such
+// as ".A.B", "$.A.", or "$x.A".
+var selectorRe = regexp.MustCompile(`(^|[^\w)$])((\$\w*)?(\.\w*)+)$`)
+
+// members returns the completions of the field or method selected by
+// the expression that ends sofar, at offset pos, from the fields and
+// methods of its type. It reports false if the type is unknown.
+func (c *completer) members(sofar []byte, pos int) ([]protocol.CompletionItem, bool) {
+	if c.types == nil {
+		return nil, false
+	}
+	m := selectorRe.FindSubmatch(sofar)
+	if m == nil {
+		return nil, false
+	}
+	parts := strings.Split(string(m[2]), ".")
+	var typ types.Type
+	switch base := parts[0]; base {
+	case "":
+		typ = c.types.dotAt(pos)
+	case "$":
+		typ = c.types.root
+	default:
+		typ = c.types.vars[base]
+	}
+	for _, name := range parts[1 : len(parts)-1] {
+		if typ == nil {
+			return nil, false
+		}
+		var err string
+		_, typ, err = lookupField(typ, name, c.qf)
+		if err != "" {
+			return nil, false
+		}
+	}
+	if typ == nil {
+		return nil, false
+	}
+	if _, ok := typ.Underlying().(*types.Interface); ok {
+		return nil, false // the dynamic type is unknown
+	}
+	prefix := strings.ToLower(parts[len(parts)-1])
+	items := []protocol.CompletionItem{}
+	for _, obj := range members(typ) {
+		if !strings.HasPrefix(strings.ToLower(obj.Name()), prefix) {
+			continue
+		}
+		kind := protocol.FieldCompletion
+		if _, ok := obj.(*types.Func); ok {
+			kind = protocol.MethodCompletion
+		}
+		items = append(items, protocol.CompletionItem{
+			Label:  obj.Name(),
+			Kind:   kind,
+			Detail: describe(obj, c.qf),
+		})
+	}
+	return items, true
+}
+
 // version of c.analyze that uses go/scanner.
 func scan(buf []byte) []string {
 	fset := token.NewFileSet()
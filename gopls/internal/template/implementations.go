@@ -23,10 +23,49 @@ var errRe = regexp.MustCompile(`template.*:(\d+): (.*)`)
 // Diagnostics returns parse errors. There is only one per file.
 // The errors are not always helpful. For instance { {end}}
 // will likely point to the end of the file.
-func Diagnostics(snapshot *cache.Snapshot) map[protocol.DocumentURI][]*cache.Diagnostic {
+//
+// For a file without parse errors that is executed by Go code, it
+// returns instead the references to fields and methods that do not
+// exist in the type of its data, provided that the type is already
+// known (see DotTypesKnown): Diagnostics does not wait for the Go code
+// to be type-checked.
+func Diagnostics(ctx context.Context, snapshot *cache.Snapshot) map[protocol.DocumentURI][]*cache.Diagnostic {
 	diags := make(map[protocol.DocumentURI][]*cache.Diagnostic)
 	for uri, fh := range snapshot.Templates() {
 		diags[uri] = diagnoseOne(fh)
+		if len(diags[uri]) == 0 {
+			diags[uri] = diagnoseTypes(snapshot, fh)
+		}
+	}
+	return diags
+}
+
+// diagnoseTypes reports the references to fields and methods by the
+// template that do not exist in the type of its data, if the type is
+// known. It does not wait for the dot types to be computed.
+func diagnoseTypes(snapshot *cache.Snapshot, fh file.Handle) []*cache.Diagnostic {
+	dot := cachedDotTypes(snapshot)[fh.URI()]
+	if dot == nil {
+		return nil
+	}
+	buf, err := fh.Content()
+	if err != nil {
+		return nil
+	}
+	p := parseBuffer(buf)
+	if p.ParseErr != nil {
+		return nil
+	}
+	info := checkTypes(p, dot.typ, dot.qualifier())
+	var diags []*cache.Diagnostic
+	for _, e := range info.errors {
+		diags = append(diags, &cache.Diagnostic{
+			URI:      fh.URI(),
+			Range:    p.Range(e.start, e.length),
+			Severity: protocol.SeverityError,
+			Source:   cache.TemplateError,
+			Message:  e.msg,
+		})
 	}
 	return diags
 }
@@ -81,9 +120,20 @@ func diagnoseOne(fh file.Handle) []*cache.Diagnostic {
 // Definition finds the definitions of the symbol at loc. It
 // does not understand scoping (if any) in templates. This code is
 // for definitions, type definitions, and implementations.
-// Results only for variables and templates.
-func Definition(snapshot *cache.Snapshot, fh file.Handle, loc protocol.Position) ([]protocol.Location, error) {
-	x, _, err := symAtPosition(fh, loc)
+// Results only for variables and templates, and for fields and
+// methods of the data of a template executed by Go code, which are
+// declared in Go.
+func Definition(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, loc protocol.Position) ([]protocol.Location, error) {
+	x, p, err := symAtPosition(fh, loc)
+	if p != nil {
+		if ref, dot := fieldRefAt(ctx, snapshot, fh, p, loc); ref != nil {
+			objLoc, err := objectLocation(ctx, snapshot, dot.pkg, ref.obj)
+			if err != nil {
+				return nil, err
+			}
+			return []protocol.Location{objLoc}, nil
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +154,17 @@ func Definition(snapshot *cache.Snapshot, fh file.Handle, loc protocol.Position)
 
 func Hover(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, position protocol.Position) (*protocol.Hover, error) {
 	sym, p, err := symAtPosition(fh, position)
+	if p != nil {
+		if ref, dot := fieldRefAt(ctx, snapshot, fh, p, position); ref != nil {
+			return &protocol.Hover{
+				Range: p.Range(ref.start, ref.length),
+				Contents: protocol.MarkupContent{
+					Kind:  protocol.Markdown,
+					Value: fmt.Sprintf("```go\n%s\n```", describe(ref.obj, dot.qualifier())),
+				},
+			}, nil
+		}
+	}
 	if sym == nil || err != nil {
 		return nil, err
 	}
@@ -0,0 +1,816 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+// This file infers the type of the data with which Go code executes
+// each template file, and uses it to check and describe the fields
+// and methods referenced by the template.
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template/parse"
+	"unicode/utf8"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/types/typeutil"
+	"golang.org/x/tools/gopls/internal/cache"
+	"golang.org/x/tools/gopls/internal/file"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/internal/event"
+)
+
+// A dotType is the type of the data with which a template file is
+// executed, and the package of the Go code that executes it.
+type dotType struct {
+	typ types.Type
+	pkg *cache.Package
+}
+
+// qualifier returns the qualifier for types in messages about the
+// template, relative to the package that executes it.
+func (d *dotType) qualifier() types.Qualifier {
+	return types.RelativeTo(d.pkg.GetTypes())
+}
+
+// dotTypesKey is the key of the dot types in the program results of
+// a snapshot.
+const dotTypesKey = "template dot types"
+
+// DotTypesKnown reports whether the types of the data of the template
+// files of the snapshot have been computed, by ComputeDotTypes or a
+// request that needs them. Until then, Diagnostics does not check the
+// fields and methods referenced by the templates.
+func DotTypesKnown(snapshot *cache.Snapshot) bool {
+	if len(snapshot.Templates()) == 0 {
+		return true
+	}
+	_, ok := snapshot.CachedProgramResult(dotTypesKey)
+	return ok
+}
+
+// ComputeDotTypes computes the types of the data of the template files
+// of the snapshot, which requires type-checking the workspace packages
+// that may execute them.
+func ComputeDotTypes(ctx context.Context, snapshot *cache.Snapshot) error {
+	_, err := dotTypes(ctx, snapshot)
+	return err
+}
+
+// cachedDotTypes returns the dot types of the snapshot, if they are
+// known.
+func cachedDotTypes(snapshot *cache.Snapshot) map[protocol.DocumentURI]*dotType {
+	v, ok := snapshot.CachedProgramResult(dotTypesKey)
+	if !ok {
+		return nil
+	}
+	return v.(map[protocol.DocumentURI]*dotType)
+}
+
+// dotTypes returns the type of the data with which each template file
+// of the snapshot is executed by the Go code of the workspace, as found
+// by findBindings. Files that are executed with several different
+// types, or not at all, are absent. The result is computed at most
+// once per snapshot.
+func dotTypes(ctx context.Context, snapshot *cache.Snapshot) (map[protocol.DocumentURI]*dotType, error) {
+	v, err := snapshot.ProgramResult(ctx, dotTypesKey, func(ctx context.Context, snapshot *cache.Snapshot) (interface{}, error) {
+		return computeDotTypes(ctx, snapshot)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[protocol.DocumentURI]*dotType), nil
+}
+
+// computeDotTypes computes the result of dotTypes.
+func computeDotTypes(ctx context.Context, snapshot *cache.Snapshot) (map[protocol.DocumentURI]*dotType, error) {
+	tmpls := snapshot.Templates()
+	if len(tmpls) == 0 {
+		return nil, nil
+	}
+	var tmplFiles []string
+	for uri := range tmpls {
+		tmplFiles = append(tmplFiles, uri.Path())
+	}
+	sort.Strings(tmplFiles)
+
+	// Only the packages that import a template package can execute
+	// templates.
+	workspace, err := snapshot.WorkspaceMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ids []cache.PackageID
+	for _, mp := range workspace {
+		if mp.ForTest != "" || len(mp.CompiledGoFiles) == 0 {
+			continue
+		}
+		if _, ok := mp.DepsByPkgPath["text/template"]; ok {
+			ids = append(ids, mp.ID)
+		} else if _, ok := mp.DepsByPkgPath["html/template"]; ok {
+			ids = append(ids, mp.ID)
+		}
+	}
+	pkgs, err := snapshot.TypeCheck(ctx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[protocol.DocumentURI]*dotType)
+	ambiguous := make(map[protocol.DocumentURI]bool)
+	for _, pkg := range pkgs {
+		mp := pkg.Metadata()
+		// Relative file names are resolved against the directory of
+		// the package, or else the root of its module, which are the
+		// usual working directories of programs and their tests.
+		dirs := []string{filepath.Dir(mp.CompiledGoFiles[0].Path())}
+		if mp.Module != nil && mp.Module.Dir != "" && mp.Module.Dir != dirs[0] {
+			dirs = append(dirs, mp.Module.Dir)
+		}
+		bindings := findBindings(pkg.GetTypesInfo(), pkg.GetSyntax(), dirs, tmplFiles)
+		for filename, typ := range bindings {
+			uri := protocol.URIFromPath(filename)
+			if prev, ok := result[uri]; ok && !types.Identical(prev.typ, typ) {
+				ambiguous[uri] = true
+			}
+			result[uri] = &dotType{typ: typ, pkg: pkg}
+		}
+	}
+	for uri := range ambiguous {
+		delete(result, uri)
+	}
+
+	return result, nil
+}
+
+// dotTypeOf returns the dot type of the specified template file, or
+// nil if it is unknown.
+func dotTypeOf(ctx context.Context, snapshot *cache.Snapshot, uri protocol.DocumentURI) *dotType {
+	dots, err := dotTypes(ctx, snapshot)
+	if err != nil {
+		event.Error(ctx, "computing template dot types", err)
+		return nil
+	}
+	return dots[uri]
+}
+
+// findBindings returns the type of the data with which the Go files
+// of a package execute each template file, keyed by the name of the
+// file, which is one of tmplFiles.
+//
+// A template is found by following the *Template values of Execute and
+// ExecuteTemplate calls to the ParseFiles, ParseGlob, and ParseFS calls
+// that create them, through variables, template.Must, and the methods
+// that return their receiver. Relative file names and patterns are
+// resolved against each of dirs.
+func findBindings(info *types.Info, files []*ast.File, dirs []string, tmplFiles []string) map[string]types.Type {
+	// Record the values assigned to each variable.
+	assigned := make(map[*types.Var][]ast.Expr)
+	assign := func(lhs []*ast.Ident, rhs []ast.Expr) {
+		if len(rhs) != len(lhs) && len(rhs) != 1 {
+			return
+		}
+		for i, id := range lhs {
+			v, ok := info.ObjectOf(id).(*types.Var)
+			if !ok {
+				continue
+			}
+			if len(rhs) == len(lhs) {
+				assigned[v] = append(assigned[v], rhs[i])
+			} else if i == 0 {
+				// t, err := template.ParseFiles(...)
+				assigned[v] = append(assigned[v], rhs[0])
+			}
+		}
+	}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				var lhs []*ast.Ident
+				for _, expr := range n.Lhs {
+					var id *ast.Ident
+					switch expr := astutil.Unparen(expr).(type) {
+					case *ast.Ident:
+						id = expr
+					case *ast.SelectorExpr: // a struct field
+						id = expr.Sel
+					default:
+						id = &ast.Ident{Name: "_"}
+					}
+					lhs = append(lhs, id)
+				}
+				assign(lhs, n.Rhs)
+			case *ast.ValueSpec:
+				if len(n.Values) > 0 {
+					assign(n.Names, n.Values)
+				}
+			case *ast.KeyValueExpr:
+				if id, ok := n.Key.(*ast.Ident); ok {
+					if v, ok := info.Uses[id].(*types.Var); ok && v.IsField() {
+						assign([]*ast.Ident{id}, []ast.Expr{n.Value})
+					}
+				}
+			}
+			return true
+		})
+	}
+
+	// templateMethod returns the name of the function or method of a
+	// template package called by call, and its receiver, if any.
+	templateMethod := func(call *ast.CallExpr) (name string, recv ast.Expr) {
+		fn, ok := typeutil.Callee(info, call).(*types.Func)
+		if !ok || fn.Pkg() == nil {
+			return "", nil
+		}
+		if path := fn.Pkg().Path(); path != "text/template" && path != "html/template" {
+			return "", nil
+		}
+		if fn.Type().(*types.Signature).Recv() != nil {
+			if sel, ok := astutil.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+				recv = sel.X
+			}
+		}
+		return fn.Name(), recv
+	}
+
+	// matches returns the template files named by the file name or
+	// pattern arg, in order.
+	matches := func(arg ast.Expr, glob bool) []string {
+		tv, ok := info.Types[arg]
+		if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+			return nil
+		}
+		name := filepath.FromSlash(constant.StringVal(tv.Value))
+		var res []string
+		for _, dir := range dirs {
+			pattern := name
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(dir, pattern)
+			}
+			for _, filename := range tmplFiles {
+				if filename == pattern {
+					res = append(res, filename)
+				} else if ok, _ := filepath.Match(pattern, filename); ok && glob {
+					res = append(res, filename)
+				}
+			}
+			if len(res) > 0 {
+				break
+			}
+		}
+		return res
+	}
+
+	// templates returns the name of the template denoted by expr, if
+	// known, and the files parsed into it and its associated templates.
+	var templates func(expr ast.Expr, seen map[*types.Var]bool) (string, []string)
+	templates = func(expr ast.Expr, seen map[*types.Var]bool) (string, []string) {
+		expr = astutil.Unparen(expr)
+		if id, ok := expr.(*ast.SelectorExpr); ok {
+			expr = id.Sel
+		}
+		if id, ok := expr.(*ast.Ident); ok {
+			v, ok := info.Uses[id].(*types.Var)
+			if !ok || seen[v] {
+				return "", nil
+			}
+			seen[v] = true
+			var (
+				name  string
+				files []string
+			)
+			for _, rhs := range assigned[v] {
+				n, fs := templates(rhs, seen)
+				if name == "" {
+					name = n
+				}
+				files = append(files, fs...)
+			}
+			return name, files
+		}
+
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return "", nil
+		}
+		method, recv := templateMethod(call)
+		switch method {
+		case "Must":
+			if len(call.Args) == 1 {
+				return templates(call.Args[0], seen)
+			}
+		case "New":
+			if recv == nil && len(call.Args) == 1 {
+				if tv, ok := info.Types[call.Args[0]]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+					return constant.StringVal(tv.Value), nil
+				}
+			}
+		case "Funcs", "Delims", "Option":
+			if recv != nil {
+				return templates(recv, seen)
+			}
+		case "ParseFiles", "ParseGlob", "ParseFS":
+			var name string
+			var files []string
+			if recv != nil {
+				name, files = templates(recv, seen)
+			}
+			args := call.Args
+			if method == "ParseFS" && len(args) > 0 {
+				args = args[1:]
+			}
+			var parsed []string
+			for _, arg := range args {
+				parsed = append(parsed, matches(arg, method != "ParseFiles")...)
+			}
+			// The template is named after the first file parsed,
+			// unless it already has a name.
+			if name == "" && len(parsed) > 0 {
+				name = filepath.Base(parsed[0])
+			}
+			return name, append(files, parsed...)
+		}
+		return "", nil
+	}
+
+	bindings := make(map[string]types.Type)
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			method, recv := templateMethod(call)
+			if recv == nil || len(call.Args) == 0 {
+				return true
+			}
+			var name string
+			switch method {
+			case "Execute":
+				if len(call.Args) != 2 {
+					return true
+				}
+				name, _ = templates(recv, make(map[*types.Var]bool))
+			case "ExecuteTemplate":
+				if len(call.Args) != 3 {
+					return true
+				}
+				tv, ok := info.Types[call.Args[1]]
+				if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+					return true
+				}
+				name = constant.StringVal(tv.Value)
+			default:
+				return true
+			}
+			_, files := templates(recv, make(map[*types.Var]bool))
+			if name == "" && len(files) > 0 {
+				name = filepath.Base(files[0])
+			}
+			data := call.Args[len(call.Args)-1]
+			typ := info.TypeOf(data)
+			if typ == nil || types.Identical(typ, types.Typ[types.UntypedNil]) {
+				return true
+			}
+			// The template executed is the one defined by the file
+			// of the same name.
+			for _, filename := range files {
+				if filepath.Base(filename) == name {
+					bindings[filename] = typ
+				}
+			}
+			return true
+		})
+	}
+	return bindings
+}
+
+// typeInfo holds the result of checking the fields and methods
+// referenced by a template against the type of its data.
+type typeInfo struct {
+	root   types.Type
+	refs   []fieldRef            // resolved references, in order
+	scopes []dotScope            // extents of the values of dot, outermost first
+	vars   map[string]types.Type // types of variables, by name
+	errors []fieldError          // references that cannot be resolved
+}
+
+// A fieldRef is a reference to a field or method by a template.
+type fieldRef struct {
+	start, length int // as for symbol
+	obj           types.Object
+}
+
+// A dotScope is an extent of a template in which dot has a given type.
+type dotScope struct {
+	start, end int // byte offsets
+	typ        types.Type
+}
+
+// A fieldError is a reference to a field or method that does not exist.
+type fieldError struct {
+	start, length int // as for symbol
+	msg           string
+}
+
+// checkTypes resolves the references to fields and methods by the
+// template p, executed with data of type dot. Templates defined by
+// {{define}} or {{block}} are not checked, as their data is unknown.
+func checkTypes(p *Parsed, dot types.Type, qf types.Qualifier) *typeInfo {
+	c := &typeChecker{
+		p:  p,
+		qf: qf,
+		info: &typeInfo{
+			root: dot,
+			vars: make(map[string]types.Type),
+		},
+	}
+	for _, t := range p.named {
+		if t.Name() == "" && t.Root != nil {
+			c.info.scopes = append(c.info.scopes, dotScope{start: 0, end: len(p.buf), typ: dot})
+			c.list(t.Root, dot, len(p.buf))
+		}
+	}
+	sort.Slice(c.info.refs, func(i, j int) bool { return c.info.refs[i].start < c.info.refs[j].start })
+	return c.info
+}
+
+type typeChecker struct {
+	p    *Parsed
+	qf   types.Qualifier
+	info *typeInfo
+}
+
+// list checks the nodes of list, whose extent ends at end.
+func (c *typeChecker) list(list *parse.ListNode, dot types.Type, end int) {
+	if list == nil {
+		return
+	}
+	c.info.scopes = append(c.info.scopes, dotScope{start: int(list.Pos), end: end, typ: dot})
+	for i, n := range list.Nodes {
+		nend := end
+		if i+1 < len(list.Nodes) {
+			nend = int(list.Nodes[i+1].Position())
+		}
+		c.node(n, dot, nend)
+	}
+}
+
+func (c *typeChecker) node(n parse.Node, dot types.Type, end int) {
+	switch n := n.(type) {
+	case *parse.ActionNode:
+		c.pipe(n.Pipe, dot, true)
+	case *parse.IfNode:
+		c.branch(&n.BranchNode, dot, end)
+	case *parse.WithNode:
+		c.branch(&n.BranchNode, dot, end)
+	case *parse.RangeNode:
+		c.branch(&n.BranchNode, dot, end)
+	case *parse.TemplateNode:
+		c.pipe(n.Pipe, dot, true)
+	case *parse.ListNode:
+		c.list(n, dot, end)
+	}
+}
+
+func (c *typeChecker) branch(n *parse.BranchNode, dot types.Type, end int) {
+	elseStart := end
+	if n.ElseList != nil {
+		elseStart = int(n.ElseList.Pos)
+	}
+	inner := dot
+	switch n.NodeType {
+	case parse.NodeIf:
+		c.pipe(n.Pipe, dot, true)
+	case parse.NodeWith:
+		inner = c.pipe(n.Pipe, dot, true)
+	case parse.NodeRange:
+		key, elem := rangeTypes(c.pipe(n.Pipe, dot, false))
+		inner = elem
+		if n.Pipe != nil {
+			switch len(n.Pipe.Decl) {
+			case 1:
+				c.info.vars[n.Pipe.Decl[0].Ident[0]] = elem
+			case 2:
+				c.info.vars[n.Pipe.Decl[0].Ident[0]] = key
+				c.info.vars[n.Pipe.Decl[1].Ident[0]] = elem
+			}
+		}
+	}
+	c.list(n.List, inner, elseStart)
+	c.list(n.ElseList, dot, end)
+}
+
+// pipe checks a pipeline and returns its type, if known, recording the
+// types of the variables it declares if decl is set.
+func (c *typeChecker) pipe(pipe *parse.PipeNode, dot types.Type, decl bool) types.Type {
+	if pipe == nil {
+		return nil
+	}
+	var typ types.Type
+	for _, cmd := range pipe.Cmds {
+		typ = c.command(cmd, dot)
+	}
+	if decl && len(pipe.Decl) == 1 {
+		c.info.vars[pipe.Decl[0].Ident[0]] = typ
+	}
+	return typ
+}
+
+func (c *typeChecker) command(cmd *parse.CommandNode, dot types.Type) types.Type {
+	var typ types.Type
+	for i, arg := range cmd.Args {
+		t := c.arg(arg, dot)
+		if i == 0 {
+			typ = t
+		}
+	}
+	if len(cmd.Args) > 0 {
+		if _, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+			return nil // the result of a function
+		}
+	}
+	return typ
+}
+
+func (c *typeChecker) arg(n parse.Node, dot types.Type) types.Type {
+	switch n := n.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return c.chain(dot, n.Ident, c.p.fields(n.Ident, n))
+	case *parse.VariableNode:
+		base := c.info.root
+		if n.Ident[0] != "$" {
+			base = c.info.vars[n.Ident[0]]
+		}
+		return c.chain(base, n.Ident[1:], c.variableFields(n))
+	case *parse.ChainNode:
+		return c.chain(c.arg(n.Node, dot), n.Field, c.p.fields(n.Field, n))
+	case *parse.PipeNode:
+		return c.pipe(n, dot, true)
+	}
+	return nil
+}
+
+// variableFields returns the symbols of the fields selected from the
+// variable of n, such as Y and Z in $x.Y.Z.
+func (c *typeChecker) variableFields(n *parse.VariableNode) []symbol {
+	// The position may follow the variable name (go.dev/issue/43388).
+	start := int(n.Pos) - len(n.Ident[0]) - 1
+	if start < 0 {
+		start = 0
+	}
+	ix := bytes.Index(c.p.buf[start:], []byte(strings.Join(n.Ident, ".")))
+	if ix < 0 {
+		return nil
+	}
+	at := start + ix + len(n.Ident[0])
+	var syms []symbol
+	for _, f := range n.Ident[1:] {
+		at++ // .
+		syms = append(syms, symbol{name: f, kind: protocol.Method, start: at, length: utf8.RuneCountInString(f)})
+		at += len(f)
+	}
+	return syms
+}
+
+// chain resolves the fields or methods names, selected in turn from
+// a value of type typ, and returns the type of the last, if known.
+// The symbols, if any, give the positions of the names.
+func (c *typeChecker) chain(typ types.Type, names []string, syms []symbol) types.Type {
+	for i, name := range names {
+		if typ == nil {
+			return nil
+		}
+		obj, res, err := lookupField(typ, name, c.qf)
+		if i < len(syms) && syms[i].name == name {
+			if err != "" {
+				c.info.errors = append(c.info.errors, fieldError{start: syms[i].start, length: syms[i].length, msg: err})
+			} else if obj != nil {
+				c.info.refs = append(c.info.refs, fieldRef{start: syms[i].start, length: syms[i].length, obj: obj})
+			}
+		}
+		if err != "" {
+			return nil
+		}
+		typ = res
+	}
+	return typ
+}
+
+// lookupField returns the field or method name of a value of type typ,
+// and the type of its value, or an error message if there is none.
+// The object and type are nil if unknown, such as for interface types.
+func lookupField(typ types.Type, name string, qf types.Qualifier) (types.Object, types.Type, string) {
+	switch u := typ.Underlying().(type) {
+	case *types.Map:
+		if b, ok := u.Key().Underlying().(*types.Basic); ok && b.Info()&types.IsString != 0 {
+			return nil, u.Elem(), ""
+		}
+		return nil, nil, ""
+	case *types.Interface:
+		if obj, _, _ := types.LookupFieldOrMethod(typ, true, nil, name); obj != nil {
+			return obj, resultType(obj), ""
+		}
+		return nil, nil, "" // the dynamic type is unknown
+	case *types.Pointer:
+		if _, ok := u.Elem().Underlying().(*types.Interface); ok {
+			return nil, nil, ""
+		}
+	}
+	if !token.IsExported(name) {
+		return nil, nil, fmt.Sprintf("%s is an unexported field or method of type %s", name, types.TypeString(typ, qf))
+	}
+	obj, _, _ := types.LookupFieldOrMethod(typ, true, nil, name)
+	if obj == nil {
+		return nil, nil, fmt.Sprintf("can't evaluate field %s in type %s", name, types.TypeString(typ, qf))
+	}
+	return obj, resultType(obj), ""
+}
+
+// resultType returns the type of the value of the field or method obj.
+func resultType(obj types.Object) types.Type {
+	switch obj := obj.(type) {
+	case *types.Var:
+		return obj.Type()
+	case *types.Func:
+		if res := obj.Type().(*types.Signature).Results(); res.Len() > 0 {
+			return res.At(0).Type()
+		}
+	}
+	return nil
+}
+
+// rangeTypes returns the key and element types of a range over a value
+// of type typ, if known.
+func rangeTypes(typ types.Type) (key, elem types.Type) {
+	if typ == nil {
+		return nil, nil
+	}
+	switch u := typ.Underlying().(type) {
+	case *types.Slice:
+		return types.Typ[types.Int], u.Elem()
+	case *types.Array:
+		return types.Typ[types.Int], u.Elem()
+	case *types.Map:
+		return u.Key(), u.Elem()
+	case *types.Chan:
+		return u.Elem(), u.Elem()
+	case *types.Pointer:
+		if a, ok := u.Elem().Underlying().(*types.Array); ok {
+			return types.Typ[types.Int], a.Elem()
+		}
+	case *types.Basic:
+		if u.Info()&types.IsInteger != 0 {
+			return typ, typ
+		}
+	}
+	return nil, nil
+}
+
+// dotAt returns the type of dot at the offset pos, if known.
+func (info *typeInfo) dotAt(pos int) types.Type {
+	var typ types.Type
+	for _, s := range info.scopes {
+		if s.start <= pos && pos <= s.end {
+			typ = s.typ // inner scopes follow outer ones
+		}
+	}
+	return typ
+}
+
+// refAt returns the reference to a field or method at the offset pos,
+// if any.
+func (info *typeInfo) refAt(pos int) *fieldRef {
+	for i, ref := range info.refs {
+		if ref.start <= pos && pos < ref.start+ref.length {
+			return &info.refs[i]
+		}
+	}
+	return nil
+}
+
+// members returns the exported fields and methods of a value of type
+// typ, with those of embedded fields, that a template may reference.
+func members(typ types.Type) []types.Object {
+	var objs []types.Object
+	seen := make(map[string]bool)
+	add := func(obj types.Object) {
+		if obj.Exported() && !seen[obj.Name()] {
+			seen[obj.Name()] = true
+			objs = append(objs, obj)
+		}
+	}
+	var fields func(typ types.Type, depth int)
+	fields = func(typ types.Type, depth int) {
+		if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+		s, ok := typ.Underlying().(*types.Struct)
+		if !ok || depth > 3 {
+			return
+		}
+		for i := 0; i < s.NumFields(); i++ {
+			add(s.Field(i))
+		}
+		for i := 0; i < s.NumFields(); i++ {
+			if f := s.Field(i); f.Embedded() {
+				fields(f.Type(), depth+1)
+			}
+		}
+	}
+	fields(typ, 0)
+	for _, sel := range typeutil.IntuitiveMethodSet(typ, nil) {
+		add(sel.Obj())
+	}
+	return objs
+}
+
+// completionParse returns p, the parse of a template file being
+// completed at the token that starts at offset start, or if it has
+// errors, the parse of the file without that token, whose text is
+// most likely incomplete.
+func completionParse(p *Parsed, start, pos int) *Parsed {
+	if p.ParseErr == nil {
+		return p
+	}
+	end := pos
+	for _, tk := range p.tokens {
+		if tk.Start == start {
+			end = tk.End
+		}
+	}
+	buf := append([]byte(nil), p.buf...)
+	for i := start; i < end && i < len(buf); i++ {
+		if buf[i] != '\n' {
+			buf[i] = ' '
+		}
+	}
+	return parseBuffer(buf)
+}
+
+// typeCheck returns the result of checking the template file fh,
+// parsed as p, against the type of the data with which it is
+// executed, or nil if that is unknown.
+func typeCheck(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, p *Parsed) (*typeInfo, *dotType) {
+	if p.ParseErr != nil {
+		return nil, nil
+	}
+	dot := dotTypeOf(ctx, snapshot, fh.URI())
+	if dot == nil {
+		return nil, nil
+	}
+	return checkTypes(p, dot.typ, dot.qualifier()), dot
+}
+
+// fieldRefAt returns the reference to a field or method of the data
+// of the template file fh, parsed as p, at pos, if its type is known.
+func fieldRefAt(ctx context.Context, snapshot *cache.Snapshot, fh file.Handle, p *Parsed, pos protocol.Position) (*fieldRef, *dotType) {
+	info, dot := typeCheck(ctx, snapshot, fh, p)
+	if info == nil {
+		return nil, nil
+	}
+	ref := info.refAt(p.FromPosition(pos))
+	if ref == nil {
+		return nil, nil
+	}
+	return ref, dot
+}
+
+// objectLocation returns the location of the declaration of obj, a
+// field or method of a type used by the Go package pkg.
+func objectLocation(ctx context.Context, snapshot *cache.Snapshot, pkg *cache.Package, obj types.Object) (protocol.Location, error) {
+	tokFile := pkg.FileSet().File(obj.Pos())
+	if tokFile == nil {
+		return protocol.Location{}, fmt.Errorf("no file for %s", obj.Name())
+	}
+	fh, err := snapshot.ReadFile(ctx, protocol.URIFromPath(tokFile.Name()))
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	content, err := fh.Content()
+	if err != nil {
+		return protocol.Location{}, err
+	}
+	m := protocol.NewMapper(fh.URI(), content)
+	return m.PosLocation(tokFile, obj.Pos(), obj.Pos()+token.Pos(len(obj.Name())))
+}
+
+// describe returns the declaration of the field or method obj, for
+// hover and completion.
+func describe(obj types.Object, qf types.Qualifier) string {
+	s := types.ObjectString(obj, qf)
+	return strings.TrimPrefix(s, "field ")
+}
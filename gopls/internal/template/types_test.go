@@ -0,0 +1,204 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package template
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+)
+
+const typesSrc = `
+package p
+
+import (
+	"html/template"
+	"os"
+)
+
+type Page struct {
+	Title string
+	Items []Item
+	Meta  map[string]int
+	Any   interface{}
+}
+
+type Item struct{ Name string }
+
+func (Item) Price() int { return 0 }
+
+var tmpl = template.Must(template.ParseFiles("page.tmpl", "item.tmpl"))
+
+type server struct{ t *template.Template }
+
+func run() {
+	tmpl.Execute(os.Stdout, Page{})
+	tmpl.ExecuteTemplate(os.Stdout, "item.tmpl", Item{})
+
+	t, _ := template.New("a.tmpl").ParseGlob("other/*.tmpl")
+	t.Execute(os.Stdout, &Page{})
+
+	s := server{t: template.Must(template.ParseFiles("b.tmpl"))}
+	s.t.Execute(os.Stdout, 1)
+}
+`
+
+// typeCheckSource returns the package and type information of the Go
+// package in src.
+func typeCheckSource(t *testing.T, src string) (*types.Package, *types.Info, []*ast.File) {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := conf.Check("p", fset, []*ast.File{f}, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg, info, []*ast.File{f}
+}
+
+func TestFindBindings(t *testing.T) {
+	pkg, info, files := typeCheckSource(t, typesSrc)
+
+	dir := filepath.FromSlash("/w")
+	var tmplFiles []string
+	for _, name := range []string{"page.tmpl", "item.tmpl", "other/a.tmpl", "other/c.tmpl", "b.tmpl", "unused.tmpl"} {
+		tmplFiles = append(tmplFiles, filepath.Join(dir, filepath.FromSlash(name)))
+	}
+	bindings := findBindings(info, files, []string{dir}, tmplFiles)
+
+	got := make(map[string]string)
+	for filename, typ := range bindings {
+		rel, _ := filepath.Rel(dir, filename)
+		got[filepath.ToSlash(rel)] = types.TypeString(typ, types.RelativeTo(pkg))
+	}
+	want := map[string]string{
+		"page.tmpl":    "Page",
+		"item.tmpl":    "Item",
+		"other/a.tmpl": "*Page",
+		"b.tmpl":       "int",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got bindings %v, want %v", got, want)
+	}
+	for name, typ := range want {
+		if got[name] != typ {
+			t.Errorf("binding of %s: got %q, want %q", name, got[name], typ)
+		}
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	pkg, _, _ := typeCheckSource(t, typesSrc)
+	page := pkg.Scope().Lookup("Page").Type()
+	qf := types.RelativeTo(pkg)
+
+	const buf = `{{.Title}} {{.Nope}} {{.Meta.x}} {{.Any.Foo}}
+{{range .Items}}{{.Name}} {{.Price}} {{.Title}}{{end}}
+{{with $p := .}}{{$p.Title}} {{$.Items}}{{end}}
+{{range $i, $it := .Items}}{{$it.Name}}{{end}}`
+	p := parseBuffer([]byte(buf))
+	if p.ParseErr != nil {
+		t.Fatal(p.ParseErr)
+	}
+	info := checkTypes(p, page, qf)
+
+	var refs []string
+	for _, ref := range info.refs {
+		refs = append(refs, string(p.buf[ref.start:ref.start+ref.length])+" "+describe(ref.obj, qf))
+	}
+	wantRefs := []string{
+		"Title Title string",
+		"Meta Meta map[string]int",
+		"Any Any interface{}",
+		"Items Items []Item",
+		"Name Name string",
+		"Price func (Item).Price() int",
+		"Title Title string",
+		"Items Items []Item",
+		"Items Items []Item",
+		"Name Name string",
+	}
+	if strings.Join(refs, "\n") != strings.Join(wantRefs, "\n") {
+		t.Errorf("got refs:\n%s\nwant:\n%s", strings.Join(refs, "\n"), strings.Join(wantRefs, "\n"))
+	}
+
+	var errors []string
+	for _, e := range info.errors {
+		errors = append(errors, string(p.buf[e.start:e.start+e.length])+": "+e.msg)
+	}
+	wantErrors := []string{
+		"Nope: can't evaluate field Nope in type Page",
+		"Title: can't evaluate field Title in type Item",
+	}
+	if strings.Join(errors, "\n") != strings.Join(wantErrors, "\n") {
+		t.Errorf("got errors:\n%s\nwant:\n%s", strings.Join(errors, "\n"), strings.Join(wantErrors, "\n"))
+	}
+}
+
+func TestTypedCompletion(t *testing.T) {
+	pkg, _, _ := typeCheckSource(t, typesSrc)
+	page := pkg.Scope().Lookup("Page").Type()
+	qf := types.RelativeTo(pkg)
+
+	tests := []struct {
+		marked string
+		want   []string
+	}{
+		{"{{.^}}", []string{"Any", "Items", "Meta", "Title"}},
+		{"{{.T^}}", []string{"Title"}},
+		{"{{range .Items}}{{.^}}{{end}}", []string{"Name", "Price"}},
+		{"{{range .Items}}{{$.T^}}{{end}}", []string{"Title"}},
+		{"{{range $it := .Items}}{{$it.^}}{{end}}", []string{"Name", "Price"}},
+		{"{{with .Items}}{{range .}}{{.N^}}{{end}}{{end}}", []string{"Name"}},
+		{"{{.Any.^}}", nil}, // unknown dynamic type
+	}
+	for _, test := range tests {
+		col := strings.Index(test.marked, "^")
+		buf := strings.Replace(test.marked, "^", "", 1)
+		p := parseBuffer([]byte(buf))
+		pos := protocol.Position{Line: 0, Character: uint32(col)}
+		start := inTemplate(p, pos)
+		if start == -1 {
+			t.Errorf("%q: not in a template", test.marked)
+			continue
+		}
+		syms := make(map[string]symbol)
+		filterSyms(syms, p.symbols)
+		c := &completer{
+			p:      p,
+			pos:    pos,
+			offset: start + len(Left),
+			syms:   syms,
+			types:  checkTypes(completionParse(p, start, p.FromPosition(pos)), page, qf),
+			qf:     qf,
+		}
+		ans, _ := c.complete()
+		var got []string
+		for _, item := range ans.Items {
+			got = append(got, item.Label)
+		}
+		sort.Strings(got)
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("%q: got %v, want %v", test.marked, got, test.want)
+		}
+	}
+}
@@ -129,15 +129,12 @@ func connectEditor(dir string, config fake.EditorConfig, ts servertest.Connector
 }
 
 // newGoplsConnector returns a connector that connects to a new gopls process,
-// executed with the provided arguments.
-func newGoplsConnector(args []string) (servertest.Connector, error) {
+// executed with the provided arguments and additional environment bindings.
+func newGoplsConnector(args []string, env ...string) (servertest.Connector, error) {
 	if *goplsPath != "" && *goplsCommit != "" {
 		panic("can't set both -gopls_path and -gopls_commit")
 	}
-	var (
-		goplsPath = *goplsPath
-		env       []string
-	)
+	goplsPath := *goplsPath
 	if *goplsCommit != "" {
 		goplsPath = getInstalledGopls()
 	}
@@ -147,7 +144,7 @@ func newGoplsConnector(args []string) (servertest.Connector, error) {
 		if err != nil {
 			return nil, err
 		}
-		env = []string{fmt.Sprintf("%s=true", runAsGopls)}
+		env = append(env, fmt.Sprintf("%s=true", runAsGopls))
 	}
 	return &SidecarServer{
 		goplsPath: goplsPath,
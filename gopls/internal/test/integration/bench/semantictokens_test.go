@@ -0,0 +1,96 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/test/integration/fake"
+)
+
+// BenchmarkSemanticTokensDelta benchmarks semanticTokens/full/delta
+// requests following a one-line edit of a file, and reports the size
+// of the delta compared with that of the full result.
+func BenchmarkSemanticTokensDelta(b *testing.B) {
+	for _, test := range didChangeTests {
+		b.Run(test.repo, func(b *testing.B) {
+			sharedEnv := getRepo(b, test.repo).sharedEnv(b)
+			config := fake.EditorConfig{
+				Env: map[string]string{
+					"GOPATH": sharedEnv.Sandbox.GOPATH(),
+				},
+				Settings: map[string]interface{}{
+					"semanticTokens": true,
+				},
+			}
+			// Use a new env to enable semantic tokens.
+			env := getRepo(b, test.repo).newEnv(b, config, "semanticTokensDelta", false)
+			defer env.Close()
+			env.OpenFile(test.file)
+			uri := env.Sandbox.Workdir.URI(test.file)
+
+			// Insert the text we'll be modifying at the top of the file.
+			env.EditBuffer(test.file, protocol.TextEdit{NewText: "var __TEST_PLACEHOLDER_0__ = 0\n"})
+			env.AfterChange()
+			full, err := env.Editor.Server.SemanticTokensFull(env.Ctx, &protocol.SemanticTokensParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			resultID := full.ResultID
+			b.ResetTimer()
+
+			if stopAndRecord := startProfileIfSupported(b, env, qualifiedName(test.repo, "semanticTokensDelta")); stopAndRecord != nil {
+				defer stopAndRecord()
+			}
+
+			var deltaBytes int
+			for i := 0; i < b.N; i++ {
+				edits := atomic.AddInt64(&editID, 1)
+				env.EditBuffer(test.file, protocol.TextEdit{
+					Range: protocol.Range{
+						Start: protocol.Position{Line: 0, Character: 0},
+						End:   protocol.Position{Line: 1, Character: 0},
+					},
+					// Increment the placeholder text, to ensure cache misses.
+					NewText: fmt.Sprintf("var __TEST_PLACEHOLDER_%d__ = %d\n", edits, edits),
+				})
+				resp, err := env.Editor.Server.SemanticTokensFullDelta(env.Ctx, &protocol.SemanticTokensDeltaParams{
+					TextDocument:     protocol.TextDocumentIdentifier{URI: uri},
+					PreviousResultID: resultID,
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				data, err := json.Marshal(resp)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var delta protocol.SemanticTokensDelta
+				if err := json.Unmarshal(data, &delta); err != nil {
+					b.Fatal(err)
+				}
+				if delta.Edits == nil {
+					b.Fatalf("semanticTokens/full/delta returned a full result")
+				}
+				resultID = delta.ResultID
+				deltaBytes += len(data)
+			}
+			b.StopTimer()
+
+			data, err := json.Marshal(full)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(float64(len(data)), "full-bytes")
+			b.ReportMetric(float64(deltaBytes)/float64(b.N), "delta-bytes")
+		})
+	}
+}
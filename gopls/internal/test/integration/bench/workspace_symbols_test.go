@@ -5,9 +5,15 @@
 package bench
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log"
 	"testing"
+	"time"
+
+	. "golang.org/x/tools/gopls/internal/test/integration"
+	"golang.org/x/tools/gopls/internal/test/integration/fake"
 )
 
 var symbolQuery = flag.String("symbol_query", "test", "symbol query to use in benchmark")
@@ -39,3 +45,81 @@ func BenchmarkWorkspaceSymbols(b *testing.B) {
 		})
 	}
 }
+
+// BenchmarkWorkspaceSymbolsStartup benchmarks the time for a new gopls
+// process to answer its first workspace symbols request, with an empty file
+// cache ("cold"), and with the file cache of an earlier session ("warm"),
+// from which gopls answers while it loads the workspace.
+func BenchmarkWorkspaceSymbolsStartup(b *testing.B) {
+	for name := range repos {
+		b.Run(name, func(b *testing.B) {
+			repo := getRepo(b, name)
+			// Reuse the GOPATH of the shared env so that we get cache hits for
+			// things in the module cache.
+			gopath := repo.sharedEnv(b).Sandbox.GOPATH()
+
+			b.Run("cold", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					cacheDir := b.TempDir()
+					b.StartTimer()
+					doSymbolStartup(b, repo, gopath, cacheDir)
+				}
+			})
+
+			b.Run("warm", func(b *testing.B) {
+				// Populate the file cache, including the symbol index, in an
+				// earlier session.
+				cacheDir := b.TempDir()
+				env := newSymbolStartupEnv(b, repo, gopath, cacheDir)
+				env.Await(InitialWorkspaceLoad)
+				env.Symbol(*symbolQuery)
+				// The index is stored in the background: give it a moment.
+				time.Sleep(1 * time.Second)
+				env.Close()
+				b.ResetTimer()
+
+				for i := 0; i < b.N; i++ {
+					doSymbolStartup(b, repo, gopath, cacheDir)
+				}
+			})
+		})
+	}
+}
+
+// doSymbolStartup times the first workspace symbols request of a new gopls
+// process using the file cache in cacheDir.
+func doSymbolStartup(b *testing.B, repo *repo, gopath, cacheDir string) {
+	// Exclude the time to set up the env from the benchmark time, as
+	// doIWL does.
+	b.StopTimer()
+	env := newSymbolStartupEnv(b, repo, gopath, cacheDir)
+	b.StartTimer()
+
+	env.Symbol(*symbolQuery)
+
+	b.StopTimer()
+	env.Close()
+	b.StartTimer()
+}
+
+// newSymbolStartupEnv returns a new Env connected to a new gopls process
+// whose file cache is in cacheDir.
+func newSymbolStartupEnv(b *testing.B, repo *repo, gopath, cacheDir string) *Env {
+	ts, err := newGoplsConnector(profileArgs(qualifiedName(repo.name, "symbolStartup"), false), "GOPLSCACHE="+cacheDir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	config := fake.EditorConfig{Env: map[string]string{"GOPATH": gopath}}
+	sandbox, editor, awaiter, err := connectEditor(repo.getDir(), config, ts)
+	if err != nil {
+		log.Fatalf("connecting editor: %v", err)
+	}
+	return &Env{
+		T:       b,
+		Ctx:     context.Background(),
+		Editor:  editor,
+		Sandbox: sandbox,
+		Awaiter: awaiter,
+	}
+}
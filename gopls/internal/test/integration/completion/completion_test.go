@@ -999,3 +999,104 @@ func Join() {}
 		}
 	})
 }
+
+func TestCompletionResolve(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.14
+
+require example.com v1.2.3
+-- go.sum --
+example.com v1.2.3 h1:ihBTGWGjTU3V4ZJ9OmHITkU9WQ4lGdQkMjgyLFk0FaY=
+example.com v1.2.3/go.mod h1:Y2Rc5rVWjWur0h3pd9aEvK5Pof8YKDANh9gHA2Maujo=
+-- a.go --
+package a
+
+import _ "example.com/blah"
+
+// T is a type.
+type T struct {
+	// Field is a field.
+	Field []int
+}
+
+var _ = T{}.Fi
+
+var _ = blah
+`
+	const capabilities = `{
+	"textDocument": {
+		"completion": {
+			"completionItem": {
+				"resolveSupport": {
+					"properties": ["documentation", "detail", "additionalTextEdits"]
+				}
+			}
+		}
+	}
+}`
+	WithOptions(
+		ProxyFiles(proxy),
+		CapabilitiesJSON([]byte(capabilities)),
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a.go")
+
+		// The documentation and detail of a field are deferred.
+		completions := env.Completion(env.RegexpSearch("a.go", `T{}.Fi()`))
+		if len(completions.Items) == 0 {
+			t.Fatalf("no completion items")
+		}
+		item := completions.Items[0]
+		if item.Label != "Field" || item.Data == nil {
+			t.Fatalf("got item %q with data %v, want deferred item Field", item.Label, item.Data)
+		}
+		if item.Documentation != nil {
+			t.Errorf("unresolved item has documentation %v", item.Documentation.Value)
+		}
+		resolved, err := env.Editor.Server.ResolveCompletionItem(env.Ctx, &item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resolved.Detail, "[]int"; got != want {
+			t.Errorf("resolved detail = %q, want %q", got, want)
+		}
+		if doc := fmt.Sprint(resolved.Documentation.Value); !strings.Contains(doc, "Field is a field.") {
+			t.Errorf("resolved documentation = %q, want it to contain the field comment", doc)
+		}
+
+		// The import edits of an unimported candidate are deferred.
+		env.RegexpReplace("a.go", `import _ "example.com/blah"`, "")
+		completions = env.Completion(env.RegexpSearch("a.go", `_ = blah()`))
+		var found bool
+		for _, item := range completions.Items {
+			if item.Label != "blah" {
+				continue
+			}
+			found = true
+			if len(item.AdditionalTextEdits) > 0 {
+				t.Errorf("unresolved item has edits %v", item.AdditionalTextEdits)
+			}
+			resolved, err := env.Editor.Server.ResolveCompletionItem(env.Ctx, &item)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resolved.AdditionalTextEdits) == 0 {
+				t.Errorf("resolved item has no import edits")
+			}
+			// Resolving the item again does not repeat its edits.
+			edits := append([]protocol.TextEdit(nil), resolved.AdditionalTextEdits...)
+			again, err := env.Editor.Server.ResolveCompletionItem(env.Ctx, resolved)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(edits, again.AdditionalTextEdits); diff != "" {
+				t.Errorf("resolving the item again changed its edits (-want +got):\n%s", diff)
+			}
+		}
+		if !found {
+			t.Errorf("no completion item for blah in %v", completions.Items)
+		}
+	})
+}
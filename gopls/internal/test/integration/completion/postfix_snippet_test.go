@@ -761,3 +761,78 @@ func _() {
 		}
 	})
 }
+
+func TestUserPostfixSnippets(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.12
+-- foo.go --
+package foo
+
+func check() error { return nil }
+
+func _() {
+	check().must
+}
+`
+
+	WithOptions(
+		Settings{
+			"experimentalPostfixCompletions": true,
+			"postfixSnippets": []interface{}{
+				map[string]interface{}{
+					"label":   "must",
+					"details": "panic on error",
+					"body":    "{{if and .StmtOK (eq (.TypeName .Type) \"error\")}}if err := {{.X}}; err != nil {\n\tpanic(err)\n}{{end}}",
+				},
+			},
+		},
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("foo.go")
+		loc := env.RegexpSearch("foo.go", "must()")
+		completions := env.Completion(loc)
+		var found bool
+		for _, item := range completions.Items {
+			if item.Label == "must!" {
+				found = true
+				if item.Detail != "panic on error" {
+					t.Errorf("must! detail = %q, want %q", item.Detail, "panic on error")
+				}
+				env.AcceptCompletion(loc, item)
+			}
+		}
+		if !found {
+			t.Fatalf("no must! completion in %v", completions.Items)
+		}
+		const want = `package foo
+
+func check() error { return nil }
+
+func _() {
+	if err := check(); err != nil {
+	panic(err)
+}
+}
+`
+		if got := env.BufferText("foo.go"); got != want {
+			t.Errorf("\nGOT:\n%s\nEXPECTED:\n%s", got, want)
+		}
+	})
+}
+
+func TestInvalidPostfixSnippets(t *testing.T) {
+	WithOptions(
+		Settings{
+			"postfixSnippets": []interface{}{
+				map[string]interface{}{"label": "must", "body": "{{if .X}}"},
+			},
+		},
+	).Run(t, "", func(t *testing.T, env *Env) {
+		env.OnceMet(
+			InitialWorkspaceLoad,
+			ShownMessage(`snippet "must"`),
+		)
+	})
+}
@@ -0,0 +1,184 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diagnostics
+
+import (
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+// The checks package is a minimal vet tool that reports, and offers to
+// rename, each occurrence of "bad" in the files of a package. It is
+// hidden from gopls by a directory filter.
+const pluginFiles = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+func bad() {}
+
+func _() { bad() }
+-- checks/main.go --
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type edit struct {
+	Filename string ` + "`json:\"filename\"`" + `
+	Start    int    ` + "`json:\"start\"`" + `
+	End      int    ` + "`json:\"end\"`" + `
+	New      string ` + "`json:\"new\"`" + `
+}
+
+type fix struct {
+	Message string ` + "`json:\"message\"`" + `
+	Edits   []edit ` + "`json:\"edits\"`" + `
+}
+
+type diagnostic struct {
+	Posn           string ` + "`json:\"posn\"`" + `
+	Message        string ` + "`json:\"message\"`" + `
+	SuggestedFixes []fix  ` + "`json:\"suggested_fixes\"`" + `
+}
+
+func main() {
+	args := os.Args[1:]
+	switch {
+	case len(args) == 1 && args[0] == "-V=full":
+		fmt.Println("checks version devel buildID=checks")
+		return
+	case len(args) == 1 && args[0] == "-flags":
+		fmt.Println(` + "`" + `[{"Name":"json","Bool":true,"Usage":"emit JSON output"}]` + "`" + `)
+		return
+	}
+	data, err := os.ReadFile(args[len(args)-1])
+	if err != nil {
+		panic(err)
+	}
+	var cfg struct {
+		ID         string
+		GoFiles    []string
+		VetxOnly   bool
+		VetxOutput string
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(cfg.VetxOutput, nil, 0666); err != nil {
+		panic(err)
+	}
+	if cfg.VetxOnly {
+		return
+	}
+	var diags []diagnostic
+	for _, filename := range cfg.GoFiles {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			panic(err)
+		}
+		offset := 0
+		for i, line := range strings.SplitAfter(string(content), "\n") {
+			if col := strings.Index(line, "bad"); col >= 0 {
+				diags = append(diags, diagnostic{
+					Posn:    fmt.Sprintf("%s:%d:%d", filename, i+1, col+1),
+					Message: "bad name",
+					SuggestedFixes: []fix{{
+						Message: "Rename to good",
+						Edits:   []edit{{filename, offset + col, offset + col + len("bad"), "good"}},
+					}},
+				})
+			}
+			offset += len(line)
+		}
+	}
+	tree := map[string]map[string]interface{}{}
+	if len(diags) > 0 {
+		tree[cfg.ID] = map[string]interface{}{"badname": diags}
+	}
+	json.NewEncoder(os.Stdout).Encode(tree)
+}
+`
+
+func TestAnalyzerPlugins(t *testing.T) {
+	WithOptions(
+		Settings{
+			"analyzerPlugins":  []string{"./checks"},
+			"directoryFilters": []string{"-checks"},
+		},
+	).Run(t, pluginFiles, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		var d protocol.PublishDiagnosticsParams
+		env.AfterChange(
+			Diagnostics(env.AtRegexp("a/a.go", `func (bad)`), WithMessage("bad name"), FromSource("badname")),
+			Diagnostics(env.AtRegexp("a/a.go", `{ (bad)`), WithMessage("bad name"), FromSource("badname")),
+			ReadDiagnostics("a/a.go", &d),
+		)
+
+		// Apply the fix of the declaration.
+		var decl []protocol.Diagnostic
+		for _, diag := range d.Diagnostics {
+			if diag.Range.Start.Line == 2 {
+				decl = append(decl, diag)
+			}
+		}
+		env.ApplyQuickFixes("a/a.go", decl)
+		const want = `package a
+
+func good() {}
+
+func _() { bad() }
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("after applying fixes, got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestAnalyzerPluginsTestPackage(t *testing.T) {
+	const files = pluginFiles + `
+-- a/x_test.go --
+package a_test
+
+func bad() {}
+`
+	WithOptions(
+		Settings{
+			"analyzerPlugins":  []string{"./checks"},
+			"directoryFilters": []string{"-checks"},
+		},
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/x_test.go")
+		env.OpenFile("a/a.go")
+		env.AfterChange(
+			Diagnostics(env.AtRegexp("a/x_test.go", `func (bad)`), WithMessage("bad name"), FromSource("badname")),
+			Diagnostics(env.AtRegexp("a/a.go", `func (bad)`), WithMessage("bad name"), FromSource("badname")),
+		)
+	})
+}
+
+func TestAnalyzerPluginsDisabled(t *testing.T) {
+	WithOptions(
+		Settings{
+			"analyzerPlugins":  []string{"./checks"},
+			"directoryFilters": []string{"-checks"},
+			"analyses":         map[string]bool{"badname": false},
+		},
+	).Run(t, pluginFiles, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		env.AfterChange(
+			NoDiagnostics(ForFile("a/a.go")),
+		)
+	})
+}
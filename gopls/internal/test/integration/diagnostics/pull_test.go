@@ -0,0 +1,125 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diagnostics
+
+import (
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+func TestPullDiagnostics(t *testing.T) {
+	const src = `
+-- go.mod --
+module mod.com
+
+go 1.12
+-- a/a.go --
+package a
+
+func _() {
+	x := 1
+}
+-- b/b.go --
+package b
+
+func B() { undefined() }
+`
+	WithOptions(
+		Settings{"pullDiagnostics": true},
+		CapabilitiesJSON([]byte(`{"textDocument": {"diagnostic": {}}}`)),
+	).Run(t, src, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		uri := env.Sandbox.Workdir.URI("a/a.go")
+
+		// Diagnostics are not also published to a client that pulls them.
+		env.AfterChange(NoDiagnostics())
+
+		// Document diagnostics.
+		report := pullDiagnostics(t, env, uri, "")
+		if report.Kind != string(protocol.DiagnosticFull) || len(report.Items) != 1 || report.ResultID == "" {
+			t.Fatalf("Diagnostic returned %+v, want full report with 1 item and a result ID", report)
+		}
+		resultID := report.ResultID
+
+		// A repeated request with the same result ID is "unchanged".
+		report = pullDiagnostics(t, env, uri, resultID)
+		if report.Kind != string(protocol.DiagnosticUnchanged) || report.ResultID != resultID {
+			t.Fatalf("Diagnostic returned %+v, want unchanged report", report)
+		}
+
+		// Workspace diagnostics report the same result for a/a.go.
+		ws, err := env.Editor.Server.DiagnosticWorkspace(env.Ctx, &protocol.WorkspaceDiagnosticParams{
+			PreviousResultIds: []protocol.PreviousResultID{{URI: uri, Value: resultID}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := make(map[protocol.DocumentURI]bool)
+		for _, item := range ws.Items {
+			var got struct {
+				URI     protocol.DocumentURI `json:"uri"`
+				Version *int32               `json:"version"`
+				Kind    string               `json:"kind"`
+			}
+			remarshal(t, item, &got)
+			found[got.URI] = true
+			switch got.URI {
+			case uri:
+				if got.Kind != string(protocol.DiagnosticUnchanged) {
+					t.Errorf("DiagnosticWorkspace returned a %s report for unchanged file %s", got.Kind, uri)
+				}
+				if got.Version == nil || *got.Version != 1 {
+					t.Errorf("DiagnosticWorkspace returned version %v for open file %s, want 1", got.Version, uri)
+				}
+			case env.Sandbox.Workdir.URI("b/b.go"):
+				if got.Version != nil {
+					t.Errorf("DiagnosticWorkspace returned version %d for unopened file %s, want null", *got.Version, got.URI)
+				}
+			}
+		}
+		for _, name := range []string{"a/a.go", "b/b.go"} {
+			if !found[env.Sandbox.Workdir.URI(name)] {
+				t.Errorf("DiagnosticWorkspace did not report on %s", name)
+			}
+		}
+
+		// Fixing the error changes the result.
+		env.RegexpReplace("a/a.go", "x := 1", "_ = 1")
+		report = pullDiagnostics(t, env, uri, resultID)
+		if report.Kind != string(protocol.DiagnosticFull) || len(report.Items) != 0 {
+			t.Errorf("Diagnostic returned %+v after edit, want empty full report", report)
+		}
+	})
+}
+
+// pullDiagnostics requests textDocument/diagnostic for the given URI,
+// decoding the report independent of its kind.
+func pullDiagnostics(t *testing.T, env *Env, uri protocol.DocumentURI, previousResultID string) protocol.FullDocumentDiagnosticReport {
+	t.Helper()
+	report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+		TextDocument:     protocol.TextDocumentIdentifier{URI: uri},
+		PreviousResultID: previousResultID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got protocol.FullDocumentDiagnosticReport
+	remarshal(t, report, &got)
+	return got
+}
+
+func remarshal(t *testing.T, from, to any) {
+	t.Helper()
+	data, err := json.Marshal(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, to); err != nil {
+		t.Fatal(err)
+	}
+}
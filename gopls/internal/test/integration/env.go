@@ -186,7 +186,10 @@ func (a *Awaiter) onDiagnostics(_ context.Context, d *protocol.PublishDiagnostic
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	pth := a.workdir.URIToPath(d.URI)
+	pth := string(d.URI) // notebook cells are recorded by URI
+	if !d.URI.IsNotebookCell() {
+		pth = a.workdir.URIToPath(d.URI)
+	}
 	a.state.diagnostics[pth] = d
 	a.checkConditionsLocked()
 	return nil
@@ -354,12 +354,14 @@ func clientCapabilities(cfg EditorConfig) (protocol.ClientCapabilities, error) {
 	capabilities.TextDocument.DocumentSymbol.HierarchicalDocumentSymbolSupport = true
 	// Glob pattern watching is enabled.
 	capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration = true
-	// "rename" operations are used for package renaming.
+	// "rename" operations are used for package renaming, and "create"
+	// operations for extracting declarations to a new file.
 	//
-	// TODO(rfindley): add support for other resource operations (create, delete, ...)
+	// TODO(rfindley): add support for other resource operations (delete, ...)
 	capabilities.Workspace.WorkspaceEdit = &protocol.WorkspaceEditClientCapabilities{
 		ResourceOperations: []protocol.ResourceOperationKind{
 			"rename",
+			"create",
 		},
 	}
 
@@ -1399,10 +1401,22 @@ func (e *Editor) applyDocumentChange(ctx context.Context, change protocol.Docume
 
 		return e.RenameFile(ctx, oldPath, newPath)
 	}
+	if change.CreateFile != nil {
+		path := e.sandbox.Workdir.URIToPath(change.CreateFile.URI)
+		if _, err := e.sandbox.Workdir.ReadFile(path); err == nil {
+			if opts := change.CreateFile.Options; opts == nil || !opts.Overwrite {
+				if opts != nil && opts.IgnoreIfExists {
+					return nil
+				}
+				return fmt.Errorf("cannot create %q: file exists", path)
+			}
+		}
+		return e.sandbox.Workdir.WriteFile(ctx, path, "")
+	}
 	if change.TextDocumentEdit != nil {
 		return e.applyTextDocumentEdit(ctx, *change.TextDocumentEdit)
 	}
-	panic("Internal error: one of RenameFile or TextDocumentEdit must be set")
+	panic("Internal error: one of RenameFile, CreateFile or TextDocumentEdit must be set")
 }
 
 func (e *Editor) applyTextDocumentEdit(ctx context.Context, change protocol.TextDocumentEdit) error {
@@ -1583,6 +1597,50 @@ func (e *Editor) DocumentHighlight(ctx context.Context, loc protocol.Location) (
 	return e.Server.DocumentHighlight(ctx, params)
 }
 
+// LinkedEditingRange invokes textDocument/linkedEditingRange at the given
+// location.
+func (e *Editor) LinkedEditingRange(ctx context.Context, loc protocol.Location) (*protocol.LinkedEditingRanges, error) {
+	if e.Server == nil {
+		return nil, nil
+	}
+	if err := e.checkBufferLocation(loc); err != nil {
+		return nil, err
+	}
+	params := &protocol.LinkedEditingRangeParams{}
+	params.TextDocument.URI = loc.URI
+	params.Position = loc.Range.Start
+
+	return e.Server.LinkedEditingRange(ctx, params)
+}
+
+// DocumentColor invokes textDocument/documentColor for the given path.
+func (e *Editor) DocumentColor(ctx context.Context, path string) ([]protocol.ColorInformation, error) {
+	if e.Server == nil {
+		return nil, nil
+	}
+	params := &protocol.DocumentColorParams{}
+	params.TextDocument.URI = e.sandbox.Workdir.URI(path)
+
+	return e.Server.DocumentColor(ctx, params)
+}
+
+// ColorPresentation invokes textDocument/colorPresentation for the
+// given color at the given location.
+func (e *Editor) ColorPresentation(ctx context.Context, loc protocol.Location, color protocol.Color) ([]protocol.ColorPresentation, error) {
+	if e.Server == nil {
+		return nil, nil
+	}
+	if err := e.checkBufferLocation(loc); err != nil {
+		return nil, err
+	}
+	params := &protocol.ColorPresentationParams{}
+	params.TextDocument.URI = loc.URI
+	params.Range = loc.Range
+	params.Color = color
+
+	return e.Server.ColorPresentation(ctx, params)
+}
+
 // SemanticTokensFull invokes textDocument/semanticTokens/full, and interprets
 // its result.
 func (e *Editor) SemanticTokensFull(ctx context.Context, path string) ([]SemanticToken, error) {
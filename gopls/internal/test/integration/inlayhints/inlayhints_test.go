@@ -4,11 +4,15 @@
 package inlayhint
 
 import (
+	"strings"
 	"testing"
 
 	"golang.org/x/tools/gopls/internal/golang"
 	"golang.org/x/tools/gopls/internal/hooks"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/server"
 	. "golang.org/x/tools/gopls/internal/test/integration"
+	"golang.org/x/tools/gopls/internal/test/integration/fake"
 	"golang.org/x/tools/gopls/internal/util/bug"
 )
 
@@ -67,3 +71,79 @@ const (
 		})
 	}
 }
+
+func TestGCDetailsInlayHints(t *testing.T) {
+	const workspace = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- lib.go --
+package lib
+
+func Add(x, y int) int { return x + y }
+
+func Index(s []int, i int) int { return s[i] }
+`
+	labels := func(hints []protocol.InlayHint) []string {
+		var labels []string
+		for _, hint := range hints {
+			for _, part := range hint.Label {
+				labels = append(labels, part.Value)
+			}
+		}
+		return labels
+	}
+	contains := func(labels []string, prefix string) bool {
+		for _, label := range labels {
+			if strings.HasPrefix(label, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	WithOptions(
+		Settings{"hints": map[string]bool{golang.GCDetails: true}},
+	).Run(t, workspace, func(t *testing.T, env *Env) {
+		env.OpenFile("lib.go")
+		got := labels(gcDetailsHints(env, "lib.go"))
+		for _, want := range []string{"canInline", "isInBounds"} {
+			if !contains(got, want) {
+				t.Errorf("gcDetails inlay hints: got %q, want a %s hint", got, want)
+			}
+		}
+
+		// The details of a file with unsaved changes are not shown.
+		env.EditBuffer("lib.go", fake.NewEdit(0, 0, 0, 0, "\n"))
+		if got := labels(env.InlayHints("lib.go")); len(got) > 0 {
+			t.Errorf("gcDetails inlay hints of an unsaved file: got %q, want none", got)
+		}
+	})
+
+	// Only the kinds of details selected by the annotations setting
+	// are shown.
+	WithOptions(
+		Settings{
+			"hints":       map[string]bool{golang.GCDetails: true},
+			"annotations": map[string]bool{"bounds": true},
+		},
+	).Run(t, workspace, func(t *testing.T, env *Env) {
+		env.OpenFile("lib.go")
+		got := labels(gcDetailsHints(env, "lib.go"))
+		if !contains(got, "isInBounds") || contains(got, "canInline") {
+			t.Errorf("gcDetails inlay hints with bounds annotations: got %q, want only isInBounds hints", got)
+		}
+	})
+}
+
+// gcDetailsHints returns the inlay hints of the saved file name, once
+// the compilation of its package, which is started by the first
+// request for its hints, is complete.
+func gcDetailsHints(env *Env, name string) []protocol.InlayHint {
+	if hints := env.InlayHints(name); len(hints) > 0 {
+		env.T.Fatalf("got inlay hints %v before compiling the package", hints)
+	}
+	env.Await(CompletedWork(server.GCDetailsWorkTitle, 1, true))
+	return env.InlayHints(name)
+}
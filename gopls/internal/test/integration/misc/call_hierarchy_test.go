@@ -33,3 +33,100 @@ package pkg
 		env.Editor.Server.PrepareCallHierarchy(env.Ctx, &params)
 	})
 }
+
+func TestDynamicCallHierarchy(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+type Shape interface{ Area() int }
+
+type Square struct{ n int }
+
+func (s Square) Area() int { return s.n * s.n }
+
+type Circle struct{}
+
+func (Circle) Area() int { return 3 }
+
+func Total(shapes []Shape) int {
+	t := 0
+	for _, s := range shapes {
+		t += s.Area()
+	}
+	return t
+}
+
+func Use() int { return Total([]Shape{Square{2}}) }
+
+func Apply(f func() int) int { return f() }
+
+func Run() int { return Apply(Circle{}.Area) }
+`
+	for _, algorithm := range []string{"off", "rta", "vta"} {
+		t.Run(algorithm, func(t *testing.T) {
+			WithOptions(
+				Settings{"dynamicCallHierarchy": algorithm},
+			).Run(t, files, func(t *testing.T, env *Env) {
+				env.OpenFile("a/a.go")
+
+				incoming := func(re string) map[string]bool {
+					loc := env.RegexpSearch("a/a.go", re)
+					var params protocol.CallHierarchyIncomingCallsParams
+					params.Item = protocol.CallHierarchyItem{URI: loc.URI, Range: loc.Range, SelectionRange: loc.Range}
+					calls, err := env.Editor.Server.IncomingCalls(env.Ctx, &params)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got := make(map[string]bool)
+					for _, call := range calls {
+						got[call.From.Name+" "+call.From.Detail] = true
+					}
+					return got
+				}
+
+				// Square.Area is only called through the Shape interface.
+				squareCalls := incoming(`\) (Area)\(\) int \{ return s`)
+				const totalDynamic = "Total mod.com/a • a.go • dynamic"
+				if got, want := squareCalls[totalDynamic], algorithm != "off"; got != want {
+					t.Errorf("incoming calls of Square.Area: got %v, want %q present = %t", squareCalls, totalDynamic, want)
+				}
+
+				// Circle.Area is called through a method value by Apply.
+				circleCalls := incoming(`\) (Area)\(\) int \{ return 3`)
+				const applyDynamic = "Apply mod.com/a • a.go • dynamic"
+				if algorithm == "vta" && !circleCalls[applyDynamic] {
+					t.Errorf("incoming calls of Circle.Area: got %v, want %q", circleCalls, applyDynamic)
+				}
+				if !circleCalls["Run mod.com/a • a.go"] {
+					t.Errorf("incoming calls of Circle.Area: got %v, want the static call from Run", circleCalls)
+				}
+
+				// Total calls Square.Area dynamically.
+				loc := env.RegexpSearch("a/a.go", `func (Total)`)
+				var params protocol.CallHierarchyOutgoingCallsParams
+				params.Item = protocol.CallHierarchyItem{URI: loc.URI, Range: loc.Range, SelectionRange: loc.Range}
+				calls, err := env.Editor.Server.OutgoingCalls(env.Ctx, &params)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var outgoing []string
+				for _, call := range calls {
+					outgoing = append(outgoing, call.To.Name+" "+call.To.Detail)
+				}
+				const areaDynamic = "Area mod.com/a • a.go • dynamic"
+				found := false
+				for _, call := range outgoing {
+					found = found || call == areaDynamic
+				}
+				if found != (algorithm != "off") {
+					t.Errorf("outgoing calls of Total: got %v, want %q present = %t", outgoing, areaDynamic, algorithm != "off")
+				}
+			})
+		})
+	}
+}
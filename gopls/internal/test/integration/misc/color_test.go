@@ -0,0 +1,52 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+func TestDocumentColor(t *testing.T) {
+	const src = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a.go --
+package a
+
+func setBackground(name, color string) {}
+
+func _() {
+	setBackground("#123", "#aabbcc")
+}
+`
+	Run(t, src, func(t *testing.T, env *Env) {
+		env.OpenFile("a.go")
+		colors := env.DocumentColor("a.go")
+		if len(colors) != 1 {
+			t.Fatalf("DocumentColor returned %d colors, want 1", len(colors))
+		}
+		loc := env.RegexpSearch("a.go", `"#aabbcc"`)
+		if colors[0].Range != loc.Range {
+			t.Errorf("DocumentColor: got range %v, want %v", colors[0].Range, loc.Range)
+		}
+		want := protocol.Color{Red: 0xaa / 255.0, Green: 0xbb / 255.0, Blue: 0xcc / 255.0, Alpha: 1}
+		if colors[0].Color != want {
+			t.Errorf("DocumentColor: got color %v, want %v", colors[0].Color, want)
+		}
+
+		presentations := env.ColorPresentation(loc, protocol.Color{Red: 1, Green: 0x80 / 255.0, Alpha: 1})
+		if len(presentations) != 1 {
+			t.Fatalf("ColorPresentation returned %d presentations, want 1", len(presentations))
+		}
+		if got, want := presentations[0].TextEdit.NewText, `"#ff8000"`; got != want {
+			t.Errorf("ColorPresentation: got %s, want %s", got, want)
+		}
+	})
+}
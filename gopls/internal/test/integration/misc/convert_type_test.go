@@ -0,0 +1,202 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/test/compare"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+func TestNameStructType(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- b/b.go --
+package b
+
+type Time struct{}
+-- a/a.go --
+package a
+
+import "mod.com/b"
+
+type ID int
+
+func Pairs() []struct {
+	ID   ID
+	Name string ` + "`json:\"name\"`" + `
+} {
+	return []struct {
+		ID   ID
+		Name string ` + "`json:\"name\"`" + `
+	}{{1, "one"}}
+}
+
+func Local[T any]() struct{ x T } {
+	return struct{ x T }{}
+}
+
+var Stamp struct{ T b.Time }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		action := findCodeAction(t, env, env.RegexpSearch("a/a.go", `\[\](struct) \{`), "Convert anonymous struct to named type")
+		if action == nil {
+			t.Fatal("could not find action")
+		}
+		env.ApplyCodeAction(*action)
+		want := `package a
+
+import "mod.com/b"
+
+type ID int
+
+type newStruct struct {
+	ID   ID
+	Name string ` + "`json:\"name\"`" + `
+}
+
+func Pairs() []newStruct {
+	return []newStruct{{1, "one"}}
+}
+
+func Local[T any]() struct{ x T } {
+	return struct{ x T }{}
+}
+
+var Stamp struct{ T b.Time }
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("converted struct:\n%s", compare.Text(want, got))
+		}
+
+		// A struct type that uses a type parameter cannot be named.
+		if action := findCodeAction(t, env, env.RegexpSearch("a/a.go", `\(\) (struct)`), "Convert anonymous struct to named type"); action != nil {
+			t.Errorf("unexpected action for struct with type parameter")
+		}
+
+		// A struct type that uses an imported type can be named.
+		action = findCodeAction(t, env, env.RegexpSearch("a/a.go", `Stamp (struct)`), "Convert anonymous struct to named type")
+		if action == nil {
+			t.Fatal("could not find action for struct with imported type")
+		}
+		env.ApplyCodeAction(*action)
+		if got, want := env.BufferText("a/a.go"), "var Stamp newStruct1"; !strings.Contains(got, want) {
+			t.Errorf("converted struct with imported type: file does not contain %q:\n%s", want, got)
+		}
+	})
+}
+
+func TestGeneralizeParam(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+type Celsius float64
+
+func (c Celsius) String() string { return "" }
+
+func Warmer(a, b Celsius) bool { return a > b }
+
+func Label(c Celsius) string { return c.String() }
+
+func Same(x, y Celsius) bool { return x == y }
+
+type point struct{ x int }
+
+func X(p point) int { return p.x }
+
+var _ = Label(Celsius(1))
+
+func Name(c Celsius) string { return c.String() }
+
+var _ = Name(1)
+
+func Ignore(c Celsius) {}
+
+func _() { Ignore(1) }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		for _, test := range []struct {
+			re, want string
+		}{
+			{`Warmer\((a)`, "func Warmer[T ~float64](a, b T) bool"},
+			{`Label\((c)`, "func Label[T interface{ String() string }](c T) string"},
+			{`Same\((x)`, "func Same[T comparable](x, y T) bool"},
+		} {
+			action := findCodeAction(t, env, env.RegexpSearch("a/a.go", test.re), "Convert parameter type to type parameter")
+			if action == nil {
+				t.Fatalf("%s: could not find action", test.re)
+			}
+			env.ApplyCodeAction(*action)
+			if got := env.BufferText("a/a.go"); !strings.Contains(got, test.want) {
+				t.Errorf("%s: converted file does not contain %q:\n%s", test.re, test.want, got)
+			}
+		}
+
+		// The use of a field cannot be generalized.
+		action := findCodeAction(t, env, env.RegexpSearch("a/a.go", `X\((p)`), "Convert parameter type to type parameter")
+		if action == nil {
+			t.Fatal("could not find action for X")
+		}
+		if _, err := env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+			Command:   action.Command.Command,
+			Arguments: action.Command.Arguments,
+		}); err == nil || !strings.Contains(err.Error(), "field x") {
+			t.Errorf("converting parameter of X: got error %v, want use of field", err)
+		}
+
+		// The call Name(1) would not type-check.
+		action = findCodeAction(t, env, env.RegexpSearch("a/a.go", `Name\((c)`), "Convert parameter type to type parameter")
+		if action == nil {
+			t.Fatal("could not find action for Name")
+		}
+		if _, err := env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+			Command:   action.Command.Command,
+			Arguments: action.Command.Arguments,
+		}); err == nil || !strings.Contains(err.Error(), "does not satisfy") {
+			t.Errorf("converting parameter of Name: got error %v, want unsatisfied constraint", err)
+		}
+
+		// The call Ignore(1) would type-check, but with int for Celsius.
+		action = findCodeAction(t, env, env.RegexpSearch("a/a.go", `Ignore\((c)`), "Convert parameter type to type parameter")
+		if action == nil {
+			t.Fatal("could not find action for Ignore")
+		}
+		if _, err := env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+			Command:   action.Command.Command,
+			Arguments: action.Command.Arguments,
+		}); err == nil || !strings.Contains(err.Error(), "would infer int") {
+			t.Errorf("converting parameter of Ignore: got error %v, want inference of int", err)
+		}
+	})
+}
+
+// findCodeAction returns the code action with the specified title at
+// loc, or nil if there is none.
+func findCodeAction(t *testing.T, env *Env, loc protocol.Location, title string) *protocol.CodeAction {
+	t.Helper()
+	actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range actions {
+		if action.Title == title {
+			return &action
+		}
+	}
+	return nil
+}
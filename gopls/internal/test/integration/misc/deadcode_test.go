@@ -0,0 +1,120 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/test/compare"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+func TestDeadcode(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+
+require example.com/dep v0.0.0
+
+replace example.com/dep => ./dep
+-- dep/go.mod --
+module example.com/dep
+
+go 1.18
+-- dep/dep.go --
+package dep
+
+func Call(f func()) { f() }
+-- main.go --
+package main
+
+import (
+	"example.com/dep"
+	"mod.com/lib"
+)
+
+func main() {
+	var s lib.Shape = lib.Square{}
+	_ = s.Area() + lib.Used()
+	dep.Call(callback)
+}
+
+func helper() {}
+
+func callback() {}
+-- lib/lib.go --
+package lib
+
+type Shape interface{ Area() int }
+
+type Square struct{}
+
+func (Square) Area() int { return 1 }
+
+func (Square) Perimeter() int { return 4 }
+
+func Used() int { return 0 }
+
+func Unused() int { return Used() }
+
+func Generic[T any](x T) T { return x }
+-- other/other.go --
+package other
+
+func Other() {}
+`
+	WithOptions(
+		Settings{"deadcode": true},
+	).Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("lib/lib.go")
+		var d protocol.PublishDiagnosticsParams
+		env.AfterChange(
+			Diagnostics(env.AtRegexp("main.go", `helper`), WithMessage("helper is unreachable from any main")),
+			Diagnostics(env.AtRegexp("lib/lib.go", `func (Unused)`), WithMessage("Unused is unreachable")),
+			Diagnostics(env.AtRegexp("lib/lib.go", `Generic`), WithMessage("Generic is unreachable")),
+			// The methods of a type converted to an interface are
+			// reachable, as reflection may call them.
+			NoDiagnostics(env.AtRegexp("lib/lib.go", `Perimeter`)),
+			NoDiagnostics(env.AtRegexp("lib/lib.go", `func (Used)`)),
+			// A function passed to a dependency, whose calls are
+			// unknown, may be called.
+			NoDiagnostics(env.AtRegexp("main.go", `callback`)),
+			NoDiagnostics(ForFile("other/other.go")),
+			ReadDiagnostics("lib/lib.go", &d),
+		)
+
+		var unused []protocol.Diagnostic
+		for _, diag := range d.Diagnostics {
+			if diag.Message == "Unused is unreachable from any main" {
+				unused = append(unused, diag)
+			}
+		}
+		env.ApplyQuickFixes("lib/lib.go", unused)
+		const want = `package lib
+
+type Shape interface{ Area() int }
+
+type Square struct{}
+
+func (Square) Area() int { return 1 }
+
+func (Square) Perimeter() int { return 4 }
+
+func Used() int { return 0 }
+
+func Generic[T any](x T) T { return x }
+`
+		if got := env.BufferText("lib/lib.go"); got != want {
+			t.Errorf("after deleting Unused:\n%s", compare.Text(want, got))
+		}
+		env.AfterChange(
+			NoDiagnostics(env.AtRegexp("lib/lib.go", `func (Used)`)),
+			Diagnostics(env.AtRegexp("lib/lib.go", `Generic`)),
+		)
+	})
+}
@@ -4,12 +4,14 @@
 package misc
 
 import (
+	"strings"
 	"testing"
 
 	"golang.org/x/tools/gopls/internal/test/compare"
 	. "golang.org/x/tools/gopls/internal/test/integration"
 
 	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
 )
 
 func TestExtractFunction(t *testing.T) {
@@ -63,3 +65,288 @@ func newFunction() int {
 		}
 	})
 }
+
+const extractToFileSrc = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- b/b.go --
+package b
+
+var B = 1
+-- c/c.go --
+package c
+
+const C = 2
+-- a/a.go --
+// Copyright header.
+
+//go:build !never
+
+// Package a is a package.
+package a
+
+import (
+	"mod.com/b"
+	"mod.com/c"
+)
+
+var X = b.B
+
+// T is a type.
+type T struct{ C int }
+
+func (T) M() int { return c.C } // M returns C.
+
+var Y = 1
+-- a/other.go --
+package a
+
+import "mod.com/b"
+
+var Z = b.B
+`
+
+func TestExtractToNewFile(t *testing.T) {
+	Run(t, extractToFileSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `(?s)// T is a type.*return c.C \}`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var extract *protocol.CodeAction
+		for _, action := range actions {
+			if action.Kind == protocol.RefactorExtract && action.Title == "Extract declarations to new file" {
+				extract = &action
+				break
+			}
+		}
+		if extract == nil {
+			t.Fatal("could not find extract to new file action")
+		}
+
+		env.ApplyCodeAction(*extract)
+		want := `// Copyright header.
+
+//go:build !never
+
+// Package a is a package.
+package a
+
+import (
+	"mod.com/b"
+)
+
+var X = b.B
+
+var Y = 1
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("a/a.go after extraction:\n%s", compare.Text(want, got))
+		}
+		want = `// Copyright header.
+
+//go:build !never
+
+package a
+
+import "mod.com/c"
+
+// T is a type.
+type T struct{ C int }
+
+func (T) M() int { return c.C } // M returns C.
+`
+		if got := env.BufferText("a/t.go"); got != want {
+			t.Errorf("a/t.go after extraction:\n%s", compare.Text(want, got))
+		}
+		env.AfterChange(NoDiagnostics())
+	})
+}
+
+func TestExtractToExistingFile(t *testing.T) {
+	Run(t, extractToFileSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `var X = b.B`)
+		args, err := command.MarshalArgs(command.ExtractToFileArgs{
+			Location: loc,
+			Dest:     env.Sandbox.Workdir.URI("a/other.go"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.ExecuteCommand(&protocol.ExecuteCommandParams{
+			Command:   command.ExtractToFile.ID(),
+			Arguments: args,
+		}, nil)
+
+		want := `package a
+
+import "mod.com/b"
+
+var Z = b.B
+
+var X = b.B
+`
+		if got := env.BufferText("a/other.go"); got != want {
+			t.Errorf("a/other.go after extraction:\n%s", compare.Text(want, got))
+		}
+		env.AfterChange(NoDiagnostics())
+	})
+}
+
+func TestExtractToNewFileName(t *testing.T) {
+	const src = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+var Sys_windows = 1
+
+func Helper_test() {}
+
+func _amd64() {}
+
+var ()
+`
+	Run(t, src, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		for _, test := range []struct {
+			decl, want string
+		}{
+			{`var Sys_windows = 1`, "a/sys_windows_decls.go"},
+			{`func Helper_test\(\) {}`, "a/helper_test_decls.go"},
+			{`func _amd64\(\) {}`, "a/amd64_decls.go"},
+			{`var \(\)`, "a/extracted.go"},
+		} {
+			args, err := command.MarshalArgs(command.ExtractToFileArgs{
+				Location:     env.RegexpSearch("a/a.go", test.decl),
+				ResolveEdits: true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var edit protocol.WorkspaceEdit
+			env.ExecuteCommand(&protocol.ExecuteCommandParams{
+				Command:   command.ExtractToFile.ID(),
+				Arguments: args,
+			}, &edit)
+			var created protocol.DocumentURI
+			for _, change := range edit.DocumentChanges {
+				if change.CreateFile != nil {
+					created = change.CreateFile.URI
+				}
+			}
+			if want := env.Sandbox.Workdir.URI(test.want); created != want {
+				t.Errorf("extracting %s created %s, want %s", test.decl, created, want)
+			}
+		}
+	})
+}
+
+const extractInterfaceSrc = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- b/b.go --
+package b
+
+type Buf struct{}
+-- a/a.go --
+package a
+
+import "mod.com/b"
+
+// T is a type.
+type T struct{ x int }
+
+func (t *T) Get() int { return t.x }
+
+func (t *T) Fill(buf *b.Buf) {}
+
+func (t *T) reset() { t.x = 0 }
+
+func use(t *T) int {
+	return t.Get()
+}
+
+func main() {
+	use(new(T))
+}
+`
+
+func TestExtractInterface(t *testing.T) {
+	Run(t, extractInterfaceSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `type (T) struct`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var extract *protocol.CodeAction
+		for _, action := range actions {
+			if action.Kind == protocol.RefactorExtract && action.Title == "Extract interface" {
+				extract = &action
+				break
+			}
+		}
+		if extract == nil {
+			t.Fatal("could not find extract interface action")
+		}
+
+		env.ApplyCodeAction(*extract)
+		want := `package a
+
+import "mod.com/b"
+
+// TInterface is implemented by T.
+type TInterface interface {
+	Fill(buf *b.Buf)
+	Get() int
+}
+
+// T is a type.
+type T struct{ x int }
+`
+		if got := env.BufferText("a/a.go"); !strings.HasPrefix(got, want) {
+			t.Errorf("a/a.go after extraction:\n%s", compare.Text(want, got))
+		}
+		env.AfterChange(NoDiagnostics())
+	})
+}
+
+func TestExtractInterfaceReplaceParams(t *testing.T) {
+	Run(t, extractInterfaceSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `type (T) struct`)
+		args, err := command.MarshalArgs(command.ExtractInterfaceArgs{
+			Location:      loc,
+			Name:          "Getter",
+			Methods:       []string{"Get"},
+			ReplaceParams: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		env.ExecuteCommand(&protocol.ExecuteCommandParams{
+			Command:   command.ExtractInterface.ID(),
+			Arguments: args,
+		}, nil)
+
+		for _, want := range []string{
+			"type Getter interface {\n\tGet() int\n}",
+			"func use(t Getter) int {",
+		} {
+			if got := env.BufferText("a/a.go"); !strings.Contains(got, want) {
+				t.Errorf("a/a.go after extraction does not contain %q:\n%s", want, got)
+			}
+		}
+		env.AfterChange(NoDiagnostics())
+	})
+}
@@ -158,8 +158,10 @@ func _() {
 		if err != nil {
 			t.Fatal(err)
 		}
-		if len(actions) > 0 {
-			t.Errorf("CodeAction(): got %d code actions, want 0", len(actions))
+		for _, action := range actions {
+			if action.Kind == protocol.RefactorRewrite {
+				t.Errorf("CodeAction(): got rewrite action %q, want none", action.Title)
+			}
 		}
 	})
 }
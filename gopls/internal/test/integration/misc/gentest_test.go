@@ -0,0 +1,236 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/gopls/internal/test/compare"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+// The generated tests import standard packages, so these tests
+// inspect the resolved edits rather than applying them.
+
+const generateTestSrc = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- b/b.go --
+package b
+
+type Buf struct{}
+-- a/a.go --
+package a
+
+import "mod.com/b"
+
+func Sum(xs ...int) int { return 0 }
+
+func fill(buf *b.Buf) error { return nil }
+-- a/other.go --
+package a
+
+func Other() {}
+-- a/other_test.go --
+package a_test
+
+var _ = 1
+`
+
+func TestGenerateTestCodeAction(t *testing.T) {
+	Run(t, generateTestSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `func (Sum)`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found bool
+		for _, action := range actions {
+			if action.Kind == protocol.SourceGenerateTest {
+				found = true
+				if want := "Add test TestSum"; action.Title != want {
+					t.Errorf("got title %q, want %q", action.Title, want)
+				}
+			}
+		}
+		if !found {
+			t.Fatal("could not find generate test action")
+		}
+
+		// No action in a function body.
+		loc = env.RegexpSearch("a/a.go", `return 0`)
+		actions, err = env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, action := range actions {
+			if action.Kind == protocol.SourceGenerateTest {
+				t.Errorf("unexpected action %q in function body", action.Title)
+			}
+		}
+	})
+}
+
+func TestGenerateTestRunCommand(t *testing.T) {
+	const capabilities = `{ "textDocument": {"codeAction": { "dataSupport": true, "resolveSupport": { "properties": ["edit"] } } } }`
+	WithOptions(
+		CapabilitiesJSON([]byte(capabilities)),
+	).Run(t, generateTestSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `func (Sum)`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var action *protocol.CodeAction
+		for i := range actions {
+			if actions[i].Kind == protocol.SourceGenerateTest {
+				action = &actions[i]
+			}
+		}
+		if action == nil {
+			t.Fatal("could not find generate test action")
+		}
+		if action.Command == nil || action.Command.Command != command.RunTests.ID() {
+			t.Fatalf("got command %v, want %s", action.Command, command.RunTests.ID())
+		}
+		args, err := command.MarshalArgs(command.RunTestsArgs{
+			URI:   env.Sandbox.Workdir.URI("a/a_test.go"),
+			Tests: []string{"TestSum"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(action.Command.Arguments[0]), string(args[0]); got != want {
+			t.Errorf("got RunTests arguments %s, want %s", got, want)
+		}
+		resolved, err := env.Editor.Server.ResolveCodeAction(env.Ctx, action)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolved.Edit == nil || len(resolved.Edit.DocumentChanges) != 2 {
+			t.Errorf("got resolved edit %v, want creation and edit of a/a_test.go", resolved.Edit)
+		}
+	})
+}
+
+func TestGenerateTestTypeError(t *testing.T) {
+	const src = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+var testing = 0
+
+func F() {}
+`
+	Run(t, src, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		args, err := command.MarshalArgs(command.GenerateTestArgs{
+			Location:     env.RegexpSearch("a/a.go", `func (F)`),
+			ResolveEdits: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+			Command:   command.GenerateTest.ID(),
+			Arguments: args,
+		})
+		if err == nil || !strings.Contains(err.Error(), "testing already declared") {
+			t.Errorf("GenerateTest returned error %v, want a conflict with testing", err)
+		}
+	})
+}
+
+func TestGenerateTestNewFile(t *testing.T) {
+	Run(t, generateTestSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		edit := generateTest(t, env, env.RegexpSearch("a/a.go", `func (fill)`))
+		if len(edit.DocumentChanges) != 2 || edit.DocumentChanges[0].CreateFile == nil {
+			t.Fatalf("got %d changes, want creation and edit of a/a_test.go", len(edit.DocumentChanges))
+		}
+		if got, want := edit.DocumentChanges[0].CreateFile.URI, env.Sandbox.Workdir.URI("a/a_test.go"); got != want {
+			t.Errorf("created %s, want %s", got, want)
+		}
+		want := `package a
+
+import (
+	"testing"
+
+	"mod.com/b"
+)
+
+func TestFill(t *testing.T) {
+	tests := []struct {
+		name    string
+		buf     *b.Buf
+		wantErr bool
+	}{
+		// TODO: Add test cases.
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fill(tt.buf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fill() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+`
+		edits := protocol.AsTextEdits(edit.DocumentChanges[1].TextDocumentEdit.Edits)
+		if got := edits[0].NewText; got != want {
+			t.Errorf("a/a_test.go:\n%s", compare.Text(want, got))
+		}
+	})
+}
+
+func TestGenerateTestExternal(t *testing.T) {
+	Run(t, generateTestSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/other.go")
+		edit := generateTest(t, env, env.RegexpSearch("a/other.go", `func (Other)`))
+		if len(edit.DocumentChanges) != 1 {
+			t.Fatalf("got %d changes, want 1", len(edit.DocumentChanges))
+		}
+		var got string
+		for _, e := range protocol.AsTextEdits(edit.DocumentChanges[0].TextDocumentEdit.Edits) {
+			got += e.NewText
+		}
+		for _, want := range []string{`"testing"`, `"mod.com/a"`, "func TestOther(t *testing.T) {", "a.Other()"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("edits of a/other_test.go do not contain %q:\n%s", want, got)
+			}
+		}
+	})
+}
+
+// generateTest executes the GenerateTest command at loc and returns
+// its resolved edit.
+func generateTest(t *testing.T, env *Env, loc protocol.Location) *protocol.WorkspaceEdit {
+	t.Helper()
+	args, err := command.MarshalArgs(command.GenerateTestArgs{
+		Location:     loc,
+		ResolveEdits: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var edit protocol.WorkspaceEdit
+	env.ExecuteCommand(&protocol.ExecuteCommandParams{
+		Command:   command.GenerateTest.ID(),
+		Arguments: args,
+	}, &edit)
+	return &edit
+}
@@ -0,0 +1,241 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+const indexSrc = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+// I is an interface.
+type I interface{ M() }
+
+// T implements I.
+type T struct{ X int }
+
+func (T) M() {}
+
+func F() {}
+-- b/b.go --
+package b
+
+import "mod.com/a"
+
+func _() {
+	a.F()
+	var t a.T
+	_ = t.X
+	var i a.I = t
+	i.M()
+}
+`
+
+func TestIndex(t *testing.T) {
+	Run(t, indexSrc, func(t *testing.T, env *Env) {
+		args, err := command.MarshalArgs(command.IndexArgs{URI: env.Sandbox.Workdir.RootURI()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result command.IndexResult
+		env.ExecuteCommand(&protocol.ExecuteCommandParams{
+			Command:   command.Index.ID(),
+			Arguments: args,
+		}, &result)
+		defer os.Remove(result.File)
+		dump := readLSIF(t, result.File)
+
+		// Each symbol is found by its moniker.
+		for _, test := range []struct {
+			identifier string
+			kind       string
+			pkg        string   // name of packageInformation
+			refs       []string // "file:property" for each reference, sorted
+			hover      string   // substring of hover text
+		}{
+			{"mod.com/a:F", "export", "mod.com", []string{"a.go:definitions", "b.go:references"}, "func F()"},
+			{"mod.com/a:T", "export", "mod.com", []string{"a.go:definitions", "a.go:references", "b.go:references"}, "T implements I."},
+			{"mod.com/a:T.X", "export", "mod.com", []string{"a.go:definitions", "b.go:references"}, "field X int"},
+			{"mod.com/a:I.M", "export", "mod.com", []string{"a.go:definitions", "b.go:references"}, "func (I) M()"},
+		} {
+			rs, moniker := dump.symbol(test.identifier)
+			if rs == 0 {
+				t.Errorf("no symbol with moniker %q", test.identifier)
+				continue
+			}
+			if got := moniker["kind"]; got != test.kind {
+				t.Errorf("moniker %q has kind %v, want %s", test.identifier, got, test.kind)
+			}
+			if info := dump.out(int(moniker["id"].(float64)), "packageInformation"); len(info) != 1 || info[0]["name"] != test.pkg {
+				t.Errorf("moniker %q has package information %v, want %s", test.identifier, info, test.pkg)
+			}
+			if diff := cmp.Diff(test.refs, dump.items(rs, "textDocument/references")); diff != "" {
+				t.Errorf("references of %q mismatch (-want +got):\n%s", test.identifier, diff)
+			}
+			hovers := dump.out(rs, "textDocument/hover")
+			if len(hovers) != 1 {
+				t.Errorf("%q has %d hovers, want 1", test.identifier, len(hovers))
+			} else if data, _ := json.Marshal(hovers[0]["result"]); !strings.Contains(string(data), test.hover) {
+				t.Errorf("hover of %q is %s, want it to contain %q", test.identifier, data, test.hover)
+			}
+		}
+
+		// T implements I, and vice versa.
+		for _, test := range []struct{ identifier, impl string }{
+			{"mod.com/a:I", "a.go"},
+			{"mod.com/a:T", "a.go"},
+		} {
+			rs, _ := dump.symbol(test.identifier)
+			if got := dump.items(rs, "textDocument/implementation"); len(got) != 1 || !strings.HasPrefix(got[0], test.impl) {
+				t.Errorf("implementations of %q = %v, want one in %s", test.identifier, got, test.impl)
+			}
+		}
+	})
+}
+
+func TestMoniker(t *testing.T) {
+	Run(t, indexSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("b/b.go")
+		for _, test := range []struct {
+			re   string
+			want string // "identifier kind", or "" for none
+		}{
+			{`a\.(F)`, "mod.com/a:F export"},
+			{`t\.(X)`, "mod.com/a:T.X export"},
+			{`i\.(M)`, "mod.com/a:I.M export"},
+			{`var (t)`, ""},
+		} {
+			loc := env.RegexpSearch("b/b.go", test.re)
+			monikers, err := env.Editor.Server.Moniker(env.Ctx, &protocol.MonikerParams{
+				TextDocumentPositionParams: protocol.LocationTextDocumentPositionParams(loc),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got string
+			if len(monikers) > 0 {
+				got = monikers[0].Identifier + " " + string(*monikers[0].Kind)
+			}
+			if got != test.want {
+				t.Errorf("Moniker(%q) = %q, want %q", test.re, got, test.want)
+			}
+		}
+	})
+}
+
+// An lsifDump is a decoded LSIF dump.
+type lsifDump struct {
+	vertices map[int]map[string]any
+	edges    []map[string]any
+}
+
+func readLSIF(t *testing.T, filename string) *lsifDump {
+	t.Helper()
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dump := &lsifDump{vertices: make(map[int]map[string]any)}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var elem map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &elem); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		if elem["type"] == "edge" {
+			dump.edges = append(dump.edges, elem)
+		} else {
+			dump.vertices[int(elem["id"].(float64))] = elem
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return dump
+}
+
+// inVs returns the targets of an edge.
+func inVs(edge map[string]any) []int {
+	if v, ok := edge["inV"]; ok {
+		return []int{int(v.(float64))}
+	}
+	var ids []int
+	for _, v := range edge["inVs"].([]any) {
+		ids = append(ids, int(v.(float64)))
+	}
+	return ids
+}
+
+// out returns the vertices reached from the given vertex by edges
+// with the given label.
+func (d *lsifDump) out(id int, label string) []map[string]any {
+	var result []map[string]any
+	for _, edge := range d.edges {
+		if edge["label"] == label && int(edge["outV"].(float64)) == id {
+			for _, in := range inVs(edge) {
+				result = append(result, d.vertices[in])
+			}
+		}
+	}
+	return result
+}
+
+// symbol returns the ID of the result set with the given moniker, and
+// the moniker vertex.
+func (d *lsifDump) symbol(identifier string) (int, map[string]any) {
+	for _, edge := range d.edges {
+		if edge["label"] != "moniker" {
+			continue
+		}
+		moniker := d.vertices[inVs(edge)[0]]
+		if moniker["identifier"] == identifier {
+			return int(edge["outV"].(float64)), moniker
+		}
+	}
+	return 0, nil
+}
+
+// items returns the sorted list of "file:property" strings (or just
+// "file", if the edge has no property) for the ranges of the result of
+// the given request on the result set.
+func (d *lsifDump) items(rs int, request string) []string {
+	var got []string
+	for _, result := range d.out(rs, request) {
+		for _, edge := range d.edges {
+			if edge["label"] != "item" || edge["outV"] != result["id"] {
+				continue
+			}
+			doc := d.vertices[int(edge["document"].(float64))]
+			item := path.Base(doc["uri"].(string))
+			if prop, ok := edge["property"]; ok {
+				item += ":" + prop.(string)
+			}
+			for range inVs(edge) {
+				got = append(got, item)
+			}
+		}
+	}
+	sort.Strings(got)
+	return got
+}
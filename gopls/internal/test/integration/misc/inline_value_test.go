@@ -0,0 +1,100 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/tools/gopls/internal/protocol"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+func TestInlineValue(t *testing.T) {
+	const src = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a.go --
+package a
+
+var global = 1
+
+func f(param int) (result int) {
+	x := param + global
+	for i, v := range []int{x} {
+		x := v * i
+		_ = x
+	}
+	y := x
+	result = y
+	z := 0
+	return z
+}
+`
+	Run(t, src, func(t *testing.T, env *Env) {
+		env.OpenFile("a.go")
+		uri := env.Sandbox.Workdir.URI("a.go")
+		m, err := env.Editor.Mapper("a.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		whole, err := m.OffsetRange(0, len(m.Content))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// inlineValues returns the values reported when stopped at the
+		// line matching re, as "name@line" strings (1-based lines).
+		inlineValues := func(re string) []string {
+			stopped := env.RegexpSearch("a.go", re)
+			values, err := env.Editor.Server.InlineValue(env.Ctx, &protocol.InlineValueParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Range:        whole,
+				Context:      protocol.InlineValueContext{StoppedLocation: stopped.Range},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, value := range values {
+				data, err := json.Marshal(value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// The client cannot distinguish the variants of
+				// InlineValue, so use the text of the range.
+				var lookup protocol.InlineValueVariableLookup
+				if err := json.Unmarshal(data, &lookup); err != nil {
+					t.Fatal(err)
+				}
+				start, end, err := m.RangeOffsets(lookup.Range)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, fmt.Sprintf("%s@%d", m.Content[start:end], lookup.Range.Start.Line+1))
+			}
+			return got
+		}
+
+		// Stopped inside the loop: the inner x shadows the outer one,
+		// and y and z are not yet declared.
+		got := inlineValues(`_ = x`)
+		want := []string{"param@5", "result@5", "param@6", "i@7", "v@7", "x@8", "v@8", "i@8", "x@9"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("InlineValue inside loop mismatch (-want +got):\n%s", diff)
+		}
+
+		// Stopped at the return: the loop variables are out of scope.
+		got = inlineValues(`return z`)
+		want = []string{"param@5", "result@5", "x@6", "param@6", "x@7", "y@11", "x@11", "result@12", "y@12", "z@13", "z@14"}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("InlineValue at return mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
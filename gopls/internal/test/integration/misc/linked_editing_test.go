@@ -0,0 +1,99 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"testing"
+
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+func TestLinkedEditingRange(t *testing.T) {
+	const src = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a.go --
+package a
+
+var global = 1
+
+type S struct {
+	ID   int    ` + "`json:\"id,omitempty\" db:\"id\" yaml:\"ident\"`" + `
+	Name string ` + "`json:\"-\" db:\"-\"`" + `
+}
+
+func f(param int) (result int) {
+	local := param + global
+outer:
+	for i := 0; i < local; i++ {
+		if i > 2 {
+			continue outer
+		}
+		break outer
+	}
+	switch v := any(local).(type) {
+	case int:
+		result = v
+	case string:
+		_ = v
+	}
+	type T struct{}
+	_ = struct{ T }{}
+	return result
+}
+`
+	Run(t, src, func(t *testing.T, env *Env) {
+		env.OpenFile("a.go")
+		for _, test := range []struct {
+			re   string // regexp locating the request position
+			want int    // number of linked ranges, or 0 for none
+		}{
+			{`(local) :=`, 3},
+			{`\+ (global)`, 0}, // package level
+			{`f\((param)`, 2},
+			{`\((result) int\)`, 3},
+			{`continue (outer)`, 3},
+			{`(v) :=`, 3},
+			{`_ = (v)`, 3},
+			{`"(id),omitempty`, 2},
+			{`"(ident)"`, 0}, // no matching name
+			{`json:"(-)"`, 0},
+			{`type (T) struct`, 0}, // embedded as a field
+		} {
+			got := env.LinkedEditingRange(env.RegexpSearch("a.go", test.re))
+			var n int
+			if got != nil {
+				n = len(got.Ranges)
+			}
+			if n != test.want {
+				t.Errorf("LinkedEditingRange(%q) returned %d ranges, want %d", test.re, n, test.want)
+				continue
+			}
+			// All ranges must have the same text.
+			if got != nil {
+				m, err := env.Editor.Mapper("a.go")
+				if err != nil {
+					t.Fatal(err)
+				}
+				var texts []string
+				for _, rng := range got.Ranges {
+					start, end, err := m.RangeOffsets(rng)
+					if err != nil {
+						t.Fatal(err)
+					}
+					texts = append(texts, string(m.Content[start:end]))
+				}
+				for _, text := range texts[1:] {
+					if text != texts[0] {
+						t.Errorf("LinkedEditingRange(%q) returned ranges with differing text: %q", test.re, texts)
+						break
+					}
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	"golang.org/x/tools/gopls/internal/test/compare"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+const moveToPackageSrc = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+import "mod.com/c"
+
+type item struct{ n int }
+
+func Total(xs []int) int {
+	t := 0
+	for _, x := range xs {
+		t += weight(x)
+	}
+	return t + c.Base
+}
+
+func weight(x int) int { return x * scale }
+
+const scale = 2
+
+func Use() int { return Total(nil) }
+-- a/b.go --
+package a
+
+func Other() int { return Total([]int{1}) }
+-- a/h.go --
+package a
+
+func Helper() int { return limit }
+
+const limit = 3
+
+func Cap() int { return limit }
+-- b/b.go --
+package b
+
+var _ = 0
+-- c/c.go --
+package c
+
+const Base = 1
+-- d/d.go --
+package d
+
+import "mod.com/a"
+
+var X = a.Total(nil) + a.Other()
+-- e/e.go --
+package e
+
+import "mod.com/a"
+
+var Y = a.Total(nil)
+`
+
+func TestMoveToPackage(t *testing.T) {
+	Run(t, moveToPackageSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `func (Total)`)
+		moveToPackage(t, env, loc, "mod.com/b")
+
+		want := map[string]string{
+			"a/a.go": `package a
+
+import (
+	"mod.com/b"
+)
+
+type item struct{ n int }
+
+func Use() int { return b.Total(nil) }
+`,
+			"a/b.go": `package a
+
+import "mod.com/b"
+
+func Other() int { return b.Total([]int{1}) }
+`,
+			"b/a.go": `package b
+
+import "mod.com/c"
+
+func Total(xs []int) int {
+	t := 0
+	for _, x := range xs {
+		t += weight(x)
+	}
+	return t + c.Base
+}
+
+func weight(x int) int { return x * scale }
+
+const scale = 2
+`,
+			"d/d.go": `package d
+
+import (
+	"mod.com/a"
+	"mod.com/b"
+)
+
+var X = b.Total(nil) + a.Other()
+`,
+			"e/e.go": `package e
+
+import (
+	"mod.com/b"
+)
+
+var Y = b.Total(nil)
+`,
+		}
+		for _, file := range []string{"a/a.go", "a/b.go", "b/a.go", "d/d.go", "e/e.go"} {
+			env.OpenFile(file)
+			if got := env.BufferText(file); got != want[file] {
+				t.Errorf("%s:\n%s", file, compare.Text(want[file], got))
+			}
+		}
+	})
+}
+
+func TestMoveToPackageExport(t *testing.T) {
+	Run(t, moveToPackageSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/h.go")
+		loc := env.RegexpSearch("a/h.go", `func (Helper)`)
+		moveToPackage(t, env, loc, "mod.com/b")
+
+		want := map[string]string{
+			"a/h.go": `package a
+
+const Limit = 3
+
+func Cap() int { return Limit }
+`,
+			"b/h.go": `package b
+
+import "mod.com/a"
+
+func Helper() int { return a.Limit }
+`,
+		}
+		for _, file := range []string{"a/h.go", "b/h.go"} {
+			env.OpenFile(file)
+			if got := env.BufferText(file); got != want[file] {
+				t.Errorf("%s:\n%s", file, compare.Text(want[file], got))
+			}
+		}
+	})
+}
+
+func TestMoveToPackageCycle(t *testing.T) {
+	Run(t, moveToPackageSrc, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `func (Total)`)
+		args, err := command.MarshalArgs(command.MoveToPackageArgs{
+			Location:     loc,
+			PkgPath:      "mod.com/d",
+			ResolveEdits: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Package d imports a, which would import d.
+		_, err = env.Editor.ExecuteCommand(env.Ctx, &protocol.ExecuteCommandParams{
+			Command:   command.MoveToPackage.ID(),
+			Arguments: args,
+		})
+		if err == nil || !strings.Contains(err.Error(), "which imports it") {
+			t.Errorf("MoveToPackage to mod.com/d: got error %v, want an import cycle", err)
+		}
+	})
+}
+
+// moveToPackage executes the MoveToPackage command at loc, which
+// applies its edits.
+func moveToPackage(t *testing.T, env *Env, loc protocol.Location, pkgPath string) {
+	t.Helper()
+	args, err := command.MarshalArgs(command.MoveToPackageArgs{
+		Location: loc,
+		PkgPath:  pkgPath,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.ExecuteCommand(&protocol.ExecuteCommandParams{
+		Command:   command.MoveToPackage.ID(),
+		Arguments: args,
+	}, nil)
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+func TestNotebook(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- nb/notes.ipynb --
+{}
+-- nb/notes.go --
+package notes
+
+const two = 2
+-- util/util.go --
+package util
+
+func Util() int { return 1 }
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		notebook := string(env.Sandbox.Workdir.URI("nb/notes.ipynb"))
+		cellURI := func(id string) protocol.DocumentURI {
+			return protocol.DocumentURI("vscode-notebook-cell:" + strings.TrimPrefix(notebook, "file://") + "#" + id)
+		}
+		code, markdown, bad, imp := cellURI("a"), cellURI("b"), cellURI("c"), cellURI("d")
+		if err := env.Editor.Server.DidOpenNotebookDocument(env.Ctx, &protocol.DidOpenNotebookDocumentParams{
+			NotebookDocument: protocol.NotebookDocument{
+				URI:          notebook,
+				NotebookType: "jupyter-notebook",
+				Version:      1,
+				Cells: []protocol.NotebookCell{
+					{Kind: protocol.Code, Document: code},
+					{Kind: protocol.Markup, Document: markdown},
+					{Kind: protocol.Code, Document: bad},
+					{Kind: protocol.Code, Document: imp},
+				},
+			},
+			CellTextDocuments: []protocol.TextDocumentItem{
+				{URI: code, LanguageID: "go", Version: 1, Text: "const one = 1\n\nfunc f() int { return one + two }\n"},
+				{URI: markdown, LanguageID: "markdown", Version: 1, Text: "# Notes\n"},
+				{URI: bad, LanguageID: "go", Version: 1, Text: "var x string = f()"},
+				{URI: imp, LanguageID: "go", Version: 1, Text: "var y = util.U"},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		// Diagnostics are reported in the cell, relative to its start.
+		var d protocol.PublishDiagnosticsParams
+		env.Await(Diagnostics(ForFile(string(bad))))
+		env.Await(ReadDiagnostics(string(bad), &d))
+		if got, want := d.Diagnostics[0].Range.Start, (protocol.Position{Line: 0, Character: 15}); got != want {
+			t.Errorf("diagnostic %q at %v, want %v", d.Diagnostics[0].Message, got, want)
+		}
+
+		// Pulled diagnostics are those of the cell too.
+		report, err := env.Editor.Server.Diagnostic(env.Ctx, &protocol.DocumentDiagnosticParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: bad},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		full, ok := report.Value.(protocol.RelatedFullDocumentDiagnosticReport)
+		if !ok || len(full.Items) != 1 || full.Items[0].Range != d.Diagnostics[0].Range {
+			t.Errorf("Diagnostic(cell) = %+v, want the published diagnostic %v", report.Value, d.Diagnostics[0])
+		}
+
+		// Other requests in cells have empty results.
+		symbols, err := env.Editor.Server.DocumentSymbol(env.Ctx, &protocol.DocumentSymbolParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: code},
+		})
+		if err != nil || symbols != nil {
+			t.Errorf("DocumentSymbol(cell) = %v, %v, want empty result", symbols, err)
+		}
+
+		// Fix the error.
+		if err := env.Editor.Server.DidChangeNotebookDocument(env.Ctx, &protocol.DidChangeNotebookDocumentParams{
+			NotebookDocument: protocol.VersionedNotebookDocumentIdentifier{URI: notebook, Version: 2},
+			Change: protocol.NotebookDocumentChangeEvent{
+				Cells: &protocol.NotebookDocumentCellChanges{
+					TextContent: []protocol.NotebookDocumentCellContentChanges{{
+						Document: protocol.VersionedTextDocumentIdentifier{
+							TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: bad},
+							Version:                2,
+						},
+						Changes: []protocol.TextDocumentContentChangeEvent{{
+							Range: &protocol.Range{
+								Start: protocol.Position{Line: 0, Character: 6},
+								End:   protocol.Position{Line: 0, Character: 12},
+							},
+							Text: "int",
+						}},
+					}},
+				},
+			},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		env.Await(NoDiagnostics(ForFile(string(bad))))
+
+		// The notebook file belongs to the package of its directory.
+		env.Await(NoDiagnostics(ForFile(string(code))))
+
+		// Hover and completion of the cell "var x int = f()".
+		at := func(char uint32) protocol.TextDocumentPositionParams {
+			return protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: bad},
+				Position:     protocol.Position{Line: 0, Character: char},
+			}
+		}
+		hover, err := env.Editor.Server.Hover(env.Ctx, &protocol.HoverParams{TextDocumentPositionParams: at(12)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(hover.Contents.Value, "func f() int") {
+			t.Errorf("hover of f: got %q, want its signature", hover.Contents.Value)
+		}
+		if want := (protocol.Range{Start: at(12).Position, End: at(13).Position}); hover.Range != want {
+			t.Errorf("hover range: got %v, want %v", hover.Range, want)
+		}
+
+		list, err := env.Editor.Server.Completion(env.Ctx, &protocol.CompletionParams{TextDocumentPositionParams: at(13)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var found bool
+		for _, item := range list.Items {
+			if item.Label == "f" {
+				found = true
+				if want := (protocol.Range{Start: at(12).Position, End: at(13).Position}); item.TextEdit.Range != want {
+					t.Errorf("completion of f: got range %v, want %v", item.TextEdit.Range, want)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("completion: f not found among %d items", len(list.Items))
+		}
+
+		// Completion of an unimported package inserts its import into
+		// the first cell through the command of the item.
+		list, err = env.Editor.Server.Completion(env.Ctx, &protocol.CompletionParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: imp},
+				Position:     protocol.Position{Line: 0, Character: 14},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var util *protocol.CompletionItem
+		for i := range list.Items {
+			if list.Items[i].Label == "Util" {
+				util = &list.Items[i]
+			}
+		}
+		if util == nil || util.Command == nil {
+			t.Fatalf("completion: no item Util with a command among %v", list.Items)
+		}
+		var args command.ApplyEditsArgs
+		if util.Command.Command != command.ApplyEdits.ID() || command.UnmarshalArgs(util.Command.Arguments, &args) != nil || len(args.Edits) != 1 {
+			t.Fatalf("completion of Util: got command %v, want %s of one cell", util.Command, command.ApplyEdits)
+		}
+		if edit := args.Edits[0]; edit.TextDocument.URI != code || edit.TextDocument.Version != 1 ||
+			len(edit.Edits) != 1 || !strings.Contains(protocol.AsTextEdits(edit.Edits)[0].NewText, `import "mod.com/util"`) {
+			t.Errorf("completion of Util: got edit %+v, want import in version 1 of cell %s", edit, code)
+		}
+
+		// Closing the notebook clears the diagnostics of its cells.
+		if err := env.Editor.Server.DidCloseNotebookDocument(env.Ctx, &protocol.DidCloseNotebookDocumentParams{
+			NotebookDocument:  protocol.NotebookDocumentIdentifier{URI: notebook},
+			CellTextDocuments: []protocol.TextDocumentIdentifier{{URI: code}, {URI: markdown}, {URI: bad}, {URI: imp}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		env.Await(NoDiagnostics())
+	})
+}
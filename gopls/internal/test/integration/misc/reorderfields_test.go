@@ -0,0 +1,162 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package misc
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/test/compare"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+func TestReorderFields(t *testing.T) {
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+type base struct{ id int64 }
+
+type T struct {
+	base
+	// flag is set when the T is ready.
+	flag bool // line comment
+	ptr  *int ` + "`json:\"ptr\"`" + `
+	ok   bool
+	x, y int32
+	n    int64
+}
+
+type U struct {
+	p *int
+	n int64
+}
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `type T (struct)`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reorder *protocol.CodeAction
+		for _, action := range actions {
+			if action.Kind == protocol.RefactorRewrite && strings.HasPrefix(action.Title, "Reorder fields") {
+				reorder = &action
+				break
+			}
+		}
+		if reorder == nil {
+			t.Fatal("could not find reorder fields action")
+		}
+		if want := "Reorder fields (size 48 -> 40, pointer bytes 24 -> 8)"; reorder.Title != want {
+			t.Errorf("got title %q, want %q", reorder.Title, want)
+		}
+
+		env.ApplyCodeAction(*reorder)
+		want := `package a
+
+type base struct{ id int64 }
+
+type T struct {
+	ptr *int ` + "`json:\"ptr\"`" + `
+	base
+	n    int64
+	x, y int32
+
+	// flag is set when the T is ready.
+	flag bool // line comment
+	ok   bool
+}
+
+type U struct {
+	p *int
+	n int64
+}
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("reordered fields:\n%s", compare.Text(want, got))
+		}
+
+		// U is already optimal.
+		loc = env.RegexpSearch("a/a.go", `type U (struct)`)
+		actions, err = env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, action := range actions {
+			if strings.HasPrefix(action.Title, "Reorder fields") {
+				t.Errorf("unexpected action %q for optimal struct", action.Title)
+			}
+		}
+	})
+}
+
+func TestReorderManyFields(t *testing.T) {
+	// T has more fields than sort.Slice sorts by insertion.
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+-- a/a.go --
+package a
+
+type T struct {
+	a, b bool
+	c    int64
+	d, e bool
+	f    int64
+	g, h bool
+	i    int64
+	j, k bool
+	l    int64
+	m, n bool
+	o    int64
+}
+`
+	Run(t, files, func(t *testing.T, env *Env) {
+		env.OpenFile("a/a.go")
+		loc := env.RegexpSearch("a/a.go", `type T (struct)`)
+		actions, err := env.Editor.CodeAction(env.Ctx, loc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var reorder *protocol.CodeAction
+		for _, action := range actions {
+			if action.Kind == protocol.RefactorRewrite && strings.HasPrefix(action.Title, "Reorder fields") {
+				reorder = &action
+				break
+			}
+		}
+		if reorder == nil {
+			t.Fatal("could not find reorder fields action")
+		}
+		env.ApplyCodeAction(*reorder)
+		want := `package a
+
+type T struct {
+	c    int64
+	f    int64
+	i    int64
+	l    int64
+	o    int64
+	a, b bool
+	d, e bool
+	g, h bool
+	j, k bool
+	m, n bool
+}
+`
+		if got := env.BufferText("a/a.go"); got != want {
+			t.Errorf("reordered fields:\n%s", compare.Text(want, got))
+		}
+	})
+}
@@ -5,6 +5,7 @@
 package misc
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -237,3 +238,94 @@ const bad = `
 		}
 	})
 }
+
+func TestSemanticTokensDelta(t *testing.T) {
+	const src = `
+-- go.mod --
+module example.com
+
+go 1.21
+-- main.go --
+package main
+
+func main() {
+	x := 1
+	_ = x
+}
+
+func f() {}
+`
+	WithOptions(
+		Modes(Default),
+		Settings{"semanticTokens": true},
+	).Run(t, src, func(t *testing.T, env *Env) {
+		env.OpenFile("main.go")
+		uri := env.Sandbox.Workdir.URI("main.go")
+		full, err := env.Editor.Server.SemanticTokensFull(env.Ctx, &protocol.SemanticTokensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if full.ResultID == "" {
+			t.Fatal("SemanticTokensFull returned no result ID")
+		}
+
+		env.RegexpReplace("main.go", "x := 1", "y := 1\n\t_ = y\n\tx := 2")
+		resp, err := env.Editor.Server.SemanticTokensFullDelta(env.Ctx, &protocol.SemanticTokensDeltaParams{
+			TextDocument:     protocol.TextDocumentIdentifier{URI: uri},
+			PreviousResultID: full.ResultID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var delta protocol.SemanticTokensDelta
+		if err := json.Unmarshal(data, &delta); err != nil {
+			t.Fatal(err)
+		}
+		if delta.ResultID == "" || delta.ResultID == full.ResultID {
+			t.Fatalf("SemanticTokensFullDelta returned result ID %q, want a new one", delta.ResultID)
+		}
+		if len(delta.Edits) != 1 {
+			t.Fatalf("SemanticTokensFullDelta returned %d edits, want 1", len(delta.Edits))
+		}
+
+		// Applying the delta must yield the current tokens.
+		got := append([]uint32(nil), full.Data...)
+		for _, edit := range delta.Edits {
+			tail := append([]uint32(nil), got[edit.Start+edit.DeleteCount:]...)
+			got = append(append(got[:edit.Start], edit.Data...), tail...)
+		}
+		want, err := env.Editor.Server.SemanticTokensFull(env.Ctx, &protocol.SemanticTokensParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want.Data, got); diff != "" {
+			t.Errorf("applying delta does not yield the current tokens (-want +got):\n%s", diff)
+		}
+		if len(delta.Edits[0].Data) >= len(want.Data) {
+			t.Errorf("delta has %d values, no smaller than the full result (%d)", len(delta.Edits[0].Data), len(want.Data))
+		}
+
+		// Closing the file discards its results, so a delta relative
+		// to a result from before the file was closed is a full result.
+		env.CloseBuffer("main.go")
+		env.OpenFile("main.go")
+		resp, err = env.Editor.Server.SemanticTokensFullDelta(env.Ctx, &protocol.SemanticTokensDeltaParams{
+			TextDocument:     protocol.TextDocumentIdentifier{URI: uri},
+			PreviousResultID: want.ResultID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m, ok := resp.(map[string]interface{}); !ok || m["data"] == nil {
+			t.Errorf("SemanticTokensFullDelta after reopening returned %v, want full tokens", resp)
+		}
+	})
+}
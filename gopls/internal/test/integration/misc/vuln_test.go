@@ -751,6 +751,87 @@ require (
 	})
 }
 
+func TestVulnerableCallDiagnostics(t *testing.T) {
+	db, opts, err := vulnTestEnv(proxy1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Clean()
+	WithOptions(opts...).Run(t, workspace1, func(t *testing.T, env *Env) {
+		env.OpenFile("go.mod")
+		env.OpenFile("x/x.go")
+		env.OpenFile("y/y.go")
+
+		var result command.RunVulncheckResult
+		env.ExecuteCodeLensCommand("go.mod", command.RunGovulncheck, &result)
+		env.OnceMet(
+			CompletedProgress(result.Token, nil),
+			ShownMessage("Found"),
+		)
+
+		// Each call in the call stacks is reported, down to the call of
+		// the vulnerable symbol.
+		var xDiags protocol.PublishDiagnosticsParams
+		env.OnceMet(
+			Diagnostics(
+				env.AtRegexp("x/x.go", `Vuln1`),
+				WithMessage("call to vulnerable function golang.org/amod/avuln.VulnData.Vuln1 (GO-2022-01)"),
+				FromSource(string(cache.Govulncheck)),
+			),
+			Diagnostics(
+				env.AtRegexp("x/x.go", `C1`),
+				WithMessage("call to golang.org/cmod/c.C1 reaches vulnerable function golang.org/amod/avuln.VulnData.Vuln2 (GO-2022-01)"),
+			),
+			Diagnostics(
+				env.AtRegexp("y/y.go", `c.C2\(\)`),
+				WithMessage("call to vulnerable function golang.org/bmod/bvuln.Vuln (GO-2022-02)"),
+			),
+			ReadDiagnostics("x/x.go", &xDiags),
+		)
+
+		// The related information holds the call stack.
+		var diag protocol.Diagnostic
+		for _, d := range xDiags.Diagnostics {
+			if strings.Contains(d.Message, "c.C1") {
+				diag = d
+			}
+		}
+		var related []string
+		for _, r := range diag.RelatedInformation {
+			related = append(related, r.Message)
+		}
+		wantRelated := []string{
+			"golang.org/entry/x.X calls golang.org/cmod/c.C1",
+			"golang.org/cmod/c.C1 calls golang.org/amod/avuln.VulnData.Vuln2",
+		}
+		if diff := cmp.Diff(wantRelated, related); diff != "" {
+			t.Errorf("related information mismatch (-want +got):\n%s", diff)
+		}
+
+		// The "show path" code action renders the call stack.
+		var actions []protocol.CodeAction
+		for _, action := range env.CodeAction("x/x.go", []protocol.Diagnostic{diag}) {
+			if action.Kind == protocol.QuickFix {
+				actions = append(actions, action)
+			}
+		}
+		if diff := diffCodeActions(actions, []string{
+			"Show call path to golang.org/amod/avuln.VulnData.Vuln2",
+			"Reset govulncheck result",
+		}); diff != "" {
+			t.Fatalf("code actions mismatch (-want +got):\n%s", diff)
+		}
+		env.ApplyCodeAction(actions[0])
+		env.Await(ShownMessage("GO-2022-01: call path to golang.org/amod/avuln.VulnData.Vuln2:\n\tgolang.org/entry/x.X (x/x.go:9:6)\n\tgolang.org/cmod/c.C1 ("))
+
+		// Stale calls are not reported.
+		env.RegexpReplace("y/y.go", `c.C2\(\)\(\)`, "_ = c.C2")
+		env.AfterChange(
+			NoDiagnostics(ForFile("y/y.go")),
+		)
+	})
+}
+
 func diffCodeActions(gotActions []protocol.CodeAction, want []string) string {
 	var gotTitles []string
 	for _, ca := range gotActions {
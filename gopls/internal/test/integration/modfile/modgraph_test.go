@@ -0,0 +1,252 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package modfile
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/gopls/internal/protocol"
+	"golang.org/x/tools/gopls/internal/protocol/command"
+	. "golang.org/x/tools/gopls/internal/test/integration"
+)
+
+const modGraphProxy = `
+-- example.com/x@v1.0.0/go.mod --
+module example.com/x
+
+go 1.18
+
+require example.com/y v1.0.0
+-- example.com/x@v1.1.0/go.mod --
+module example.com/x
+
+go 1.18
+
+require example.com/y v1.2.0
+-- example.com/y@v1.0.0/go.mod --
+module example.com/y
+
+go 1.18
+-- example.com/y@v1.1.0/go.mod --
+module example.com/y
+
+go 1.18
+-- example.com/y@v1.2.0/go.mod --
+module example.com/y
+
+go 1.18
+-- example.com/z@v1.0.0/go.mod --
+module example.com/z
+
+go 1.18
+
+require example.com/y v1.1.0
+`
+
+func TestModuleGraph(t *testing.T) {
+	const files = `
+-- go.work --
+go 1.18
+
+use (
+	./a
+	./b
+)
+-- a/go.mod --
+module mod.com/a
+
+go 1.18
+
+require example.com/x v1.0.0
+-- a/a.go --
+package a
+-- b/go.mod --
+module mod.com/b
+
+go 1.18
+
+require example.com/z v1.0.0
+-- b/b.go --
+package b
+`
+	WithOptions(
+		ProxyFiles(modGraphProxy),
+	).Run(t, files, func(t *testing.T, env *Env) {
+		run := func(target string) command.ModuleGraphResult {
+			args, err := command.MarshalArgs(command.ModuleGraphArgs{
+				URI:    env.Sandbox.Workdir.URI("a/go.mod"),
+				Target: target,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result command.ModuleGraphResult
+			env.ExecuteCommand(&protocol.ExecuteCommandParams{
+				Command:   command.ModuleGraph.ID(),
+				Arguments: args,
+			}, &result)
+			return result
+		}
+
+		result := run("")
+		type node struct {
+			name     string
+			selected bool
+			why      []string
+		}
+		var got []node
+		for _, n := range result.Modules {
+			name := n.Path
+			if n.Version != "" {
+				name += "@" + n.Version
+			}
+			got = append(got, node{name, n.Selected, n.Why})
+		}
+		want := []node{
+			{"mod.com/a", true, []string{"mod.com/a"}},
+			{"mod.com/b", true, []string{"mod.com/b"}},
+			{"example.com/x@v1.0.0", true, []string{"mod.com/a", "example.com/x@v1.0.0"}},
+			{"example.com/y@v1.0.0", false, []string{"mod.com/a", "example.com/x@v1.0.0", "example.com/y@v1.0.0"}},
+			{"example.com/y@v1.1.0", true, []string{"mod.com/b", "example.com/z@v1.0.0", "example.com/y@v1.1.0"}},
+			{"example.com/z@v1.0.0", true, []string{"mod.com/b", "example.com/z@v1.0.0"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ModuleGraph: got nodes\n%v\nwant\n%v", got, want)
+		}
+
+		// Upgrading x to its latest version upgrades y, which b must
+		// require to select the same version on its own.
+		plan := run("example.com/x").Upgrade
+		if plan == nil {
+			t.Fatal("ModuleGraph: no upgrade plan")
+		}
+		if want := "example.com/x@v1.1.0"; plan.Target != want {
+			t.Errorf("upgrade target: got %q, want %q", plan.Target, want)
+		}
+		wantUpgrades := []command.ModuleVersionChange{
+			{Path: "example.com/x", From: "v1.0.0", To: "v1.1.0"},
+			{Path: "example.com/y", From: "v1.1.0", To: "v1.2.0"},
+		}
+		if !reflect.DeepEqual(plan.Upgrades, wantUpgrades) {
+			t.Errorf("upgrades: got %v, want %v", plan.Upgrades, wantUpgrades)
+		}
+		wantEdits := []command.ModuleUpgradeEdit{
+			{URI: env.Sandbox.Workdir.URI("a/go.mod"), Require: []string{"example.com/x@v1.1.0"}},
+			{URI: env.Sandbox.Workdir.URI("b/go.mod"), Require: []string{"example.com/y@v1.2.0"}},
+		}
+		if !reflect.DeepEqual(plan.Edits, wantEdits) {
+			t.Errorf("edits: got %v, want %v", plan.Edits, wantEdits)
+		}
+		if len(plan.Missing) > 0 {
+			t.Errorf("missing go.mod files: %v", plan.Missing)
+		}
+	})
+}
+
+func TestModuleGraphPruning(t *testing.T) {
+	// The requirements of q, reached only through p, which is at go
+	// 1.17 or later, are pruned out of the graph, so that r@v1.1.0 is
+	// not selected. Those of s, reached through u, which is before go
+	// 1.17, are not.
+	const proxy = `
+-- example.com/p@v1.0.0/go.mod --
+module example.com/p
+
+go 1.18
+
+require example.com/q v1.0.0
+-- example.com/q@v1.0.0/go.mod --
+module example.com/q
+
+go 1.18
+
+require example.com/r v1.1.0
+-- example.com/r@v1.0.0/go.mod --
+module example.com/r
+
+go 1.18
+-- example.com/r@v1.1.0/go.mod --
+module example.com/r
+
+go 1.18
+-- example.com/u@v1.0.0/go.mod --
+module example.com/u
+
+go 1.16
+
+require example.com/s v1.0.0
+-- example.com/s@v1.0.0/go.mod --
+module example.com/s
+
+go 1.18
+
+require example.com/t v1.1.0
+-- example.com/t@v1.0.0/go.mod --
+module example.com/t
+
+go 1.18
+-- example.com/t@v1.1.0/go.mod --
+module example.com/t
+
+go 1.18
+`
+	const files = `
+-- go.mod --
+module mod.com
+
+go 1.18
+
+require (
+	example.com/p v1.0.0
+	example.com/r v1.0.0
+	example.com/t v1.0.0
+	example.com/u v1.0.0
+)
+-- a.go --
+package a
+`
+	WithOptions(
+		ProxyFiles(proxy),
+	).Run(t, files, func(t *testing.T, env *Env) {
+		args, err := command.MarshalArgs(command.ModuleGraphArgs{
+			URI: env.Sandbox.Workdir.URI("go.mod"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result command.ModuleGraphResult
+		env.ExecuteCommand(&protocol.ExecuteCommandParams{
+			Command:   command.ModuleGraph.ID(),
+			Arguments: args,
+		}, &result)
+
+		type node struct {
+			name             string
+			selected, pruned bool
+		}
+		var got []node
+		for _, n := range result.Modules {
+			name := n.Path
+			if n.Version != "" {
+				name += "@" + n.Version
+			}
+			got = append(got, node{name, n.Selected, n.Pruned})
+		}
+		want := []node{
+			{"mod.com", true, false},
+			{"example.com/p@v1.0.0", true, false},
+			{"example.com/q@v1.0.0", true, true},
+			{"example.com/r@v1.0.0", true, false},
+			{"example.com/s@v1.0.0", true, false},
+			{"example.com/t@v1.0.0", false, false},
+			{"example.com/t@v1.1.0", true, false},
+			{"example.com/u@v1.0.0", true, false},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ModuleGraph: got nodes\n%v\nwant\n%v", got, want)
+		}
+	})
+}
@@ -260,6 +260,39 @@ func (e *Env) DocumentHighlight(loc protocol.Location) []protocol.DocumentHighli
 	return highlights
 }
 
+// LinkedEditingRange invokes textDocument/linkedEditingRange at the given
+// location, calling t.Fatal on any error.
+func (e *Env) LinkedEditingRange(loc protocol.Location) *protocol.LinkedEditingRanges {
+	e.T.Helper()
+	ranges, err := e.Editor.LinkedEditingRange(e.Ctx, loc)
+	if err != nil {
+		e.T.Fatal(err)
+	}
+	return ranges
+}
+
+// DocumentColor invokes textDocument/documentColor for the given path,
+// calling t.Fatal on any error.
+func (e *Env) DocumentColor(path string) []protocol.ColorInformation {
+	e.T.Helper()
+	colors, err := e.Editor.DocumentColor(e.Ctx, path)
+	if err != nil {
+		e.T.Fatal(err)
+	}
+	return colors
+}
+
+// ColorPresentation invokes textDocument/colorPresentation for the
+// given color at the given location, calling t.Fatal on any error.
+func (e *Env) ColorPresentation(loc protocol.Location, color protocol.Color) []protocol.ColorPresentation {
+	e.T.Helper()
+	presentations, err := e.Editor.ColorPresentation(e.Ctx, loc, color)
+	if err != nil {
+		e.T.Fatal(err)
+	}
+	return presentations
+}
+
 // RunGenerate runs "go generate" in the given dir, calling t.Fatal on any error.
 // It waits for the generate command to complete and checks for file changes
 // before returning.
@@ -0,0 +1,136 @@
+// Copyright 2024 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package structlayout_test
+
+import (
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/internal/structlayout"
+)
+
+var (
+	boolType   = types.Typ[types.Bool]
+	int32Type  = types.Typ[types.Int32]
+	int64Type  = types.Typ[types.Int64]
+	stringType = types.Typ[types.String]
+	ptrType    = types.NewPointer(int64Type)
+)
+
+func newStruct(fieldTypes ...types.Type) *types.Struct {
+	var fields []*types.Var
+	for i, t := range fieldTypes {
+		name := string(rune('a' + i))
+		fields = append(fields, types.NewField(token.NoPos, nil, name, t, false))
+	}
+	return types.NewStruct(fields, nil)
+}
+
+var sizes64 = &structlayout.Sizes{WordSize: 8, MaxAlign: 8}
+
+func TestSizes(t *testing.T) {
+	gc := types.SizesFor("gc", "amd64")
+	for _, typ := range []types.Type{
+		boolType,
+		int32Type,
+		stringType,
+		ptrType,
+		types.NewSlice(boolType),
+		types.NewArray(int32Type, 3),
+		types.NewInterfaceType(nil, nil),
+		newStruct(),
+		newStruct(boolType, int64Type, boolType),
+		newStruct(int32Type, newStruct(boolType, stringType)),
+	} {
+		if got, want := sizes64.Sizeof(typ), gc.Sizeof(typ); got != want {
+			t.Errorf("Sizeof(%s) = %d, want %d", typ, got, want)
+		}
+		if got, want := sizes64.Alignof(typ), gc.Alignof(typ); got != want {
+			t.Errorf("Alignof(%s) = %d, want %d", typ, got, want)
+		}
+	}
+}
+
+func TestPtrData(t *testing.T) {
+	for _, test := range []struct {
+		typ  types.Type
+		want int64
+	}{
+		{int64Type, 0},
+		{stringType, 8},
+		{ptrType, 8},
+		{types.NewSlice(boolType), 8},
+		{types.NewInterfaceType(nil, nil), 16},
+		{types.NewArray(stringType, 3), 40},
+		{types.NewArray(int64Type, 3), 0},
+		{newStruct(int64Type, ptrType, boolType), 16},
+		{newStruct(ptrType, int64Type), 8},
+	} {
+		if got := sizes64.PtrData(test.typ); got != test.want {
+			t.Errorf("PtrData(%s) = %d, want %d", test.typ, got, test.want)
+		}
+	}
+}
+
+func TestOptimalOrder(t *testing.T) {
+	for _, test := range []struct {
+		str         *types.Struct
+		wantIndexes []int
+		wantSize    int64
+		wantPtrData int64
+	}{
+		{
+			// Padding around the int64 is removed.
+			newStruct(boolType, int64Type, int32Type),
+			[]int{1, 2, 0},
+			16, 0,
+		},
+		{
+			// Pointers are moved to the front.
+			newStruct(int64Type, ptrType),
+			[]int{1, 0},
+			16, 8,
+		},
+		{
+			// Zero-sized fields come first, then by alignment.
+			newStruct(int32Type, newStruct(), int64Type),
+			[]int{1, 2, 0},
+			16, 0,
+		},
+	} {
+		optimal, indexes := structlayout.OptimalOrder(test.str, sizes64)
+		if !reflect.DeepEqual(indexes, test.wantIndexes) {
+			t.Errorf("OptimalOrder(%s) indexes = %v, want %v", test.str, indexes, test.wantIndexes)
+		}
+		for i, index := range indexes {
+			if optimal.Field(i) != test.str.Field(index) {
+				t.Errorf("OptimalOrder(%s) field %d is %s, want %s", test.str, i, optimal.Field(i), test.str.Field(index))
+			}
+		}
+		if got := sizes64.Sizeof(optimal); got != test.wantSize {
+			t.Errorf("Sizeof(%s) = %d, want %d", optimal, got, test.wantSize)
+		}
+		if got := sizes64.PtrData(optimal); got != test.wantPtrData {
+			t.Errorf("PtrData(%s) = %d, want %d", optimal, got, test.wantPtrData)
+		}
+	}
+}
+
+func TestStableOptimalOrder(t *testing.T) {
+	// More fields than sort.Slice sorts by insertion, alternating
+	// between bools and int64s: each kind keeps its relative order.
+	var fieldTypes []types.Type
+	for i := 0; i < 8; i++ {
+		fieldTypes = append(fieldTypes, boolType, int64Type)
+	}
+	str := newStruct(fieldTypes...)
+	_, indexes := structlayout.StableOptimalOrder(str, sizes64)
+	want := []int{1, 3, 5, 7, 9, 11, 13, 15, 0, 2, 4, 6, 8, 10, 12, 14}
+	if !reflect.DeepEqual(indexes, want) {
+		t.Errorf("StableOptimalOrder(%s) indexes = %v, want %v", str, indexes, want)
+	}
+}